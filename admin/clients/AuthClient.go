@@ -7,17 +7,24 @@ import (
 	"fmt"
 	"go.uber.org/zap"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type AuthClient interface {
 	VerifyAuthToken(token string) (models.UserAuthData, error)
 	GetUsers() ([]models.User, error)
+	// GetUsersByIDs returns the users with the IDs, IDs of users which don't exist are skipped
+	GetUsersByIDs(ids []int) ([]models.User, error)
 	UpdateUser(user models.UserPayload) error
 	GetUser(id string) (models.User, error)
 	DeleteUser(id string) error
 	GetSummary() (models.AuthSummary, error)
+	GetCohortMembers(id string) (models.CohortMembers, error)
 }
 
+var ErrCohortNotFound = fmt.Errorf("cohort not found")
+
 type RestAuthClient struct {
 	addr   string
 	apiKey string
@@ -109,6 +116,38 @@ func (c *RestAuthClient) GetUsers() ([]models.User, error) {
 	return users, nil
 }
 
+func (c *RestAuthClient) GetUsersByIDs(ids []int) ([]models.User, error) {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	req, err := c.NewRequestWithAuth("GET", "/users?ids="+strings.Join(values, ","), nil)
+	if err != nil {
+		c.logger.Error("failed to create request", zap.Error(err))
+		return nil, err
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.logger.Error("failed to send request", zap.Error(err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var users []models.User
+	err = json.NewDecoder(resp.Body).Decode(&users)
+	if err != nil {
+		c.logger.Error("failed to decode response", zap.Error(err))
+		return nil, err
+	}
+	return users, nil
+}
+
 func (c *RestAuthClient) UpdateUser(user models.UserPayload) error {
 	req, err := c.NewRequestWithAuth("PATCH", "/users/"+user.ID, user)
 	if err != nil {
@@ -199,3 +238,32 @@ func (c *RestAuthClient) GetSummary() (models.AuthSummary, error) {
 	}
 	return summary, nil
 }
+
+func (c *RestAuthClient) GetCohortMembers(id string) (models.CohortMembers, error) {
+	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/cohorts/%s/members", id), nil)
+	if err != nil {
+		c.logger.Error("failed to create request", zap.Error(err))
+		return models.CohortMembers{}, err
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.logger.Error("failed to send request", zap.Error(err))
+		return models.CohortMembers{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return models.CohortMembers{}, ErrCohortNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return models.CohortMembers{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var members models.CohortMembers
+	err = json.NewDecoder(resp.Body).Decode(&members)
+	if err != nil {
+		c.logger.Error("failed to decode response", zap.Error(err))
+		return models.CohortMembers{}, err
+	}
+	return members, nil
+}
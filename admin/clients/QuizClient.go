@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"go.uber.org/zap"
+	"io"
 	"net/http"
+	"net/url"
 )
 
 type QuizClient interface {
@@ -16,6 +18,10 @@ type QuizClient interface {
 	GetAllOptions() ([]models.Option, error)
 	GetQuestion(id string) (models.Question, error)
 	UpdateQuestion(id string, question models.Question) error
+	UpdateQuestionStatus(id string, status string) (models.Question, error)
+	PreviewQuestion(id string, userID int) (models.PreviewSession, error)
+	GetQuestionVersions(id string) ([]models.QuestionVersion, error)
+	GetQuestionVersionsDiff(id string, from string, to string) (models.QuestionVersionsDiff, error)
 	CreateParameter(parameter models.Parameter) (models.Parameter, error)
 	UpdateOption(id string, option models.Option) error
 	CreateOption(option models.Option) (models.Option, error)
@@ -24,8 +30,49 @@ type QuizClient interface {
 	UpdateParametersOrder(order []models.Parameter) error
 	GetSettings() ([]models.Settings, error)
 	UpdateSettings(settings []models.Settings) error
+	GetAllGroups() ([]models.QuestionsGroup, error)
+	GetGroup(id string) (models.QuestionsGroup, error)
+	CreateGroup(group models.QuestionsGroup) (models.QuestionsGroup, error)
+	UpdateGroup(id string, group models.QuestionsGroup) error
+	DeleteGroup(id string) error
+	UpdateGroupsOrder(order []models.QuestionsGroup) error
+	MoveQuestionsToGroup(id string, payload models.GroupQuestionsPayload) error
+	GetAllCases() ([]models.Case, error)
+	GetCase(id string) (models.Case, error)
+	CreateCase(newCase models.Case) (models.Case, error)
+	UpdateCase(id string, updatedCase models.Case) (models.Case, error)
+	DeleteCase(id string) error
+	GetCaseLandmarks(id string) ([]models.Landmark, error)
+	UpdateCaseLandmarks(id string, landmarks []models.Landmark) ([]models.Landmark, error)
+	ImportQuestionBank(archive []byte, dryRun bool) (models.ImportReport, error)
+	ExportQuestionBank() ([]byte, string, error)
+	ImportQTI(archive []byte, dryRun bool) (models.ImportReport, error)
+	ExportQTI() ([]byte, string, error)
+	GetAssignments(teacherID int) ([]models.Assignment, error)
+	GetAssignment(id string) (models.Assignment, error)
+	CreateAssignment(assignment models.Assignment) (models.Assignment, error)
+	UpdateAssignment(id string, assignment models.Assignment) (models.Assignment, error)
+	DeleteAssignment(id string) error
+	GetAssignmentSessions(id string) ([]models.AssignmentSession, error)
+	GetTranslations(entity string, id string) ([]models.Translation, error)
+	SaveTranslation(translation models.Translation) (models.Translation, error)
+	DeleteTranslation(entity string, id string, field string, locale string) error
+	GetMissingTranslations(locale string) ([]models.MissingTranslation, error)
+	GetStuckOutboxEvents() ([]models.OutboxEvent, error)
+	RetryOutboxEvent(id string) error
 }
 
+var ErrAssignmentNotFound = fmt.Errorf("assignment not found")
+var ErrQuestionVersionNotFound = fmt.Errorf("question version not found")
+var ErrQuestionNotFound = fmt.Errorf("question not found")
+var ErrQuestionStatusConflict = fmt.Errorf("question status cannot be changed")
+var ErrInvalidAssignment = fmt.Errorf("invalid assignment")
+var ErrTranslationNotFound = fmt.Errorf("translation not found")
+var ErrInvalidTranslation = fmt.Errorf("invalid translation")
+var ErrCaseNotFound = fmt.Errorf("case not found")
+var ErrInvalidLandmarks = fmt.Errorf("invalid landmarks")
+var ErrOutboxEventNotFound = fmt.Errorf("outbox event not found")
+
 type QuizRestClient struct {
 	addr   string
 	apiKey string
@@ -93,6 +140,104 @@ func (c *QuizRestClient) GetQuestion(id string) (models.Question, error) {
 	return question, err
 }
 
+func (c *QuizRestClient) UpdateQuestionStatus(id string, status string) (models.Question, error) {
+	var question models.Question
+	req, err := c.NewRequestWithAuth("PUT", fmt.Sprintf("/questions/%s/status", id), models.QuestionStatusPayload{Status: status})
+	if err != nil {
+		return question, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return question, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return question, ErrQuestionNotFound
+	case http.StatusBadRequest, http.StatusConflict:
+		message, _ := io.ReadAll(resp.Body)
+		return question, fmt.Errorf("%w: %s", ErrQuestionStatusConflict, bytes.TrimSpace(message))
+	default:
+		return question, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&question)
+	return question, err
+}
+
+// PreviewQuestion creates a sandbox session in which the user answers only the question
+func (c *QuizRestClient) PreviewQuestion(id string, userID int) (models.PreviewSession, error) {
+	var session models.PreviewSession
+	req, err := c.NewRequestWithAuth("POST", fmt.Sprintf("/questions/%s/preview", id), map[string]int{"user_id": userID})
+	if err != nil {
+		return session, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return session, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return session, ErrQuestionNotFound
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return session, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&session)
+	return session, err
+}
+
+func (c *QuizRestClient) GetQuestionVersions(id string) ([]models.QuestionVersion, error) {
+	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/questions/%s/versions", id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var versions []models.QuestionVersion
+	err = json.NewDecoder(resp.Body).Decode(&versions)
+	return versions, err
+}
+
+// GetQuestionVersionsDiff compares two versions of the question, empty from and to compare the latest two versions
+func (c *QuizRestClient) GetQuestionVersionsDiff(id string, from string, to string) (models.QuestionVersionsDiff, error) {
+	query := url.Values{}
+	if from != "" {
+		query.Set("from", from)
+	}
+	if to != "" {
+		query.Set("to", to)
+	}
+	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/questions/%s/versions/diff?%s", id, query.Encode()), nil)
+	if err != nil {
+		return models.QuestionVersionsDiff{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.QuestionVersionsDiff{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return models.QuestionVersionsDiff{}, ErrQuestionVersionNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return models.QuestionVersionsDiff{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var diff models.QuestionVersionsDiff
+	err = json.NewDecoder(resp.Body).Decode(&diff)
+	return diff, err
+}
+
 func (c *QuizRestClient) GetAllParameters() ([]models.Parameter, error) {
 	req, err := c.NewRequestWithAuth("GET", "/parameters", nil)
 	if err != nil {
@@ -313,3 +458,574 @@ func (c *QuizRestClient) UpdateSettings(settings []models.Settings) error {
 	}
 	return nil
 }
+
+func (c *QuizRestClient) GetAllGroups() ([]models.QuestionsGroup, error) {
+	req, err := c.NewRequestWithAuth("GET", "/groups", nil)
+	if err != nil {
+		return []models.QuestionsGroup{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return []models.QuestionsGroup{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return []models.QuestionsGroup{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var groups []models.QuestionsGroup
+	err = json.NewDecoder(resp.Body).Decode(&groups)
+	return groups, err
+}
+func (c *QuizRestClient) GetGroup(id string) (models.QuestionsGroup, error) {
+	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/groups/%s", id), nil)
+	if err != nil {
+		return models.QuestionsGroup{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.QuestionsGroup{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.QuestionsGroup{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var group models.QuestionsGroup
+	err = json.NewDecoder(resp.Body).Decode(&group)
+	return group, err
+}
+func (c *QuizRestClient) CreateGroup(group models.QuestionsGroup) (models.QuestionsGroup, error) {
+	req, err := c.NewRequestWithAuth("POST", "/groups", group)
+	if err != nil {
+		return models.QuestionsGroup{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.QuestionsGroup{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return models.QuestionsGroup{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var createdGroup models.QuestionsGroup
+	err = json.NewDecoder(resp.Body).Decode(&createdGroup)
+	if err != nil {
+		return models.QuestionsGroup{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return createdGroup, nil
+}
+func (c *QuizRestClient) UpdateGroup(id string, group models.QuestionsGroup) error {
+	req, err := c.NewRequestWithAuth("PUT", fmt.Sprintf("/groups/%s", id), group)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+func (c *QuizRestClient) DeleteGroup(id string) error {
+	req, err := c.NewRequestWithAuth("DELETE", fmt.Sprintf("/groups/%s", id), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+func (c *QuizRestClient) UpdateGroupsOrder(order []models.QuestionsGroup) error {
+	req, err := c.NewRequestWithAuth("PUT", "/groups/order", order)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+func (c *QuizRestClient) MoveQuestionsToGroup(id string, payload models.GroupQuestionsPayload) error {
+	req, err := c.NewRequestWithAuth("PUT", fmt.Sprintf("/groups/%s/questions", id), payload)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *QuizRestClient) GetAllCases() ([]models.Case, error) {
+	req, err := c.NewRequestWithAuth("GET", "/cases", nil)
+	if err != nil {
+		return []models.Case{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return []models.Case{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return []models.Case{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var cases []models.Case
+	err = json.NewDecoder(resp.Body).Decode(&cases)
+	return cases, err
+}
+func (c *QuizRestClient) GetCase(id string) (models.Case, error) {
+	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/cases/%s", id), nil)
+	if err != nil {
+		return models.Case{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.Case{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.Case{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var dbCase models.Case
+	err = json.NewDecoder(resp.Body).Decode(&dbCase)
+	return dbCase, err
+}
+func (c *QuizRestClient) CreateCase(newCase models.Case) (models.Case, error) {
+	req, err := c.NewRequestWithAuth("POST", "/cases", newCase)
+	if err != nil {
+		return models.Case{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.Case{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return models.Case{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var createdCase models.Case
+	err = json.NewDecoder(resp.Body).Decode(&createdCase)
+	if err != nil {
+		return models.Case{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return createdCase, nil
+}
+func (c *QuizRestClient) UpdateCase(id string, updatedCase models.Case) (models.Case, error) {
+	req, err := c.NewRequestWithAuth("PUT", fmt.Sprintf("/cases/%s", id), updatedCase)
+	if err != nil {
+		return models.Case{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.Case{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.Case{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var dbCase models.Case
+	err = json.NewDecoder(resp.Body).Decode(&dbCase)
+	if err != nil {
+		return models.Case{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return dbCase, nil
+}
+func (c *QuizRestClient) DeleteCase(id string) error {
+	req, err := c.NewRequestWithAuth("DELETE", fmt.Sprintf("/cases/%s", id), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ImportQuestionBank sends the zip archive to the quiz service, the report is returned also when the import is rejected
+func (c *QuizRestClient) ImportQuestionBank(archive []byte, dryRun bool) (models.ImportReport, error) {
+	return c.importArchive("/import", archive, dryRun)
+}
+
+// ImportQTI sends the QTI 2.1 content package to the quiz service
+func (c *QuizRestClient) ImportQTI(archive []byte, dryRun bool) (models.ImportReport, error) {
+	return c.importArchive("/import/qti", archive, dryRun)
+}
+
+func (c *QuizRestClient) importArchive(path string, archive []byte, dryRun bool) (models.ImportReport, error) {
+	var report models.ImportReport
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s%s?dry_run=%t", c.addr, path, dryRun), bytes.NewReader(archive))
+	if err != nil {
+		return report, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/zip")
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return report, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusUnprocessableEntity {
+		return report, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return report, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return report, nil
+}
+
+// ExportQuestionBank returns the question bank archive and the Content-Disposition header set by the quiz service
+func (c *QuizRestClient) ExportQuestionBank() ([]byte, string, error) {
+	return c.exportArchive("/export")
+}
+
+// ExportQTI returns the question bank as a QTI 2.1 content package
+func (c *QuizRestClient) ExportQTI() ([]byte, string, error) {
+	return c.exportArchive("/export/qti")
+}
+
+func (c *QuizRestClient) exportArchive(path string) ([]byte, string, error) {
+	req, err := c.NewRequestWithAuth("GET", path, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	archive, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return archive, resp.Header.Get("Content-Disposition"), nil
+}
+
+// GetAssignments returns assignments of the teacher, or all assignments when teacherID is 0
+func (c *QuizRestClient) GetAssignments(teacherID int) ([]models.Assignment, error) {
+	path := "/assignments"
+	if teacherID != 0 {
+		path = fmt.Sprintf("/assignments?teacher_id=%d", teacherID)
+	}
+	req, err := c.NewRequestWithAuth("GET", path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var assignments []models.Assignment
+	err = json.NewDecoder(resp.Body).Decode(&assignments)
+	return assignments, err
+}
+
+func (c *QuizRestClient) GetAssignment(id string) (models.Assignment, error) {
+	return c.sendAssignment("GET", fmt.Sprintf("/assignments/%s", id), nil, http.StatusOK)
+}
+
+func (c *QuizRestClient) CreateAssignment(assignment models.Assignment) (models.Assignment, error) {
+	return c.sendAssignment("POST", "/assignments", assignment, http.StatusCreated)
+}
+
+func (c *QuizRestClient) UpdateAssignment(id string, assignment models.Assignment) (models.Assignment, error) {
+	return c.sendAssignment("PUT", fmt.Sprintf("/assignments/%s", id), assignment, http.StatusOK)
+}
+
+// sendAssignment sends the request and decodes the assignment returned by the quiz service. Validation errors
+// are returned as ErrInvalidAssignment with the message of the quiz service
+func (c *QuizRestClient) sendAssignment(method string, path string, body interface{}, expectedStatus int) (models.Assignment, error) {
+	var assignment models.Assignment
+	req, err := c.NewRequestWithAuth(method, path, body)
+	if err != nil {
+		return assignment, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return assignment, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case expectedStatus:
+	case http.StatusNotFound:
+		return assignment, ErrAssignmentNotFound
+	case http.StatusBadRequest:
+		message, _ := io.ReadAll(resp.Body)
+		return assignment, fmt.Errorf("%w: %s", ErrInvalidAssignment, bytes.TrimSpace(message))
+	default:
+		return assignment, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&assignment)
+	if err != nil {
+		return assignment, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return assignment, nil
+}
+
+func (c *QuizRestClient) DeleteAssignment(id string) error {
+	req, err := c.NewRequestWithAuth("DELETE", fmt.Sprintf("/assignments/%s", id), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrAssignmentNotFound
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *QuizRestClient) GetAssignmentSessions(id string) ([]models.AssignmentSession, error) {
+	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/assignments/%s/sessions", id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var sessions []models.AssignmentSession
+	err = json.NewDecoder(resp.Body).Decode(&sessions)
+	return sessions, err
+}
+
+func (c *QuizRestClient) GetTranslations(entity string, id string) ([]models.Translation, error) {
+	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/translations/%s/%s", url.PathEscape(entity), url.PathEscape(id)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		message, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTranslation, bytes.TrimSpace(message))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var translations []models.Translation
+	err = json.NewDecoder(resp.Body).Decode(&translations)
+	return translations, err
+}
+
+// SaveTranslation creates or replaces the translation, ErrTranslationNotFound is returned when the translated entity does not exist
+func (c *QuizRestClient) SaveTranslation(translation models.Translation) (models.Translation, error) {
+	req, err := c.NewRequestWithAuth("PUT", "/translations", translation)
+	if err != nil {
+		return translation, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return translation, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return translation, ErrTranslationNotFound
+	case http.StatusBadRequest:
+		message, _ := io.ReadAll(resp.Body)
+		return translation, fmt.Errorf("%w: %s", ErrInvalidTranslation, bytes.TrimSpace(message))
+	default:
+		return translation, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&translation)
+	return translation, err
+}
+
+func (c *QuizRestClient) DeleteTranslation(entity string, id string, field string, locale string) error {
+	path := fmt.Sprintf("/translations/%s/%s/%s/%s", url.PathEscape(entity), url.PathEscape(id), url.PathEscape(field), url.PathEscape(locale))
+	req, err := c.NewRequestWithAuth("DELETE", path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrTranslationNotFound
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetMissingTranslations lists the fields without a translation in the locale
+func (c *QuizRestClient) GetMissingTranslations(locale string) ([]models.MissingTranslation, error) {
+	req, err := c.NewRequestWithAuth("GET", "/translations/missing?locale="+url.QueryEscape(locale), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		message, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTranslation, bytes.TrimSpace(message))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var missing []models.MissingTranslation
+	err = json.NewDecoder(resp.Body).Decode(&missing)
+	return missing, err
+}
+
+// GetCaseLandmarks returns the reference landmarks of the case
+func (c *QuizRestClient) GetCaseLandmarks(id string) ([]models.Landmark, error) {
+	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/cases/%s/landmarks", url.PathEscape(id)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrCaseNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var payload models.CaseLandmarks
+	err = json.NewDecoder(resp.Body).Decode(&payload)
+	return payload.Landmarks, err
+}
+
+// UpdateCaseLandmarks replaces the reference landmarks of the case
+func (c *QuizRestClient) UpdateCaseLandmarks(id string, landmarks []models.Landmark) ([]models.Landmark, error) {
+	req, err := c.NewRequestWithAuth("PUT", fmt.Sprintf("/cases/%s/landmarks", url.PathEscape(id)), models.CaseLandmarks{Landmarks: landmarks})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrCaseNotFound
+	case http.StatusBadRequest:
+		message, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLandmarks, bytes.TrimSpace(message))
+	default:
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var payload models.CaseLandmarks
+	err = json.NewDecoder(resp.Body).Decode(&payload)
+	return payload.Landmarks, err
+}
+
+// GetStuckOutboxEvents lists events the quiz service repeatedly failed to deliver to the stats service
+func (c *QuizRestClient) GetStuckOutboxEvents() ([]models.OutboxEvent, error) {
+	req, err := c.NewRequestWithAuth("GET", "/outbox/stuck", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var events []models.OutboxEvent
+	err = json.NewDecoder(resp.Body).Decode(&events)
+	return events, err
+}
+
+func (c *QuizRestClient) RetryOutboxEvent(id string) error {
+	req, err := c.NewRequestWithAuth("POST", fmt.Sprintf("/outbox/%s/retry", url.PathEscape(id)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrOutboxEventNotFound
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -7,21 +7,29 @@ import (
 	"fmt"
 	"go.uber.org/zap"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
 type StatsClient interface {
 	GetUserStats(userID string) (models.UserStats, error)
-	GetAllResponses() ([]models.QuestionResponse, error)
-	GetStatsForQuestion(id string) (models.QuestionStats, error)
-	GetStatsForAllQuestions() ([]models.QuestionStats, error)
-	GetActivityStats() ([]models.ActivityStats, error)
+	// cohortID restricts the stats to members of the cohort, an empty cohortID means all users
+	GetAllResponses(cohortID string) ([]models.QuestionResponse, error)
+	GetStatsForQuestion(id string, cohortID string) (models.QuestionStats, error)
+	GetLandmarkAnswers(questionID string, cohortID string) ([]models.LandmarkAnswer, error)
+	GetStatsForAllQuestions(cohortID string) ([]models.QuestionStats, error)
+	GetActivityStats(cohortID string) ([]models.ActivityStats, error)
 	GetSummary() (models.StatsSummary, error)
 	GetSurvey(id string) (models.SurveyResponse, error)
 	GetAllSurveys() ([]models.SurveyResponse, error)
-	GetStatsGroupedBySurvey(groupBy string) ([]models.SurveyGroupedStats, error)
+	GetStatsGroupedBySurvey(groupBy string, cohortID string) ([]models.SurveyGroupedStats, error)
+	GetUserCalibration(userID string) (models.Calibration, error)
+	GetCalibrationGroupedBySurvey(groupBy string, cohortID string) ([]models.SurveyGroupedCalibration, error)
 	DeleteResponse(id string) error
 	DeleteUserResponses(id string) error
-	GetAllUsersStats() ([]models.UserQuizStats, error)
+	GetAllUsersStats(cohortID string) ([]models.UserQuizStats, error)
+	GetSessionsScores(sessionsIDs []int) ([]models.SessionScore, error)
 }
 
 type StatsRestClient struct {
@@ -54,6 +62,18 @@ func (c *StatsRestClient) NewRequestWithAuth(method, path string, body interface
 	return req, nil
 }
 
+// withCohort appends the cohort filter to the path of a stats request
+func withCohort(path string, cohortID string) string {
+	if cohortID == "" {
+		return path
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + "cohort_id=" + url.QueryEscape(cohortID)
+}
+
 func (c *StatsRestClient) MakeRequest(req *http.Request) (*http.Response, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -85,8 +105,8 @@ func (c *StatsRestClient) GetUserStats(userID string) (models.UserStats, error)
 	return userStats, nil
 }
 
-func (c *StatsRestClient) GetAllResponses() ([]models.QuestionResponse, error) {
-	req, err := c.NewRequestWithAuth("GET", "/responses", nil)
+func (c *StatsRestClient) GetAllResponses(cohortID string) ([]models.QuestionResponse, error) {
+	req, err := c.NewRequestWithAuth("GET", withCohort("/responses", cohortID), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -105,8 +125,8 @@ func (c *StatsRestClient) GetAllResponses() ([]models.QuestionResponse, error) {
 	}
 	return responses, nil
 }
-func (c *StatsRestClient) GetStatsForQuestion(id string) (models.QuestionStats, error) {
-	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/questions/%s/stats", id), nil)
+func (c *StatsRestClient) GetStatsForQuestion(id string, cohortID string) (models.QuestionStats, error) {
+	req, err := c.NewRequestWithAuth("GET", withCohort(fmt.Sprintf("/questions/%s/stats", id), cohortID), nil)
 	if err != nil {
 		return models.QuestionStats{}, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -125,8 +145,31 @@ func (c *StatsRestClient) GetStatsForQuestion(id string) (models.QuestionStats,
 	}
 	return stats, nil
 }
-func (c *StatsRestClient) GetStatsForAllQuestions() ([]models.QuestionStats, error) {
-	req, err := c.NewRequestWithAuth("GET", "/questions/-/stats", nil)
+
+// GetLandmarkAnswers returns the placed points of the answers to a landmark question
+func (c *StatsRestClient) GetLandmarkAnswers(questionID string, cohortID string) ([]models.LandmarkAnswer, error) {
+	req, err := c.NewRequestWithAuth("GET", withCohort(fmt.Sprintf("/questions/%s/landmarks", questionID), cohortID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var answers []models.LandmarkAnswer
+	err = json.NewDecoder(resp.Body).Decode(&answers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
+	return answers, nil
+}
+
+func (c *StatsRestClient) GetStatsForAllQuestions(cohortID string) ([]models.QuestionStats, error) {
+	req, err := c.NewRequestWithAuth("GET", withCohort("/questions/-/stats", cohortID), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -146,8 +189,8 @@ func (c *StatsRestClient) GetStatsForAllQuestions() ([]models.QuestionStats, err
 	return stats, nil
 }
 
-func (c *StatsRestClient) GetActivityStats() ([]models.ActivityStats, error) {
-	req, err := c.NewRequestWithAuth("GET", "/activity", nil)
+func (c *StatsRestClient) GetActivityStats(cohortID string) ([]models.ActivityStats, error) {
+	req, err := c.NewRequestWithAuth("GET", withCohort("/activity", cohortID), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -228,8 +271,8 @@ func (c *StatsRestClient) GetAllSurveys() ([]models.SurveyResponse, error) {
 	return surveys, nil
 }
 
-func (c *StatsRestClient) GetStatsGroupedBySurvey(groupBy string) ([]models.SurveyGroupedStats, error) {
-	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/grouped?groupBy=%s", groupBy), nil)
+func (c *StatsRestClient) GetStatsGroupedBySurvey(groupBy string, cohortID string) ([]models.SurveyGroupedStats, error) {
+	req, err := c.NewRequestWithAuth("GET", withCohort(fmt.Sprintf("/grouped?groupBy=%s", groupBy), cohortID), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -246,6 +289,45 @@ func (c *StatsRestClient) GetStatsGroupedBySurvey(groupBy string) ([]models.Surv
 	return stats, err
 }
 
+func (c *StatsRestClient) GetUserCalibration(userID string) (models.Calibration, error) {
+	req, err := c.NewRequestWithAuth("GET", fmt.Sprintf("/users/%s/calibration", userID), nil)
+	if err != nil {
+		return models.Calibration{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return models.Calibration{}, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.Calibration{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var calibration models.Calibration
+	err = json.NewDecoder(resp.Body).Decode(&calibration)
+	if err != nil {
+		return models.Calibration{}, fmt.Errorf("failed to decode response body: %w", err)
+	}
+	return calibration, nil
+}
+
+func (c *StatsRestClient) GetCalibrationGroupedBySurvey(groupBy string, cohortID string) ([]models.SurveyGroupedCalibration, error) {
+	req, err := c.NewRequestWithAuth("GET", withCohort(fmt.Sprintf("/calibration/grouped?groupBy=%s", url.QueryEscape(groupBy)), cohortID), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var stats []models.SurveyGroupedCalibration
+	err = json.NewDecoder(resp.Body).Decode(&stats)
+	return stats, err
+}
+
 func (c *StatsRestClient) DeleteResponse(id string) error {
 	req, err := c.NewRequestWithAuth("DELETE", fmt.Sprintf("/responses/%s", id), nil)
 	if err != nil {
@@ -277,8 +359,8 @@ func (c *StatsRestClient) DeleteUserResponses(id string) error {
 	}
 	return nil
 }
-func (c *StatsRestClient) GetAllUsersStats() ([]models.UserQuizStats, error) {
-	req, err := c.NewRequestWithAuth("GET", "/users/stats", nil)
+func (c *StatsRestClient) GetAllUsersStats(cohortID string) ([]models.UserQuizStats, error) {
+	req, err := c.NewRequestWithAuth("GET", withCohort("/users/stats", cohortID), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -292,3 +374,26 @@ func (c *StatsRestClient) GetAllUsersStats() ([]models.UserQuizStats, error) {
 	err = json.NewDecoder(resp.Body).Decode(&stats)
 	return stats, err
 }
+
+func (c *StatsRestClient) GetSessionsScores(sessionsIDs []int) ([]models.SessionScore, error) {
+	ids := make([]string, len(sessionsIDs))
+	for i, id := range sessionsIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	req, err := c.NewRequestWithAuth("GET", "/sessions/scores?ids="+strings.Join(ids, ","), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var scores []models.SessionScore
+	err = json.NewDecoder(resp.Body).Decode(&scores)
+	return scores, err
+}
@@ -71,18 +71,26 @@ func (a *ApiServer) Run() {
 }
 
 func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
+	// VerifyAdmin lets teachers read, reads of data of all users which can't be limited
+	// to the cohorts of a teacher are guarded by RequireAdmin
+
 	// users
 	usersHandler := handlers.NewUsersHandler(a.logger, a.authClient, a.statsClient)
-	mux.HandleFunc("GET /admin/users", middleware.VerifyAdmin(usersHandler.GetUsers, a.authClient))
-	mux.HandleFunc("GET /admin/users/{id}", middleware.VerifyAdmin(usersHandler.GetUserDetails, a.authClient))
+	mux.HandleFunc("GET /admin/users", middleware.RequireAdmin(usersHandler.GetUsers, a.authClient))
+	mux.HandleFunc("GET /admin/users/{id}", middleware.RequireAdmin(usersHandler.GetUserDetails, a.authClient))
+	mux.HandleFunc("GET /admin/users/{id}/calibration", middleware.RequireAdmin(usersHandler.GetUserCalibration, a.authClient))
 	mux.HandleFunc("PATCH /admin/users/{id}", middleware.VerifyAdmin(usersHandler.UpdateUser, a.authClient))
 	mux.HandleFunc("DELETE /admin/users/{id}", middleware.VerifyAdmin(usersHandler.DeleteUser, a.authClient))
-	mux.HandleFunc("GET /admin/users/-/surveys", middleware.VerifyAdmin(usersHandler.GetAllUsersSurveys, a.authClient))
+	mux.HandleFunc("GET /admin/users/-/surveys", middleware.RequireAdmin(usersHandler.GetAllUsersSurveys, a.authClient))
 
 	// quiz
 	quizHandler := handlers.NewQuizHandler(a.logger, a.quizClient, a.statsClient)
 	mux.HandleFunc("GET /admin/questions", middleware.VerifyAdmin(quizHandler.GetAllQuestions, a.authClient))
 	mux.HandleFunc("GET /admin/questions/{id}", middleware.VerifyAdmin(quizHandler.GetQuestion, a.authClient))
+	mux.HandleFunc("PUT /admin/questions/{id}/status", middleware.VerifyAdmin(quizHandler.UpdateQuestionStatus, a.authClient))
+	mux.HandleFunc("POST /admin/questions/{id}/preview", middleware.VerifyAdmin(quizHandler.PreviewQuestion, a.authClient))
+	mux.HandleFunc("GET /admin/questions/{id}/versions", middleware.VerifyAdmin(quizHandler.GetQuestionVersions, a.authClient))
+	mux.HandleFunc("GET /admin/questions/{id}/versions/diff", middleware.VerifyAdmin(quizHandler.GetQuestionVersionsDiff, a.authClient))
 	mux.HandleFunc("GET /admin/parameters", middleware.VerifyAdmin(quizHandler.GetAllParameters, a.authClient))
 	mux.HandleFunc("POST /admin/parameters", middleware.VerifyAdmin(quizHandler.CreateParameter, a.authClient))
 	mux.HandleFunc("PATCH /admin/parameters/{id}", middleware.VerifyAdmin(quizHandler.UpdateParameter, a.authClient))
@@ -93,18 +101,57 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("PATCH /admin/questions/{id}", middleware.VerifyAdmin(quizHandler.UpdateQuestion, a.authClient))
 	mux.HandleFunc("PUT /admin/parameters/order", middleware.VerifyAdmin(quizHandler.UpdateParametersOrder, a.authClient))
 
+	mux.HandleFunc("GET /admin/translations/missing", middleware.VerifyAdmin(quizHandler.GetMissingTranslations, a.authClient))
+	mux.HandleFunc("GET /admin/translations/{entity}/{id}", middleware.VerifyAdmin(quizHandler.GetTranslations, a.authClient))
+	mux.HandleFunc("PUT /admin/translations", middleware.VerifyAdmin(quizHandler.SaveTranslation, a.authClient))
+	mux.HandleFunc("DELETE /admin/translations/{entity}/{id}/{field}/{locale}", middleware.VerifyAdmin(quizHandler.DeleteTranslation, a.authClient))
+	mux.HandleFunc("GET /admin/outbox/stuck", middleware.RequireAdmin(quizHandler.GetStuckOutboxEvents, a.authClient))
+	mux.HandleFunc("POST /admin/outbox/{id}/retry", middleware.VerifyAdmin(quizHandler.RetryOutboxEvent, a.authClient))
+
+	mux.HandleFunc("GET /admin/groups", middleware.VerifyAdmin(quizHandler.GetAllGroups, a.authClient))
+	mux.HandleFunc("GET /admin/groups/{id}", middleware.VerifyAdmin(quizHandler.GetGroup, a.authClient))
+	mux.HandleFunc("POST /admin/groups", middleware.VerifyAdmin(quizHandler.CreateGroup, a.authClient))
+	mux.HandleFunc("PUT /admin/groups/{id}", middleware.VerifyAdmin(quizHandler.UpdateGroup, a.authClient))
+	mux.HandleFunc("DELETE /admin/groups/{id}", middleware.VerifyAdmin(quizHandler.DeleteGroup, a.authClient))
+	mux.HandleFunc("PUT /admin/groups/order", middleware.VerifyAdmin(quizHandler.UpdateGroupsOrder, a.authClient))
+	mux.HandleFunc("PUT /admin/groups/{id}/questions", middleware.VerifyAdmin(quizHandler.MoveQuestionsToGroup, a.authClient))
+
+	mux.HandleFunc("GET /admin/cases", middleware.VerifyAdmin(quizHandler.GetAllCases, a.authClient))
+	mux.HandleFunc("GET /admin/cases/{id}", middleware.VerifyAdmin(quizHandler.GetCase, a.authClient))
+	mux.HandleFunc("POST /admin/cases", middleware.VerifyAdmin(quizHandler.CreateCase, a.authClient))
+	mux.HandleFunc("PUT /admin/cases/{id}", middleware.VerifyAdmin(quizHandler.UpdateCase, a.authClient))
+	mux.HandleFunc("DELETE /admin/cases/{id}", middleware.VerifyAdmin(quizHandler.DeleteCase, a.authClient))
+	mux.HandleFunc("GET /admin/cases/{id}/landmarks", middleware.VerifyAdmin(quizHandler.GetCaseLandmarks, a.authClient))
+	mux.HandleFunc("PUT /admin/cases/{id}/landmarks", middleware.VerifyAdmin(quizHandler.UpdateCaseLandmarks, a.authClient))
+
+	mux.HandleFunc("POST /admin/import", middleware.VerifyAdmin(quizHandler.ImportQuestionBank, a.authClient))
+	mux.HandleFunc("GET /admin/export", middleware.VerifyAdmin(quizHandler.ExportQuestionBank, a.authClient))
+	mux.HandleFunc("POST /admin/import/qti", middleware.VerifyAdmin(quizHandler.ImportQTI, a.authClient))
+	mux.HandleFunc("GET /admin/export/qti", middleware.VerifyAdmin(quizHandler.ExportQTI, a.authClient))
+
 	mux.HandleFunc("GET /admin/settings", middleware.VerifyAdmin(quizHandler.GetSettings, a.authClient))
 	mux.HandleFunc("PATCH /admin/settings", middleware.VerifyAdmin(quizHandler.UpdateSettings, a.authClient))
 
+	// assignments, available to teachers
+	assignmentsHandler := handlers.NewAssignmentsHandler(a.logger, a.authClient, a.quizClient, a.statsClient)
+	mux.HandleFunc("GET /admin/assignments", middleware.VerifyTeacher(assignmentsHandler.GetAssignments, a.authClient))
+	mux.HandleFunc("GET /admin/assignments/{id}", middleware.VerifyTeacher(assignmentsHandler.GetAssignment, a.authClient))
+	mux.HandleFunc("POST /admin/assignments", middleware.VerifyTeacher(assignmentsHandler.CreateAssignment, a.authClient))
+	mux.HandleFunc("PUT /admin/assignments/{id}", middleware.VerifyTeacher(assignmentsHandler.UpdateAssignment, a.authClient))
+	mux.HandleFunc("DELETE /admin/assignments/{id}", middleware.VerifyTeacher(assignmentsHandler.DeleteAssignment, a.authClient))
+	mux.HandleFunc("GET /admin/assignments/{id}/results", middleware.VerifyTeacher(assignmentsHandler.GetAssignmentResults, a.authClient))
+
 	// stats
-	statsHandler := handlers.NewAllStatsHandler(a.logger, a.statsClient)
+	statsHandler := handlers.NewAllStatsHandler(a.logger, a.authClient, a.statsClient)
 	mux.HandleFunc("GET /admin/responses", middleware.VerifyAdmin(statsHandler.GetAllResponses, a.authClient))
 	mux.HandleFunc("DELETE /admin/responses/{id}", middleware.VerifyAdmin(statsHandler.DeleteResponse, a.authClient))
 	mux.HandleFunc("GET /admin/stats/questions/{questionId}", middleware.VerifyAdmin(statsHandler.GetStatsForQuestion, a.authClient))
+	mux.HandleFunc("GET /admin/stats/questions/{questionId}/landmarks", middleware.VerifyAdmin(statsHandler.GetLandmarkAnswers, a.authClient))
 	mux.HandleFunc("GET /admin/stats/questions", middleware.VerifyAdmin(statsHandler.GetStatsForAllQuestions, a.authClient))
 	mux.HandleFunc("GET /admin/stats/activity", middleware.VerifyAdmin(statsHandler.GetActivityStats, a.authClient))
 	mux.HandleFunc("GET /admin/stats/grouped", middleware.VerifyAdmin(statsHandler.GetStatsGroupedBySurvey, a.authClient))
+	mux.HandleFunc("GET /admin/stats/calibration/grouped", middleware.VerifyAdmin(statsHandler.GetCalibrationGroupedBySurvey, a.authClient))
 	mux.HandleFunc("GET /admin/stats/users", middleware.VerifyAdmin(statsHandler.GetStatsForUsers, a.authClient))
 
-	mux.HandleFunc("GET /admin/dashboard", middleware.VerifyAdmin(handlers.NewSummaryHandler(a.logger, a.authClient, a.statsClient, a.quizClient).GetSummary, a.authClient))
+	mux.HandleFunc("GET /admin/dashboard", middleware.RequireAdmin(handlers.NewSummaryHandler(a.logger, a.authClient, a.statsClient, a.quizClient).GetSummary, a.authClient))
 }
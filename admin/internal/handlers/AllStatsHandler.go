@@ -2,24 +2,57 @@ package handlers
 
 import (
 	"admin/clients"
+	"admin/internal/models"
 	"encoding/json"
+	"errors"
 	"go.uber.org/zap"
 	"net/http"
 )
 
 type AllStatsHandler struct {
 	logger      *zap.Logger
+	authClient  clients.AuthClient
 	statsClient clients.StatsClient
 }
 
-func NewAllStatsHandler(logger *zap.Logger, statsClient clients.StatsClient) *AllStatsHandler {
+func NewAllStatsHandler(logger *zap.Logger, authClient clients.AuthClient, statsClient clients.StatsClient) *AllStatsHandler {
 	return &AllStatsHandler{
 		logger:      logger,
+		authClient:  authClient,
 		statsClient: statsClient,
 	}
 }
-func (h *AllStatsHandler) GetAllResponses(w http.ResponseWriter, _ *http.Request) {
-	stats, err := h.statsClient.GetAllResponses()
+
+// getCohortFilter returns the cohort_id query parameter to forward to the stats service. Admins may leave it out
+// to get stats of all users, teachers must filter by a cohort they own, cohorts of other teachers are reported as not found
+func (h *AllStatsHandler) getCohortFilter(w http.ResponseWriter, r *http.Request) (string, bool) {
+	cohortID := r.URL.Query().Get("cohort_id")
+	if r.Context().Value("user_role") == models.RoleAdmin {
+		return cohortID, true
+	}
+	if cohortID == "" {
+		http.Error(w, "cohort_id is required", http.StatusBadRequest)
+		return "", false
+	}
+	members, err := h.authClient.GetCohortMembers(cohortID)
+	if err != nil && !errors.Is(err, clients.ErrCohortNotFound) {
+		h.logger.Error("failed to get cohort", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return "", false
+	}
+	if err != nil || members.OwnerID != r.Context().Value("user_id").(int) {
+		http.Error(w, "cohort not found", http.StatusNotFound)
+		return "", false
+	}
+	return cohortID, true
+}
+
+func (h *AllStatsHandler) GetAllResponses(w http.ResponseWriter, r *http.Request) {
+	cohortID, ok := h.getCohortFilter(w, r)
+	if !ok {
+		return
+	}
+	stats, err := h.statsClient.GetAllResponses(cohortID)
 	if err != nil {
 		h.logger.Error("failed to get stats", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -36,8 +69,12 @@ func (h *AllStatsHandler) GetAllResponses(w http.ResponseWriter, _ *http.Request
 }
 
 func (h *AllStatsHandler) GetStatsForQuestion(w http.ResponseWriter, r *http.Request) {
+	cohortID, ok := h.getCohortFilter(w, r)
+	if !ok {
+		return
+	}
 	questionId := r.PathValue("questionId")
-	stats, err := h.statsClient.GetStatsForQuestion(questionId)
+	stats, err := h.statsClient.GetStatsForQuestion(questionId, cohortID)
 	if err != nil {
 		h.logger.Error("failed to get stats", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -53,8 +90,30 @@ func (h *AllStatsHandler) GetStatsForQuestion(w http.ResponseWriter, r *http.Req
 	}
 }
 
-func (h *AllStatsHandler) GetStatsForAllQuestions(w http.ResponseWriter, _ *http.Request) {
-	stats, err := h.statsClient.GetStatsForAllQuestions()
+// GetLandmarkAnswers returns the points placed in answers to a landmark question
+func (h *AllStatsHandler) GetLandmarkAnswers(w http.ResponseWriter, r *http.Request) {
+	cohortID, ok := h.getCohortFilter(w, r)
+	if !ok {
+		return
+	}
+	answers, err := h.statsClient.GetLandmarkAnswers(r.PathValue("questionId"), cohortID)
+	if err != nil {
+		h.logger.Error("failed to get landmark answers", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(answers); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *AllStatsHandler) GetStatsForAllQuestions(w http.ResponseWriter, r *http.Request) {
+	cohortID, ok := h.getCohortFilter(w, r)
+	if !ok {
+		return
+	}
+	stats, err := h.statsClient.GetStatsForAllQuestions(cohortID)
 	if err != nil {
 		h.logger.Error("failed to get stats", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -71,7 +130,11 @@ func (h *AllStatsHandler) GetStatsForAllQuestions(w http.ResponseWriter, _ *http
 }
 
 func (h *AllStatsHandler) GetActivityStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.statsClient.GetActivityStats()
+	cohortID, ok := h.getCohortFilter(w, r)
+	if !ok {
+		return
+	}
+	stats, err := h.statsClient.GetActivityStats(cohortID)
 	if err != nil {
 		h.logger.Error("failed to get stats", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -92,8 +155,12 @@ func (h *AllStatsHandler) GetStatsGroupedBySurvey(w http.ResponseWriter, r *http
 		http.Error(w, "groupBy parameter is required", http.StatusBadRequest)
 		return
 	}
+	cohortID, ok := h.getCohortFilter(w, r)
+	if !ok {
+		return
+	}
 
-	stats, err := h.statsClient.GetStatsGroupedBySurvey(groupBy)
+	stats, err := h.statsClient.GetStatsGroupedBySurvey(groupBy, cohortID)
 	if err != nil {
 		h.logger.Error("failed to get grouped stats", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -107,6 +174,28 @@ func (h *AllStatsHandler) GetStatsGroupedBySurvey(w http.ResponseWriter, r *http
 	}
 }
 
+func (h *AllStatsHandler) GetCalibrationGroupedBySurvey(w http.ResponseWriter, r *http.Request) {
+	groupBy := r.URL.Query().Get("groupBy")
+	if groupBy == "" {
+		http.Error(w, "groupBy parameter is required", http.StatusBadRequest)
+		return
+	}
+	cohortID, ok := h.getCohortFilter(w, r)
+	if !ok {
+		return
+	}
+	stats, err := h.statsClient.GetCalibrationGroupedBySurvey(groupBy, cohortID)
+	if err != nil {
+		h.logger.Error("failed to get grouped calibration", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
 func (h *AllStatsHandler) DeleteResponse(w http.ResponseWriter, r *http.Request) {
 	responseId := r.PathValue("id")
 	err := h.statsClient.DeleteResponse(responseId)
@@ -118,8 +207,12 @@ func (h *AllStatsHandler) DeleteResponse(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *AllStatsHandler) GetStatsForUsers(w http.ResponseWriter, _ *http.Request) {
-	stats, err := h.statsClient.GetAllUsersStats()
+func (h *AllStatsHandler) GetStatsForUsers(w http.ResponseWriter, r *http.Request) {
+	cohortID, ok := h.getCohortFilter(w, r)
+	if !ok {
+		return
+	}
+	stats, err := h.statsClient.GetAllUsersStats(cohortID)
 	if err != nil {
 		h.logger.Error("failed to get user stats", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
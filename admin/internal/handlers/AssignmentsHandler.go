@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"admin/clients"
+	"admin/internal/models"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"slices"
+)
+
+// AssignmentsHandler serves assignments to teachers and admins. Teachers see and change only their own assignments,
+// assignments of other teachers are reported as not found
+type AssignmentsHandler struct {
+	logger      *zap.Logger
+	authClient  clients.AuthClient
+	quizClient  clients.QuizClient
+	statsClient clients.StatsClient
+}
+
+func NewAssignmentsHandler(logger *zap.Logger, authClient clients.AuthClient, quizClient clients.QuizClient, statsClient clients.StatsClient) *AssignmentsHandler {
+	return &AssignmentsHandler{
+		logger:      logger,
+		authClient:  authClient,
+		quizClient:  quizClient,
+		statsClient: statsClient,
+	}
+}
+
+func (h *AssignmentsHandler) GetAssignments(w http.ResponseWriter, r *http.Request) {
+	teacherID := 0
+	if r.Context().Value("user_role") != models.RoleAdmin {
+		teacherID = r.Context().Value("user_id").(int)
+	}
+	assignments, err := h.quizClient.GetAssignments(teacherID)
+	if err != nil {
+		h.logger.Error("Failed to get assignments", zap.Error(err))
+		http.Error(w, "Failed to get assignments", http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, assignments)
+}
+
+func (h *AssignmentsHandler) GetAssignment(w http.ResponseWriter, r *http.Request) {
+	assignment, ok := h.getOwnAssignment(w, r)
+	if !ok {
+		return
+	}
+	h.writeJSON(w, http.StatusOK, assignment)
+}
+
+func (h *AssignmentsHandler) CreateAssignment(w http.ResponseWriter, r *http.Request) {
+	var assignment models.Assignment
+	if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil {
+		h.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Failed to decode request", http.StatusBadRequest)
+		return
+	}
+	assignment.TeacherID = r.Context().Value("user_id").(int)
+	created, err := h.quizClient.CreateAssignment(assignment)
+	if errors.Is(err, clients.ErrInvalidAssignment) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to create assignment", zap.Error(err))
+		http.Error(w, "Failed to create assignment", http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *AssignmentsHandler) UpdateAssignment(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.getOwnAssignment(w, r)
+	if !ok {
+		return
+	}
+	var assignment models.Assignment
+	if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil {
+		h.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Failed to decode request", http.StatusBadRequest)
+		return
+	}
+	assignment.TeacherID = existing.TeacherID
+	updated, err := h.quizClient.UpdateAssignment(r.PathValue("id"), assignment)
+	if errors.Is(err, clients.ErrInvalidAssignment) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update assignment", zap.Error(err))
+		http.Error(w, "Failed to update assignment", http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *AssignmentsHandler) DeleteAssignment(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.getOwnAssignment(w, r); !ok {
+		return
+	}
+	if err := h.quizClient.DeleteAssignment(r.PathValue("id")); err != nil {
+		h.logger.Error("Failed to delete assignment", zap.Error(err))
+		http.Error(w, "Failed to delete assignment", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAssignmentResults returns attempts of every student of the assignment with their scores from the stats service
+func (h *AssignmentsHandler) GetAssignmentResults(w http.ResponseWriter, r *http.Request) {
+	assignment, ok := h.getOwnAssignment(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := h.quizClient.GetAssignmentSessions(r.PathValue("id"))
+	if err != nil {
+		h.logger.Error("Failed to get assignment sessions", zap.Error(err))
+		http.Error(w, "Failed to get assignment results", http.StatusInternalServerError)
+		return
+	}
+	sessionsIDs := make([]int, len(sessions))
+	for i, session := range sessions {
+		sessionsIDs[i] = session.SessionID
+	}
+	scores := make(map[int]models.SessionScore, len(sessions))
+	if len(sessionsIDs) > 0 {
+		sessionsScores, err := h.statsClient.GetSessionsScores(sessionsIDs)
+		if err != nil {
+			h.logger.Error("Failed to get sessions scores", zap.Error(err))
+			http.Error(w, "Failed to get assignment results", http.StatusInternalServerError)
+			return
+		}
+		for _, score := range sessionsScores {
+			scores[score.SessionID] = score
+		}
+	}
+	// only the students of the assignment and the users who attempted it are fetched from the auth service
+	usersIDs := slices.Clone(assignment.StudentsIDs)
+	for _, session := range sessions {
+		usersIDs = append(usersIDs, session.UserID)
+	}
+	slices.Sort(usersIDs)
+	usersIDs = slices.Compact(usersIDs)
+	usersByID := make(map[int]models.User, len(usersIDs))
+	if len(usersIDs) > 0 {
+		users, err := h.authClient.GetUsersByIDs(usersIDs)
+		if err != nil {
+			h.logger.Error("Failed to get users", zap.Error(err))
+			http.Error(w, "Failed to get assignment results", http.StatusInternalServerError)
+			return
+		}
+		for _, user := range users {
+			usersByID[user.ID] = user
+		}
+	}
+
+	results := models.AssignmentResults{
+		Assignment: assignment,
+		Students:   make([]models.StudentResult, 0, len(assignment.StudentsIDs)),
+	}
+	studentIndex := make(map[int]int, len(assignment.StudentsIDs))
+	addStudent := func(userID int) int {
+		if i, ok := studentIndex[userID]; ok {
+			return i
+		}
+		user := usersByID[userID]
+		studentIndex[userID] = len(results.Students)
+		results.Students = append(results.Students, models.StudentResult{
+			UserID:    userID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Email:     user.Email,
+			Sessions:  make([]models.AssignmentAttempt, 0),
+		})
+		return studentIndex[userID]
+	}
+	for _, studentID := range assignment.StudentsIDs {
+		addStudent(studentID)
+	}
+	// students removed from the assignment after starting it are still listed with their attempts
+	for _, session := range sessions {
+		student := &results.Students[addStudent(session.UserID)]
+		score := scores[session.SessionID]
+		attempt := models.AssignmentAttempt{
+			AssignmentSession: session,
+			Answers:           score.TotalQuestions,
+			CorrectAnswers:    score.CorrectAnswers,
+			TimedOut:          score.TimedOut,
+		}
+		if assignment.QuestionsCount > 0 {
+			attempt.Score = float64(score.CorrectAnswers) / float64(assignment.QuestionsCount)
+		}
+		student.Attempts++
+		student.Sessions = append(student.Sessions, attempt)
+		if session.Status == "finished" {
+			student.Completed = true
+			if student.BestScore == nil || attempt.Score > *student.BestScore {
+				bestScore := attempt.Score
+				student.BestScore = &bestScore
+			}
+		}
+	}
+	for _, student := range results.Students {
+		if student.Completed {
+			results.Completed++
+		}
+	}
+	h.writeJSON(w, http.StatusOK, results)
+}
+
+// getOwnAssignment returns the assignment from the path if the user may access it, otherwise an error response is written
+func (h *AssignmentsHandler) getOwnAssignment(w http.ResponseWriter, r *http.Request) (models.Assignment, bool) {
+	assignment, err := h.quizClient.GetAssignment(r.PathValue("id"))
+	if errors.Is(err, clients.ErrAssignmentNotFound) {
+		http.Error(w, "Assignment not found", http.StatusNotFound)
+		return assignment, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to get assignment", zap.Error(err))
+		http.Error(w, "Failed to get assignment", http.StatusInternalServerError)
+		return assignment, false
+	}
+	if r.Context().Value("user_role") != models.RoleAdmin && assignment.TeacherID != r.Context().Value("user_id").(int) {
+		http.Error(w, "Assignment not found", http.StatusNotFound)
+		return assignment, false
+	}
+	return assignment, true
+}
+
+func (h *AssignmentsHandler) writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		h.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
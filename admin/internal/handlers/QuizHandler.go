@@ -4,10 +4,19 @@ import (
 	"admin/clients"
 	"admin/internal/models"
 	"encoding/json"
+	"errors"
 	"go.uber.org/zap"
+	"io"
 	"net/http"
+	"time"
 )
 
+// maxImportSize is the maximum size of a question bank archive
+const maxImportSize = 200 << 20
+
+// transferTimeout replaces the server timeouts for import and export of the question bank
+const transferTimeout = 5 * time.Minute
+
 type QuizHandler struct {
 	logger      *zap.Logger
 	quizClient  clients.QuizClient
@@ -117,6 +126,86 @@ func (h *QuizHandler) GetQuestion(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// UpdateQuestionStatus moves the question through the draft, in_review, published and archived lifecycle
+func (h *QuizHandler) UpdateQuestionStatus(w http.ResponseWriter, r *http.Request) {
+	var payload models.QuestionStatusPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	question, err := h.quizClient.UpdateQuestionStatus(r.PathValue("id"), payload.Status)
+	if errors.Is(err, clients.ErrQuestionNotFound) {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, clients.ErrQuestionStatusConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update question status", zap.Error(err))
+		http.Error(w, "Failed to update question status", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(question); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// PreviewQuestion starts a sandbox session for the logged in author, the session is then answered
+// through the quiz endpoints like any other session and is not recorded in stats
+func (h *QuizHandler) PreviewQuestion(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	session, err := h.quizClient.PreviewQuestion(r.PathValue("id"), userID)
+	if errors.Is(err, clients.ErrQuestionNotFound) {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to preview question", zap.Error(err))
+		http.Error(w, "Failed to preview question", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(session); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// GetQuestionVersions returns the history of the question, oldest version first
+func (h *QuizHandler) GetQuestionVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.quizClient.GetQuestionVersions(r.PathValue("id"))
+	if err != nil {
+		h.logger.Error("Failed to get question versions", zap.Error(err))
+		http.Error(w, "Failed to get question versions", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(versions); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// GetQuestionVersionsDiff compares versions given by the from and to query parameters
+func (h *QuizHandler) GetQuestionVersionsDiff(w http.ResponseWriter, r *http.Request) {
+	diff, err := h.quizClient.GetQuestionVersionsDiff(r.PathValue("id"), r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if errors.Is(err, clients.ErrQuestionVersionNotFound) {
+		http.Error(w, "Question version not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get question versions diff", zap.Error(err))
+		http.Error(w, "Failed to get question versions diff", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(diff); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
 func (h *QuizHandler) UpdateQuestion(w http.ResponseWriter, r *http.Request) {
 	questionId := r.PathValue("id")
 	updatedQuestion := models.Question{}
@@ -257,3 +346,464 @@ func (h *QuizHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (h *QuizHandler) GetAllGroups(w http.ResponseWriter, _ *http.Request) {
+	groups, err := h.quizClient.GetAllGroups()
+	if err != nil {
+		h.logger.Error("Failed to get groups", zap.Error(err))
+		http.Error(w, "Failed to get groups", http.StatusInternalServerError)
+		return
+	}
+	groupsJSON, err := json.Marshal(groups)
+	if err != nil {
+		h.logger.Error("Failed to marshal groups", zap.Error(err))
+		http.Error(w, "Failed to marshal groups", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(groupsJSON)
+	if err != nil {
+		h.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	groupId := r.PathValue("id")
+	group, err := h.quizClient.GetGroup(groupId)
+	if err != nil {
+		h.logger.Error("Failed to get group", zap.Error(err))
+		http.Error(w, "Failed to get group", http.StatusInternalServerError)
+		return
+	}
+	groupJSON, err := json.Marshal(group)
+	if err != nil {
+		h.logger.Error("Failed to marshal group", zap.Error(err))
+		http.Error(w, "Failed to marshal group", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(groupJSON)
+	if err != nil {
+		h.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	newGroup := models.QuestionsGroup{}
+	err := json.NewDecoder(r.Body).Decode(&newGroup)
+	if err != nil {
+		h.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Failed to decode request", http.StatusBadRequest)
+		return
+	}
+	group, err := h.quizClient.CreateGroup(newGroup)
+	if err != nil {
+		h.logger.Error("Failed to create group", zap.Error(err))
+		http.Error(w, "Failed to create group", http.StatusInternalServerError)
+		return
+	}
+	groupJSON, err := json.Marshal(group)
+	if err != nil {
+		h.logger.Error("Failed to marshal group", zap.Error(err))
+		http.Error(w, "Failed to marshal group", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(groupJSON)
+	if err != nil {
+		h.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	groupId := r.PathValue("id")
+	updatedGroup := models.QuestionsGroup{}
+	err := json.NewDecoder(r.Body).Decode(&updatedGroup)
+	if err != nil {
+		h.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Failed to decode request", http.StatusBadRequest)
+		return
+	}
+	err = h.quizClient.UpdateGroup(groupId, updatedGroup)
+	if err != nil {
+		h.logger.Error("Failed to update group", zap.Error(err))
+		http.Error(w, "Failed to update group", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *QuizHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	groupId := r.PathValue("id")
+	err := h.quizClient.DeleteGroup(groupId)
+	if err != nil {
+		h.logger.Error("Failed to delete group", zap.Error(err))
+		http.Error(w, "Failed to delete group", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *QuizHandler) UpdateGroupsOrder(w http.ResponseWriter, r *http.Request) {
+	var newOrder []models.QuestionsGroup
+	err := json.NewDecoder(r.Body).Decode(&newOrder)
+	if err != nil {
+		h.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Failed to decode request", http.StatusBadRequest)
+		return
+	}
+	err = h.quizClient.UpdateGroupsOrder(newOrder)
+	if err != nil {
+		h.logger.Error("Failed to update groups order", zap.Error(err))
+		http.Error(w, "Failed to update groups order", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *QuizHandler) MoveQuestionsToGroup(w http.ResponseWriter, r *http.Request) {
+	groupId := r.PathValue("id")
+	var payload models.GroupQuestionsPayload
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Failed to decode request", http.StatusBadRequest)
+		return
+	}
+	err = h.quizClient.MoveQuestionsToGroup(groupId, payload)
+	if err != nil {
+		h.logger.Error("Failed to move questions to group", zap.Error(err))
+		http.Error(w, "Failed to move questions to group", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *QuizHandler) GetAllCases(w http.ResponseWriter, _ *http.Request) {
+	cases, err := h.quizClient.GetAllCases()
+	if err != nil {
+		h.logger.Error("Failed to get cases", zap.Error(err))
+		http.Error(w, "Failed to get cases", http.StatusInternalServerError)
+		return
+	}
+	casesJSON, err := json.Marshal(cases)
+	if err != nil {
+		h.logger.Error("Failed to marshal cases", zap.Error(err))
+		http.Error(w, "Failed to marshal cases", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(casesJSON)
+	if err != nil {
+		h.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) GetCase(w http.ResponseWriter, r *http.Request) {
+	caseId := r.PathValue("id")
+	dbCase, err := h.quizClient.GetCase(caseId)
+	if err != nil {
+		h.logger.Error("Failed to get case", zap.Error(err))
+		http.Error(w, "Failed to get case", http.StatusInternalServerError)
+		return
+	}
+	caseJSON, err := json.Marshal(dbCase)
+	if err != nil {
+		h.logger.Error("Failed to marshal case", zap.Error(err))
+		http.Error(w, "Failed to marshal case", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(caseJSON)
+	if err != nil {
+		h.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) GetCaseLandmarks(w http.ResponseWriter, r *http.Request) {
+	landmarks, err := h.quizClient.GetCaseLandmarks(r.PathValue("id"))
+	if errors.Is(err, clients.ErrCaseNotFound) {
+		http.Error(w, "Case not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get case landmarks", zap.Error(err))
+		http.Error(w, "Failed to get case landmarks", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(models.CaseLandmarks{Landmarks: landmarks}); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) UpdateCaseLandmarks(w http.ResponseWriter, r *http.Request) {
+	var payload models.CaseLandmarks
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	landmarks, err := h.quizClient.UpdateCaseLandmarks(r.PathValue("id"), payload.Landmarks)
+	if errors.Is(err, clients.ErrInvalidLandmarks) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, clients.ErrCaseNotFound) {
+		http.Error(w, "Case not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update case landmarks", zap.Error(err))
+		http.Error(w, "Failed to update case landmarks", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(models.CaseLandmarks{Landmarks: landmarks}); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) CreateCase(w http.ResponseWriter, r *http.Request) {
+	newCase := models.Case{}
+	err := json.NewDecoder(r.Body).Decode(&newCase)
+	if err != nil {
+		h.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Failed to decode request", http.StatusBadRequest)
+		return
+	}
+	createdCase, err := h.quizClient.CreateCase(newCase)
+	if err != nil {
+		h.logger.Error("Failed to create case", zap.Error(err))
+		http.Error(w, "Failed to create case", http.StatusInternalServerError)
+		return
+	}
+	caseJSON, err := json.Marshal(createdCase)
+	if err != nil {
+		h.logger.Error("Failed to marshal case", zap.Error(err))
+		http.Error(w, "Failed to marshal case", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(caseJSON)
+	if err != nil {
+		h.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) UpdateCase(w http.ResponseWriter, r *http.Request) {
+	caseId := r.PathValue("id")
+	updatedCase := models.Case{}
+	err := json.NewDecoder(r.Body).Decode(&updatedCase)
+	if err != nil {
+		h.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Failed to decode request", http.StatusBadRequest)
+		return
+	}
+	dbCase, err := h.quizClient.UpdateCase(caseId, updatedCase)
+	if err != nil {
+		h.logger.Error("Failed to update case", zap.Error(err))
+		http.Error(w, "Failed to update case", http.StatusInternalServerError)
+		return
+	}
+	caseJSON, err := json.Marshal(dbCase)
+	if err != nil {
+		h.logger.Error("Failed to marshal case", zap.Error(err))
+		http.Error(w, "Failed to marshal case", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(caseJSON)
+	if err != nil {
+		h.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) DeleteCase(w http.ResponseWriter, r *http.Request) {
+	caseId := r.PathValue("id")
+	err := h.quizClient.DeleteCase(caseId)
+	if err != nil {
+		h.logger.Error("Failed to delete case", zap.Error(err))
+		http.Error(w, "Failed to delete case", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *QuizHandler) ImportQuestionBank(w http.ResponseWriter, r *http.Request) {
+	h.importArchive(w, r, h.quizClient.ImportQuestionBank)
+}
+
+func (h *QuizHandler) ImportQTI(w http.ResponseWriter, r *http.Request) {
+	h.importArchive(w, r, h.quizClient.ImportQTI)
+}
+
+func (h *QuizHandler) importArchive(w http.ResponseWriter, r *http.Request, send func(archive []byte, dryRun bool) (models.ImportReport, error)) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	extendDeadlines(w, h.logger)
+	archive, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		h.logger.Error("Failed to read archive", zap.Error(err))
+		http.Error(w, "Failed to read archive", http.StatusBadRequest)
+		return
+	}
+	report, err := send(archive, dryRun)
+	if err != nil {
+		h.logger.Error("Failed to import question bank", zap.Error(err))
+		http.Error(w, "Failed to import question bank", http.StatusInternalServerError)
+		return
+	}
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		h.logger.Error("Failed to marshal report", zap.Error(err))
+		http.Error(w, "Failed to marshal report", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case len(report.Errors) > 0:
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	case !report.DryRun:
+		w.WriteHeader(http.StatusCreated)
+	}
+	_, err = w.Write(reportJSON)
+	if err != nil {
+		h.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) ExportQuestionBank(w http.ResponseWriter, _ *http.Request) {
+	h.exportArchive(w, h.quizClient.ExportQuestionBank)
+}
+
+func (h *QuizHandler) ExportQTI(w http.ResponseWriter, _ *http.Request) {
+	h.exportArchive(w, h.quizClient.ExportQTI)
+}
+
+func (h *QuizHandler) exportArchive(w http.ResponseWriter, fetch func() ([]byte, string, error)) {
+	extendDeadlines(w, h.logger)
+	archive, disposition, err := fetch()
+	if err != nil {
+		h.logger.Error("Failed to export question bank", zap.Error(err))
+		http.Error(w, "Failed to export question bank", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	if disposition != "" {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+	_, err = w.Write(archive)
+	if err != nil {
+		h.logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+func extendDeadlines(w http.ResponseWriter, logger *zap.Logger) {
+	controller := http.NewResponseController(w)
+	if err := controller.SetReadDeadline(time.Now().Add(transferTimeout)); err != nil {
+		logger.Warn("Failed to extend read deadline", zap.Error(err))
+	}
+	if err := controller.SetWriteDeadline(time.Now().Add(transferTimeout)); err != nil {
+		logger.Warn("Failed to extend write deadline", zap.Error(err))
+	}
+}
+
+// GetTranslations returns all translations of a question, option or parameter
+func (h *QuizHandler) GetTranslations(w http.ResponseWriter, r *http.Request) {
+	translations, err := h.quizClient.GetTranslations(r.PathValue("entity"), r.PathValue("id"))
+	if errors.Is(err, clients.ErrInvalidTranslation) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get translations", zap.Error(err))
+		http.Error(w, "Failed to get translations", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(translations); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) SaveTranslation(w http.ResponseWriter, r *http.Request) {
+	var translation models.Translation
+	if err := json.NewDecoder(r.Body).Decode(&translation); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	translation, err := h.quizClient.SaveTranslation(translation)
+	if errors.Is(err, clients.ErrInvalidTranslation) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, clients.ErrTranslationNotFound) {
+		http.Error(w, "Translated entity not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to save translation", zap.Error(err))
+		http.Error(w, "Failed to save translation", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(translation); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) DeleteTranslation(w http.ResponseWriter, r *http.Request) {
+	err := h.quizClient.DeleteTranslation(r.PathValue("entity"), r.PathValue("id"), r.PathValue("field"), r.PathValue("locale"))
+	if errors.Is(err, clients.ErrTranslationNotFound) {
+		http.Error(w, "Translation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete translation", zap.Error(err))
+		http.Error(w, "Failed to delete translation", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMissingTranslations reports the fields which have no translation in the locale query parameter
+func (h *QuizHandler) GetMissingTranslations(w http.ResponseWriter, r *http.Request) {
+	missing, err := h.quizClient.GetMissingTranslations(r.URL.Query().Get("locale"))
+	if errors.Is(err, clients.ErrInvalidTranslation) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get missing translations", zap.Error(err))
+		http.Error(w, "Failed to get missing translations", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(missing); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// GetStuckOutboxEvents lists answers and sessions which did not reach the stats service
+func (h *QuizHandler) GetStuckOutboxEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.quizClient.GetStuckOutboxEvents()
+	if err != nil {
+		h.logger.Error("Failed to get stuck outbox events", zap.Error(err))
+		http.Error(w, "Failed to get stuck outbox events", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(events); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *QuizHandler) RetryOutboxEvent(w http.ResponseWriter, r *http.Request) {
+	err := h.quizClient.RetryOutboxEvent(r.PathValue("id"))
+	if errors.Is(err, clients.ErrOutboxEventNotFound) {
+		http.Error(w, "Undelivered event not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to retry outbox event", zap.Error(err))
+		http.Error(w, "Failed to retry outbox event", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
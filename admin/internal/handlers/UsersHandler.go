@@ -100,6 +100,20 @@ func (u *UsersHandler) GetUserDetails(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetUserCalibration returns how well the confidence ratings of the user match the accuracy of their answers
+func (u *UsersHandler) GetUserCalibration(w http.ResponseWriter, r *http.Request) {
+	calibration, err := u.statsClient.GetUserCalibration(r.PathValue("id"))
+	if err != nil {
+		u.logger.Error("failed to get user calibration", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(calibration); err != nil {
+		u.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
 func (u *UsersHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	userID := r.PathValue("id")
 	query := r.URL.Query().Get("withResponses")
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"admin/clients"
+	"admin/internal/models"
+	"context"
+	"log"
+	"net/http"
+)
+
+// RequireAdmin lets only admins through, unlike VerifyAdmin it denies teachers also the reads.
+// It guards routes returning data of all users which can't be limited to the cohorts of a teacher
+func RequireAdmin(next http.HandlerFunc, authClient clients.AuthClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := ExtractAccessTokenFromRequest(r)
+		if err != nil {
+			log.Println("No access token provided")
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		userData, err := authClient.VerifyAuthToken(accessToken)
+		if err != nil {
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			log.Println("failed to verify token: ", err)
+			return
+		}
+		if userData.Role != models.RoleAdmin {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			log.Println("not admin user attempted admin only action")
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), "user_id", userData.UserID))
+		r = r.WithContext(context.WithValue(r.Context(), "user_role", userData.Role))
+		next(w, r)
+	}
+}
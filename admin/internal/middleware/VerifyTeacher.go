@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"admin/clients"
+	"admin/internal/models"
+	"context"
+	"log"
+	"net/http"
+)
+
+// VerifyTeacher lets teachers and admins through with all methods, handlers limit teachers to their own data
+func VerifyTeacher(next http.HandlerFunc, authClient clients.AuthClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := ExtractAccessTokenFromRequest(r)
+		if err != nil {
+			log.Println("No access token provided")
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		userData, err := authClient.VerifyAuthToken(accessToken)
+		if err != nil {
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			log.Println("failed to verify token: ", err)
+			return
+		}
+		if userData.Role != models.RoleAdmin && userData.Role != models.RoleTeacher {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			log.Println("not teacher user attempted teacher action")
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), "user_id", userData.UserID))
+		r = r.WithContext(context.WithValue(r.Context(), "user_role", userData.Role))
+		next(w, r)
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Assignment is a quiz a teacher shares with chosen students, it is kept by the quiz service
+type Assignment struct {
+	ID             int        `json:"id"`
+	TeacherID      int        `json:"teacher_id"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	Mode           string     `json:"mode"`
+	QuestionsIDs   []int      `json:"questions_ids"`
+	GroupsIDs      []int      `json:"groups_ids"`
+	Deadline       time.Time  `json:"deadline"`
+	MaxAttempts    int        `json:"max_attempts"`
+	StudentsIDs    []int      `json:"students_ids"`
+	QuestionsCount int        `json:"questions_count"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+}
+
+type AssignmentSession struct {
+	SessionID  int        `json:"session_id"`
+	UserID     int        `json:"user_id"`
+	Status     string     `json:"status"`
+	CreatedAt  *time.Time `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// SessionScore is the number of answers and correct answers of a session kept by the stats service
+type SessionScore struct {
+	SessionID      int `json:"session_id"`
+	TotalQuestions int `json:"total_questions"`
+	CorrectAnswers int `json:"correct_answers"`
+	TimedOut       int `json:"timed_out"`
+}
+
+// AssignmentResults is the completion and scores of the students of an assignment. Scores are the share of
+// the assignment questions answered correctly
+type AssignmentResults struct {
+	Assignment Assignment      `json:"assignment"`
+	Completed  int             `json:"completed"`
+	Students   []StudentResult `json:"students"`
+}
+
+type StudentResult struct {
+	UserID    int                 `json:"user_id"`
+	FirstName string              `json:"first_name"`
+	LastName  string              `json:"last_name"`
+	Email     string              `json:"email"`
+	Attempts  int                 `json:"attempts"`
+	Completed bool                `json:"completed"`
+	BestScore *float64            `json:"best_score"`
+	Sessions  []AssignmentAttempt `json:"sessions"`
+}
+
+type AssignmentAttempt struct {
+	AssignmentSession
+	Answers        int     `json:"answers"`
+	CorrectAnswers int     `json:"correct_answers"`
+	TimedOut       int     `json:"timed_out"`
+	Score          float64 `json:"score"`
+}
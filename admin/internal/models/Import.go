@@ -0,0 +1,18 @@
+package models
+
+// ImportReport describes changes made by a question bank import, or changes that would be made in dry run mode
+type ImportReport struct {
+	DryRun            bool     `json:"dry_run"`
+	CasesCreated      []string `json:"cases_created"`
+	CasesReused       []string `json:"cases_reused"`
+	ParametersCreated []string `json:"parameters_created"`
+	OptionsCreated    []string `json:"options_created"`
+	GroupsCreated     []string `json:"groups_created"`
+	SettingsUpdated   int      `json:"settings_updated"`
+	QuestionsCreated  int      `json:"questions_created"`
+	QuestionsIDs      []int    `json:"questions_ids,omitempty"`
+	OptionLinks       int      `json:"option_links"`
+	Images            int      `json:"images"`
+	Skipped           []string `json:"skipped,omitempty"`
+	Errors            []string `json:"errors"`
+}
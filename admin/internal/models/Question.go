@@ -8,6 +8,13 @@ type Question struct {
 	Case          Case     `json:"case"`
 	Correct       *string  `json:"correct"`
 	Group         int      `json:"group"`
+	Status        string   `json:"status,omitempty"`
+	// Type is choice or numeric, numeric questions ask for the value3 of the target parameter
+	Type              string   `json:"type,omitempty"`
+	TargetParameterID *int     `json:"target_parameter_id,omitempty"`
+	Tolerance         *float64 `json:"tolerance,omitempty"`
+	// Landmarks are the names of the landmarks to place, set when serving landmark questions
+	Landmarks []string `json:"landmarks,omitempty"`
 }
 
 type Case struct {
@@ -19,6 +26,19 @@ type Case struct {
 	Age3            int              `json:"age3"`
 	Parameters      []Parameter      `json:"parameters,omitempty"`
 	ParameterValues []ParameterValue `json:"parameters_values,omitempty"`
+	Landmarks       []Landmark       `json:"landmarks,omitempty"`
+}
+
+// Landmark is a reference cephalometric landmark on the final image of a case
+type Landmark struct {
+	Name string  `json:"name"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+}
+
+// CaseLandmarks is the payload replacing the reference landmarks of a case
+type CaseLandmarks struct {
+	Landmarks []Landmark `json:"landmarks"`
 }
 
 type Parameter struct {
@@ -30,10 +50,10 @@ type Parameter struct {
 }
 
 type ParameterValue struct {
-	ParameterID int     `json:"parameter_id"`
-	Value1      float64 `json:"value1"`
-	Value2      float64 `json:"value2"`
-	Value3      float64 `json:"value3"`
+	ParameterID int      `json:"parameter_id"`
+	Value1      float64  `json:"value1"`
+	Value2      float64  `json:"value2"`
+	Value3      *float64 `json:"value3,omitempty"`
 }
 
 type Option struct {
@@ -41,3 +61,29 @@ type Option struct {
 	Option    string `json:"option"`
 	Questions *int   `json:"questions,omitempty"`
 }
+
+type QuestionsGroup struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Order        int    `json:"order"`
+	Enabled      bool   `json:"enabled"`
+	QuestionsIDs []int  `json:"questions"`
+}
+
+type GroupQuestionsPayload struct {
+	QuestionsIDs []int `json:"questions"`
+}
+
+type QuestionStatusPayload struct {
+	Status string `json:"status"`
+}
+
+// PreviewSession is a sandbox quiz session asking a single question, answers in it are not recorded in stats
+type PreviewSession struct {
+	SessionID int    `json:"session_id"`
+	UserID    int    `json:"user_id"`
+	QuizMode  string `json:"quiz_mode"`
+	Status    string `json:"status"`
+	Sandbox   bool   `json:"sandbox"`
+}
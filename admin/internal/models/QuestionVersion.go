@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// QuestionVersion is an immutable snapshot of a question recorded by the quiz service
+type QuestionVersion struct {
+	QuestionID        int       `json:"question_id"`
+	Version           int       `json:"version"`
+	Question          string    `json:"question"`
+	PredictionAge     int       `json:"prediction_age"`
+	Options           []string  `json:"options"`
+	Correct           string    `json:"correct"`
+	Case              Case      `json:"case"`
+	CreatedAt         time.Time `json:"created_at"`
+	Type              string    `json:"type"`
+	TargetParameterID *int      `json:"target_parameter_id,omitempty"`
+	Tolerance         *float64  `json:"tolerance,omitempty"`
+}
+
+type QuestionChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type QuestionVersionsDiff struct {
+	QuestionID  int              `json:"question_id"`
+	FromVersion int              `json:"from_version"`
+	ToVersion   int              `json:"to_version"`
+	Changes     []QuestionChange `json:"changes"`
+}
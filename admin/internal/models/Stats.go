@@ -3,15 +3,67 @@ package models
 import "time"
 
 type QuestionResponse struct {
-	ID         int        `json:"id"`
-	QuestionID int        `json:"question_id"`
-	Answer     string     `json:"answer"`
-	IsCorrect  bool       `json:"is_correct"`
-	Time       *time.Time `json:"time,omitempty"`
-	UserID     *int       `json:"user_id,omitempty"`
-	ScreenSize string     `json:"screen_size"`
-	TimeSpent  int        `json:"time_spent"`
-	CaseCode   string     `json:"case_code"`
+	ID         int `json:"id"`
+	QuestionID int `json:"question_id"`
+	// QuestionVersion is the version of the question the answer was given to
+	QuestionVersion int        `json:"question_version"`
+	Answer          string     `json:"answer"`
+	IsCorrect       bool       `json:"is_correct"`
+	Time            *time.Time `json:"time,omitempty"`
+	UserID          *int       `json:"user_id,omitempty"`
+	ScreenSize      string     `json:"screen_size"`
+	TimeSpent       int        `json:"time_spent"`
+	CaseCode        string     `json:"case_code"`
+	TimedOut        bool       `json:"timed_out"`
+	Position        int        `json:"position"`
+	Seed            int64      `json:"seed"`
+	PredictionError *float64   `json:"prediction_error,omitempty"`
+	Score           *float64   `json:"score,omitempty"`
+	// Points are the landmarks placed in answers to landmark questions
+	Points       []PlacedLandmark `json:"points,omitempty"`
+	MeanDistance *float64         `json:"mean_distance,omitempty"`
+	Confidence   *int             `json:"confidence,omitempty"`
+}
+
+// Calibration compares the confidence ratings given with answers with their accuracy
+type Calibration struct {
+	Answers        int                 `json:"answers"`
+	Buckets        []CalibrationBucket `json:"buckets"`
+	BrierScore     float64             `json:"brier_score"`
+	Overconfidence float64             `json:"overconfidence"`
+}
+
+type CalibrationBucket struct {
+	Confidence       int     `json:"confidence"`
+	Total            int     `json:"total"`
+	Correct          int     `json:"correct"`
+	Accuracy         float64 `json:"accuracy"`
+	ExpectedAccuracy float64 `json:"expected_accuracy"`
+}
+
+type SurveyGroupedCalibration struct {
+	Group string `json:"group"`
+	Value string `json:"value"`
+	Calibration
+}
+
+// PlacedLandmark is a landmark placed by the user, Error is its distance from the reference landmark
+type PlacedLandmark struct {
+	Name  string  `json:"name"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Error float64 `json:"error"`
+}
+
+// LandmarkAnswer is an answer to a landmark question with the placed points
+type LandmarkAnswer struct {
+	ResponseID      int              `json:"response_id"`
+	UserID          int              `json:"user_id"`
+	QuestionVersion int              `json:"question_version"`
+	Time            time.Time        `json:"time"`
+	Points          []PlacedLandmark `json:"points"`
+	MeanDistance    float64          `json:"mean_distance"`
+	Score           float64          `json:"score"`
 }
 
 type QuestionStats struct {
@@ -19,6 +71,9 @@ type QuestionStats struct {
 	CaseCode   string `json:"case_id"`
 	Total      int    `json:"total"`
 	Correct    int    `json:"correct"`
+	TimedOut   int    `json:"timed_out"`
+	// MeanAbsoluteError of predictions, set only for numeric questions
+	MeanAbsoluteError *float64 `json:"mean_absolute_error,omitempty"`
 }
 
 type ActivityStats struct {
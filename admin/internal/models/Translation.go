@@ -0,0 +1,18 @@
+package models
+
+// Translation is the text of a field of a question, option or parameter in a locale
+type Translation struct {
+	Entity   string `json:"entity"`
+	EntityID int    `json:"entity_id"`
+	Field    string `json:"field"`
+	Locale   string `json:"locale"`
+	Value    string `json:"value"`
+}
+
+// MissingTranslation is a field without a translation in the requested locale, Source is the original text
+type MissingTranslation struct {
+	Entity   string `json:"entity"`
+	EntityID int    `json:"entity_id"`
+	Field    string `json:"field"`
+	Source   string `json:"source"`
+}
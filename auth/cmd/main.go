@@ -2,6 +2,7 @@ package main
 
 import (
 	"auth/internal/api"
+	"auth/internal/events"
 	"auth/internal/storage"
 	"database/sql"
 	"fmt"
@@ -9,12 +10,17 @@ import (
 	"os"
 	"time"
 
+	"auth/internal/migrations"
+	"context"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
 const PingDbAttempts = 3
 
+// DefaultQueryTimeout bounds each storage call when DB_QUERY_TIMEOUT is not set
+const DefaultQueryTimeout = 5 * time.Second
+
 func main() {
 	// Initialize logger
 	var err error
@@ -59,8 +65,35 @@ func main() {
 	if err = db.Ping(); err != nil {
 		logger.Fatal("Failed to ping database, exiting", zap.Error(err))
 	}
-	postgresStorage := storage.NewPostgresStorage(db, logger)
-	apiServer := api.NewApiServer(":8080", postgresStorage, logger)
+
+	// Migrate the schema, "migrate up|down [steps]|version" runs only the migration command
+	migrator, err := migrations.New(db)
+	if err != nil {
+		logger.Fatal("Failed to load migrations", zap.Error(err))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err = migrations.Command(context.Background(), migrator, os.Args[2:], logger); err != nil {
+			logger.Fatal("Failed to migrate database", zap.Error(err))
+		}
+		return
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		logger.Fatal("Failed to migrate database", zap.Error(err))
+	}
+	logger.Info("Database schema is up to date", zap.Int("applied_migrations", applied))
+	postgresStorage := storage.NewPostgresStorage(db, logger, queryTimeout(logger))
+	publisher := events.Discard
+	bus, err := events.Connect(logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to the event bus", zap.Error(err))
+	}
+	if bus != nil {
+		defer bus.Close()
+		publisher = bus
+		logger.Info("Connected to the event bus")
+	}
+	apiServer := api.NewApiServer(":8080", postgresStorage, logger, publisher)
 	apiServer.Run()
 }
 func connectToPostgres() (*sql.DB, error) {
@@ -78,3 +111,17 @@ func connectToPostgres() (*sql.DB, error) {
 	connString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s", host, port, user, password, dbName, sslMode)
 	return sql.Open("postgres", connString)
 }
+
+// queryTimeout reads the timeout of storage calls from DB_QUERY_TIMEOUT, a duration such as 3s
+func queryTimeout(logger *zap.Logger) time.Duration {
+	value := os.Getenv("DB_QUERY_TIMEOUT")
+	if value == "" {
+		return DefaultQueryTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		logger.Warn("Invalid DB_QUERY_TIMEOUT, using the default", zap.String("value", value))
+		return DefaultQueryTimeout
+	}
+	return timeout
+}
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"auth/internal/events"
 	"auth/internal/handlers"
 	"auth/internal/middleware"
+	"auth/internal/outbox"
 	"auth/internal/storage"
 	"context"
 	"encoding/json"
@@ -16,16 +18,18 @@ import (
 )
 
 type ApiServer struct {
-	addr    string
-	storage storage.Store
-	logger  *zap.Logger
+	addr      string
+	storage   storage.Store
+	logger    *zap.Logger
+	publisher events.Publisher
 }
 
-func NewApiServer(addr string, store storage.Store, logger *zap.Logger) *ApiServer {
+func NewApiServer(addr string, store storage.Store, logger *zap.Logger, publisher events.Publisher) *ApiServer {
 	return &ApiServer{
-		addr:    addr,
-		storage: store,
-		logger:  logger,
+		addr:      addr,
+		storage:   store,
+		logger:    logger,
+		publisher: publisher,
 	}
 }
 
@@ -46,6 +50,11 @@ func (a *ApiServer) Run() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// user events are published from the outbox in the background until the server exits
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+	go outbox.NewDispatcher(a.storage, a.publisher, a.logger).Run(eventsCtx)
+
 	// Start server
 	go func() {
 		a.logger.Info("Starting server on :8080")
@@ -88,6 +97,19 @@ func (a *ApiServer) registerRoutes(router *http.ServeMux) {
 
 	router.HandleFunc("GET /auth/verify-email", handlers.NewRegisterHandler(a.storage, a.logger).Verify)
 
+	// cohorts, members join with a code and teachers manage their own cohorts
+	cohortHandler := handlers.NewCohortHandler(a.storage, a.logger)
+	router.HandleFunc("GET /auth/cohorts", middleware.ValidateAccessToken(cohortHandler.GetUserCohorts, a.storage))
+	router.HandleFunc("POST /auth/cohorts/join", middleware.ValidateAccessToken(cohortHandler.Join, a.storage))
+	router.HandleFunc("POST /auth/cohorts/{id}/leave", middleware.ValidateAccessToken(cohortHandler.Leave, a.storage))
+	router.HandleFunc("GET /auth/cohorts/owned", middleware.ValidateAccessToken(middleware.RequireTeacher(cohortHandler.GetOwnedCohorts), a.storage))
+	router.HandleFunc("POST /auth/cohorts", middleware.ValidateAccessToken(middleware.RequireTeacher(cohortHandler.Create), a.storage))
+	router.HandleFunc("GET /auth/cohorts/{id}", middleware.ValidateAccessToken(middleware.RequireTeacher(cohortHandler.Get), a.storage))
+	router.HandleFunc("PUT /auth/cohorts/{id}", middleware.ValidateAccessToken(middleware.RequireTeacher(cohortHandler.Update), a.storage))
+	router.HandleFunc("DELETE /auth/cohorts/{id}", middleware.ValidateAccessToken(middleware.RequireTeacher(cohortHandler.Delete), a.storage))
+	router.HandleFunc("POST /auth/cohorts/{id}/code", middleware.ValidateAccessToken(middleware.RequireTeacher(cohortHandler.RegenerateJoinCode), a.storage))
+	router.HandleFunc("DELETE /auth/cohorts/{id}/members/{userId}", middleware.ValidateAccessToken(middleware.RequireTeacher(cohortHandler.RemoveMember), a.storage))
+
 	// internal
 	internalApiKey := os.Getenv("INTERNAL_API_KEY")
 	router.HandleFunc("GET /auth/users", middleware.InternalAuth(handlers.NewGetAllUsersHandler(a.storage, a.logger).Handle, a.logger, internalApiKey))
@@ -99,12 +121,13 @@ func (a *ApiServer) registerRoutes(router *http.ServeMux) {
 	router.HandleFunc("PUT /auth/roles/{id}", middleware.InternalAuth(handlers.NewUpdateRoleHandler(a.storage, a.logger).Handle, a.logger, internalApiKey))
 	router.HandleFunc("DELETE /auth/roles/{id}", middleware.InternalAuth(handlers.NewDeleteRoleHandler(a.storage, a.logger).Handle, a.logger, internalApiKey))
 
+	router.HandleFunc("GET /auth/cohorts/{id}/members", middleware.InternalAuth(cohortHandler.GetMembers, a.logger, internalApiKey))
 	router.HandleFunc("GET /auth/summary", middleware.InternalAuth(handlers.NewSummaryHandler(a.storage, a.logger).Handle, a.logger, internalApiKey))
 
 }
 
-func (a *ApiServer) HealthCheckHandler(w http.ResponseWriter, _ *http.Request) {
-	if err := a.storage.Ping(); err != nil {
+func (a *ApiServer) HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if err := a.storage.Ping(r.Context()); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy"})
 		return
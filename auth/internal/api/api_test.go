@@ -0,0 +1,318 @@
+package api
+
+import (
+	"auth/internal/events"
+	"auth/internal/models"
+	"auth/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPassword = "correct horse battery staple"
+
+type testServer struct {
+	store *storage.MemoryStore
+	mux   *http.ServeMux
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+	store := storage.NewMemoryStore()
+	mux := http.NewServeMux()
+	NewApiServer(":0", store, zap.NewNop(), events.Discard).registerRoutes(mux)
+	return &testServer{store: store, mux: mux}
+}
+
+// addUser creates a user with testPassword
+func (s *testServer) addUser(t *testing.T, email string, verified bool) *models.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.store.CreateUser(context.Background(), &models.User{FirstName: "Test", LastName: "User", Email: email, Password: string(hash)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified {
+		user, err = s.store.GetUserByIdInternal(context.Background(), user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.Verified = true
+		if err = s.store.UpdateUser(context.Background(), user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return user
+}
+
+func (s *testServer) do(method string, path string, body string, prepare func(r *http.Request)) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if prepare != nil {
+		prepare(r)
+	}
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, r)
+	return w
+}
+
+type loginResponse struct {
+	UserID      int    `json:"user_id"`
+	Role        string `json:"role"`
+	AccessToken string `json:"access_token"`
+}
+
+// login logs the user in and returns the response with the session cookie
+func (s *testServer) login(t *testing.T, email string) (loginResponse, *http.Cookie) {
+	t.Helper()
+	w := s.do(http.MethodPost, "/auth/login", `{"email":"`+email+`","password":"`+testPassword+`"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: status %d, body %q", w.Code, w.Body.String())
+	}
+	var response loginResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "session_id" {
+			return response, cookie
+		}
+	}
+	t.Fatal("login: no session cookie")
+	return response, nil
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t)
+	user := s.addUser(t, "student@example.com", true)
+	s.addUser(t, "unverified@example.com", false)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid credentials", `{"email":"student@example.com","password":"` + testPassword + `"}`, http.StatusOK},
+		{"email is case insensitive", `{"email":"Student@Example.com","password":"` + testPassword + `"}`, http.StatusOK},
+		{"wrong password", `{"email":"student@example.com","password":"wrong"}`, http.StatusUnauthorized},
+		{"unknown email", `{"email":"nobody@example.com","password":"` + testPassword + `"}`, http.StatusUnauthorized},
+		{"unverified user", `{"email":"unverified@example.com","password":"` + testPassword + `"}`, http.StatusUnauthorized},
+		{"malformed body", `{"email":`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodPost, "/auth/login", tt.body, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var response loginResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.UserID != user.ID || response.Role != string(models.RoleUser) || response.AccessToken == "" {
+				t.Errorf("response %+v, want user %d with role %s and an access token", response, user.ID, models.RoleUser)
+			}
+		})
+	}
+}
+
+func TestAccessTokenVerification(t *testing.T) {
+	s := newTestServer(t)
+	user := s.addUser(t, "student@example.com", true)
+	login, _ := s.login(t, user.Email)
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{"bearer token", "Bearer " + login.AccessToken, http.StatusOK},
+		{"missing token", "", http.StatusUnauthorized},
+		{"token without bearer prefix", login.AccessToken, http.StatusUnauthorized},
+		{"tampered token", "Bearer " + login.AccessToken + "x", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodPost, "/auth/verify", "", func(r *http.Request) {
+				if tt.header != "" {
+					r.Header.Set("Authorization", tt.header)
+				}
+			})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				UserID int    `json:"user_id"`
+				Role   string `json:"role"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.UserID != user.ID || response.Role != string(models.RoleUser) {
+				t.Errorf("response %+v, want user %d with role %s", response, user.ID, models.RoleUser)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare changes the session of the logged in user and returns the cookie sent with the refresh
+		prepare    func(t *testing.T, s *testServer, userID int, cookie *http.Cookie) *http.Cookie
+		wantStatus int
+	}{
+		{
+			name:       "valid session",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "missing session cookie",
+			prepare: func(*testing.T, *testServer, int, *http.Cookie) *http.Cookie {
+				return nil
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown session",
+			prepare: func(*testing.T, *testServer, int, *http.Cookie) *http.Cookie {
+				return &http.Cookie{Name: "session_id", Value: "unknown"}
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired session",
+			prepare: func(t *testing.T, s *testServer, userID int, cookie *http.Cookie) *http.Cookie {
+				err := s.store.UpdateUserSession(context.Background(), models.UserSession{UserID: userID, SessionID: cookie.Value, Expiration: time.Now().Add(-time.Minute)})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return cookie
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "after logout",
+			prepare: func(t *testing.T, s *testServer, _ int, cookie *http.Cookie) *http.Cookie {
+				w := s.do(http.MethodPost, "/auth/logout", "", func(r *http.Request) { r.AddCookie(cookie) })
+				if w.Code != http.StatusOK {
+					t.Fatalf("logout: status %d", w.Code)
+				}
+				return cookie
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			user := s.addUser(t, "student@example.com", true)
+			_, cookie := s.login(t, user.Email)
+			if tt.prepare != nil {
+				cookie = tt.prepare(t, s, user.ID, cookie)
+			}
+
+			w := s.do(http.MethodPost, "/auth/refresh", "", func(r *http.Request) {
+				if cookie != nil {
+					r.AddCookie(cookie)
+				}
+			})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var response struct {
+				AccessToken string `json:"access_token"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			// the refreshed token is accepted by the other services
+			w = s.do(http.MethodPost, "/auth/verify", "", func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+response.AccessToken)
+			})
+			if w.Code != http.StatusOK {
+				t.Errorf("verify refreshed token: status %d, body %q", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUserEventsOutbox(t *testing.T) {
+	t.Setenv("INTERNAL_API_KEY", "test-key")
+	s := newTestServer(t)
+	user := s.addUser(t, "student@example.com", true)
+	w := s.do(http.MethodDelete, "/auth/users/"+strconv.Itoa(user.ID), "", func(r *http.Request) {
+		r.Header.Set("X-Api-Key", "test-key")
+	})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete user: status %d, body %q", w.Code, w.Body.String())
+	}
+
+	// events of the user are published in order, the deletion only after the registration was delivered
+	ctx := context.Background()
+	for _, want := range []string{events.UserRegistered, events.UserDeleted} {
+		due, err := s.store.GetDueOutboxEvents(ctx, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 1 || due[0].Type != want || due[0].UserID != user.ID {
+			t.Fatalf("due events %+v, want one %s event of user %d", due, want, user.ID)
+		}
+		var payload events.UserPayload
+		if err = json.Unmarshal(due[0].Payload, &payload); err != nil || payload.UserID != user.ID {
+			t.Fatalf("payload %s, want user %d", due[0].Payload, user.ID)
+		}
+		if err = s.store.MarkOutboxEventDelivered(ctx, due[0].ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if due, err := s.store.GetDueOutboxEvents(ctx, 10); err != nil || len(due) != 0 {
+		t.Fatalf("due events after delivery %+v, %v", due, err)
+	}
+}
+
+func TestGetUsersByIDs(t *testing.T) {
+	t.Setenv("INTERNAL_API_KEY", "test-key")
+	s := newTestServer(t)
+	first := s.addUser(t, "first@example.com", true)
+	s.addUser(t, "second@example.com", true)
+	third := s.addUser(t, "third@example.com", true)
+	withKey := func(r *http.Request) { r.Header.Set("X-Api-Key", "test-key") }
+
+	w := s.do(http.MethodGet, fmt.Sprintf("/auth/users?ids=%d,%d,999", first.ID, third.ID), "", withKey)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+	}
+	var users []models.User
+	if err := json.NewDecoder(w.Body).Decode(&users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].ID != first.ID || users[1].ID != third.ID {
+		t.Errorf("users %+v, want users %d and %d", users, first.ID, third.ID)
+	}
+
+	if w = s.do(http.MethodGet, "/auth/users?ids=1,x", "", withKey); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ids: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
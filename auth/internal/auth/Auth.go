@@ -67,6 +67,21 @@ func GenerateSessionID(length int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// joinCodeAlphabet leaves out characters which are easy to confuse when a code is read aloud or copied by hand
+const joinCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateJoinCode returns a random code of the given length users enter to join a cohort
+func GenerateJoinCode(length int) (string, error) {
+	bytes := make([]byte, length)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	for i, b := range bytes {
+		bytes[i] = joinCodeAlphabet[int(b)%len(joinCodeAlphabet)]
+	}
+	return string(bytes), nil
+}
+
 // todo: use this function to set cookies in handlers
 func SetCookie(w http.ResponseWriter, name, value string) {
 	isProd := os.Getenv("ENV") == "production"
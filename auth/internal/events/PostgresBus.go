@@ -0,0 +1,235 @@
+package events
+
+import (
+	"auth/internal/migrations"
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"slices"
+	"time"
+)
+
+const (
+	// channel is notified after each published event, consumers also poll in case a notification is missed
+	channel      = "events"
+	pollInterval = 30 * time.Second
+	// retryInterval is the delay before an event a handler failed to process is delivered again
+	retryInterval = 5 * time.Second
+	// maxAttempts is the number of times a consumer tries to handle an event before parking it
+	maxAttempts = 10
+	batchSize   = 100
+	// publishLock is the key of the advisory lock serializing publishers
+	publishLock = 7_091_355
+)
+
+// migrationFiles are the migrations of the events database, every service using the bus carries the same ones
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type subscription struct {
+	consumer   string
+	eventTypes []string
+	handler    Handler
+}
+
+// PostgresBus keeps events in the events table and wakes consumers up with LISTEN/NOTIFY.
+// The position of each consumer in the table is kept in event_consumers, so no events are lost
+// while a consumer is down. Failed attempts to handle an event are counted in event_failures,
+// an event failing maxAttempts times is parked there and the consumer moves past it
+type PostgresBus struct {
+	db            *sql.DB
+	connString    string
+	logger        *zap.Logger
+	subscriptions []subscription
+}
+
+func NewPostgresBus(connString string, logger *zap.Logger) (*PostgresBus, error) {
+	db, err := sql.Open("postgres", connString)
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := migrations.NewFromFS(db, migrationFiles, "migrations")
+	if err == nil {
+		_, err = migrator.Up(context.Background())
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate events database: %w", err)
+	}
+	return &PostgresBus{
+		db:         db,
+		connString: connString,
+		logger:     logger,
+	}, nil
+}
+
+func (b *PostgresBus) Publish(ctx context.Context, eventType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// publishers are serialized so that events become visible in the order of their ids,
+	// otherwise a consumer could move past an id which is committed later
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, publishLock); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO events (type, payload, created_at) VALUES ($1, $2, NOW())`, eventType, data); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, channel, eventType); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (b *PostgresBus) Subscribe(consumer string, eventTypes []string, handler Handler) {
+	b.subscriptions = append(b.subscriptions, subscription{consumer: consumer, eventTypes: eventTypes, handler: handler})
+}
+
+func (b *PostgresBus) Run(ctx context.Context) error {
+	if len(b.subscriptions) == 0 {
+		return nil
+	}
+	listener := pq.NewListener(b.connString, time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			b.logger.Error("events listener failed", zap.Error(err))
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(channel); err != nil {
+		return err
+	}
+	for {
+		wait := pollInterval
+		for _, sub := range b.subscriptions {
+			if err := b.consume(ctx, sub); err != nil {
+				b.logger.Error("failed to consume events", zap.String("consumer", sub.consumer), zap.Error(err))
+				wait = retryInterval
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-listener.Notify:
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (b *PostgresBus) Close() error {
+	return b.db.Close()
+}
+
+// consume delivers all events after the position of the consumer
+func (b *PostgresBus) consume(ctx context.Context, sub subscription) error {
+	for {
+		read, err := b.consumeBatch(ctx, sub)
+		if err != nil || read < batchSize {
+			return err
+		}
+	}
+}
+
+// consumeBatch delivers the next batch of events to the consumer and moves its position past the processed ones,
+// it returns the number of events read
+func (b *PostgresBus) consumeBatch(ctx context.Context, sub subscription) (int, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO event_consumers (consumer, last_event_id) VALUES ($1, 0) ON CONFLICT (consumer) DO NOTHING`, sub.consumer)
+	if err != nil {
+		return 0, err
+	}
+	// the row of the consumer stays locked until the batch is processed, so replicas of a service
+	// do not process the same events at the same time
+	var lastEventID int64
+	err = tx.QueryRowContext(ctx, `SELECT last_event_id FROM event_consumers WHERE consumer = $1 FOR UPDATE`, sub.consumer).Scan(&lastEventID)
+	if err != nil {
+		return 0, err
+	}
+	batch, err := readEvents(ctx, tx, lastEventID)
+	if err != nil {
+		return 0, err
+	}
+	var handlerErr error
+	for _, event := range batch {
+		if slices.Contains(sub.eventTypes, event.Type) {
+			if failure := sub.handler(ctx, event); failure != nil {
+				parked, err := b.recordFailure(ctx, tx, sub.consumer, event, failure)
+				if err != nil {
+					return 0, err
+				}
+				if !parked {
+					handlerErr = fmt.Errorf("failed to handle event %d of type %s: %w", event.ID, event.Type, failure)
+					break
+				}
+			}
+		}
+		lastEventID = event.ID
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE event_consumers SET last_event_id = $2 WHERE consumer = $1`, sub.consumer, lastEventID)
+	if err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(batch), handlerErr
+}
+
+// recordFailure counts the failed attempt of the consumer to handle the event and reports whether the event
+// is parked, which happens on the last of maxAttempts attempts. Parked events stay in event_failures with
+// their last error to be inspected, they are not delivered to the consumer again
+func (b *PostgresBus) recordFailure(ctx context.Context, tx *sql.Tx, consumer string, event Event, handlerErr error) (bool, error) {
+	var attempts int
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO event_failures (consumer, event_id, attempts, last_error, failed_at, parked)
+		VALUES ($1, $2, 1, $3, NOW(), $4::integer <= 1)
+		ON CONFLICT (consumer, event_id) DO UPDATE SET
+			attempts = event_failures.attempts + 1,
+			last_error = EXCLUDED.last_error,
+			failed_at = EXCLUDED.failed_at,
+			parked = event_failures.attempts + 1 >= $4::integer
+		RETURNING attempts`, consumer, event.ID, handlerErr.Error(), maxAttempts).Scan(&attempts)
+	if err != nil {
+		return false, err
+	}
+	if attempts < maxAttempts {
+		return false, nil
+	}
+	b.logger.Error("parked event after failed attempts", zap.String("consumer", consumer), zap.Int64("event_id", event.ID),
+		zap.String("event_type", event.Type), zap.Int("attempts", attempts), zap.Error(handlerErr))
+	return true, nil
+}
+
+func readEvents(ctx context.Context, tx *sql.Tx, afterID int64) ([]Event, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, type, payload, created_at FROM events WHERE id > $1 ORDER BY id LIMIT $2`, afterID, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	batch := make([]Event, 0, batchSize)
+	for rows.Next() {
+		var event Event
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.Type, &payload, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		batch = append(batch, event)
+	}
+	return batch, rows.Err()
+}
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS events (
+    id         bigserial PRIMARY KEY,
+    type       text NOT NULL,
+    payload    jsonb NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS event_consumers (
+    consumer      text PRIMARY KEY,
+    last_event_id bigint NOT NULL DEFAULT 0
+);
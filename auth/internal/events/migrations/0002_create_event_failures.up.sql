@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS event_failures (
+    consumer   text NOT NULL,
+    event_id   bigint NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    attempts   integer NOT NULL,
+    last_error text NOT NULL,
+    failed_at  timestamptz NOT NULL,
+    parked     boolean NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (consumer, event_id)
+);
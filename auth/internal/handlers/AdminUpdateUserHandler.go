@@ -32,19 +32,19 @@ func (h *AdminUpdateUserHandler) Handle(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "invalid request payload", http.StatusBadRequest)
 		return
 	}
-	dbUser, err := h.storage.GetUserById(userID, true)
+	dbUser, err := h.storage.GetUserById(r.Context(), userID, true)
 	if err != nil {
 		h.logger.Error("failed to get user", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 
 	userToUpdate := dbUser
 	userToUpdate.Role = *userPayload.Role
 
-	if err := h.storage.UpdateUser(userToUpdate); err != nil {
+	if err := h.storage.UpdateUser(r.Context(), userToUpdate); err != nil {
 		h.logger.Error("failed to update user", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"auth/internal/auth"
+	"auth/internal/models"
+	"auth/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+const joinCodeLength = 8
+
+// CohortHandler serves cohorts to their members and owners. Owners are teachers, admins may manage all cohorts
+type CohortHandler struct {
+	storage storage.Store
+	logger  *zap.Logger
+}
+
+func NewCohortHandler(store storage.Store, logger *zap.Logger) *CohortHandler {
+	return &CohortHandler{
+		storage: store,
+		logger:  logger,
+	}
+}
+
+// GetUserCohorts returns cohorts the logged in user is a member of, without join codes
+func (h *CohortHandler) GetUserCohorts(rw http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	cohorts, err := h.storage.GetUserCohorts(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to get user cohorts", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	for i := range cohorts {
+		cohorts[i].JoinCode = ""
+	}
+	h.writeJSON(rw, http.StatusOK, cohorts)
+}
+
+func (h *CohortHandler) Join(rw http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var payload models.JoinCohortPayload
+	if err := payload.FromJSON(r.Body); err != nil {
+		http.Error(rw, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cohort, err := h.storage.GetCohortByJoinCode(r.Context(), payload.Code)
+	if errors.Is(err, storage.ErrCohortNotFound) {
+		http.Error(rw, "invalid join code", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get cohort by join code", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	if err = h.storage.AddCohortMember(r.Context(), cohort.ID, userID); err != nil {
+		h.logger.Error("failed to join cohort", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	cohort.JoinCode = ""
+	h.writeJSON(rw, http.StatusOK, cohort)
+}
+
+func (h *CohortHandler) Leave(rw http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	cohortID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(rw, "invalid cohort id", http.StatusBadRequest)
+		return
+	}
+	err = h.storage.RemoveCohortMember(r.Context(), cohortID, userID)
+	if errors.Is(err, storage.ErrNotCohortMember) {
+		http.Error(rw, "not a member of the cohort", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to leave cohort", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// GetOwnedCohorts returns cohorts of the logged in teacher, admins get all cohorts
+func (h *CohortHandler) GetOwnedCohorts(rw http.ResponseWriter, r *http.Request) {
+	ownerID := r.Context().Value("user_id").(int)
+	if r.Context().Value("user_role") == models.RoleAdmin {
+		ownerID = 0
+	}
+	cohorts, err := h.storage.GetCohorts(r.Context(), ownerID)
+	if err != nil {
+		h.logger.Error("failed to get cohorts", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	h.writeJSON(rw, http.StatusOK, cohorts)
+}
+
+func (h *CohortHandler) Create(rw http.ResponseWriter, r *http.Request) {
+	var cohort models.Cohort
+	if err := cohort.FromJSON(r.Body); err != nil {
+		http.Error(rw, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := cohort.Validate(); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var err error
+	cohort.OwnerID = r.Context().Value("user_id").(int)
+	cohort.JoinCode, err = auth.GenerateJoinCode(joinCodeLength)
+	if err != nil {
+		h.logger.Error("failed to generate join code", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	created, err := h.storage.CreateCohort(r.Context(), cohort)
+	if err != nil {
+		h.logger.Error("failed to create cohort", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	h.writeJSON(rw, http.StatusCreated, created)
+}
+
+// Get returns the cohort with its members
+func (h *CohortHandler) Get(rw http.ResponseWriter, r *http.Request) {
+	cohort, ok := h.getOwnCohort(rw, r)
+	if !ok {
+		return
+	}
+	h.writeJSON(rw, http.StatusOK, cohort)
+}
+
+func (h *CohortHandler) Update(rw http.ResponseWriter, r *http.Request) {
+	cohort, ok := h.getOwnCohort(rw, r)
+	if !ok {
+		return
+	}
+	var payload models.Cohort
+	if err := payload.FromJSON(r.Body); err != nil {
+		http.Error(rw, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cohort.Name = payload.Name
+	h.saveCohort(r.Context(), rw, cohort)
+}
+
+// RegenerateJoinCode replaces the join code, members who already joined stay in the cohort
+func (h *CohortHandler) RegenerateJoinCode(rw http.ResponseWriter, r *http.Request) {
+	cohort, ok := h.getOwnCohort(rw, r)
+	if !ok {
+		return
+	}
+	var err error
+	cohort.JoinCode, err = auth.GenerateJoinCode(joinCodeLength)
+	if err != nil {
+		h.logger.Error("failed to generate join code", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	h.saveCohort(r.Context(), rw, cohort)
+}
+
+func (h *CohortHandler) Delete(rw http.ResponseWriter, r *http.Request) {
+	cohort, ok := h.getOwnCohort(rw, r)
+	if !ok {
+		return
+	}
+	if err := h.storage.DeleteCohort(r.Context(), cohort.ID); err != nil {
+		h.logger.Error("failed to delete cohort", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CohortHandler) RemoveMember(rw http.ResponseWriter, r *http.Request) {
+	cohort, ok := h.getOwnCohort(rw, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(r.PathValue("userId"))
+	if err != nil {
+		http.Error(rw, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	err = h.storage.RemoveCohortMember(r.Context(), cohort.ID, userID)
+	if errors.Is(err, storage.ErrNotCohortMember) {
+		http.Error(rw, "user is not a member of the cohort", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to remove cohort member", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// GetMembers returns the owner and members of the cohort to other services
+func (h *CohortHandler) GetMembers(rw http.ResponseWriter, r *http.Request) {
+	cohortID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(rw, "invalid cohort id", http.StatusBadRequest)
+		return
+	}
+	cohort, err := h.storage.GetCohortByID(r.Context(), cohortID)
+	if errors.Is(err, storage.ErrCohortNotFound) {
+		http.Error(rw, "cohort not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get cohort", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	members := models.CohortMembers{
+		CohortID: cohort.ID,
+		OwnerID:  cohort.OwnerID,
+		UsersIDs: make([]int, len(cohort.Members)),
+	}
+	for i, member := range cohort.Members {
+		members.UsersIDs[i] = member.UserID
+	}
+	h.writeJSON(rw, http.StatusOK, members)
+}
+
+// getOwnCohort returns the cohort from the path if the user owns it or is an admin,
+// otherwise an error response is written. Cohorts of other teachers are reported as not found
+func (h *CohortHandler) getOwnCohort(rw http.ResponseWriter, r *http.Request) (models.Cohort, bool) {
+	cohortID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(rw, "invalid cohort id", http.StatusBadRequest)
+		return models.Cohort{}, false
+	}
+	cohort, err := h.storage.GetCohortByID(r.Context(), cohortID)
+	if err != nil && !errors.Is(err, storage.ErrCohortNotFound) {
+		h.logger.Error("failed to get cohort", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return cohort, false
+	}
+	if err != nil || (r.Context().Value("user_role") != models.RoleAdmin && cohort.OwnerID != r.Context().Value("user_id").(int)) {
+		http.Error(rw, "cohort not found", http.StatusNotFound)
+		return cohort, false
+	}
+	return cohort, true
+}
+
+func (h *CohortHandler) saveCohort(ctx context.Context, rw http.ResponseWriter, cohort models.Cohort) {
+	err := h.storage.UpdateCohort(ctx, cohort)
+	if errors.Is(err, storage.ErrCohortNotFound) {
+		http.Error(rw, "cohort not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to update cohort", zap.Error(err))
+		serverError(ctx, rw, err, "internal server error")
+		return
+	}
+	h.writeJSON(rw, http.StatusOK, cohort)
+}
+
+func (h *CohortHandler) writeJSON(rw http.ResponseWriter, status int, value interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(value); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
@@ -27,10 +27,10 @@ func (h *CreateRoleHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createdRole, err := h.storage.CreateRole(newRole)
+	createdRole, err := h.storage.CreateRole(r.Context(), newRole)
 	if err != nil {
 		h.logger.Error("failed to create role", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 
@@ -26,9 +26,9 @@ func (h *DeleteRoleHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.storage.DeleteRole(roleID); err != nil {
+	if err := h.storage.DeleteRole(r.Context(), roleID); err != nil {
 		h.logger.Error("failed to delete role", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 
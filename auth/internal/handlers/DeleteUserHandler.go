@@ -26,9 +26,9 @@ func (h *DeleteUserHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.storage.DeleteUser(userID); err != nil {
+	if err := h.storage.DeleteUser(r.Context(), userID); err != nil {
 		h.logger.Error("failed to delete user", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"auth/internal/storage"
+	"context"
+	"errors"
+	"net/http"
+)
+
+// StatusClientClosedRequest is the non-standard status of requests cancelled by the client
+const StatusClientClosedRequest = 499
+
+// serverError responds to a request which failed on err. Requests cancelled by the client get 499
+// and requests which ran out of time get 503, other failures are internal errors with the message
+func serverError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		// nobody reads the response anymore, the status is only logged
+		w.WriteHeader(StatusClientClosedRequest)
+	case storage.IsTimeout(err) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		http.Error(w, "service unavailable, request timed out", http.StatusServiceUnavailable)
+	case message == "":
+		w.WriteHeader(http.StatusInternalServerError)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -20,10 +20,10 @@ func NewGetAllRolesHandler(store storage.Store, logger *zap.Logger) *GetAllRoles
 }
 
 func (h *GetAllRolesHandler) Handle(rw http.ResponseWriter, r *http.Request) {
-	roles, err := h.storage.GetAllRoles()
+	roles, err := h.storage.GetAllRoles(r.Context())
 	if err != nil {
 		h.logger.Error("failed to get roles from db", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"auth/internal/models"
 	"auth/internal/storage"
 	"encoding/json"
 	"go.uber.org/zap"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type GetAllUsersHandler struct {
@@ -19,11 +22,27 @@ func NewGetAllUsersHandler(store storage.Store, logger *zap.Logger) *GetAllUsers
 	}
 }
 
+// Handle returns all users, or only the users listed in the ids query parameter as comma separated IDs
 func (h *GetAllUsersHandler) Handle(rw http.ResponseWriter, r *http.Request) {
-	users, err := h.storage.GetAllUsers()
+	var users []models.User
+	var err error
+	if value := r.URL.Query().Get("ids"); value != "" {
+		var ids []int
+		for _, field := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(field)
+			if err != nil {
+				http.Error(rw, "invalid user id", http.StatusBadRequest)
+				return
+			}
+			ids = append(ids, id)
+		}
+		users, err = h.storage.GetUsersByIDs(r.Context(), ids)
+	} else {
+		users, err = h.storage.GetAllUsers(r.Context())
+	}
 	if err != nil {
 		h.logger.Error("failed to get users from db", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 
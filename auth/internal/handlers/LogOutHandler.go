@@ -22,14 +22,14 @@ func NewLogOutHandler(store storage.Store, logger *zap.Logger) *LogOutHandler {
 
 func (h *LogOutHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(int)
-	err := h.store.UpdateUserSession(models.UserSession{
+	err := h.store.UpdateUserSession(r.Context(), models.UserSession{
 		UserID:     userID,
 		SessionID:  "",
 		Expiration: time.Now(),
 	})
 	if err != nil {
 		h.logger.Error("Error updating session", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 	}
 	w.WriteHeader(http.StatusOK)
 }
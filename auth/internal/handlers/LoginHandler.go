@@ -32,7 +32,7 @@ func (h *LoginHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	userPayload.Email = strings.ToLower(userPayload.Email)
-	dbUser, err := h.store.GetUserByEmail(userPayload.Email)
+	dbUser, err := h.store.GetUserByEmail(r.Context(), userPayload.Email)
 	if err != nil {
 		h.logger.Error("Error getting user by email", zap.Error(err))
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
@@ -42,7 +42,7 @@ func (h *LoginHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "User not verified", http.StatusUnauthorized)
 		return
 	}
-	//userSession, err := h.store.GetUserSession(dbUser.ID)
+	//userSession, err := h.store.GetUserSession(r.Context(), dbUser.ID)
 	//if err == nil {
 	//	if userSession.Expiration.After(time.Now()) {
 	//		http.Error(w, "User already logged in", http.StatusConflict)
@@ -57,10 +57,10 @@ func (h *LoginHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	sessionId, err := auth.GenerateSessionID(64)
 	if err != nil {
 		h.logger.Error("Error generating session id", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
-	err = h.store.SaveUserSession(models.UserSession{
+	err = h.store.SaveUserSession(r.Context(), models.UserSession{
 		UserID:     dbUser.ID,
 		SessionID:  sessionId,
 		Expiration: time.Now().Add(7 * 24 * time.Hour),
@@ -68,7 +68,7 @@ func (h *LoginHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	accessToken, err := auth.GenerateAccessToken(strconv.Itoa(dbUser.ID))
 	if err != nil {
 		h.logger.Error("Error generating access token", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	auth.SetCookie(w, "session_id", sessionId)
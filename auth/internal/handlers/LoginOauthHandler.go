@@ -4,6 +4,7 @@ import (
 	"auth/internal/auth"
 	"auth/internal/models"
 	"auth/internal/storage"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -40,35 +41,35 @@ func (h *OauthLoginHandler) HandleGoogle(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	dbUser, firstLogin, err := h.findOrCreateUser(userInfo)
+	dbUser, firstLogin, err := h.findOrCreateUser(r.Context(), userInfo)
 	if err != nil {
 		h.logger.Error("Error finding/creating user", zap.Error(err))
-		http.Error(w, "Error processing user", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Error processing user")
 		return
 	}
 
 	sessionId, err := auth.GenerateSessionID(64)
 	if err != nil {
 		h.logger.Error("Error generating session id", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
-	err = h.store.SaveUserSession(models.UserSession{
+	err = h.store.SaveUserSession(r.Context(), models.UserSession{
 		UserID:     dbUser.ID,
 		SessionID:  sessionId,
 		Expiration: time.Now().Add(7 * 24 * time.Hour),
 	})
 	if err != nil {
 		h.logger.Error("Error saving session", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
 	accessToken, err := auth.GenerateAccessToken(strconv.Itoa(dbUser.ID))
 	if err != nil {
 		h.logger.Error("Error generating access token", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
@@ -119,8 +120,8 @@ func (h *OauthLoginHandler) verifyGoogleToken(token string) (*models.GoogleUserI
 	return &userInfo, nil
 }
 
-func (h *OauthLoginHandler) findOrCreateUser(googleInfo *models.GoogleUserInfo) (*models.User, bool, error) {
-	dbUser, err := h.store.GetUserByEmail(googleInfo.Email)
+func (h *OauthLoginHandler) findOrCreateUser(ctx context.Context, googleInfo *models.GoogleUserInfo) (*models.User, bool, error) {
+	dbUser, err := h.store.GetUserByEmail(ctx, googleInfo.Email)
 	if err == nil {
 		if dbUser.GoogleID != googleInfo.ID {
 			dbUser.GoogleID = googleInfo.ID
@@ -131,7 +132,7 @@ func (h *OauthLoginHandler) findOrCreateUser(googleInfo *models.GoogleUserInfo)
 		if dbUser.LastName != googleInfo.LastName {
 			dbUser.LastName = googleInfo.LastName
 		}
-		if err = h.store.UpdateUser(dbUser); err != nil {
+		if err = h.store.UpdateUser(ctx, dbUser); err != nil {
 			return nil, false, err
 		}
 		return dbUser, false, nil
@@ -143,7 +144,7 @@ func (h *OauthLoginHandler) findOrCreateUser(googleInfo *models.GoogleUserInfo)
 		LastName:  googleInfo.LastName,
 		Role:      models.RoleUser,
 	}
-	createdUser, err := h.store.CreateUser(newUser)
+	createdUser, err := h.store.CreateUser(ctx, newUser)
 	if err != nil {
 		return nil, false, err
 	}
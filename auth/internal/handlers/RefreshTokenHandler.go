@@ -26,7 +26,7 @@ func (h *RefreshTokenHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	accessToken, err := auth.GenerateAccessToken(strconv.Itoa(userID))
 	if err != nil {
 		h.logger.Error("Error generating access token", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
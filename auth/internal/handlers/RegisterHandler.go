@@ -40,21 +40,21 @@ func (h *RegisterHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	user.Email = strings.ToLower(user.Email)
-	if _, err := h.store.GetUserByEmail(user.Email); err == nil {
+	if _, err := h.store.GetUserByEmail(r.Context(), user.Email); err == nil {
 		http.Error(w, "User already exists", http.StatusConflict)
 		return
 	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		h.logger.Error("Error hashing password", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	user.Password = string(hashedPassword)
-	userCreated, err := h.store.CreateUser(&user)
+	userCreated, err := h.store.CreateUser(r.Context(), &user)
 	if err != nil {
 		h.logger.Error("Error creating user", zap.Error(err))
-		http.Error(w, "Error creating user", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Error creating user")
 		return
 	}
 
@@ -62,7 +62,7 @@ func (h *RegisterHandler) Register(w http.ResponseWriter, r *http.Request) {
 	err = auth.SendVerificationEmail(userCreated.Email, verificationToken)
 	if err != nil {
 		h.logger.Error("Error sending verification email", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
@@ -84,7 +84,7 @@ func (h *RegisterHandler) Register(w http.ResponseWriter, r *http.Request) {
 	//	http.Error(w, "Internal server error", http.StatusInternalServerError)
 	//	return
 	//}
-	//err = h.store.SaveUserSession(models.UserSession{
+	//err = h.store.SaveUserSession(r.Context(), models.UserSession{
 	//	UserID:     userCreated.ID,
 	//	SessionID:  sessionId,
 	//	Expiration: time.Now().Add(7 * 24 * time.Hour),
@@ -128,17 +128,17 @@ func (h *RegisterHandler) Verify(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid token", http.StatusBadRequest)
 		return
 	}
-	user, err := h.store.GetUserByIdInternal(userID)
+	user, err := h.store.GetUserByIdInternal(r.Context(), userID)
 	if err != nil {
 		h.logger.Error("Error getting user by id", zap.Error(err))
 		http.Error(w, "Invalid token", http.StatusBadRequest)
 		return
 	}
 	user.Verified = true
-	err = h.store.UpdateUser(user)
+	err = h.store.UpdateUser(r.Context(), user)
 	if err != nil {
 		h.logger.Error("Error updating user", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	_, err = w.Write([]byte("User verified"))
@@ -29,7 +29,7 @@ func (h *ResetPasswordHandler) RequestReset(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	user, err := h.storage.GetUserByEmail(payload.Email)
+	user, err := h.storage.GetUserByEmail(r.Context(), payload.Email)
 	if err != nil {
 		// Return 200 even if email doesn't exist for security
 		w.WriteHeader(http.StatusOK)
@@ -39,14 +39,14 @@ func (h *ResetPasswordHandler) RequestReset(w http.ResponseWriter, r *http.Reque
 	token, err := auth.GenerateVerificationToken(strconv.Itoa(user.ID))
 	if err != nil {
 		h.logger.Error("failed to generate token", zap.Error(err))
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal error")
 		return
 	}
 
 	err = auth.SendPasswordResetEmail(user.Email, token)
 	if err != nil {
 		h.logger.Error("failed to send email", zap.Error(err))
-		http.Error(w, "Failed to send email", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Failed to send email")
 		return
 	}
 
@@ -78,14 +78,14 @@ func (h *ResetPasswordHandler) Reset(w http.ResponseWriter, r *http.Request) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
 	if err != nil {
 		h.logger.Error("failed to hash password", zap.Error(err))
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal error")
 		return
 	}
 
-	err = h.storage.UpdateUserPassword(userID, string(hashedPassword))
+	err = h.storage.UpdateUserPassword(r.Context(), userID, string(hashedPassword))
 	if err != nil {
 		h.logger.Error("failed to update password", zap.Error(err))
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal error")
 		return
 	}
 
@@ -20,17 +20,17 @@ func NewSummaryHandler(storage storage.Store, logger *zap.Logger) *SummaryHandle
 	}
 }
 
-func (h *SummaryHandler) Handle(w http.ResponseWriter, _ *http.Request) {
+func (h *SummaryHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	var summary models.AuthSummary
-	summary.Users = h.storage.GetUsersCount()
-	summary.ActiveUsers = h.storage.GetActiveUsersCount()
-	summary.Last24hRegistered = h.storage.GetLast24hRegisteredCount()
+	summary.Users = h.storage.GetUsersCount(r.Context())
+	summary.ActiveUsers = h.storage.GetActiveUsersCount(r.Context())
+	summary.Last24hRegistered = h.storage.GetLast24hRegisteredCount(r.Context())
 
 	w.Header().Set("Content-Type", "application/json")
 	err := json.NewEncoder(w).Encode(summary)
 	if err != nil {
 		h.logger.Error("failed to encode response", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "")
 		return
 	}
 }
@@ -35,9 +35,9 @@ func (h *UpdateRoleHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	updatedRole.ID = roleID
-	if err := h.storage.UpdateRole(updatedRole); err != nil {
+	if err := h.storage.UpdateRole(r.Context(), updatedRole); err != nil {
 		h.logger.Error("failed to update role", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 
@@ -37,17 +37,17 @@ func (h *UpdateUserHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		http.Error(rw, "invalid request payload", http.StatusBadRequest)
 		return
 	}
-	dbUser, err := h.storage.GetUserById(userID, true)
+	dbUser, err := h.storage.GetUserById(r.Context(), userID, true)
 	if err != nil {
 		h.logger.Error("failed to get user", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 	userToUpdate := applyUpdates(dbUser, &userPayload)
 
-	if err := h.storage.UpdateUser(userToUpdate); err != nil {
+	if err := h.storage.UpdateUser(r.Context(), userToUpdate); err != nil {
 		h.logger.Error("failed to update user", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 
@@ -26,7 +26,7 @@ func (h *VerifyTokenHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	err := json.NewEncoder(rw).Encode(resp)
 	if err != nil {
 		log.Printf("Failed to encode response: %v", err)
-		http.Error(rw, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "Internal server error")
 		return
 	}
 
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"auth/internal/models"
+	"net/http"
+)
+
+// RequireTeacher lets through teachers and admins, it expects the user to be set by ValidateAccessToken
+func RequireTeacher(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role := r.Context().Value("user_role")
+		if role != models.RoleTeacher && role != models.RoleAdmin {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -18,7 +18,7 @@ func ValidateSession(next http.HandlerFunc, storage storage.Store) http.HandlerF
 			http.Error(w, "Invalid session ID", http.StatusUnauthorized)
 			return
 		}
-		session, err := storage.GetUserSessionBySessionID(sessionID)
+		session, err := storage.GetUserSessionBySessionID(r.Context(), sessionID)
 		if err != nil {
 			log.Println("Failed to get session from storage", err)
 			http.Error(w, "Invalid session id", http.StatusUnauthorized)
@@ -29,7 +29,7 @@ func ValidateSession(next http.HandlerFunc, storage storage.Store) http.HandlerF
 			http.Error(w, "Session expired. Please log in", http.StatusUnauthorized)
 			return
 		}
-		user, err := storage.GetUserById(session.UserID, false)
+		user, err := storage.GetUserById(r.Context(), session.UserID, false)
 		if err != nil {
 			log.Printf("Failed to get user from storage: %v", err)
 			http.Error(w, "User not found", http.StatusUnauthorized)
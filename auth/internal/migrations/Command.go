@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+// Command runs the migrate subcommand of the service: "up", "down [steps]" or "version".
+// Down reverts one migration unless the number of steps is given
+func Command(ctx context.Context, m *Migrator, args []string, logger *zap.Logger) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [steps] | version")
+	}
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info("Applied migrations", zap.Int("count", applied))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %s", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		logger.Info("Reverted migrations", zap.Int("count", reverted))
+	case "version":
+		version, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info("Schema version", zap.Int("version", version))
+	default:
+		return fmt.Errorf("unknown migrate command %s", args[0])
+	}
+	return nil
+}
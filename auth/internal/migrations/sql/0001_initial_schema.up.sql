@@ -0,0 +1,26 @@
+-- tables are created only when missing so databases created before migrations adopt this version
+CREATE TABLE IF NOT EXISTS roles (
+    id   serial PRIMARY KEY,
+    name text NOT NULL UNIQUE
+);
+
+INSERT INTO roles (name) VALUES ('admin'), ('user') ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS users (
+    id         serial PRIMARY KEY,
+    first_name text NOT NULL DEFAULT '',
+    last_name  text NOT NULL DEFAULT '',
+    email      text NOT NULL UNIQUE,
+    pwd        text NOT NULL DEFAULT '',
+    role       text NOT NULL DEFAULT 'user',
+    google_id  text NOT NULL DEFAULT '',
+    verified   boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    updated_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS users_sessions (
+    user_id    integer PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    session_id text NOT NULL UNIQUE,
+    expiration timestamptz NOT NULL
+);
@@ -0,0 +1,20 @@
+INSERT INTO roles (name) VALUES ('teacher') ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS cohorts (
+    id         serial PRIMARY KEY,
+    name       text NOT NULL,
+    owner_id   integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    join_code  text NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS cohorts_owner_id_idx ON cohorts (owner_id);
+
+CREATE TABLE IF NOT EXISTS cohort_members (
+    cohort_id integer NOT NULL REFERENCES cohorts (id) ON DELETE CASCADE,
+    user_id   integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    joined_at timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (cohort_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS cohort_members_user_id_idx ON cohort_members (user_id);
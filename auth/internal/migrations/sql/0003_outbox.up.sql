@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              serial PRIMARY KEY,
+    type            text NOT NULL,
+    user_id         integer NOT NULL,
+    payload         jsonb NOT NULL,
+    attempts        integer NOT NULL DEFAULT 0,
+    last_error      text,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    next_attempt_at timestamptz NOT NULL DEFAULT NOW(),
+    delivered_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx ON outbox_events (next_attempt_at) WHERE delivered_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_events_user_idx ON outbox_events (user_id, id) WHERE delivered_at IS NULL;
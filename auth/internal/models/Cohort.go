@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/go-playground/validator/v10"
+	"io"
+)
+
+// Cohort is a class of students managed by its owner teacher. Users join a cohort with its join code
+type Cohort struct {
+	ID           int            `json:"id"`
+	Name         string         `json:"name" validate:"required"`
+	OwnerID      int            `json:"owner_id"`
+	JoinCode     string         `json:"join_code,omitempty"`
+	CreatedAt    string         `json:"created_at"`
+	MembersCount int            `json:"members_count"`
+	Members      []CohortMember `json:"members,omitempty"`
+}
+
+type CohortMember struct {
+	UserID    int    `json:"user_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	JoinedAt  string `json:"joined_at"`
+}
+
+// CohortMembers is the owner and members of a cohort used by other services to filter data by cohort
+type CohortMembers struct {
+	CohortID int   `json:"cohort_id"`
+	OwnerID  int   `json:"owner_id"`
+	UsersIDs []int `json:"users_ids"`
+}
+
+type JoinCohortPayload struct {
+	Code string `json:"code" validate:"required"`
+}
+
+func (c *Cohort) Validate() error {
+	return validator.New().Struct(c)
+}
+func (c *Cohort) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(c)
+}
+func (c *Cohort) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c)
+}
+
+func (p *JoinCohortPayload) Validate() error {
+	return validator.New().Struct(p)
+}
+func (p *JoinCohortPayload) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(p)
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a change of a user to be published to other services, Type is one of the types of the events
+// package. Events are written in the same transaction as the change and published in order per user
+// until the publisher accepts them
+type OutboxEvent struct {
+	ID        int             `json:"id"`
+	Type      string          `json:"type"`
+	UserID    int             `json:"user_id"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	LastError *string         `json:"last_error,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	// NextAttemptAt is when delivery is tried next, it is moved forward with backoff after failed attempts
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// NewOutboxEvent creates an event of the user with the payload encoded as JSON
+func NewOutboxEvent(eventType string, userID int, payload any) (OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	return OutboxEvent{Type: eventType, UserID: userID, Payload: data}, nil
+}
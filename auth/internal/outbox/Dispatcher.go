@@ -0,0 +1,84 @@
+package outbox
+
+import (
+	"auth/internal/events"
+	"auth/internal/storage"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	// pollInterval is how often the outbox is checked for due events
+	pollInterval = 2 * time.Second
+	// batchSize bounds the events published in one pass
+	batchSize = 100
+	// backoff after the first failed attempt, doubled after each next one up to maxBackoff
+	initialBackoff = 5 * time.Second
+	maxBackoff     = 10 * time.Minute
+)
+
+// Dispatcher publishes events from the outbox. Publishing is at least once,
+// consumers ignore events they have already received
+type Dispatcher struct {
+	storage   storage.Store
+	publisher events.Publisher
+	logger    *zap.Logger
+}
+
+func NewDispatcher(store storage.Store, publisher events.Publisher, logger *zap.Logger) *Dispatcher {
+	return &Dispatcher{
+		storage:   store,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Run publishes due events until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		// a full batch means more events may be waiting, they are published without waiting for the next tick
+		for d.dispatch(ctx) == batchSize {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch tries to publish one batch of due events and returns the number of events in the batch
+func (d *Dispatcher) dispatch(ctx context.Context) int {
+	due, err := d.storage.GetDueOutboxEvents(ctx, batchSize)
+	if err != nil {
+		d.logger.Error("failed to get due outbox events", zap.Error(err))
+		return 0
+	}
+	for _, event := range due {
+		if err = d.publisher.Publish(ctx, event.Type, event.Payload); err != nil {
+			d.logger.Warn("failed to publish outbox event", zap.Int("event_id", event.ID), zap.String("type", event.Type), zap.Int("attempts", event.Attempts+1), zap.Error(err))
+			err = d.storage.MarkOutboxEventFailed(ctx, event.ID, err.Error(), time.Now().Add(Backoff(event.Attempts+1)))
+		} else {
+			err = d.storage.MarkOutboxEventDelivered(ctx, event.ID)
+		}
+		if err != nil {
+			d.logger.Error("failed to update outbox event", zap.Int("event_id", event.ID), zap.Error(err))
+		}
+	}
+	return len(due)
+}
+
+// Backoff returns the delay before the next attempt to publish an event which failed the given number of times
+func Backoff(attempts int) time.Duration {
+	backoff := initialBackoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
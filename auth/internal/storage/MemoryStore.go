@@ -0,0 +1,524 @@
+package storage
+
+import (
+	"auth/internal/events"
+	"auth/internal/models"
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps users, sessions, roles, cohorts and the outbox in memory. It follows the constraints of the
+// Postgres schema (unique emails, session ids and role names, one session per user), so handlers
+// behave as they do against PostgresStorage. It is used by tests and by local runs without a database
+type MemoryStore struct {
+	mu       sync.Mutex
+	users    map[int]memoryUser
+	sessions map[int]models.UserSession
+	roles    map[int]models.Role
+	cohorts  map[int]memoryCohort
+	// members maps cohorts to their members and the time they joined
+	members map[int]map[int]time.Time
+	outbox  []models.OutboxEvent
+	nextID  int
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+type memoryUser struct {
+	models.User
+	createdAt time.Time
+}
+
+type memoryCohort struct {
+	models.Cohort
+	createdAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		users:    make(map[int]memoryUser),
+		sessions: make(map[int]models.UserSession),
+		roles:    make(map[int]models.Role),
+		cohorts:  make(map[int]memoryCohort),
+		members:  make(map[int]map[int]time.Time),
+	}
+	for _, role := range []models.UserRole{models.RoleAdmin, models.RoleUser, models.RoleTeacher} {
+		s.nextID++
+		s.roles[s.nextID] = models.Role{ID: s.nextID, Name: string(role)}
+	}
+	return s
+}
+
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Email == user.Email {
+			return nil, fmt.Errorf("user with email %s already exists", user.Email)
+		}
+	}
+	s.nextID++
+	created := memoryUser{User: *user, createdAt: time.Now()}
+	created.ID = s.nextID
+	created.Role = models.RoleUser
+	created.Verified = false
+	created.CreatedAt = created.createdAt.Format(time.RFC3339Nano)
+	s.users[created.ID] = created
+	if err := s.insertUserEvent(events.UserRegistered, created.ID); err != nil {
+		return nil, err
+	}
+	return &models.User{ID: created.ID, Email: created.Email, FirstName: created.FirstName, LastName: created.LastName, GoogleID: created.GoogleID, Role: created.Role}, nil
+}
+
+func (s *MemoryStore) GetUserById(ctx context.Context, id int, withPwd bool) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	user := u.User
+	if !withPwd {
+		user.Password = ""
+	}
+	return &user, nil
+}
+
+func (s *MemoryStore) GetUserByIdInternal(ctx context.Context, id int) (*models.User, error) {
+	return s.GetUserById(ctx, id, true)
+}
+
+func (s *MemoryStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Email == email {
+			user := u.User
+			return &user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *MemoryStore) SaveUserSession(ctx context.Context, session models.UserSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[session.UserID]; !ok {
+		return fmt.Errorf("user %d does not exist", session.UserID)
+	}
+	if _, ok := s.sessions[session.UserID]; ok {
+		return fmt.Errorf("user %d already has a session", session.UserID)
+	}
+	for _, other := range s.sessions {
+		if other.SessionID == session.SessionID {
+			return fmt.Errorf("session id already exists")
+		}
+	}
+	s.sessions[session.UserID] = models.UserSession{UserID: session.UserID, SessionID: session.SessionID, Expiration: session.Expiration}
+	return nil
+}
+
+func (s *MemoryStore) GetUserSession(ctx context.Context, userID int) (models.UserSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[userID]
+	if !ok {
+		return models.UserSession{}, sql.ErrNoRows
+	}
+	return session, nil
+}
+
+func (s *MemoryStore) UpdateUserSession(ctx context.Context, session models.UserSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[session.UserID]; ok {
+		s.sessions[session.UserID] = models.UserSession{UserID: session.UserID, SessionID: session.SessionID, Expiration: session.Expiration}
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetUserSessionBySessionID(ctx context.Context, sessionID string) (models.UserSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, session := range s.sessions {
+		if session.SessionID == sessionID {
+			return session, nil
+		}
+	}
+	return models.UserSession{}, sql.ErrNoRows
+}
+
+func (s *MemoryStore) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var users []models.User
+	for _, u := range s.users {
+		users = append(users, models.User{ID: u.ID, Email: u.Email, FirstName: u.FirstName, LastName: u.LastName, Role: u.Role, GoogleID: u.GoogleID, CreatedAt: u.CreatedAt})
+	}
+	slices.SortFunc(users, func(a, b models.User) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return users, nil
+}
+
+func (s *MemoryStore) GetUsersByIDs(ctx context.Context, ids []int) ([]models.User, error) {
+	users, err := s.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(users, func(u models.User) bool {
+		return !slices.Contains(ids, u.ID)
+	}), nil
+}
+
+func (s *MemoryStore) UpdateUser(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[user.ID]
+	if !ok {
+		return nil
+	}
+	for id, other := range s.users {
+		if id != user.ID && other.Email == user.Email {
+			return fmt.Errorf("error updating user: email %s already exists", user.Email)
+		}
+	}
+	u.FirstName, u.LastName, u.Email, u.Role = user.FirstName, user.LastName, user.Email, user.Role
+	u.Password, u.GoogleID, u.Verified = user.Password, user.GoogleID, user.Verified
+	s.users[user.ID] = u
+	return nil
+}
+
+func (s *MemoryStore) DeleteUser(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, id)
+	delete(s.sessions, id)
+	for cohortID, cohort := range s.cohorts {
+		if cohort.OwnerID == id {
+			delete(s.cohorts, cohortID)
+			delete(s.members, cohortID)
+		}
+	}
+	for _, members := range s.members {
+		delete(members, id)
+	}
+	return s.insertUserEvent(events.UserDeleted, id)
+}
+
+func (s *MemoryStore) GetAllRoles(ctx context.Context) ([]models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var roles []models.Role
+	for _, role := range s.roles {
+		roles = append(roles, role)
+	}
+	slices.SortFunc(roles, func(a, b models.Role) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return roles, nil
+}
+
+func (s *MemoryStore) CreateRole(ctx context.Context, role models.Role) (models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.roles {
+		if other.Name == role.Name {
+			return models.Role{}, fmt.Errorf("error creating role: role %s already exists", role.Name)
+		}
+	}
+	s.nextID++
+	role.ID = s.nextID
+	s.roles[role.ID] = role
+	return role, nil
+}
+
+func (s *MemoryStore) UpdateRole(ctx context.Context, role models.Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.roles[role.ID]; ok {
+		s.roles[role.ID] = role
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteRole(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.roles, id)
+	return nil
+}
+
+func (s *MemoryStore) GetUsersCount(ctx context.Context) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users)
+}
+
+func (s *MemoryStore) GetActiveUsersCount(ctx context.Context) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+func (s *MemoryStore) GetLast24hRegisteredCount(ctx context.Context) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, u := range s.users {
+		if time.Since(u.createdAt) < 24*time.Hour {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *MemoryStore) UpdateUserPassword(ctx context.Context, userID int, hashedPassword string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[userID]; ok {
+		u.Password = hashedPassword
+		s.users[userID] = u
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateCohort(ctx context.Context, cohort models.Cohort) (models.Cohort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[cohort.OwnerID]; !ok {
+		return models.Cohort{}, fmt.Errorf("error creating cohort: owner %d does not exist", cohort.OwnerID)
+	}
+	if s.joinCodeTaken(cohort.JoinCode, 0) {
+		return models.Cohort{}, fmt.Errorf("error creating cohort: join code already exists")
+	}
+	s.nextID++
+	created := memoryCohort{Cohort: models.Cohort{ID: s.nextID, Name: cohort.Name, OwnerID: cohort.OwnerID, JoinCode: cohort.JoinCode}, createdAt: time.Now()}
+	created.CreatedAt = created.createdAt.Format(time.RFC3339Nano)
+	s.cohorts[created.ID] = created
+	s.members[created.ID] = make(map[int]time.Time)
+	cohort.ID, cohort.CreatedAt = created.ID, created.CreatedAt
+	return cohort, nil
+}
+
+func (s *MemoryStore) joinCodeTaken(code string, exceptID int) bool {
+	for id, cohort := range s.cohorts {
+		if id != exceptID && cohort.JoinCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// cohort returns the cohort without members, callers hold the lock
+func (s *MemoryStore) cohort(id int) models.Cohort {
+	cohort := s.cohorts[id].Cohort
+	cohort.MembersCount = len(s.members[id])
+	return cohort
+}
+
+// GetCohortByID returns the cohort with its members
+func (s *MemoryStore) GetCohortByID(ctx context.Context, id int) (models.Cohort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cohorts[id]; !ok {
+		return models.Cohort{}, ErrCohortNotFound
+	}
+	cohort := s.cohort(id)
+	cohort.Members = make([]models.CohortMember, 0, cohort.MembersCount)
+	for userID, joinedAt := range s.members[id] {
+		u := s.users[userID]
+		cohort.Members = append(cohort.Members, models.CohortMember{UserID: userID, FirstName: u.FirstName, LastName: u.LastName, Email: u.Email, JoinedAt: joinedAt.Format(time.RFC3339Nano)})
+	}
+	slices.SortFunc(cohort.Members, func(a, b models.CohortMember) int {
+		return cmp.Or(cmp.Compare(a.LastName, b.LastName), cmp.Compare(a.FirstName, b.FirstName), cmp.Compare(a.UserID, b.UserID))
+	})
+	return cohort, nil
+}
+
+func (s *MemoryStore) GetCohortByJoinCode(ctx context.Context, code string) (models.Cohort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, cohort := range s.cohorts {
+		if cohort.JoinCode == code {
+			return s.cohort(id), nil
+		}
+	}
+	return models.Cohort{}, ErrCohortNotFound
+}
+
+// GetCohorts returns cohorts owned by the user, or all cohorts when ownerID is 0
+func (s *MemoryStore) GetCohorts(ctx context.Context, ownerID int) ([]models.Cohort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.collectCohorts(func(cohort models.Cohort) bool {
+		return ownerID == 0 || cohort.OwnerID == ownerID
+	}), nil
+}
+
+// GetUserCohorts returns cohorts the user is a member of
+func (s *MemoryStore) GetUserCohorts(ctx context.Context, userID int) ([]models.Cohort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.collectCohorts(func(cohort models.Cohort) bool {
+		_, ok := s.members[cohort.ID][userID]
+		return ok
+	}), nil
+}
+
+func (s *MemoryStore) collectCohorts(include func(cohort models.Cohort) bool) []models.Cohort {
+	cohorts := make([]models.Cohort, 0)
+	for id := range s.cohorts {
+		if cohort := s.cohort(id); include(cohort) {
+			cohorts = append(cohorts, cohort)
+		}
+	}
+	slices.SortFunc(cohorts, func(a, b models.Cohort) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+	return cohorts
+}
+
+func (s *MemoryStore) UpdateCohort(ctx context.Context, cohort models.Cohort) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.cohorts[cohort.ID]
+	if !ok {
+		return ErrCohortNotFound
+	}
+	if s.joinCodeTaken(cohort.JoinCode, cohort.ID) {
+		return fmt.Errorf("error updating cohort: join code already exists")
+	}
+	stored.Name, stored.JoinCode = cohort.Name, cohort.JoinCode
+	s.cohorts[cohort.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) DeleteCohort(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cohorts[id]; !ok {
+		return ErrCohortNotFound
+	}
+	delete(s.cohorts, id)
+	delete(s.members, id)
+	return nil
+}
+
+func (s *MemoryStore) AddCohortMember(ctx context.Context, cohortID int, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members, ok := s.members[cohortID]
+	if !ok {
+		return fmt.Errorf("error adding cohort member: cohort %d does not exist", cohortID)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("error adding cohort member: user %d does not exist", userID)
+	}
+	if _, ok := members[userID]; !ok {
+		members[userID] = time.Now()
+	}
+	return nil
+}
+
+func (s *MemoryStore) RemoveCohortMember(ctx context.Context, cohortID int, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.members[cohortID][userID]; !ok {
+		return ErrNotCohortMember
+	}
+	delete(s.members[cohortID], userID)
+	return nil
+}
+
+func (s *MemoryStore) GetCohortMembersIDs(ctx context.Context, cohortID int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usersIDs := make([]int, 0, len(s.members[cohortID]))
+	for userID := range s.members[cohortID] {
+		usersIDs = append(usersIDs, userID)
+	}
+	slices.Sort(usersIDs)
+	return usersIDs, nil
+}
+
+// Outbox
+
+func (s *MemoryStore) insertUserEvent(eventType string, userID int) error {
+	event, err := models.NewOutboxEvent(eventType, userID, events.UserPayload{UserID: userID})
+	if err != nil {
+		return err
+	}
+	s.nextID++
+	now := time.Now()
+	event.ID, event.CreatedAt, event.NextAttemptAt = s.nextID, now, now
+	s.outbox = append(s.outbox, event)
+	return nil
+}
+
+// GetDueOutboxEvents returns undelivered events whose next attempt is due, oldest first, with only the
+// oldest undelivered event of each user, see PostgresStorage.GetDueOutboxEvents
+func (s *MemoryStore) GetDueOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	due := make([]models.OutboxEvent, 0)
+	blocked := make(map[int]bool)
+	for _, event := range s.outbox {
+		if event.DeliveredAt != nil {
+			continue
+		}
+		if !blocked[event.UserID] && !event.NextAttemptAt.After(now) && len(due) < limit {
+			event.Payload = slices.Clone(event.Payload)
+			due = append(due, event)
+		}
+		blocked[event.UserID] = true
+	}
+	return due, nil
+}
+
+// outboxEvent returns the event with the ID, or nil when there is none
+func (s *MemoryStore) outboxEvent(id int) *models.OutboxEvent {
+	for i := range s.outbox {
+		if s.outbox[i].ID == id {
+			return &s.outbox[i]
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) MarkOutboxEventDelivered(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if event := s.outboxEvent(id); event != nil {
+		now := time.Now()
+		event.DeliveredAt = &now
+		event.Attempts++
+		event.LastError = nil
+	}
+	return nil
+}
+
+func (s *MemoryStore) MarkOutboxEventFailed(ctx context.Context, id int, lastError string, nextAttemptAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if event := s.outboxEvent(id); event != nil {
+		event.Attempts++
+		event.LastError = &lastError
+		event.NextAttemptAt = nextAttemptAt
+	}
+	return nil
+}
@@ -1,120 +1,208 @@
 package storage
 
 import (
+	"auth/internal/events"
 	"auth/internal/models"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
+	"time"
 )
 
 type Store interface {
-	Ping() error
+	Ping(ctx context.Context) error
 	Close() error
-	CreateUser(user *models.User) (*models.User, error)
-	GetUserById(id int, withPwd bool) (*models.User, error)
-	GetUserByIdInternal(id int) (*models.User, error)
-	GetUserByEmail(email string) (*models.User, error)
-	SaveUserSession(token models.UserSession) error
-	GetUserSession(userID int) (models.UserSession, error)
-	UpdateUserSession(token models.UserSession) error
-	GetUserSessionBySessionID(sessionID string) (models.UserSession, error)
-	GetAllUsers() ([]models.User, error)
-	UpdateUser(user *models.User) error
-	DeleteUser(id int) error
-	GetAllRoles() ([]models.Role, error)
-	CreateRole(role models.Role) (models.Role, error)
-	UpdateRole(role models.Role) error
-	DeleteRole(id int) error
-	GetUsersCount() int
-	GetActiveUsersCount() int
-	GetLast24hRegisteredCount() int
-	UpdateUserPassword(userID int, hashedPassword string) error
+	CreateUser(ctx context.Context, user *models.User) (*models.User, error)
+	GetUserById(ctx context.Context, id int, withPwd bool) (*models.User, error)
+	GetUserByIdInternal(ctx context.Context, id int) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	SaveUserSession(ctx context.Context, token models.UserSession) error
+	GetUserSession(ctx context.Context, userID int) (models.UserSession, error)
+	UpdateUserSession(ctx context.Context, token models.UserSession) error
+	GetUserSessionBySessionID(ctx context.Context, sessionID string) (models.UserSession, error)
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	GetUsersByIDs(ctx context.Context, ids []int) ([]models.User, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	DeleteUser(ctx context.Context, id int) error
+	GetAllRoles(ctx context.Context) ([]models.Role, error)
+	CreateRole(ctx context.Context, role models.Role) (models.Role, error)
+	UpdateRole(ctx context.Context, role models.Role) error
+	DeleteRole(ctx context.Context, id int) error
+	GetUsersCount(ctx context.Context) int
+	GetActiveUsersCount(ctx context.Context) int
+	GetLast24hRegisteredCount(ctx context.Context) int
+	UpdateUserPassword(ctx context.Context, userID int, hashedPassword string) error
+	CreateCohort(ctx context.Context, cohort models.Cohort) (models.Cohort, error)
+	GetCohortByID(ctx context.Context, id int) (models.Cohort, error)
+	GetCohortByJoinCode(ctx context.Context, code string) (models.Cohort, error)
+	GetCohorts(ctx context.Context, ownerID int) ([]models.Cohort, error)
+	GetUserCohorts(ctx context.Context, userID int) ([]models.Cohort, error)
+	UpdateCohort(ctx context.Context, cohort models.Cohort) error
+	DeleteCohort(ctx context.Context, id int) error
+	AddCohortMember(ctx context.Context, cohortID int, userID int) error
+	RemoveCohortMember(ctx context.Context, cohortID int, userID int) error
+	GetCohortMembersIDs(ctx context.Context, cohortID int) ([]int, error)
+	// outbox of events published to other services
+	GetDueOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	MarkOutboxEventDelivered(ctx context.Context, id int) error
+	MarkOutboxEventFailed(ctx context.Context, id int, lastError string, nextAttemptAt time.Time) error
 }
+
+var ErrCohortNotFound = fmt.Errorf("cohort not found")
+var ErrNotCohortMember = fmt.Errorf("user is not a member of the cohort")
+
 type FirestoreStorage struct {
 	config string
 }
 type PostgresStorage struct {
 	db     *sql.DB
 	logger *zap.Logger
+	// queryTimeout bounds each storage call, the deadline of the caller's context applies when it is earlier
+	queryTimeout time.Duration
+}
+
+func NewPostgresStorage(db *sql.DB, logger *zap.Logger, queryTimeout time.Duration) *PostgresStorage {
+	return &PostgresStorage{db, logger, queryTimeout}
 }
 
-func NewPostgresStorage(db *sql.DB, logger *zap.Logger) *PostgresStorage {
-	return &PostgresStorage{db, logger}
+func (p *PostgresStorage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, p.queryTimeout)
 }
-func (p *PostgresStorage) Ping() error {
-	return p.db.Ping()
+
+// IsTimeout reports whether a storage call failed because it ran out of time,
+// either on the query timeout or on the deadline of the context
+func IsTimeout(err error) bool {
+	var pqErr *pq.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &pqErr) && pqErr.Code == queryCanceled
+}
+
+// queryCanceled is the code of the error returned by Postgres for statements cancelled on a deadline
+const queryCanceled = "57014"
+
+func (p *PostgresStorage) Ping(ctx context.Context) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	return p.db.PingContext(ctx)
 }
 
 func (p *PostgresStorage) Close() error {
 	return p.db.Close()
 }
 
-func (p *PostgresStorage) CreateUser(user *models.User) (*models.User, error) {
+// CreateUser inserts the user and the UserRegistered event in the same transaction
+func (p *PostgresStorage) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 	var userCreated models.User
-	err := p.db.QueryRow("INSERT INTO users (first_name, last_name, email, pwd, google_id) VALUES ($1, $2, $3, $4, $5) RETURNING id, email, first_name, last_name, google_id, role", user.FirstName, user.LastName, user.Email, user.Password, user.GoogleID).Scan(&userCreated.ID, &userCreated.Email, &userCreated.FirstName, &userCreated.LastName, &userCreated.GoogleID, &userCreated.Role)
+	err = tx.QueryRowContext(ctx, "INSERT INTO users (first_name, last_name, email, pwd, google_id) VALUES ($1, $2, $3, $4, $5) RETURNING id, email, first_name, last_name, google_id, role", user.FirstName, user.LastName, user.Email, user.Password, user.GoogleID).Scan(&userCreated.ID, &userCreated.Email, &userCreated.FirstName, &userCreated.LastName, &userCreated.GoogleID, &userCreated.Role)
 	if err != nil {
 		return nil, err
 	}
+	if err = insertUserEvent(ctx, tx, events.UserRegistered, userCreated.ID); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
 	return &userCreated, nil
 }
 
-func (p *PostgresStorage) GetUserById(id int, withPwd bool) (*models.User, error) {
+func (p *PostgresStorage) GetUserById(ctx context.Context, id int, withPwd bool) (*models.User, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var user models.User
 	if withPwd {
-		err := p.db.QueryRow("SELECT id, first_name, last_name, email, pwd, role, google_id, verified, created_at FROM users WHERE id = $1", id).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.Role, &user.GoogleID, &user.Verified, &user.CreatedAt)
+		err := p.db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, pwd, role, google_id, verified, created_at FROM users WHERE id = $1", id).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.Role, &user.GoogleID, &user.Verified, &user.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		err := p.db.QueryRow("SELECT id, first_name, last_name, email, role, google_id, verified, created_at FROM users WHERE id = $1", id).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Role, &user.GoogleID, &user.Verified, &user.CreatedAt)
+		err := p.db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, role, google_id, verified, created_at FROM users WHERE id = $1", id).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Role, &user.GoogleID, &user.Verified, &user.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
 	}
 	return &user, nil
 }
-func (p *PostgresStorage) GetUserByIdInternal(id int) (*models.User, error) {
+func (p *PostgresStorage) GetUserByIdInternal(ctx context.Context, id int) (*models.User, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var user models.User
-	err := p.db.QueryRow("SELECT id, email, pwd, created_at, first_name, last_name, role, google_id, verified FROM users WHERE id = $1", id).Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt, &user.FirstName, &user.LastName, &user.Role, &user.GoogleID, &user.Verified)
+	err := p.db.QueryRowContext(ctx, "SELECT id, email, pwd, created_at, first_name, last_name, role, google_id, verified FROM users WHERE id = $1", id).Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt, &user.FirstName, &user.LastName, &user.Role, &user.GoogleID, &user.Verified)
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (p *PostgresStorage) GetUserByEmail(email string) (*models.User, error) {
+func (p *PostgresStorage) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var user models.User
-	err := p.db.QueryRow("SELECT id, first_name, last_name, email, pwd, role, google_id, verified FROM users WHERE email = $1", email).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.Role, &user.GoogleID, &user.Verified)
+	err := p.db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, pwd, role, google_id, verified FROM users WHERE email = $1", email).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.Role, &user.GoogleID, &user.Verified)
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (p *PostgresStorage) SaveUserSession(session models.UserSession) error {
-	_, err := p.db.Exec("INSERT INTO users_sessions (user_id, session_id, expiration) VALUES ($1, $2, $3)", session.UserID, session.SessionID, session.Expiration)
+func (p *PostgresStorage) SaveUserSession(ctx context.Context, session models.UserSession) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, "INSERT INTO users_sessions (user_id, session_id, expiration) VALUES ($1, $2, $3)", session.UserID, session.SessionID, session.Expiration)
 	return err
 }
-func (p *PostgresStorage) UpdateUserSession(session models.UserSession) error {
-	_, err := p.db.Exec("UPDATE users_sessions SET session_id = $1, expiration = $2 WHERE user_id = $3", session.SessionID, session.Expiration, session.UserID)
+func (p *PostgresStorage) UpdateUserSession(ctx context.Context, session models.UserSession) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, "UPDATE users_sessions SET session_id = $1, expiration = $2 WHERE user_id = $3", session.SessionID, session.Expiration, session.UserID)
 	return err
 }
-func (p *PostgresStorage) GetUserSession(userID int) (models.UserSession, error) {
+func (p *PostgresStorage) GetUserSession(ctx context.Context, userID int) (models.UserSession, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var session models.UserSession
-	err := p.db.QueryRow("SELECT user_id, session_id, expiration FROM users_sessions WHERE user_id = $1", userID).Scan(&session.UserID, &session.SessionID, &session.Expiration)
+	err := p.db.QueryRowContext(ctx, "SELECT user_id, session_id, expiration FROM users_sessions WHERE user_id = $1", userID).Scan(&session.UserID, &session.SessionID, &session.Expiration)
 	return session, err
 }
-func (p *PostgresStorage) GetUserSessionBySessionID(sessionID string) (models.UserSession, error) {
+func (p *PostgresStorage) GetUserSessionBySessionID(ctx context.Context, sessionID string) (models.UserSession, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var session models.UserSession
-	err := p.db.QueryRow("SELECT user_id, session_id, expiration FROM users_sessions WHERE session_id = $1", sessionID).Scan(&session.UserID, &session.SessionID, &session.Expiration)
+	err := p.db.QueryRowContext(ctx, "SELECT user_id, session_id, expiration FROM users_sessions WHERE session_id = $1", sessionID).Scan(&session.UserID, &session.SessionID, &session.Expiration)
 	return session, err
 }
 
-func (p *PostgresStorage) GetAllUsers() ([]models.User, error) {
-	rows, err := p.db.Query("SELECT id, email, first_name, last_name, role, google_id, created_at FROM users ORDER BY id")
+func (p *PostgresStorage) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, "SELECT id, email, first_name, last_name, role, google_id, created_at FROM users ORDER BY id")
 	if err != nil {
 		return nil, fmt.Errorf("error fetching users: %w", err)
 	}
+	return scanUsers(rows)
+}
+
+// GetUsersByIDs returns the users with the IDs, IDs of users which don't exist are skipped
+func (p *PostgresStorage) GetUsersByIDs(ctx context.Context, ids []int) ([]models.User, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, "SELECT id, email, first_name, last_name, role, google_id, created_at FROM users WHERE id = ANY($1) ORDER BY id", pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching users: %w", err)
+	}
+	return scanUsers(rows)
+}
+
+func scanUsers(rows *sql.Rows) ([]models.User, error) {
 	defer rows.Close()
 
 	var users []models.User
@@ -127,20 +215,22 @@ func (p *PostgresStorage) GetAllUsers() ([]models.User, error) {
 		users = append(users, user)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error with rows iteration: %w", err)
 	}
 
 	return users, nil
 }
 
-func (p *PostgresStorage) UpdateUser(user *models.User) error {
+func (p *PostgresStorage) UpdateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	query := `
         UPDATE users 
         SET first_name = $1, last_name = $2, email = $3, role = $4, pwd = $5, google_id = $6, verified=$7, updated_at = NOW()
         WHERE id = $8
     `
-	_, err := p.db.Exec(query, user.FirstName, user.LastName, user.Email, user.Role, user.Password, user.GoogleID, user.Verified, user.ID)
+	_, err := p.db.ExecContext(ctx, query, user.FirstName, user.LastName, user.Email, user.Role, user.Password, user.GoogleID, user.Verified, user.ID)
 	if err != nil {
 		return fmt.Errorf("error updating user: %w", err)
 	}
@@ -148,17 +238,33 @@ func (p *PostgresStorage) UpdateUser(user *models.User) error {
 	return nil
 }
 
-func (p *PostgresStorage) DeleteUser(id int) error {
-	_, err := p.db.Exec("DELETE FROM users WHERE id = $1", id)
+// DeleteUser deletes the user and inserts the UserDeleted event in the same transaction
+func (p *PostgresStorage) DeleteUser(ctx context.Context, id int) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("error deleting user: %w", err)
 	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+	if err = insertUserEvent(ctx, tx, events.UserDeleted, id); err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
 
 	return nil
 }
 
-func (p *PostgresStorage) GetAllRoles() ([]models.Role, error) {
-	rows, err := p.db.Query("SELECT id, name FROM roles")
+func (p *PostgresStorage) GetAllRoles(ctx context.Context) ([]models.Role, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, "SELECT id, name FROM roles")
 	if err != nil {
 		return nil, fmt.Errorf("error fetching roles: %w", err)
 	}
@@ -181,10 +287,12 @@ func (p *PostgresStorage) GetAllRoles() ([]models.Role, error) {
 	return roles, nil
 }
 
-func (p *PostgresStorage) CreateRole(role models.Role) (models.Role, error) {
+func (p *PostgresStorage) CreateRole(ctx context.Context, role models.Role) (models.Role, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	query := "INSERT INTO roles (name) VALUES ($1) RETURNING id, name"
 	var newRole models.Role
-	err := p.db.QueryRow(query, role.Name).Scan(&newRole.ID, &newRole.Name)
+	err := p.db.QueryRowContext(ctx, query, role.Name).Scan(&newRole.ID, &newRole.Name)
 	if err != nil {
 		return models.Role{}, fmt.Errorf("error creating role: %w", err)
 	}
@@ -192,9 +300,11 @@ func (p *PostgresStorage) CreateRole(role models.Role) (models.Role, error) {
 	return newRole, nil
 }
 
-func (p *PostgresStorage) UpdateRole(role models.Role) error {
+func (p *PostgresStorage) UpdateRole(ctx context.Context, role models.Role) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	query := "UPDATE roles SET name = $1 WHERE id = $2"
-	_, err := p.db.Exec(query, role.Name, role.ID)
+	_, err := p.db.ExecContext(ctx, query, role.Name, role.ID)
 	if err != nil {
 		return fmt.Errorf("error updating role: %w", err)
 	}
@@ -202,8 +312,10 @@ func (p *PostgresStorage) UpdateRole(role models.Role) error {
 	return nil
 }
 
-func (p *PostgresStorage) DeleteRole(id int) error {
-	_, err := p.db.Exec("DELETE FROM roles WHERE id = $1", id)
+func (p *PostgresStorage) DeleteRole(ctx context.Context, id int) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, "DELETE FROM roles WHERE id = $1", id)
 	if err != nil {
 		return fmt.Errorf("error deleting role: %w", err)
 	}
@@ -211,33 +323,271 @@ func (p *PostgresStorage) DeleteRole(id int) error {
 	return nil
 }
 
-func (p *PostgresStorage) GetUsersCount() int {
+func (p *PostgresStorage) GetUsersCount(ctx context.Context) int {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var count int
-	err := p.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&count)
 	if err != nil {
 		return 0
 	}
 	return count
 }
 
-func (p *PostgresStorage) GetActiveUsersCount() int {
+func (p *PostgresStorage) GetActiveUsersCount(ctx context.Context) int {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var count int
-	err := p.db.QueryRow("SELECT COUNT(*) FROM users_sessions").Scan(&count)
+	err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users_sessions").Scan(&count)
 	if err != nil {
 		return 0
 	}
 	return count
 }
 
-func (p *PostgresStorage) GetLast24hRegisteredCount() int {
+func (p *PostgresStorage) GetLast24hRegisteredCount(ctx context.Context) int {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var count int
-	err := p.db.QueryRow("SELECT COUNT(*) FROM users WHERE created_at > NOW() - INTERVAL '24 hours'").Scan(&count)
+	err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE created_at > NOW() - INTERVAL '24 hours'").Scan(&count)
 	if err != nil {
 		return 0
 	}
 	return count
 }
-func (p *PostgresStorage) UpdateUserPassword(userID int, hashedPassword string) error {
-	_, err := p.db.Exec("UPDATE users SET pwd = $1 WHERE id = $2", hashedPassword, userID)
+func (p *PostgresStorage) UpdateUserPassword(ctx context.Context, userID int, hashedPassword string) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, "UPDATE users SET pwd = $1 WHERE id = $2", hashedPassword, userID)
+	return err
+}
+
+func (p *PostgresStorage) CreateCohort(ctx context.Context, cohort models.Cohort) (models.Cohort, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	query := "INSERT INTO cohorts (name, owner_id, join_code) VALUES ($1, $2, $3) RETURNING id, created_at"
+	err := p.db.QueryRowContext(ctx, query, cohort.Name, cohort.OwnerID, cohort.JoinCode).Scan(&cohort.ID, &cohort.CreatedAt)
+	if err != nil {
+		return models.Cohort{}, fmt.Errorf("error creating cohort: %w", err)
+	}
+	return cohort, nil
+}
+
+const cohortColumns = `c.id, c.name, c.owner_id, c.join_code, c.created_at,
+	(SELECT count(*) FROM cohort_members m WHERE m.cohort_id = c.id)`
+
+func scanCohort(row interface{ Scan(dest ...any) error }) (models.Cohort, error) {
+	var cohort models.Cohort
+	err := row.Scan(&cohort.ID, &cohort.Name, &cohort.OwnerID, &cohort.JoinCode, &cohort.CreatedAt, &cohort.MembersCount)
+	return cohort, err
+}
+
+// GetCohortByID returns the cohort with its members
+func (p *PostgresStorage) GetCohortByID(ctx context.Context, id int) (models.Cohort, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	cohort, err := scanCohort(p.db.QueryRowContext(ctx, "SELECT "+cohortColumns+" FROM cohorts c WHERE c.id = $1", id))
+	if err == sql.ErrNoRows {
+		return cohort, ErrCohortNotFound
+	}
+	if err != nil {
+		return cohort, fmt.Errorf("error fetching cohort: %w", err)
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT u.id, u.first_name, u.last_name, u.email, m.joined_at
+		FROM cohort_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.cohort_id = $1
+		ORDER BY u.last_name, u.first_name, u.id`, id)
+	if err != nil {
+		return cohort, fmt.Errorf("error fetching cohort members: %w", err)
+	}
+	defer rows.Close()
+
+	cohort.Members = make([]models.CohortMember, 0, cohort.MembersCount)
+	for rows.Next() {
+		var member models.CohortMember
+		if err := rows.Scan(&member.UserID, &member.FirstName, &member.LastName, &member.Email, &member.JoinedAt); err != nil {
+			return cohort, fmt.Errorf("error scanning cohort member: %w", err)
+		}
+		cohort.Members = append(cohort.Members, member)
+	}
+	return cohort, rows.Err()
+}
+
+func (p *PostgresStorage) GetCohortByJoinCode(ctx context.Context, code string) (models.Cohort, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	cohort, err := scanCohort(p.db.QueryRowContext(ctx, "SELECT "+cohortColumns+" FROM cohorts c WHERE c.join_code = $1", code))
+	if err == sql.ErrNoRows {
+		return cohort, ErrCohortNotFound
+	}
+	return cohort, err
+}
+
+// GetCohorts returns cohorts owned by the user, or all cohorts when ownerID is 0
+func (p *PostgresStorage) GetCohorts(ctx context.Context, ownerID int) ([]models.Cohort, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, "SELECT "+cohortColumns+" FROM cohorts c WHERE $1 = 0 OR c.owner_id = $1 ORDER BY c.name, c.id", ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching cohorts: %w", err)
+	}
+	return collectCohorts(rows)
+}
+
+// GetUserCohorts returns cohorts the user is a member of
+func (p *PostgresStorage) GetUserCohorts(ctx context.Context, userID int) ([]models.Cohort, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, `SELECT `+cohortColumns+` FROM cohorts c
+		JOIN cohort_members m ON m.cohort_id = c.id
+		WHERE m.user_id = $1
+		ORDER BY c.name, c.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching cohorts: %w", err)
+	}
+	return collectCohorts(rows)
+}
+
+func collectCohorts(rows *sql.Rows) ([]models.Cohort, error) {
+	defer rows.Close()
+	cohorts := make([]models.Cohort, 0)
+	for rows.Next() {
+		cohort, err := scanCohort(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning cohort: %w", err)
+		}
+		cohorts = append(cohorts, cohort)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with rows iteration: %w", err)
+	}
+	return cohorts, nil
+}
+
+func (p *PostgresStorage) UpdateCohort(ctx context.Context, cohort models.Cohort) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	result, err := p.db.ExecContext(ctx, "UPDATE cohorts SET name = $1, join_code = $2 WHERE id = $3", cohort.Name, cohort.JoinCode, cohort.ID)
+	if err != nil {
+		return fmt.Errorf("error updating cohort: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrCohortNotFound
+	}
+	return nil
+}
+
+func (p *PostgresStorage) DeleteCohort(ctx context.Context, id int) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	result, err := p.db.ExecContext(ctx, "DELETE FROM cohorts WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error deleting cohort: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrCohortNotFound
+	}
+	return nil
+}
+
+func (p *PostgresStorage) AddCohortMember(ctx context.Context, cohortID int, userID int) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, `INSERT INTO cohort_members (cohort_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, cohortID, userID)
+	if err != nil {
+		return fmt.Errorf("error adding cohort member: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresStorage) RemoveCohortMember(ctx context.Context, cohortID int, userID int) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	result, err := p.db.ExecContext(ctx, "DELETE FROM cohort_members WHERE cohort_id = $1 AND user_id = $2", cohortID, userID)
+	if err != nil {
+		return fmt.Errorf("error removing cohort member: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotCohortMember
+	}
+	return nil
+}
+
+func (p *PostgresStorage) GetCohortMembersIDs(ctx context.Context, cohortID int) ([]int, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, "SELECT user_id FROM cohort_members WHERE cohort_id = $1 ORDER BY user_id", cohortID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching cohort members: %w", err)
+	}
+	defer rows.Close()
+	usersIDs := make([]int, 0)
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("error scanning cohort member: %w", err)
+		}
+		usersIDs = append(usersIDs, userID)
+	}
+	return usersIDs, rows.Err()
+}
+
+// Outbox
+
+// insertUserEvent inserts the event of the user to the outbox in the transaction of the change
+func insertUserEvent(ctx context.Context, tx *sql.Tx, eventType string, userID int) error {
+	event, err := models.NewOutboxEvent(eventType, userID, events.UserPayload{UserID: userID})
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO outbox_events (type, user_id, payload, attempts, created_at, next_attempt_at) VALUES ($1, $2, $3, 0, NOW(), NOW())`,
+		event.Type, event.UserID, []byte(event.Payload))
+	return err
+}
+
+// GetDueOutboxEvents returns undelivered events whose next attempt is due, oldest first. Only the oldest undelivered
+// event of each user is returned, so a user is not announced as deleted before it is announced as registered
+func (p *PostgresStorage) GetDueOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, `
+        SELECT id, type, user_id, payload, attempts, last_error, created_at, next_attempt_at, delivered_at
+        FROM outbox_events o
+        WHERE o.delivered_at IS NULL AND o.next_attempt_at <= NOW()
+          AND NOT EXISTS (SELECT 1 FROM outbox_events e WHERE e.user_id = o.user_id AND e.delivered_at IS NULL AND e.id < o.id)
+        ORDER BY o.id
+        LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	outboxEvents := make([]models.OutboxEvent, 0)
+	for rows.Next() {
+		var event models.OutboxEvent
+		var payload []byte
+		err = rows.Scan(&event.ID, &event.Type, &event.UserID, &payload, &event.Attempts, &event.LastError, &event.CreatedAt, &event.NextAttemptAt, &event.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		outboxEvents = append(outboxEvents, event)
+	}
+	return outboxEvents, rows.Err()
+}
+
+func (p *PostgresStorage) MarkOutboxEventDelivered(ctx context.Context, id int) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, `UPDATE outbox_events SET delivered_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = $1`, id)
+	return err
+}
+
+func (p *PostgresStorage) MarkOutboxEventFailed(ctx context.Context, id int, lastError string, nextAttemptAt time.Time) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, `UPDATE outbox_events SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1`, id, lastError, nextAttemptAt)
 	return err
 }
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 	"images/internal/api"
 	"images/internal/clients"
+	"images/internal/migrations"
 	"log"
 	"os"
 	"time"
@@ -59,6 +61,23 @@ func main() {
 		logger.Fatal("Failed to ping database, exiting", zap.Error(err))
 	}
 
+	// Migrate the schema, "migrate up|down [steps]|version" runs only the migration command
+	migrator, err := migrations.New(db)
+	if err != nil {
+		logger.Fatal("Failed to load migrations", zap.Error(err))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err = migrations.Command(context.Background(), migrator, os.Args[2:], logger); err != nil {
+			logger.Fatal("Failed to migrate database", zap.Error(err))
+		}
+		return
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		logger.Fatal("Failed to migrate database", zap.Error(err))
+	}
+	logger.Info("Database schema is up to date", zap.Int("applied_migrations", applied))
+
 	authClient := clients.NewAuthClient("http://auth:8080/auth", logger)
 	apiServer := api.NewApiServer(":8080", logger, authClient, db)
 	apiServer.Run()
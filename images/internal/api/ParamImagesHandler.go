@@ -86,3 +86,20 @@ func (h *ParamImagesHandler) PostImage(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// DeleteImage removes the image of the parameter, it is used by the quiz service to clean up images of a failed import
+func (h *ParamImagesHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.db.Exec("DELETE FROM params_images WHERE param_id = $1", id); err != nil {
+		h.logger.Error("Failed to delete image", zap.Error(err))
+		http.Error(w, "Failed to delete image", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"go.uber.org/zap"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -12,6 +13,8 @@ import (
 	"net/http"
 )
 
+const imagesDir = "/app/images"
+
 type QuestionImagesHandler struct {
 	logger *zap.Logger
 	db     *sql.DB
@@ -37,16 +40,20 @@ func (h *QuestionImagesHandler) Handle(rw http.ResponseWriter, r *http.Request)
 		http.Error(rw, "Invalid image id", http.StatusBadRequest)
 		return
 	}
-	var imagePath string
-	err = h.db.QueryRow("SELECT image"+id+"_path FROM question_images WHERE question_id = $1", questionID).Scan(&imagePath)
+	var path sql.NullString
+	err = h.db.QueryRow("SELECT image"+id+"_path FROM question_images WHERE question_id = $1", questionID).Scan(&path)
+	if err == sql.ErrNoRows || (err == nil && !path.Valid) {
+		http.Error(rw, "Image not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(rw, "Failed to get image", http.StatusInternalServerError)
 		return
 	}
-	imagePath = strings.Replace(imagePath, "\\", "/", -1)
+	imagePath := strings.Replace(path.String, "\\", "/", -1)
 	imagePath = filepath.Clean(imagePath)
 
-	fullPath := filepath.Join("/app/images", imagePath)
+	fullPath := filepath.Join(imagesDir, imagePath)
 
 	fmt.Println("serving from: ", fullPath)
 	ext := strings.ToLower(filepath.Ext(fullPath))
@@ -71,3 +78,105 @@ func (h *QuestionImagesHandler) Handle(rw http.ResponseWriter, r *http.Request)
 	http.ServeFile(rw, r, fullPath)
 
 }
+
+// Upload stores images sent as image1, image2 and image3 multipart files and replaces question images paths
+func (h *QuestionImagesHandler) Upload(rw http.ResponseWriter, r *http.Request) {
+	questionID, err := strconv.Atoi(r.PathValue("questionId"))
+	if err != nil {
+		http.Error(rw, "Invalid question id", http.StatusBadRequest)
+		return
+	}
+	// Max 50MB
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		http.Error(rw, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	paths := make([]sql.NullString, 3)
+	for i := range paths {
+		field := "image" + strconv.Itoa(i+1)
+		file, header, err := r.FormFile(field)
+		if err == http.ErrMissingFile {
+			continue
+		}
+		if err != nil {
+			http.Error(rw, "Failed to get "+field+" from form", http.StatusBadRequest)
+			return
+		}
+		ext := strings.ToLower(filepath.Ext(header.Filename))
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".gif" && ext != ".webp" {
+			file.Close()
+			http.Error(rw, "Unsupported image type: "+header.Filename, http.StatusBadRequest)
+			return
+		}
+		relPath := filepath.Join("questions", strconv.Itoa(questionID), field+ext)
+		err = saveFile(filepath.Join(imagesDir, relPath), file)
+		file.Close()
+		if err != nil {
+			h.logger.Error("Failed to save image", zap.Error(err))
+			http.Error(rw, "Failed to save image", http.StatusInternalServerError)
+			return
+		}
+		paths[i] = sql.NullString{String: relPath, Valid: true}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		http.Error(rw, "Failed to save image", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("DELETE FROM question_images WHERE question_id = $1", questionID); err != nil {
+		h.logger.Error("Failed to remove previous images", zap.Error(err))
+		http.Error(rw, "Failed to save image", http.StatusInternalServerError)
+		return
+	}
+	_, err = tx.Exec(`
+        INSERT INTO question_images (question_id, image1_path, image2_path, image3_path)
+        VALUES ($1, $2, $3, $4)`, questionID, paths[0], paths[1], paths[2])
+	if err != nil {
+		h.logger.Error("Failed to save images paths", zap.Error(err))
+		http.Error(rw, "Failed to save image", http.StatusInternalServerError)
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		h.logger.Error("Failed to commit images", zap.Error(err))
+		http.Error(rw, "Failed to save image", http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+}
+
+// Delete removes images of the question, it is used by the quiz service to clean up images of a failed import
+func (h *QuestionImagesHandler) Delete(rw http.ResponseWriter, r *http.Request) {
+	questionID, err := strconv.Atoi(r.PathValue("questionId"))
+	if err != nil {
+		http.Error(rw, "Invalid question id", http.StatusBadRequest)
+		return
+	}
+	if _, err = h.db.Exec("DELETE FROM question_images WHERE question_id = $1", questionID); err != nil {
+		h.logger.Error("Failed to remove images paths", zap.Error(err))
+		http.Error(rw, "Failed to delete images", http.StatusInternalServerError)
+		return
+	}
+	if err = os.RemoveAll(filepath.Join(imagesDir, "questions", strconv.Itoa(questionID))); err != nil {
+		h.logger.Error("Failed to remove images", zap.Error(err))
+		http.Error(rw, "Failed to delete images", http.StatusInternalServerError)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func saveFile(path string, src io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
@@ -70,9 +70,16 @@ func (a *ApiServer) Run() {
 
 }
 func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
+	apiKey := os.Getenv("INTERNAL_API_KEY")
 	mux.HandleFunc("GET /images/questions/{questionId}/image/{id}", middleware.VerifyToken(NewQuestionImagesHandler(a.logger, a.db).Handle, a.authClient))
+	// internal api used by the quiz service for question bank import and export
+	mux.HandleFunc("GET /images/questions/{questionId}/images/{id}", middleware.InternalAuth(NewQuestionImagesHandler(a.logger, a.db).Handle, a.logger, apiKey))
+	mux.HandleFunc("POST /images/questions/{questionId}/images", middleware.InternalAuth(NewQuestionImagesHandler(a.logger, a.db).Upload, a.logger, apiKey))
+	mux.HandleFunc("DELETE /images/questions/{questionId}/images", middleware.InternalAuth(NewQuestionImagesHandler(a.logger, a.db).Delete, a.logger, apiKey))
 
 	paramImagesHandler := NewParamImagesHandler(a.logger, a.db)
 	mux.HandleFunc("GET /images/params/{id}", paramImagesHandler.GetImage)
 	mux.HandleFunc("POST /images/params/{id}", middleware.VerifyToken(paramImagesHandler.PostImage, a.authClient))
+	mux.HandleFunc("PUT /images/params/{id}", middleware.InternalAuth(paramImagesHandler.PostImage, a.logger, apiKey))
+	mux.HandleFunc("DELETE /images/params/{id}", middleware.InternalAuth(paramImagesHandler.DeleteImage, a.logger, apiKey))
 }
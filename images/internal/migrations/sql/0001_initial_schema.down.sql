@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS params_images;
+DROP TABLE IF EXISTS question_images;
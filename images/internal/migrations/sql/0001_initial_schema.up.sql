@@ -0,0 +1,12 @@
+-- tables are created only when missing so databases created before migrations adopt this version
+CREATE TABLE IF NOT EXISTS question_images (
+    question_id integer PRIMARY KEY,
+    image1_path text,
+    image2_path text,
+    image3_path text
+);
+
+CREATE TABLE IF NOT EXISTS params_images (
+    param_id integer PRIMARY KEY,
+    image    bytea NOT NULL
+);
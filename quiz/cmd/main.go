@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	_ "github.com/lib/pq"
@@ -9,12 +10,17 @@ import (
 	"os"
 	"quiz/internal/api"
 	"quiz/internal/clients"
+	"quiz/internal/events"
+	"quiz/internal/migrations"
 	"quiz/internal/storage"
 	"time"
 )
 
 const PingDbAttempts = 3
 
+// DefaultQueryTimeout bounds each storage call when DB_QUERY_TIMEOUT is not set
+const DefaultQueryTimeout = 5 * time.Second
+
 func main() {
 	// Initialize logger
 	var err error
@@ -60,12 +66,40 @@ func main() {
 	if err = db.Ping(); err != nil {
 		logger.Fatal("Failed to ping database, exiting", zap.Error(err))
 	}
-	postgresStorage := storage.NewPostgresStorage(db, logger)
+
+	// Migrate the schema, "migrate up|down [steps]|version" runs only the migration command
+	migrator, err := migrations.New(db)
+	if err != nil {
+		logger.Fatal("Failed to load migrations", zap.Error(err))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err = migrations.Command(context.Background(), migrator, os.Args[2:], logger); err != nil {
+			logger.Fatal("Failed to migrate database", zap.Error(err))
+		}
+		return
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		logger.Fatal("Failed to migrate database", zap.Error(err))
+	}
+	logger.Info("Database schema is up to date", zap.Int("applied_migrations", applied))
+	postgresStorage := storage.NewPostgresStorage(db, logger, queryTimeout(logger))
+	// questions are cached in this process, it is the only writer of the question bank
+	store := storage.NewCachedStore(postgresStorage)
 	authClient := clients.NewAuthClient("http://auth:8080/auth", logger)
 	logger.Info("Connected to auth service")
 	statsClient := clients.NewStatsClient("http://stats:8080/stats", os.Getenv("INTERNAL_API_KEY"), logger)
 	logger.Info("Connected to stats service")
-	apiServer := api.NewApiServer(":8080", postgresStorage, logger, authClient, statsClient)
+	imagesClient := clients.NewImagesClient("http://images:8080/images", os.Getenv("INTERNAL_API_KEY"), logger)
+	bus, err := events.Connect(logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to the event bus", zap.Error(err))
+	}
+	if bus != nil {
+		defer bus.Close()
+		logger.Info("Connected to the event bus")
+	}
+	apiServer := api.NewApiServer(":8080", store, logger, authClient, statsClient, imagesClient, bus)
 	apiServer.Run()
 }
 func connectToPostgres() (*sql.DB, error) {
@@ -83,3 +117,17 @@ func connectToPostgres() (*sql.DB, error) {
 	connString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s", host, port, user, password, dbName, sslMode)
 	return sql.Open("postgres", connString)
 }
+
+// queryTimeout reads the timeout of storage calls from DB_QUERY_TIMEOUT, a duration such as 3s
+func queryTimeout(logger *zap.Logger) time.Duration {
+	value := os.Getenv("DB_QUERY_TIMEOUT")
+	if value == "" {
+		return DefaultQueryTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		logger.Warn("Invalid DB_QUERY_TIMEOUT, using the default", zap.String("value", value))
+		return DefaultQueryTimeout
+	}
+	return timeout
+}
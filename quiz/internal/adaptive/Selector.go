@@ -0,0 +1,72 @@
+package adaptive
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"quiz/internal/models"
+	"slices"
+)
+
+const (
+	// TargetProbability is the expected probability of a correct answer the selected question should be closest to,
+	// questions a bit easier than the ability of the user keep both experts and students engaged
+	TargetProbability = 0.7
+	// repeatPenalty makes questions the user has already answered in previous sessions less likely to be selected
+	repeatPenalty = 0.1
+)
+
+var ErrNoQuestions = fmt.Errorf("no questions available")
+
+// Probability returns the expected probability of a correct answer in the one parameter IRT model
+// used by the stats service to estimate abilities and difficulties
+func Probability(ability float64, difficulty float64) float64 {
+	return 1 / (1 + math.Exp(difficulty-ability))
+}
+
+// NextQuestion selects a question from candidates which was not asked yet in the session and returns it together
+// with the updated list of asked questions. When all candidates have been asked a new round is started.
+// Questions without answers are treated as average ones with difficulty 0
+func NextQuestion(profile models.AdaptiveProfile, candidates []int, asked []int) (int, []int, error) {
+	if len(candidates) == 0 {
+		return 0, asked, ErrNoQuestions
+	}
+	available := make([]int, 0, len(candidates))
+	for _, id := range candidates {
+		if !slices.Contains(asked, id) {
+			available = append(available, id)
+		}
+	}
+	if len(available) == 0 {
+		// all questions were asked, start a new round without repeating the last question right away
+		var last int
+		if len(asked) > 0 {
+			last = asked[len(asked)-1]
+		}
+		asked = nil
+		for _, id := range candidates {
+			if id != last || len(candidates) == 1 {
+				available = append(available, id)
+			}
+		}
+	}
+
+	questions := make(map[int]models.QuestionDifficulty, len(profile.Questions))
+	for _, question := range profile.Questions {
+		questions[question.QuestionID] = question
+	}
+	// shuffle first, so that questions with equal scores are picked at random
+	rand.Shuffle(len(available), func(i, j int) {
+		available[i], available[j] = available[j], available[i]
+	})
+	best, bestScore := 0, math.Inf(1)
+	for _, id := range available {
+		question := questions[id]
+		score := math.Abs(Probability(profile.Ability, question.Difficulty)-TargetProbability) +
+			repeatPenalty*float64(question.UserAnswers)
+		if score < bestScore {
+			best, bestScore = id, score
+		}
+	}
+	return best, append(asked, best), nil
+}
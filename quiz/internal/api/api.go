@@ -8,28 +8,35 @@ import (
 	"os"
 	"os/signal"
 	"quiz/internal/clients"
+	"quiz/internal/events"
 	"quiz/internal/handlers"
 	"quiz/internal/middleware"
+	"quiz/internal/outbox"
 	"quiz/internal/storage"
 	"syscall"
 	"time"
 )
 
 type ApiServer struct {
-	addr        string
-	storage     storage.Store
-	logger      *zap.Logger
-	authClient  *clients.AuthClient
-	statsClient *clients.StatsClient
+	addr         string
+	storage      storage.Store
+	logger       *zap.Logger
+	authClient   clients.AuthService
+	statsClient  clients.StatsService
+	imagesClient *clients.ImagesClient
+	// bus is nil when no event bus is configured, events are then delivered to the stats service directly
+	bus events.Bus
 }
 
-func NewApiServer(addr string, store storage.Store, logger *zap.Logger, authClient *clients.AuthClient, statsClient *clients.StatsClient) *ApiServer {
+func NewApiServer(addr string, store storage.Store, logger *zap.Logger, authClient clients.AuthService, statsClient clients.StatsService, imagesClient *clients.ImagesClient, bus events.Bus) *ApiServer {
 	return &ApiServer{
-		addr:        addr,
-		storage:     store,
-		logger:      logger,
-		authClient:  authClient,
-		statsClient: statsClient,
+		addr:         addr,
+		storage:      store,
+		logger:       logger,
+		authClient:   authClient,
+		statsClient:  statsClient,
+		imagesClient: imagesClient,
+		bus:          bus,
 	}
 }
 func (a *ApiServer) Run() {
@@ -50,6 +57,21 @@ func (a *ApiServer) Run() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// events are published and consumed in the background until the server exits
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+	var publisher events.Publisher = outbox.NewStatsPublisher(a.statsClient)
+	if a.bus != nil {
+		publisher = a.bus
+		a.bus.Subscribe("quiz", []string{events.UserDeleted}, handlers.NewEventsHandler(a.storage, a.logger).Handle)
+		go func() {
+			if err := a.bus.Run(eventsCtx); err != nil {
+				a.logger.Error("event bus stopped", zap.Error(err))
+			}
+		}()
+	}
+	go outbox.NewDispatcher(a.storage, publisher, a.logger).Run(eventsCtx)
+
 	a.logger.Info("about to start the server")
 	// Start server
 	go func() {
@@ -82,20 +104,41 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /quiz/sessions/new", middleware.VerifyToken(handlers.NewStartQuizHandler(a.storage, a.logger, a.statsClient).Handle, a.authClient))
 	mux.HandleFunc("GET /quiz/sessions/{quizSessionId}/nextQuestion", middleware.VerifyToken(handlers.NewGetNextQuestionHandler(a.storage, a.logger).Handle, a.authClient))
 	mux.HandleFunc("POST /quiz/sessions/{quizSessionId}/answer", middleware.VerifyToken(handlers.NewSubmitAnswerHandler(a.storage, a.logger, a.statsClient).Handle, a.authClient))
-	mux.HandleFunc("POST /quiz/sessions/{quizSessionId}/finish", middleware.VerifyToken(handlers.NewFinishQuizHandler(a.storage, a.logger, a.statsClient).Handle, a.authClient))
+	mux.HandleFunc("POST /quiz/sessions/{quizSessionId}/finish", middleware.VerifyToken(handlers.NewFinishQuizHandler(a.storage, a.logger).Handle, a.authClient))
+	mux.HandleFunc("POST /quiz/sessions/{quizSessionId}/resume", middleware.VerifyToken(handlers.NewResumeQuizHandler(a.storage, a.logger).Handle, a.authClient))
+	mux.HandleFunc("POST /quiz/sessions/{quizSessionId}/abandon", middleware.VerifyToken(handlers.NewAbandonQuizHandler(a.storage, a.logger).Handle, a.authClient))
+	preferencesHandler := handlers.NewPreferencesHandler(a.storage, a.logger)
+	mux.HandleFunc("GET /quiz/preferences", middleware.VerifyToken(preferencesHandler.GetPreferences, a.authClient))
+	mux.HandleFunc("PUT /quiz/preferences", middleware.VerifyToken(preferencesHandler.UpdatePreferences, a.authClient))
+
+	// assignments
+	assignmentHandler := handlers.NewAssignmentHandler(a.storage, a.logger)
+	mux.HandleFunc("GET /quiz/assignments/mine", middleware.VerifyToken(assignmentHandler.GetStudentAssignments, a.authClient))
+	mux.HandleFunc("POST /quiz/assignments/{id}/start", middleware.VerifyToken(assignmentHandler.StartAssignment, a.authClient))
 
 	//// internal api
 	apiKey := os.Getenv("INTERNAL_API_KEY")
 
 	mux.HandleFunc("GET /quiz/summary", middleware.InternalAuth(handlers.NewSummaryHandler(a.storage, a.logger).Handle, a.logger, apiKey))
-	//// Case routes
-	//caseHandler := handlers.NewCaseHandler(a.storage, a.logger)
-	//mux.HandleFunc("GET /quiz/cases", middleware.InternalAuth(caseHandler.GetAllCases, a.logger, apiKey))
-	////todo: fix, idk why tf this is not working but it is not
-	////mux.HandleFunc("GET /quiz/cases/{id}", middleware.VerifyToken(middleware.InternalAuth(caseHandler.GetCaseByID), a.authClient))
-	//mux.HandleFunc("POST /quiz/cases", middleware.InternalAuth(caseHandler.CreateCase, a.logger, apiKey))
-	//mux.HandleFunc("PUT /quiz/cases/{id}", middleware.InternalAuth(caseHandler.UpdateCase, a.logger, apiKey))
-	//mux.HandleFunc("DELETE /quiz/cases/{id}", middleware.InternalAuth(caseHandler.DeleteCase, a.logger, apiKey))
+	outboxHandler := handlers.NewOutboxHandler(a.storage, a.logger)
+	mux.HandleFunc("GET /quiz/outbox/stuck", middleware.InternalAuth(outboxHandler.GetStuckEvents, a.logger, apiKey))
+	mux.HandleFunc("POST /quiz/outbox/{id}/retry", middleware.InternalAuth(outboxHandler.RetryEvent, a.logger, apiKey))
+	// Case routes
+	caseHandler := handlers.NewCaseHandler(a.storage, a.logger)
+	mux.HandleFunc("GET /quiz/cases", middleware.InternalAuth(caseHandler.GetAllCases, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/cases/{id}", middleware.InternalAuth(caseHandler.GetCaseByID, a.logger, apiKey))
+	mux.HandleFunc("POST /quiz/cases", middleware.InternalAuth(caseHandler.CreateCase, a.logger, apiKey))
+	mux.HandleFunc("PUT /quiz/cases/{id}", middleware.InternalAuth(caseHandler.UpdateCase, a.logger, apiKey))
+	mux.HandleFunc("DELETE /quiz/cases/{id}", middleware.InternalAuth(caseHandler.DeleteCase, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/cases/{id}/landmarks", middleware.InternalAuth(caseHandler.GetCaseLandmarks, a.logger, apiKey))
+	mux.HandleFunc("PUT /quiz/cases/{id}/landmarks", middleware.InternalAuth(caseHandler.UpdateCaseLandmarks, a.logger, apiKey))
+	// Assignment routes
+	mux.HandleFunc("GET /quiz/assignments", middleware.InternalAuth(assignmentHandler.GetAssignments, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/assignments/{id}", middleware.InternalAuth(assignmentHandler.GetAssignment, a.logger, apiKey))
+	mux.HandleFunc("POST /quiz/assignments", middleware.InternalAuth(assignmentHandler.CreateAssignment, a.logger, apiKey))
+	mux.HandleFunc("PUT /quiz/assignments/{id}", middleware.InternalAuth(assignmentHandler.UpdateAssignment, a.logger, apiKey))
+	mux.HandleFunc("DELETE /quiz/assignments/{id}", middleware.InternalAuth(assignmentHandler.DeleteAssignment, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/assignments/{id}/sessions", middleware.InternalAuth(assignmentHandler.GetAssignmentSessions, a.logger, apiKey))
 	// Question routes
 	questionHandler := handlers.NewQuestionHandler(a.storage, a.logger)
 	mux.HandleFunc("GET /quiz/q/{id}", middleware.VerifyToken(questionHandler.GetQuestion, a.authClient))
@@ -104,6 +147,10 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("PATCH /quiz/questions/{id}", middleware.InternalAuth(questionHandler.UpdateQuestion, a.logger, apiKey))
 	mux.HandleFunc("DELETE /quiz/questions/{id}", middleware.InternalAuth(questionHandler.DeleteQuestion, a.logger, apiKey))
 	mux.HandleFunc("GET /quiz/questions", middleware.InternalAuth(questionHandler.GetAllQuestions, a.logger, apiKey))
+	mux.HandleFunc("PUT /quiz/questions/{id}/status", middleware.InternalAuth(questionHandler.UpdateQuestionStatus, a.logger, apiKey))
+	mux.HandleFunc("POST /quiz/questions/{id}/preview", middleware.InternalAuth(questionHandler.PreviewQuestion, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/questions/{id}/versions", middleware.InternalAuth(questionHandler.GetQuestionVersions, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/questions/{id}/versions/diff", middleware.InternalAuth(questionHandler.GetQuestionVersionsDiff, a.logger, apiKey))
 
 	// options routes
 	optionsHandler := handlers.NewOptionsHandler(a.storage, a.logger)
@@ -117,6 +164,17 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("PUT /quiz/groups/{id}", middleware.InternalAuth(groupHandler.UpdateGroup, a.logger, apiKey))
 	mux.HandleFunc("DELETE /quiz/groups/{id}", middleware.InternalAuth(groupHandler.DeleteGroup, a.logger, apiKey))
 	mux.HandleFunc("GET /quiz/groups", middleware.InternalAuth(groupHandler.GetAllGroups, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/groups/{id}", middleware.InternalAuth(groupHandler.GetGroup, a.logger, apiKey))
+	mux.HandleFunc("PUT /quiz/groups/order", middleware.InternalAuth(groupHandler.UpdateOrder, a.logger, apiKey))
+	mux.HandleFunc("PUT /quiz/groups/{id}/questions", middleware.InternalAuth(groupHandler.MoveQuestions, a.logger, apiKey))
+
+	// Import and export routes
+	importHandler := handlers.NewImportHandler(a.storage, a.logger, a.imagesClient)
+	mux.HandleFunc("POST /quiz/import", middleware.InternalAuth(importHandler.ImportQuestionBank, a.logger, apiKey))
+	mux.HandleFunc("POST /quiz/import/qti", middleware.InternalAuth(importHandler.ImportQTI, a.logger, apiKey))
+	exportHandler := handlers.NewExportHandler(a.storage, a.logger, a.imagesClient)
+	mux.HandleFunc("GET /quiz/export", middleware.InternalAuth(exportHandler.ExportQuestionBank, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/export/qti", middleware.InternalAuth(exportHandler.ExportQTI, a.logger, apiKey))
 
 	// Parameter routes
 	parameterHandler := handlers.NewParameterHandler(a.storage, a.logger)
@@ -126,6 +184,13 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("DELETE /quiz/parameters/{id}", middleware.InternalAuth(parameterHandler.DeleteParameter, a.logger, apiKey))
 	mux.HandleFunc("PUT /quiz/parameters/order", middleware.InternalAuth(parameterHandler.UpdateOrder, a.logger, apiKey))
 
+	// Translation routes
+	translationHandler := handlers.NewTranslationHandler(a.storage, a.logger)
+	mux.HandleFunc("GET /quiz/translations/missing", middleware.InternalAuth(translationHandler.GetMissingTranslations, a.logger, apiKey))
+	mux.HandleFunc("GET /quiz/translations/{entity}/{id}", middleware.InternalAuth(translationHandler.GetTranslations, a.logger, apiKey))
+	mux.HandleFunc("PUT /quiz/translations", middleware.InternalAuth(translationHandler.SaveTranslation, a.logger, apiKey))
+	mux.HandleFunc("DELETE /quiz/translations/{entity}/{id}/{field}/{locale}", middleware.InternalAuth(translationHandler.DeleteTranslation, a.logger, apiKey))
+
 	mux.HandleFunc("POST /quiz/settings", middleware.InternalAuth(handlers.NewSettingsHandler(a.storage, a.logger).UpdateSettings, a.logger, apiKey))
 	mux.HandleFunc("GET /quiz/settings", middleware.InternalAuth(handlers.NewSettingsHandler(a.storage, a.logger).GetSettings, a.logger, apiKey))
 }
@@ -0,0 +1,836 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"quiz/internal/archive"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/outbox"
+	"quiz/internal/storage"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testApiKey = "test-key"
+
+type testServer struct {
+	store *storage.MemoryStore
+	auth  *clients.FakeAuthClient
+	stats *clients.FakeStatsClient
+	mux   *http.ServeMux
+	// questionsIDs are the questions of the seeded question bank, all in one enabled group
+	questionsIDs []int
+}
+
+// newTestServer returns a server with a question bank of two choice questions answered with option "A"
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	t.Setenv("INTERNAL_API_KEY", testApiKey)
+	s := &testServer{
+		store: storage.NewMemoryStore(),
+		auth:  clients.NewFakeAuthClient(),
+		stats: clients.NewFakeStatsClient(),
+		mux:   http.NewServeMux(),
+	}
+	// the server caches questions like in production, the tests change the question bank through the API
+	NewApiServer(":0", storage.NewCachedStore(s.store), zap.NewNop(), s.auth, s.stats, nil, nil).registerRoutes(s.mux)
+
+	value3 := 3.0
+	bundle := models.ImportBundle{
+		Parameters: []models.ImportParameter{{Name: "SNA", ReferenceValues: "82"}},
+		Options:    []models.ImportOption{{Option: "A"}, {Option: "B"}},
+		Groups:     []models.ImportGroup{{ID: 1, Name: "Basics", Enabled: true}},
+		Cases: []models.ImportCase{{
+			Code:       "C1",
+			Gender:     "F",
+			Age1:       9,
+			Age2:       12,
+			Age3:       15,
+			Parameters: []models.ImportParameterValue{{Parameter: "SNA", Value1: 1, Value2: 2, Value3: &value3}},
+		}},
+	}
+	for i := 1; i <= 2; i++ {
+		bundle.Questions = append(bundle.Questions, models.ImportQuestion{
+			CaseCode:   "C1",
+			Question:   fmt.Sprintf("Question %d", i),
+			Group:      1,
+			Options:    []string{"A", "B"},
+			OptionsIDs: []int{0, 0},
+			Correct:    "A",
+			Status:     models.QuestionStatusPublished,
+		})
+	}
+	if err := s.store.ImportQuestionBank(context.Background(), &bundle, false, nil); err != nil {
+		t.Fatalf("import question bank: %v", err)
+	}
+	for _, question := range bundle.Questions {
+		s.questionsIDs = append(s.questionsIDs, question.ID)
+	}
+	return s
+}
+
+// do sends the request with the access token, or with the api key of the internal routes when the token is empty
+func (s *testServer) do(method string, path string, body string, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", token)
+	} else {
+		r.Header.Set("X-Api-Key", testApiKey)
+	}
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, r)
+	return w
+}
+
+// addUser makes the token "token-<userID>" valid for the user
+func (s *testServer) addUser(userID int) string {
+	token := fmt.Sprintf("token-%d", userID)
+	s.auth.AddToken(token, models.UserData{UserID: userID, Role: models.RoleUser})
+	return token
+}
+
+type startResponse struct {
+	Session   models.QuizSession `json:"session"`
+	TimeLimit int                `json:"time_limit"`
+}
+
+func (s *testServer) start(t *testing.T, token string, mode models.QuizMode) models.QuizSession {
+	t.Helper()
+	w := s.do(http.MethodPost, "/quiz/sessions/new", fmt.Sprintf(`{"mode":%q,"screen_width":1920,"screen_height":1080}`, mode), token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("start quiz: status %d, body %q", w.Code, w.Body.String())
+	}
+	// the position in the quiz is not part of the response
+	return s.session(t, decode[startResponse](t, w).Session.ID)
+}
+
+func (s *testServer) nextQuestion(sessionID int, token string) *httptest.ResponseRecorder {
+	return s.do(http.MethodGet, fmt.Sprintf("/quiz/sessions/%d/nextQuestion", sessionID), "", token)
+}
+
+func (s *testServer) answer(sessionID int, questionID int, answer string, idempotencyKey string, token string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"question_id":%d,"answer":%q,"idempotency_key":%q}`, questionID, answer, idempotencyKey)
+	return s.do(http.MethodPost, fmt.Sprintf("/quiz/sessions/%d/answer", sessionID), body, token)
+}
+
+func (s *testServer) session(t *testing.T, sessionID int) models.QuizSession {
+	t.Helper()
+	session, err := s.store.GetQuizSessionByID(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("get session %d: %v", sessionID, err)
+	}
+	return session
+}
+
+// deliverEvents publishes the due outbox events to the fake stats client like the outbox dispatcher
+func (s *testServer) deliverEvents(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	publisher := outbox.NewStatsPublisher(s.stats)
+	for {
+		due, err := s.store.GetDueOutboxEvents(ctx, 10)
+		if err != nil {
+			t.Fatalf("get due outbox events: %v", err)
+		}
+		if len(due) == 0 {
+			return
+		}
+		for _, event := range due {
+			if err = publisher.Publish(ctx, event.Type, event.Payload); err != nil {
+				t.Fatalf("publish %s event: %v", event.Type, err)
+			}
+			if err = s.store.MarkOutboxEventDelivered(ctx, event.ID); err != nil {
+				t.Fatalf("mark outbox event delivered: %v", err)
+			}
+		}
+	}
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+func TestStartQuiz(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare runs before user 1 starts the quiz and returns the token of the request
+		prepare    func(t *testing.T, s *testServer) string
+		mode       models.QuizMode
+		wantStatus int
+	}{
+		{
+			name:       "classic",
+			mode:       models.QuizModeClassic,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "invalid token",
+			prepare: func(t *testing.T, s *testServer) string {
+				return "unknown"
+			},
+			mode:       models.QuizModeClassic,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "session of the mode is already open",
+			prepare: func(t *testing.T, s *testServer) string {
+				s.start(t, s.addUser(1), models.QuizModeClassic)
+				return s.addUser(1)
+			},
+			mode:       models.QuizModeClassic,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "open session of another mode",
+			prepare: func(t *testing.T, s *testServer) string {
+				s.start(t, s.addUser(1), models.QuizModeEducational)
+				return s.addUser(1)
+			},
+			mode:       models.QuizModeClassic,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown mode",
+			mode:       "unknown",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "review without incorrect answers",
+			mode:       models.QuizModeReview,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "review of incorrect answers",
+			prepare: func(t *testing.T, s *testServer) string {
+				s.stats.SetIncorrectQuestions(1, []models.IncorrectQuestion{{QuestionID: s.questionsIDs[1], MissedAt: time.Now()}})
+				return s.addUser(1)
+			},
+			mode:       models.QuizModeReview,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			token := s.addUser(1)
+			if tt.prepare != nil {
+				token = tt.prepare(t, s)
+			}
+			body := fmt.Sprintf(`{"mode":%q,"screen_width":1920,"screen_height":1080}`, tt.mode)
+			w := s.do(http.MethodPost, "/quiz/sessions/new", body, token)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			response := decode[startResponse](t, w)
+			session := s.session(t, response.Session.ID)
+			if session.UserID != 1 || session.Mode != tt.mode || session.Status != models.QuizStatusNotStarted {
+				t.Errorf("session = %+v, want a new %s session of user 1", session, tt.mode)
+			}
+			if !slices.Contains(s.questionsIDs, session.CurrentQuestionID) {
+				t.Errorf("current question = %d, want one of %v", session.CurrentQuestionID, s.questionsIDs)
+			}
+			if response.TimeLimit != 30 {
+				t.Errorf("time limit = %d, want 30", response.TimeLimit)
+			}
+		})
+	}
+}
+
+func TestSubmitAnswer(t *testing.T) {
+	tests := []struct {
+		name string
+		// body returns the answer to the session started by user 1
+		body       func(session models.QuizSession) string
+		token      func(s *testServer) string
+		sessionID  func(session models.QuizSession) int
+		wantStatus int
+		// wantResponses is the number of answers recorded in stats
+		wantResponses int
+	}{
+		{
+			name: "answer to the current question",
+			body: func(session models.QuizSession) string {
+				return fmt.Sprintf(`{"question_id":%d,"answer":"A","idempotency_key":"key"}`, session.CurrentQuestionID)
+			},
+			wantStatus:    http.StatusOK,
+			wantResponses: 1,
+		},
+		{
+			name: "missing idempotency key",
+			body: func(session models.QuizSession) string {
+				return fmt.Sprintf(`{"question_id":%d,"answer":"A"}`, session.CurrentQuestionID)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "confidence out of the scale",
+			body: func(session models.QuizSession) string {
+				return fmt.Sprintf(`{"question_id":%d,"answer":"A","idempotency_key":"key","confidence":9}`, session.CurrentQuestionID)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "question which is not current",
+			body: func(session models.QuizSession) string {
+				return fmt.Sprintf(`{"question_id":%d,"answer":"A","idempotency_key":"key"}`, session.CurrentQuestionID+100)
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "unknown session",
+			body: func(session models.QuizSession) string {
+				return fmt.Sprintf(`{"question_id":%d,"answer":"A","idempotency_key":"key"}`, session.CurrentQuestionID)
+			},
+			sessionID: func(session models.QuizSession) int {
+				return session.ID + 100
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "session of another user",
+			body: func(session models.QuizSession) string {
+				return fmt.Sprintf(`{"question_id":%d,"answer":"A","idempotency_key":"key"}`, session.CurrentQuestionID)
+			},
+			token: func(s *testServer) string {
+				return s.addUser(2)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			session := s.start(t, s.addUser(1), models.QuizModeClassic)
+			token, sessionID := s.addUser(1), session.ID
+			if tt.token != nil {
+				token = tt.token(s)
+			}
+			if tt.sessionID != nil {
+				sessionID = tt.sessionID(session)
+			}
+			w := s.do(http.MethodPost, fmt.Sprintf("/quiz/sessions/%d/answer", sessionID), tt.body(session), token)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			s.deliverEvents(t)
+			if responses := s.stats.Responses(session.ID); len(responses) != tt.wantResponses {
+				t.Errorf("recorded %d answers, want %d", len(responses), tt.wantResponses)
+			}
+			moved := s.session(t, session.ID).CurrentQuestionID != session.CurrentQuestionID
+			if moved != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("session moved to the next question = %t after status %d", moved, w.Code)
+			}
+		})
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	token := s.addUser(1)
+	session := s.start(t, token, models.QuizModeEducational)
+
+	w := s.nextQuestion(session.ID, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("next question: status %d, body %q", w.Code, w.Body.String())
+	}
+	question := decode[models.Question](t, w)
+	if question.ID != session.CurrentQuestionID {
+		t.Fatalf("next question = %d, want %d", question.ID, session.CurrentQuestionID)
+	}
+	for _, value := range question.Case.ParameterValues {
+		if value.Value3 != nil {
+			t.Errorf("value3 of parameter %d is exposed", value.ParameterID)
+		}
+	}
+	if w = s.nextQuestion(session.ID, s.addUser(2)); w.Code != http.StatusNotFound {
+		t.Errorf("next question of another user: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// the answer is graded and the correct option is shown in educational mode
+	w = s.answer(session.ID, question.ID, "B", "first", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("answer: status %d, body %q", w.Code, w.Body.String())
+	}
+	first := w.Body.String()
+	if result := decode[map[string]any](t, w); result["correct"] != "A" {
+		t.Errorf("answer response = %v, want the correct option A", result)
+	}
+	// a retried submission gets the first response and does not move the session again
+	next := s.session(t, session.ID).CurrentQuestionID
+	if w = s.answer(session.ID, question.ID, "B", "first", token); w.Code != http.StatusOK || w.Body.String() != first {
+		t.Errorf("retried answer: status %d, body %q, want the first response %q", w.Code, w.Body.String(), first)
+	}
+	if current := s.session(t, session.ID).CurrentQuestionID; current != next {
+		t.Errorf("current question after retry = %d, want %d", current, next)
+	}
+	// a new submission to the answered question is rejected
+	if w = s.answer(session.ID, question.ID, "A", "second", token); w.Code != http.StatusConflict {
+		t.Errorf("answer to the answered question: status %d, want %d", w.Code, http.StatusConflict)
+	}
+	// the next question is answered only after it was requested, its time spent is measured from the request
+	if w = s.answer(session.ID, next, "A", "second", token); w.Code != http.StatusConflict {
+		t.Errorf("answer to the question which was not requested: status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	finishPath := fmt.Sprintf("/quiz/sessions/%d/finish", session.ID)
+	if w = s.do(http.MethodPost, finishPath, "", s.addUser(2)); w.Code != http.StatusNotFound {
+		t.Errorf("finish by another user: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w = s.do(http.MethodPost, finishPath, "", token); w.Code != http.StatusOK {
+		t.Fatalf("finish: status %d, body %q", w.Code, w.Body.String())
+	}
+	// the session is finished in stats only once
+	if w = s.do(http.MethodPost, finishPath, "", token); w.Code != http.StatusConflict {
+		t.Errorf("repeated finish: status %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w = s.nextQuestion(session.ID, token); w.Code != http.StatusNotFound {
+		t.Errorf("next question of a finished quiz: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w = s.answer(session.ID, next, "A", "third", token); w.Code != http.StatusNotFound {
+		t.Errorf("answer to a finished quiz: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	s.deliverEvents(t)
+	saved, finished, ok := s.stats.Session(session.ID)
+	if !ok || saved.UserID != 1 || saved.Mode != models.QuizModeEducational {
+		t.Errorf("session in stats = %+v, found %t", saved, ok)
+	}
+	if !finished {
+		t.Error("session is not finished in stats")
+	}
+	responses := s.stats.Responses(session.ID)
+	if len(responses) != 1 {
+		t.Fatalf("recorded %d answers, want 1", len(responses))
+	}
+	if r := responses[0]; r.QuestionID != question.ID || r.Answer != "B" || r.IsCorrect || r.QuestionVersion != 1 {
+		t.Errorf("recorded answer = %+v, want the incorrect answer B to version 1 of question %d", r, question.ID)
+	}
+}
+
+func TestAbandonAndResume(t *testing.T) {
+	s := newTestServer(t)
+	token := s.addUser(1)
+	session := s.start(t, token, models.QuizModeClassic)
+	if w := s.answer(session.ID, session.CurrentQuestionID, "A", "key", token); w.Code != http.StatusOK {
+		t.Fatalf("answer: status %d, body %q", w.Code, w.Body.String())
+	}
+	position := s.session(t, session.ID).CurrentQuestionID
+
+	tests := []struct {
+		name       string
+		action     string
+		token      string
+		wantStatus int
+	}{
+		{name: "resume session of another user", action: "resume", token: s.addUser(2), wantStatus: http.StatusNotFound},
+		{name: "resume open session", action: "resume", token: token, wantStatus: http.StatusOK},
+		{name: "abandon session of another user", action: "abandon", token: s.addUser(2), wantStatus: http.StatusNotFound},
+		{name: "abandon open session", action: "abandon", token: token, wantStatus: http.StatusOK},
+		{name: "resume abandoned session", action: "resume", token: token, wantStatus: http.StatusConflict},
+		{name: "abandon abandoned session", action: "abandon", token: token, wantStatus: http.StatusConflict},
+	}
+	// the cases run in order, each one sees the session left by the previous ones
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodPost, fmt.Sprintf("/quiz/sessions/%d/%s", session.ID, tt.action), "", tt.token)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	// the next session of the mode continues from the position of the abandoned one
+	w := s.do(http.MethodGet, "/quiz/sessions", "", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("active sessions: status %d, body %q", w.Code, w.Body.String())
+	}
+	if active := decode[map[string][]models.QuizSession](t, w)["sessions"]; len(active) != 0 {
+		t.Errorf("active sessions = %+v, want none", active)
+	}
+	next := s.start(t, token, models.QuizModeClassic)
+	if next.CurrentQuestionID != position {
+		t.Errorf("current question of the next session = %d, want %d", next.CurrentQuestionID, position)
+	}
+
+	// abandoned sessions are not finished in stats, their answers stay recorded
+	s.deliverEvents(t)
+	if _, finished, _ := s.stats.Session(session.ID); finished {
+		t.Error("abandoned session is finished in stats")
+	}
+	if responses := s.stats.Responses(session.ID); len(responses) != 1 {
+		t.Errorf("recorded %d answers of the abandoned session, want 1", len(responses))
+	}
+}
+
+func TestQuestionCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name string
+		// change edits the cached question through the internal API
+		change func(t *testing.T, s *testServer, question models.Question) *httptest.ResponseRecorder
+		check  func(t *testing.T, question models.Question)
+	}{
+		{
+			name: "question edited",
+			change: func(t *testing.T, s *testServer, question models.Question) *httptest.ResponseRecorder {
+				question.Question = "Edited"
+				question.Case.ParameterValues[0].Value1 = 10
+				body, _ := json.Marshal(question)
+				return s.do(http.MethodPatch, fmt.Sprintf("/quiz/questions/%d", question.ID), string(body), "")
+			},
+			check: func(t *testing.T, question models.Question) {
+				if question.Question != "Edited" || question.Case.ParameterValues[0].Value1 != 10 {
+					t.Errorf("question = %q with value1 %v, want the edited question", question.Question, question.Case.ParameterValues[0].Value1)
+				}
+			},
+		},
+		{
+			name: "parameter renamed",
+			change: func(t *testing.T, s *testServer, question models.Question) *httptest.ResponseRecorder {
+				return s.do(http.MethodPatch, fmt.Sprintf("/quiz/parameters/%d", question.Case.Parameters[0].ID), `{"name":"SNB","reference_values":"80"}`, "")
+			},
+			check: func(t *testing.T, question models.Question) {
+				if p := question.Case.Parameters[0]; p.Name != "SNB" || p.ReferenceValues != "80" {
+					t.Errorf("parameter = %+v, want the renamed parameter", p)
+				}
+			},
+		},
+		{
+			name: "option renamed",
+			change: func(t *testing.T, s *testServer, question models.Question) *httptest.ResponseRecorder {
+				options, err := s.store.GetAllOptions(context.Background())
+				if err != nil {
+					t.Fatalf("get options: %v", err)
+				}
+				return s.do(http.MethodPatch, fmt.Sprintf("/quiz/options/%d", options[1].ID), `{"option":"C"}`, "")
+			},
+			check: func(t *testing.T, question models.Question) {
+				if !slices.Equal(question.Options, []string{"A", "C"}) {
+					t.Errorf("options = %v, want [A C]", question.Options)
+				}
+			},
+		},
+		{
+			name: "question bank imported",
+			change: func(t *testing.T, s *testServer, question models.Question) *httptest.ResponseRecorder {
+				// the change behind the cache is only served once the import drops the cached questions
+				err := s.store.UpdateQuestionStatus(context.Background(), question.ID, models.QuestionStatusPublished, models.QuestionStatusDraft)
+				if err != nil {
+					t.Fatalf("update status: %v", err)
+				}
+				return s.importArchive(t, `{"version":2,"cases":[],"questions":[{"case_code":"C1","question":"Imported","options":["A","B"],"correct":"A"}]}`)
+			},
+			check: func(t *testing.T, question models.Question) {
+				if question.Status != models.QuestionStatusDraft {
+					t.Errorf("status = %q, want %q", question.Status, models.QuestionStatusDraft)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			path := fmt.Sprintf("/quiz/questions/%d", s.questionsIDs[0])
+			w := s.do(http.MethodGet, path, "", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("get question: status %d, body %q", w.Code, w.Body.String())
+			}
+			if w = tt.change(t, s, decode[models.Question](t, w)); w.Code != http.StatusOK && w.Code != http.StatusCreated && w.Code != http.StatusNoContent {
+				t.Fatalf("change: status %d, body %q", w.Code, w.Body.String())
+			}
+			w = s.do(http.MethodGet, path, "", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("get changed question: status %d, body %q", w.Code, w.Body.String())
+			}
+			tt.check(t, decode[models.Question](t, w))
+		})
+	}
+}
+
+func TestReviewQueue(t *testing.T) {
+	s := newTestServer(t)
+	token := s.addUser(1)
+	questionID := s.questionsIDs[0]
+	s.stats.SetIncorrectQuestions(1, []models.IncorrectQuestion{{QuestionID: questionID, MissedAt: time.Now().Add(-time.Hour)}})
+
+	// a recalled question is scheduled for a later day and the session ends with nothing else due
+	session := s.start(t, token, models.QuizModeReview)
+	if w := s.nextQuestion(session.ID, token); w.Code != http.StatusOK {
+		t.Fatalf("next question: status %d, body %q", w.Code, w.Body.String())
+	}
+	if w := s.answer(session.ID, questionID, "A", "first", token); w.Code != http.StatusOK {
+		t.Fatalf("answer: status %d, body %q", w.Code, w.Body.String())
+	}
+	if w := s.do(http.MethodPost, "/quiz/sessions/new", `{"mode":"review","screen_width":1920,"screen_height":1080}`, token); w.Code != http.StatusNotFound {
+		t.Fatalf("review with the recalled question: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// missing the question again in another mode makes it due again
+	s.stats.SetIncorrectQuestions(1, []models.IncorrectQuestion{{QuestionID: questionID, MissedAt: time.Now()}})
+	session = s.start(t, token, models.QuizModeReview)
+	if session.CurrentQuestionID != questionID {
+		t.Errorf("current question = %d, want the missed question %d", session.CurrentQuestionID, questionID)
+	}
+	item, err := s.store.GetReviewItem(context.Background(), 1, questionID)
+	if err != nil {
+		t.Fatalf("get review item: %v", err)
+	}
+	if item.Repetitions != 0 {
+		t.Errorf("repetitions = %d, want 0 after the question was missed again", item.Repetitions)
+	}
+}
+
+func TestAssignmentAttempts(t *testing.T) {
+	s := newTestServer(t)
+	token := s.addUser(1)
+	assignment, err := s.store.CreateAssignment(context.Background(), models.Assignment{
+		TeacherID:    5,
+		Title:        "Homework",
+		Mode:         models.QuizModeClassic,
+		QuestionsIDs: s.questionsIDs,
+		Deadline:     time.Now().Add(time.Hour),
+		MaxAttempts:  2,
+		StudentsIDs:  []int{1},
+	})
+	if err != nil {
+		t.Fatalf("create assignment: %v", err)
+	}
+	start := func() *httptest.ResponseRecorder {
+		return s.do(http.MethodPost, fmt.Sprintf("/quiz/assignments/%d/start", assignment.ID), `{"screen_width":1920,"screen_height":1080}`, token)
+	}
+
+	// concurrent starts open a single session
+	codes := make(chan int, 5)
+	for range cap(codes) {
+		go func() {
+			codes <- start().Code
+		}()
+	}
+	started := 0
+	for range cap(codes) {
+		switch code := <-codes; code {
+		case http.StatusOK:
+			started++
+		case http.StatusConflict:
+		default:
+			t.Errorf("start: status %d", code)
+		}
+	}
+	if started != 1 {
+		t.Fatalf("started %d sessions, want 1", started)
+	}
+
+	sessions, err := s.store.GetAssignmentSessions(context.Background(), assignment.ID)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("assignment sessions %+v, err %v, want 1", sessions, err)
+	}
+	if w := s.do(http.MethodPost, fmt.Sprintf("/quiz/sessions/%d/abandon", sessions[0].SessionID), "", token); w.Code != http.StatusOK && w.Code != http.StatusNoContent {
+		t.Fatalf("abandon: status %d, body %q", w.Code, w.Body.String())
+	}
+	if w := start(); w.Code != http.StatusOK {
+		t.Fatalf("second attempt: status %d, body %q", w.Code, w.Body.String())
+	}
+	sessions, _ = s.store.GetAssignmentSessions(context.Background(), assignment.ID)
+	if w := s.do(http.MethodPost, fmt.Sprintf("/quiz/sessions/%d/abandon", sessions[len(sessions)-1].SessionID), "", token); w.Code != http.StatusOK && w.Code != http.StatusNoContent {
+		t.Fatalf("abandon: status %d, body %q", w.Code, w.Body.String())
+	}
+	if w := start(); w.Code != http.StatusConflict {
+		t.Errorf("third attempt: status %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+// importArchive imports a zip archive with the manifest
+func (s *testServer) importArchive(t *testing.T, manifest string) *httptest.ResponseRecorder {
+	t.Helper()
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	file, err := writer.Create("manifest.json")
+	if err == nil {
+		_, err = file.Write([]byte(manifest))
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	return s.do(http.MethodPost, "/quiz/import", archive.String(), "")
+}
+
+func TestImportQuestionStatus(t *testing.T) {
+	s := newTestServer(t)
+	manifest := `{"version":2,"cases":[],"questions":[
+		{"case_code":"C1","question":"Draft","options":["A","B"],"correct":"A"},
+		{"case_code":"C1","question":"Published","options":["A","B"],"correct":"A","status":"published"}]}`
+	w := s.importArchive(t, manifest)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("import: status %d, body %q", w.Code, w.Body.String())
+	}
+	report := decode[models.ImportReport](t, w)
+	if len(report.QuestionsIDs) != 2 {
+		t.Fatalf("imported questions %v, want 2", report.QuestionsIDs)
+	}
+	// questions without a status are imported as drafts, exports carry the status of questions
+	bundle, err := s.store.ExportQuestionBank(context.Background())
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	statuses := make(map[int]string)
+	for _, question := range bundle.Questions {
+		statuses[question.ID] = question.Status
+	}
+	if statuses[report.QuestionsIDs[0]] != models.QuestionStatusDraft || statuses[report.QuestionsIDs[1]] != models.QuestionStatusPublished {
+		t.Errorf("statuses %v, want draft and published", statuses)
+	}
+
+	w = s.importArchive(t, `{"cases":[],"questions":[{"case_code":"C1","question":"Q","options":["A"],"correct":"A","status":"live"}]}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("import with an unknown status: status %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestUpdateCase(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	caseID, err := s.store.GetCaseIDByCode(ctx, "C1")
+	if err != nil {
+		t.Fatalf("get case: %v", err)
+	}
+	path := fmt.Sprintf("/quiz/cases/%d", caseID)
+
+	// a case updated without parameter values keeps its values
+	w := s.do(http.MethodPut, path, `{"code":"C1","gender":"M","age1":9,"age2":12,"age3":15}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("update case: status %d, body %q", w.Code, w.Body.String())
+	}
+	updated := decode[models.Case](t, w)
+	if updated.Gender != "M" || len(updated.ParameterValues) != 1 || updated.ParameterValues[0].Value1 != 1 {
+		t.Errorf("case = %+v, want gender M and the value of SNA kept", updated)
+	}
+
+	body := fmt.Sprintf(`{"code":"C1","gender":"M","age1":9,"age2":12,"age3":15,"parameters_values":[{"parameter_id":%d,"value1":5,"value2":6}]}`, updated.ParameterValues[0].ParameterID)
+	if w = s.do(http.MethodPut, path, body, ""); w.Code != http.StatusOK {
+		t.Fatalf("update case values: status %d, body %q", w.Code, w.Body.String())
+	}
+	if updated = decode[models.Case](t, w); len(updated.ParameterValues) != 1 || updated.ParameterValues[0].Value1 != 5 {
+		t.Errorf("parameter values = %+v, want value1 5", updated.ParameterValues)
+	}
+
+	// a failed update of the values leaves the case unchanged
+	if w = s.do(http.MethodPut, path, `{"code":"C2","parameters_values":[{"parameter_id":999999,"value1":1}]}`, ""); w.Code == http.StatusOK {
+		t.Fatalf("update case with an unknown parameter: status %d", w.Code)
+	}
+	if c, err := s.store.GetCaseByID(ctx, caseID); err != nil || c.Code != "C1" {
+		t.Errorf("case after failed update = %+v, %v, want code C1", c, err)
+	}
+}
+
+func TestImportArchiveUncompressedSize(t *testing.T) {
+	s := newTestServer(t)
+	// a small archive of a file which is too large when uncompressed
+	var data bytes.Buffer
+	writer := zip.NewWriter(&data)
+	file, err := writer.Create("manifest.json")
+	if err == nil {
+		_, err = file.Write([]byte(`{"cases":[],"questions":[]}`))
+	}
+	if err == nil {
+		file, err = writer.Create("images/bomb.png")
+	}
+	chunk := make([]byte, 1<<20)
+	for i := 0; err == nil && i <= archive.MaxFileSize>>20; i++ {
+		_, err = file.Write(chunk)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	w := s.do(http.MethodPost, "/quiz/import", data.String(), "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("import: status %d, want %d, body %q", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+}
+
+func TestGroupUnknownQuestions(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	body := fmt.Sprintf(`{"name":"Advanced","enabled":true,"questions":[%d,999999]}`, s.questionsIDs[0])
+	if w := s.do(http.MethodPost, "/quiz/groups", body, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("create group: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	// nothing is left of the failed group
+	groups, err := s.store.GetAllGroups(ctx)
+	if err != nil {
+		t.Fatalf("get groups: %v", err)
+	}
+	if len(groups) != 1 || !slices.Equal(groups[0].QuestionsIDs, s.questionsIDs) {
+		t.Fatalf("groups = %+v, want only the seeded group with all questions", groups)
+	}
+
+	w := s.do(http.MethodPost, "/quiz/groups", `{"name":"Advanced","enabled":true}`, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create empty group: status %d, body %q", w.Code, w.Body.String())
+	}
+	group := decode[models.QuestionsGroup](t, w)
+	body = fmt.Sprintf(`{"questions":[%d,999999]}`, s.questionsIDs[0])
+	if w = s.do(http.MethodPut, fmt.Sprintf("/quiz/groups/%d/questions", group.ID), body, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("move questions: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if group, err = s.store.GetGroupByID(ctx, group.ID); err != nil || len(group.QuestionsIDs) != 0 {
+		t.Errorf("group after failed move = %+v, %v, want no questions", group, err)
+	}
+}
+
+func TestGroupInUse(t *testing.T) {
+	s := newTestServer(t)
+	token := s.addUser(1)
+	session := s.start(t, token, models.QuizModeClassic)
+	groupPath := fmt.Sprintf("/quiz/groups/%d", session.CurrentGroup)
+
+	// the open session would restart at the first group
+	if w := s.do(http.MethodDelete, groupPath, "", ""); w.Code != http.StatusConflict {
+		t.Fatalf("delete group: status %d, want %d", w.Code, http.StatusConflict)
+	}
+	body := fmt.Sprintf(`{"name":"Advanced","enabled":true,"questions":[%d]}`, s.questionsIDs[0])
+	if w := s.do(http.MethodPost, "/quiz/groups", body, ""); w.Code != http.StatusConflict {
+		t.Fatalf("create group with questions of the open session: status %d, want %d", w.Code, http.StatusConflict)
+	}
+	w := s.do(http.MethodPost, "/quiz/groups", `{"name":"Advanced","enabled":true}`, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create empty group: status %d, body %q", w.Code, w.Body.String())
+	}
+	group := decode[models.QuestionsGroup](t, w)
+	body = fmt.Sprintf(`{"questions":[%d]}`, s.questionsIDs[0])
+	if w = s.do(http.MethodPut, fmt.Sprintf("/quiz/groups/%d/questions", group.ID), body, ""); w.Code != http.StatusConflict {
+		t.Fatalf("move questions: status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	if w = s.do(http.MethodPost, fmt.Sprintf("/quiz/sessions/%d/abandon", session.ID), "", token); w.Code != http.StatusOK {
+		t.Fatalf("abandon: status %d, body %q", w.Code, w.Body.String())
+	}
+	if w = s.do(http.MethodPut, fmt.Sprintf("/quiz/groups/%d/questions", group.ID), body, ""); w.Code != http.StatusOK {
+		t.Errorf("move questions after abandon: status %d, body %q", w.Code, w.Body.String())
+	}
+	if w = s.do(http.MethodDelete, groupPath, "", ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete group after abandon: status %d, body %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,37 @@
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"quiz/internal/models"
+	"sort"
+)
+
+// WriteExportBundle writes the bundle as a zip archive with manifest.json and the bundle files,
+// the archive can be imported with ReadImportBundle
+func WriteExportBundle(w io.Writer, bundle *models.ImportBundle) error {
+	writer := zip.NewWriter(w)
+	manifest, err := writer.Create(JSONManifest)
+	if err != nil {
+		return err
+	}
+	if err = bundle.ToJSON(manifest); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(bundle.Files))
+	for name := range bundle.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file, err := writer.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err = file.Write(bundle.Files[name]); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
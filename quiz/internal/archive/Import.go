@@ -0,0 +1,248 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"quiz/internal/models"
+	"strconv"
+	"strings"
+)
+
+const (
+	JSONManifest = "manifest.json"
+	CSVManifest  = "manifest.csv"
+)
+
+// Limits of the uncompressed content of an archive, the size of the archive itself is limited by the handler
+const (
+	// MaxFileSize is the maximum uncompressed size of one file of an archive
+	MaxFileSize = 50 << 20
+	// MaxUncompressedSize is the maximum uncompressed size of all files of an archive
+	MaxUncompressedSize = 500 << 20
+)
+
+// ReadImportBundle reads a zip archive with a manifest.json or manifest.csv file in its root
+// and the radiographs referenced by the manifest
+func ReadImportBundle(data []byte) (models.ImportBundle, error) {
+	var bundle models.ImportBundle
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return bundle, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	files := make(map[string][]byte)
+	var manifestName string
+	var size int64
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		name := path.Clean(file.Name)
+		content, err := readZipFile(file, &size)
+		if err != nil {
+			return bundle, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if name == JSONManifest || name == CSVManifest {
+			if manifestName != "" {
+				return bundle, fmt.Errorf("archive contains both %s and %s", JSONManifest, CSVManifest)
+			}
+			manifestName = name
+		}
+		files[name] = content
+	}
+
+	switch manifestName {
+	case JSONManifest:
+		err = bundle.FromJSON(bytes.NewReader(files[JSONManifest]))
+	case CSVManifest:
+		bundle, err = parseCSVManifest(bytes.NewReader(files[CSVManifest]))
+	default:
+		return bundle, fmt.Errorf("archive has no %s or %s", JSONManifest, CSVManifest)
+	}
+	if err != nil {
+		return bundle, fmt.Errorf("invalid manifest: %w", err)
+	}
+	delete(files, manifestName)
+	bundle.Files = files
+	return bundle, nil
+}
+
+// readZipFile reads the file and adds its size to the size of the files read before. The size declared in the archive
+// is not trusted, reading stops once the file exceeds MaxFileSize or all files exceed MaxUncompressedSize
+func readZipFile(file *zip.File, size *int64) ([]byte, error) {
+	limit := min(MaxFileSize, MaxUncompressedSize-*size)
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, errTooLarge(*size)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, errTooLarge(*size)
+	}
+	*size += int64(len(content))
+	return content, nil
+}
+
+func errTooLarge(size int64) error {
+	if MaxUncompressedSize-size < MaxFileSize {
+		return fmt.Errorf("archive is larger than %d MB uncompressed", MaxUncompressedSize>>20)
+	}
+	return fmt.Errorf("file is larger than %d MB uncompressed", MaxFileSize>>20)
+}
+
+// parseCSVManifest reads a manifest with one question per row. Columns are case_code, gender, age1, age2, age3,
+// question, prediction_age, group, options (separated with "|"), correct, image1, image2, image3,
+// optionally type, target_parameter and tolerance for numeric questions
+// and "<parameter name>.value1", "<parameter name>.value2", "<parameter name>.value3" for case parameters.
+// The first row of each case_code defines the case
+func parseCSVManifest(r io.Reader) (models.ImportBundle, error) {
+	var bundle models.ImportBundle
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return bundle, err
+	}
+	if len(records) == 0 {
+		return bundle, fmt.Errorf("manifest is empty")
+	}
+	header := records[0]
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"case_code", "question", "options", "correct"} {
+		if _, ok := columns[required]; !ok {
+			return bundle, fmt.Errorf("missing column %s", required)
+		}
+	}
+
+	// parameter columns in the order of the header
+	var parameters []string
+	for _, name := range header {
+		name = strings.TrimSpace(name)
+		if strings.HasSuffix(name, ".value1") {
+			parameters = append(parameters, strings.TrimSuffix(name, ".value1"))
+		}
+	}
+
+	casesIndex := make(map[string]int)
+	for line, record := range records[1:] {
+		row := csvRow{columns: columns, record: record, line: line + 2}
+		caseCode := row.get("case_code")
+		if _, ok := casesIndex[caseCode]; !ok {
+			importCase := models.ImportCase{
+				Code:   caseCode,
+				Gender: row.get("gender"),
+			}
+			if importCase.Age1, err = row.getInt("age1"); err != nil {
+				return bundle, err
+			}
+			if importCase.Age2, err = row.getInt("age2"); err != nil {
+				return bundle, err
+			}
+			if importCase.Age3, err = row.getInt("age3"); err != nil {
+				return bundle, err
+			}
+			for _, parameter := range parameters {
+				value := models.ImportParameterValue{Parameter: parameter}
+				if value.Value1, err = row.getFloat(parameter + ".value1"); err != nil {
+					return bundle, err
+				}
+				if value.Value2, err = row.getFloat(parameter + ".value2"); err != nil {
+					return bundle, err
+				}
+				if row.get(parameter+".value3") != "" {
+					value3, err := row.getFloat(parameter + ".value3")
+					if err != nil {
+						return bundle, err
+					}
+					value.Value3 = &value3
+				}
+				importCase.Parameters = append(importCase.Parameters, value)
+			}
+			casesIndex[caseCode] = len(bundle.Cases)
+			bundle.Cases = append(bundle.Cases, importCase)
+		}
+
+		question := models.ImportQuestion{
+			CaseCode: caseCode,
+			Question: row.get("question"),
+			Correct:  row.get("correct"),
+		}
+		if question.PredictionAge, err = row.getInt("prediction_age"); err != nil {
+			return bundle, err
+		}
+		if question.Group, err = row.getInt("group"); err != nil {
+			return bundle, err
+		}
+		question.Type = row.get("type")
+		question.TargetParameter = row.get("target_parameter")
+		if row.get("tolerance") != "" {
+			tolerance, err := row.getFloat("tolerance")
+			if err != nil {
+				return bundle, err
+			}
+			question.Tolerance = &tolerance
+		}
+		for _, option := range strings.Split(row.get("options"), "|") {
+			if option = strings.TrimSpace(option); option != "" {
+				question.Options = append(question.Options, option)
+			}
+		}
+		for _, column := range []string{"image1", "image2", "image3"} {
+			if image := row.get(column); image != "" {
+				question.Images = append(question.Images, image)
+			}
+		}
+		bundle.Questions = append(bundle.Questions, question)
+	}
+	return bundle, nil
+}
+
+type csvRow struct {
+	columns map[string]int
+	record  []string
+	line    int
+}
+
+func (r csvRow) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+func (r csvRow) getInt(column string) (int, error) {
+	value := r.get(column)
+	if value == "" {
+		return 0, nil
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("line %d: invalid %s: %q", r.line, column, value)
+	}
+	return result, nil
+}
+
+func (r csvRow) getFloat(column string) (float64, error) {
+	value := r.get(column)
+	if value == "" {
+		return 0, nil
+	}
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("line %d: invalid %s: %q", r.line, column, value)
+	}
+	return result, nil
+}
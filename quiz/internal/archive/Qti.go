@@ -0,0 +1,503 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"quiz/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IMS QTI 2.1 content packages. Every question is exported as an assessmentItem with a single choiceInteraction,
+// the case code is kept in the item label and the prediction age in a paragraph with the prediction-age class.
+// The importer accepts any choice items with a single correct response, other items are skipped
+
+const (
+	QTIManifest     = "imsmanifest.xml"
+	qtiNamespace    = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	qtiItemType     = "imsqti_item_xmlv2p1"
+	cpNamespace     = "http://www.imsglobal.org/xsd/imscp_v1p1"
+	responseID      = "RESPONSE"
+	matchCorrect    = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"
+	predictionClass = "prediction-age"
+)
+
+type qtiItem struct {
+	XMLName             xml.Name               `xml:"assessmentItem"`
+	Xmlns               string                 `xml:"xmlns,attr"`
+	Identifier          string                 `xml:"identifier,attr"`
+	Title               string                 `xml:"title,attr"`
+	Label               string                 `xml:"label,attr,omitempty"`
+	Adaptive            bool                   `xml:"adaptive,attr"`
+	TimeDependent       bool                   `xml:"timeDependent,attr"`
+	ToolName            string                 `xml:"toolName,attr"`
+	ResponseDeclaration qtiResponseDeclaration `xml:"responseDeclaration"`
+	OutcomeDeclaration  qtiOutcomeDeclaration  `xml:"outcomeDeclaration"`
+	ItemBody            qtiItemBody            `xml:"itemBody"`
+	ResponseProcessing  qtiResponseProcessing  `xml:"responseProcessing"`
+}
+
+type qtiResponseDeclaration struct {
+	Identifier      string              `xml:"identifier,attr"`
+	Cardinality     string              `xml:"cardinality,attr"`
+	BaseType        string              `xml:"baseType,attr"`
+	CorrectResponse *qtiCorrectResponse `xml:"correctResponse,omitempty"`
+}
+
+type qtiCorrectResponse struct {
+	Values []string `xml:"value"`
+}
+
+type qtiOutcomeDeclaration struct {
+	Identifier   string          `xml:"identifier,attr"`
+	Cardinality  string          `xml:"cardinality,attr"`
+	BaseType     string          `xml:"baseType,attr"`
+	DefaultValue qtiDefaultValue `xml:"defaultValue"`
+}
+
+type qtiDefaultValue struct {
+	Value string `xml:"value"`
+}
+
+type qtiItemBody struct {
+	Div    qtiDiv               `xml:"div"`
+	Choice qtiChoiceInteraction `xml:"choiceInteraction"`
+}
+
+type qtiDiv struct {
+	Paragraphs []qtiParagraph `xml:"p"`
+}
+
+type qtiParagraph struct {
+	Class string    `xml:"class,attr,omitempty"`
+	Text  string    `xml:",chardata"`
+	Image *qtiImage `xml:"img,omitempty"`
+}
+
+type qtiImage struct {
+	Src string `xml:"src,attr"`
+	Alt string `xml:"alt,attr"`
+}
+
+type qtiChoiceInteraction struct {
+	ResponseIdentifier string      `xml:"responseIdentifier,attr"`
+	Shuffle            bool        `xml:"shuffle,attr"`
+	MaxChoices         int         `xml:"maxChoices,attr"`
+	Prompt             string      `xml:"prompt"`
+	Choices            []qtiChoice `xml:"simpleChoice"`
+}
+
+type qtiChoice struct {
+	Identifier string `xml:"identifier,attr"`
+	Text       string `xml:",chardata"`
+}
+
+type qtiResponseProcessing struct {
+	Template string `xml:"template,attr"`
+}
+
+type cpManifest struct {
+	XMLName    xml.Name     `xml:"manifest"`
+	Xmlns      string       `xml:"xmlns,attr,omitempty"`
+	Identifier string       `xml:"identifier,attr"`
+	Metadata   *cpMetadata  `xml:"metadata,omitempty"`
+	Resources  []cpResource `xml:"resources>resource"`
+}
+
+type cpMetadata struct {
+	Schema        string `xml:"schema"`
+	SchemaVersion string `xml:"schemaversion"`
+}
+
+type cpResource struct {
+	Identifier string   `xml:"identifier,attr"`
+	Type       string   `xml:"type,attr"`
+	Href       string   `xml:"href,attr"`
+	Files      []cpFile `xml:"file"`
+}
+
+type cpFile struct {
+	Href string `xml:"href,attr"`
+}
+
+// WriteQTIPackage writes questions of the bundle as a QTI 2.1 content package,
+// images listed in the questions are added to the package as media
+func WriteQTIPackage(w io.Writer, bundle *models.ImportBundle) error {
+	writer := zip.NewWriter(w)
+	manifest := cpManifest{
+		Xmlns:      cpNamespace,
+		Identifier: "predigrowee-question-bank",
+		Metadata:   &cpMetadata{Schema: "QTIv2.1 Package", SchemaVersion: "1.0.0"},
+	}
+	media := make(map[string]bool)
+	for _, question := range bundle.Questions {
+		// QTI choice items cannot express numeric predictions and landmark placements
+		if question.Type == models.QuestionTypeNumeric || question.Type == models.QuestionTypeLandmarks {
+			continue
+		}
+		identifier := "question-" + strconv.Itoa(question.ID)
+		href := "items/" + identifier + ".xml"
+		resource := cpResource{Identifier: identifier, Type: qtiItemType, Href: href, Files: []cpFile{{Href: href}}}
+		item := newQTIItem(identifier, question)
+		for i, image := range question.Images {
+			item.ItemBody.Div.Paragraphs = append(item.ItemBody.Div.Paragraphs, qtiParagraph{
+				Image: &qtiImage{Src: "../" + image, Alt: "Radiograph " + strconv.Itoa(i+1)},
+			})
+			resource.Files = append(resource.Files, cpFile{Href: image})
+			media[image] = true
+		}
+		if err := writeXML(writer, href, item); err != nil {
+			return err
+		}
+		manifest.Resources = append(manifest.Resources, resource)
+	}
+
+	names := make([]string, 0, len(media))
+	for name := range media {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file, err := writer.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err = file.Write(bundle.Files[name]); err != nil {
+			return err
+		}
+	}
+	if err := writeXML(writer, QTIManifest, manifest); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func newQTIItem(identifier string, question models.ImportQuestion) qtiItem {
+	item := qtiItem{
+		Xmlns:      qtiNamespace,
+		Identifier: identifier,
+		Title:      question.Question,
+		Label:      question.CaseCode,
+		ToolName:   "PrediGrowee",
+		ResponseDeclaration: qtiResponseDeclaration{
+			Identifier:  responseID,
+			Cardinality: "single",
+			BaseType:    "identifier",
+		},
+		OutcomeDeclaration: qtiOutcomeDeclaration{
+			Identifier:   "SCORE",
+			Cardinality:  "single",
+			BaseType:     "float",
+			DefaultValue: qtiDefaultValue{Value: "0"},
+		},
+		ItemBody: qtiItemBody{
+			Div: qtiDiv{Paragraphs: []qtiParagraph{
+				{Text: "Case " + question.CaseCode},
+				{Class: predictionClass, Text: "Prediction age: " + strconv.Itoa(question.PredictionAge)},
+			}},
+			Choice: qtiChoiceInteraction{
+				ResponseIdentifier: responseID,
+				MaxChoices:         1,
+				Prompt:             question.Question,
+			},
+		},
+		ResponseProcessing: qtiResponseProcessing{Template: matchCorrect},
+	}
+	for i, option := range question.Options {
+		choice := qtiChoice{Identifier: "choice-" + strconv.Itoa(i+1), Text: option}
+		item.ItemBody.Choice.Choices = append(item.ItemBody.Choice.Choices, choice)
+		if option == question.Correct {
+			item.ResponseDeclaration.CorrectResponse = &qtiCorrectResponse{Values: []string{choice.Identifier}}
+		}
+	}
+	return item
+}
+
+func writeXML(writer *zip.Writer, name string, v any) error {
+	file, err := writer.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(file, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(file)
+	encoder.Indent("", "  ")
+	if err = encoder.Encode(v); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// ReadQTIPackage reads choice items of a QTI 2.1 content package into an import bundle. Options and cases
+// referenced by the items are listed in the bundle, so the missing ones are created by the import. Items which
+// can't be represented as questions are listed in the bundle as skipped
+func ReadQTIPackage(data []byte) (models.ImportBundle, error) {
+	var bundle models.ImportBundle
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return bundle, fmt.Errorf("invalid zip archive: %w", err)
+	}
+	files := make(map[string][]byte)
+	var size int64
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		content, err := readZipFile(file, &size)
+		if err != nil {
+			return bundle, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		files[path.Clean(file.Name)] = content
+	}
+
+	var items []string
+	if content, ok := files[QTIManifest]; ok {
+		var manifest cpManifest
+		if err = xml.Unmarshal(content, &manifest); err != nil {
+			return bundle, fmt.Errorf("invalid %s: %w", QTIManifest, err)
+		}
+		for _, resource := range manifest.Resources {
+			if strings.HasPrefix(resource.Type, qtiItemType) {
+				items = append(items, path.Clean(resource.Href))
+			}
+		}
+	} else {
+		// packages without a manifest are accepted when they contain items only
+		for name := range files {
+			if strings.HasSuffix(strings.ToLower(name), ".xml") {
+				items = append(items, name)
+			}
+		}
+		sort.Strings(items)
+	}
+	if len(items) == 0 {
+		return bundle, fmt.Errorf("package has no assessment items")
+	}
+
+	options := make(map[string]bool)
+	cases := make(map[string]bool)
+	for _, href := range items {
+		content, ok := files[href]
+		if !ok {
+			return bundle, fmt.Errorf("item %s is missing in the package", href)
+		}
+		item, err := parseQTIItem(content)
+		if err != nil {
+			bundle.Skipped = append(bundle.Skipped, fmt.Sprintf("%s: %s", href, err))
+			continue
+		}
+		question, reason := item.toQuestion(path.Dir(href))
+		if reason != "" {
+			bundle.Skipped = append(bundle.Skipped, fmt.Sprintf("%s: %s", href, reason))
+			continue
+		}
+		for _, option := range question.Options {
+			if !options[option] {
+				options[option] = true
+				bundle.Options = append(bundle.Options, models.ImportOption{Option: option})
+			}
+		}
+		if !cases[question.CaseCode] {
+			cases[question.CaseCode] = true
+			bundle.Cases = append(bundle.Cases, models.ImportCase{Code: question.CaseCode})
+		}
+		bundle.Questions = append(bundle.Questions, question)
+	}
+	bundle.Files = files
+	return bundle, nil
+}
+
+// parsedQTIItem holds parts of an assessment item relevant for questions, QTI allows interactions
+// and images anywhere in the item body, so the item is read token by token
+type parsedQTIItem struct {
+	identifier    string
+	title         string
+	label         string
+	correct       map[string][]string
+	interactions  []parsedChoiceInteraction
+	images        []string
+	predictionAge string
+}
+
+type parsedChoiceInteraction struct {
+	responseIdentifier string
+	maxChoices         string
+	prompt             string
+	choices            []qtiChoice
+}
+
+func parseQTIItem(content []byte) (parsedQTIItem, error) {
+	item := parsedQTIItem{correct: make(map[string][]string)}
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var response string
+	var inCorrect, inValue, inPrompt, inPrediction bool
+	var choice *qtiChoice
+	var interaction *parsedChoiceInteraction
+	var text strings.Builder
+	foundItem := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return item, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "assessmentItem":
+				foundItem = true
+				item.identifier = attr(t, "identifier")
+				item.title = attr(t, "title")
+				item.label = attr(t, "label")
+			case "responseDeclaration":
+				response = attr(t, "identifier")
+			case "correctResponse":
+				inCorrect = true
+			case "value":
+				if inCorrect {
+					inValue = true
+					text.Reset()
+				}
+			case "choiceInteraction":
+				interaction = &parsedChoiceInteraction{
+					responseIdentifier: attr(t, "responseIdentifier"),
+					maxChoices:         attr(t, "maxChoices"),
+				}
+			case "prompt":
+				inPrompt = true
+				text.Reset()
+			case "simpleChoice":
+				choice = &qtiChoice{Identifier: attr(t, "identifier")}
+				text.Reset()
+			case "img":
+				item.images = append(item.images, attr(t, "src"))
+			case "p":
+				if strings.Contains(attr(t, "class"), predictionClass) {
+					inPrediction = true
+					text.Reset()
+				}
+			}
+		case xml.CharData:
+			if inValue || inPrompt || choice != nil || inPrediction {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "correctResponse":
+				inCorrect = false
+			case "value":
+				if inValue {
+					item.correct[response] = append(item.correct[response], strings.TrimSpace(text.String()))
+					inValue = false
+				}
+			case "prompt":
+				if interaction != nil {
+					interaction.prompt = normalizeSpace(text.String())
+				}
+				inPrompt = false
+			case "simpleChoice":
+				if choice != nil && interaction != nil {
+					choice.Text = normalizeSpace(text.String())
+					interaction.choices = append(interaction.choices, *choice)
+				}
+				choice = nil
+			case "choiceInteraction":
+				if interaction != nil {
+					item.interactions = append(item.interactions, *interaction)
+				}
+				interaction = nil
+			case "p":
+				if inPrediction {
+					item.predictionAge = text.String()
+					inPrediction = false
+				}
+			}
+		}
+	}
+	if !foundItem {
+		return item, fmt.Errorf("no assessmentItem element")
+	}
+	return item, nil
+}
+
+func (item parsedQTIItem) toQuestion(dir string) (models.ImportQuestion, string) {
+	question := models.ImportQuestion{}
+	if len(item.interactions) != 1 {
+		return question, "only items with a single choice interaction are supported"
+	}
+	interaction := item.interactions[0]
+	if interaction.maxChoices != "" && interaction.maxChoices != "1" {
+		return question, "multiple response items are not supported"
+	}
+	if len(interaction.choices) == 0 {
+		return question, "item has no choices"
+	}
+	question.Question = interaction.prompt
+	if question.Question == "" {
+		question.Question = item.title
+	}
+	question.CaseCode = item.label
+	if question.CaseCode == "" {
+		question.CaseCode = item.identifier
+	}
+	question.PredictionAge = firstNumber(item.predictionAge)
+
+	choices := make(map[string]string, len(interaction.choices))
+	for _, choice := range interaction.choices {
+		choices[choice.Identifier] = choice.Text
+		question.Options = append(question.Options, choice.Text)
+	}
+	correct := item.correct[interaction.responseIdentifier]
+	if len(correct) > 1 {
+		return question, "items with more than one correct response are not supported"
+	}
+	if len(correct) == 1 {
+		text, ok := choices[correct[0]]
+		if !ok {
+			return question, fmt.Sprintf("correct response %s is not one of the choices", correct[0])
+		}
+		question.Correct = text
+	}
+	for _, src := range item.images {
+		if strings.Contains(src, "://") || strings.HasPrefix(src, "data:") {
+			continue
+		}
+		question.Images = append(question.Images, path.Join(dir, src))
+	}
+	return question, ""
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// firstNumber returns the first integer found in s or 0
+func firstNumber(s string) int {
+	start := strings.IndexAny(s, "0123456789")
+	if start < 0 {
+		return 0
+	}
+	end := start
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	number, _ := strconv.Atoi(s[start:end])
+	return number
+}
@@ -9,6 +9,11 @@ import (
 	"quiz/internal/models"
 )
 
+// AuthService is the API of the auth service used by quiz, AuthClient calls the service over HTTP
+type AuthService interface {
+	VerifyAuthToken(token string) (models.UserData, error)
+}
+
 type AuthClient struct {
 	addr   string
 	logger *zap.Logger
@@ -0,0 +1,34 @@
+package clients
+
+import (
+	"fmt"
+	"quiz/internal/models"
+	"sync"
+)
+
+// FakeAuthClient is an AuthService answering from memory, for tests which do not run the auth service
+type FakeAuthClient struct {
+	mu    sync.Mutex
+	users map[string]models.UserData
+}
+
+func NewFakeAuthClient() *FakeAuthClient {
+	return &FakeAuthClient{users: make(map[string]models.UserData)}
+}
+
+// AddToken makes the token valid for the user
+func (c *FakeAuthClient) AddToken(token string, user models.UserData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.users[token] = user
+}
+
+func (c *FakeAuthClient) VerifyAuthToken(token string) (models.UserData, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	user, ok := c.users[token]
+	if !ok {
+		return models.UserData{}, fmt.Errorf("unexpected status code: 401")
+	}
+	return user, nil
+}
@@ -0,0 +1,100 @@
+package clients
+
+import (
+	"quiz/internal/models"
+	"slices"
+	"sync"
+)
+
+// FakeStatsClient is a StatsService keeping delivered sessions and answers in memory,
+// for tests which do not run the stats service
+type FakeStatsClient struct {
+	mu        sync.Mutex
+	sessions  map[int]models.QuizSession
+	responses map[int][]models.QuestionAnswer
+	finished  map[int]bool
+	profiles  map[int]models.AdaptiveProfile
+	incorrect map[int][]models.IncorrectQuestion
+}
+
+func NewFakeStatsClient() *FakeStatsClient {
+	return &FakeStatsClient{
+		sessions:  make(map[int]models.QuizSession),
+		responses: make(map[int][]models.QuestionAnswer),
+		finished:  make(map[int]bool),
+		profiles:  make(map[int]models.AdaptiveProfile),
+		incorrect: make(map[int][]models.IncorrectQuestion),
+	}
+}
+
+// SetAdaptiveProfile sets the profile returned for the user of the profile
+func (c *FakeStatsClient) SetAdaptiveProfile(profile models.AdaptiveProfile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.profiles[profile.UserID] = profile
+}
+
+// SetIncorrectQuestions sets the questions the user has answered incorrectly
+func (c *FakeStatsClient) SetIncorrectQuestions(userID int, questions []models.IncorrectQuestion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.incorrect[userID] = slices.Clone(questions)
+}
+
+// Session returns the delivered session and whether it was finished
+func (c *FakeStatsClient) Session(sessionID int) (session models.QuizSession, finished bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	session, ok = c.sessions[sessionID]
+	return session, c.finished[sessionID], ok
+}
+
+// Responses returns the answers delivered for the session in the order they were saved
+func (c *FakeStatsClient) Responses(sessionID int) []models.QuestionAnswer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.responses[sessionID])
+}
+
+func (c *FakeStatsClient) SaveResponse(sessionID int, answer models.QuestionAnswer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// the stats service saves an answer once per idempotency key
+	for _, saved := range c.responses[sessionID] {
+		if answer.IdempotencyKey != "" && saved.IdempotencyKey == answer.IdempotencyKey {
+			return nil
+		}
+	}
+	c.responses[sessionID] = append(c.responses[sessionID], answer)
+	return nil
+}
+
+func (c *FakeStatsClient) SaveSession(session models.QuizSession) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessions[session.ID] = session
+	return nil
+}
+
+func (c *FakeStatsClient) FinishSession(sessionID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finished[sessionID] = true
+	return nil
+}
+
+// GetAdaptiveProfile returns the profile set for the user, users without one have no answers yet
+func (c *FakeStatsClient) GetAdaptiveProfile(userID int) (models.AdaptiveProfile, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if profile, ok := c.profiles[userID]; ok {
+		return profile, nil
+	}
+	return models.AdaptiveProfile{UserID: userID, Questions: []models.QuestionDifficulty{}}, nil
+}
+
+func (c *FakeStatsClient) GetIncorrectQuestions(userID int) ([]models.IncorrectQuestion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.incorrect[userID]), nil
+}
@@ -0,0 +1,177 @@
+package clients
+
+import (
+	"bytes"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+type ImagesClient struct {
+	addr   string
+	apiKey string
+	logger *zap.Logger
+}
+
+func NewImagesClient(addr string, apiKey string, logger *zap.Logger) *ImagesClient {
+	return &ImagesClient{
+		addr:   addr,
+		apiKey: apiKey,
+		logger: logger,
+	}
+}
+
+// UploadQuestionImages replaces images of the question, files are keyed by their name and sent in order as image1..image3
+func (c *ImagesClient) UploadQuestionImages(questionID int, names []string, files map[string][]byte) error {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for i, name := range names {
+		part, err := writer.CreateFormFile("image"+strconv.Itoa(i+1), path.Base(name))
+		if err != nil {
+			c.logger.Error("failed to create form file", zap.Error(err))
+			return err
+		}
+		if _, err = part.Write(files[name]); err != nil {
+			c.logger.Error("failed to write form file", zap.Error(err))
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		c.logger.Error("failed to close multipart writer", zap.Error(err))
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.addr+"/questions/"+strconv.Itoa(questionID)+"/images", body)
+	if err != nil {
+		c.logger.Error("failed to create request", zap.Error(err))
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.logger.Error("failed to send request", zap.Error(err))
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// UploadParameterImage replaces the image of the parameter
+func (c *ImagesClient) UploadParameterImage(parameterID int, image []byte) error {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("image", "image.png")
+	if err != nil {
+		c.logger.Error("failed to create form file", zap.Error(err))
+		return err
+	}
+	if _, err = part.Write(image); err != nil {
+		c.logger.Error("failed to write form file", zap.Error(err))
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		c.logger.Error("failed to close multipart writer", zap.Error(err))
+		return err
+	}
+
+	req, err := http.NewRequest("PUT", c.addr+"/params/"+strconv.Itoa(parameterID), body)
+	if err != nil {
+		c.logger.Error("failed to create request", zap.Error(err))
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.logger.Error("failed to send request", zap.Error(err))
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// DeleteQuestionImages removes all images of the question
+func (c *ImagesClient) DeleteQuestionImages(questionID int) error {
+	return c.delete(c.addr + "/questions/" + strconv.Itoa(questionID) + "/images")
+}
+
+// DeleteParameterImage removes the image of the parameter
+func (c *ImagesClient) DeleteParameterImage(parameterID int) error {
+	return c.delete(c.addr + "/params/" + strconv.Itoa(parameterID))
+}
+
+func (c *ImagesClient) delete(url string) error {
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		c.logger.Error("failed to create request", zap.Error(err))
+		return err
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.logger.Error("failed to send request", zap.Error(err))
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetQuestionImage returns the image number (1-3) of the question and its content type, nil is returned when
+// the question has no such image
+func (c *ImagesClient) GetQuestionImage(questionID int, number int) ([]byte, string, error) {
+	return c.getImage(c.addr + "/questions/" + strconv.Itoa(questionID) + "/images/" + strconv.Itoa(number))
+}
+
+// GetParameterImage returns the image of the parameter and its content type, nil is returned when
+// the parameter has no image
+func (c *ImagesClient) GetParameterImage(parameterID int) ([]byte, string, error) {
+	return c.getImage(c.addr + "/params/" + strconv.Itoa(parameterID))
+}
+
+func (c *ImagesClient) getImage(url string) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		c.logger.Error("failed to create request", zap.Error(err))
+		return nil, "", err
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.logger.Error("failed to send request", zap.Error(err))
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	image, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return image, resp.Header.Get("Content-Type"), nil
+}
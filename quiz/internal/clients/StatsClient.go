@@ -3,12 +3,22 @@ package clients
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"go.uber.org/zap"
 	"net/http"
 	"quiz/internal/models"
 	"strconv"
 )
 
+// StatsService is the API of the stats service used by quiz, StatsClient calls the service over HTTP
+type StatsService interface {
+	SaveResponse(sessionID int, answer models.QuestionAnswer) error
+	SaveSession(session models.QuizSession) error
+	FinishSession(sessionID int) error
+	GetAdaptiveProfile(userID int) (models.AdaptiveProfile, error)
+	GetIncorrectQuestions(userID int) ([]models.IncorrectQuestion, error)
+}
+
 type StatsClient struct {
 	addr   string
 	apiKey string
@@ -45,7 +55,7 @@ func (c *StatsClient) SaveResponse(sessionID int, answer models.QuestionAnswer)
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
-		return err
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 	return nil
 }
@@ -71,12 +81,12 @@ func (c *StatsClient) SaveSession(session models.QuizSession) error {
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
-		return err
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 	return nil
 }
 func (c *StatsClient) FinishSession(sessionID int) error {
-	req, err := http.NewRequest("POST", c.addr+"/sessions"+strconv.Itoa(sessionID)+"/finish", nil)
+	req, err := http.NewRequest("POST", c.addr+"/sessions/"+strconv.Itoa(sessionID)+"/finish", nil)
 	if err != nil {
 		c.logger.Error("failed to create request", zap.Error(err))
 		return err
@@ -91,7 +101,54 @@ func (c *StatsClient) FinishSession(sessionID int) error {
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
-		return err
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 	return nil
 }
+
+func (c *StatsClient) GetAdaptiveProfile(userID int) (models.AdaptiveProfile, error) {
+	var profile models.AdaptiveProfile
+	req, err := http.NewRequest("GET", c.addr+"/users/"+strconv.Itoa(userID)+"/adaptive", nil)
+	if err != nil {
+		c.logger.Error("failed to create request", zap.Error(err))
+		return profile, err
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.logger.Error("failed to send request", zap.Error(err))
+		return profile, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return profile, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&profile)
+	return profile, err
+}
+
+// GetIncorrectQuestions returns questions the user has answered incorrectly at least once outside review sessions
+func (c *StatsClient) GetIncorrectQuestions(userID int) ([]models.IncorrectQuestion, error) {
+	var questions []models.IncorrectQuestion
+	req, err := http.NewRequest("GET", c.addr+"/users/"+strconv.Itoa(userID)+"/incorrect", nil)
+	if err != nil {
+		c.logger.Error("failed to create request", zap.Error(err))
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.logger.Error("failed to send request", zap.Error(err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&questions)
+	return questions, err
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+	"time"
+)
+
+type AbandonQuizHandler struct {
+	storage storage.Store
+	logger  *zap.Logger
+}
+
+func NewAbandonQuizHandler(store storage.Store, logger *zap.Logger) *AbandonQuizHandler {
+	return &AbandonQuizHandler{
+		storage: store,
+		logger:  logger,
+	}
+}
+
+// Handle closes an open session of the user. Unlike finishing, the session is not finished in the stats service,
+// answers given so far stay recorded. The next session in the mode continues from the position of this one
+func (h *AbandonQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
+	session, ok := getOpenUserSession(rw, r, h.storage, h.logger)
+	if !ok {
+		return
+	}
+	err := h.storage.UpdateQuizSession(r.Context(), session.ID, func(session *models.QuizSession) ([]models.OutboxEvent, error) {
+		// the last answer of the session may have finished it meanwhile
+		if session.IsClosed() {
+			return nil, &requestError{status: http.StatusConflict, message: "quiz session is closed"}
+		}
+		session.Status = models.QuizStatusAbandoned
+		finishTime := time.Now()
+		session.FinishedAt = &finishTime
+		return nil, nil
+	})
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		http.Error(rw, reqErr.message, reqErr.status)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to update quiz session", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
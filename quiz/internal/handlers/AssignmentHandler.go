@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type AssignmentHandler struct {
+	storage storage.Store
+	logger  *zap.Logger
+}
+
+func NewAssignmentHandler(store storage.Store, logger *zap.Logger) *AssignmentHandler {
+	return &AssignmentHandler{
+		storage: store,
+		logger:  logger,
+	}
+}
+
+func (h *AssignmentHandler) CreateAssignment(w http.ResponseWriter, r *http.Request) {
+	var assignment models.Assignment
+	if err := assignment.FromJSON(r.Body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := validateAssignment(&assignment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if assignment.TeacherID == 0 {
+		http.Error(w, "teacher_id is required", http.StatusBadRequest)
+		return
+	}
+	created, err := h.storage.CreateAssignment(r.Context(), assignment)
+	if err != nil {
+		h.logger.Error("Failed to create assignment", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	h.writeAssignment(r.Context(), w, http.StatusCreated, created.ID)
+}
+
+// GetAssignments returns all assignments, or assignments of one teacher when teacher_id is given
+func (h *AssignmentHandler) GetAssignments(w http.ResponseWriter, r *http.Request) {
+	teacherID := 0
+	if value := r.URL.Query().Get("teacher_id"); value != "" {
+		var err error
+		if teacherID, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "Invalid teacher ID", http.StatusBadRequest)
+			return
+		}
+	}
+	assignments, err := h.storage.GetAssignments(r.Context(), teacherID)
+	if err != nil {
+		h.logger.Error("Failed to get assignments", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(assignments); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *AssignmentHandler) GetAssignment(w http.ResponseWriter, r *http.Request) {
+	assignmentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid assignment ID", http.StatusBadRequest)
+		return
+	}
+	h.writeAssignment(r.Context(), w, http.StatusOK, assignmentID)
+}
+
+func (h *AssignmentHandler) UpdateAssignment(w http.ResponseWriter, r *http.Request) {
+	assignmentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid assignment ID", http.StatusBadRequest)
+		return
+	}
+	var assignment models.Assignment
+	if err = assignment.FromJSON(r.Body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err = validateAssignment(&assignment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	assignment.ID = assignmentID
+	err = h.storage.UpdateAssignment(r.Context(), assignment)
+	if errors.Is(err, storage.ErrAssignmentNotFound) {
+		http.Error(w, "Assignment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update assignment", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	h.writeAssignment(r.Context(), w, http.StatusOK, assignmentID)
+}
+
+func (h *AssignmentHandler) DeleteAssignment(w http.ResponseWriter, r *http.Request) {
+	assignmentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid assignment ID", http.StatusBadRequest)
+		return
+	}
+	err = h.storage.DeleteAssignment(r.Context(), assignmentID)
+	if errors.Is(err, storage.ErrAssignmentNotFound) {
+		http.Error(w, "Assignment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete assignment", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAssignmentSessions returns sessions started by students for the assignment, scores are kept by the stats service
+func (h *AssignmentHandler) GetAssignmentSessions(w http.ResponseWriter, r *http.Request) {
+	assignmentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid assignment ID", http.StatusBadRequest)
+		return
+	}
+	sessions, err := h.storage.GetAssignmentSessions(r.Context(), assignmentID)
+	if err != nil {
+		h.logger.Error("Failed to get assignment sessions", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(sessions); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// GetStudentAssignments returns assignments shared with the logged in user
+func (h *AssignmentHandler) GetStudentAssignments(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	assignments, err := h.storage.GetStudentAssignments(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to get student assignments", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(assignments); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// StartAssignment starts a quiz session bound to the assignment, the session asks all questions of the assignment
+// in order and is finished after the last answer
+func (h *AssignmentHandler) StartAssignment(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	assignmentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid assignment id", http.StatusBadRequest)
+		return
+	}
+	assignment, err := h.storage.GetAssignmentByID(r.Context(), assignmentID)
+	if err != nil && !errors.Is(err, storage.ErrAssignmentNotFound) {
+		h.logger.Error("failed to get assignment", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
+		return
+	}
+	if err != nil || !slices.Contains(assignment.StudentsIDs, userID) {
+		http.Error(w, "assignment not found", http.StatusNotFound)
+		return
+	}
+	if time.Now().After(assignment.Deadline) {
+		http.Error(w, "assignment deadline has passed", http.StatusForbidden)
+		return
+	}
+
+	var payload models.StartQuizPayload
+	if err = payload.FromJSON(r.Body); err != nil {
+		http.Error(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	payload.Mode = assignment.Mode
+	if err = payload.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	questions, err := h.storage.GetAssignmentQuestionsIDs(r.Context(), assignment)
+	if err != nil {
+		h.logger.Error("failed to get assignment questions", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
+		return
+	}
+	if len(questions) == 0 {
+		http.Error(w, "assignment has no questions", http.StatusConflict)
+		return
+	}
+
+	// open sessions and attempts are checked when the session is created, concurrent starts are serialized there
+	session, err := h.storage.CreateAssignmentSession(r.Context(), models.QuizSession{
+		Mode:              assignment.Mode,
+		UserID:            userID,
+		Status:            models.QuizStatusNotStarted,
+		ScreenSize:        fmt.Sprintf("%dx%d", payload.ScreenWidth, payload.ScreenHeight),
+		CurrentQuestionID: questions[0],
+		GroupOrder:        questions,
+		AssignmentID:      &assignment.ID,
+	}, assignment.MaxAttempts)
+	if errors.Is(err, storage.ErrAssignmentNotFound) {
+		http.Error(w, "assignment not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrAssignmentSessionOpen) {
+		http.Error(w, "assignment session is already open, resume or abandon it", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, storage.ErrNoAttemptsLeft) {
+		http.Error(w, "no attempts left", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to create quiz session in db", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
+		return
+	}
+	timeLimit, err := h.storage.GetTimeLimit(r.Context())
+	if err != nil {
+		h.logger.Error("failed to get time limit", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{
+		"session":    session,
+		"time_limit": timeLimit,
+	}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+func validateAssignment(assignment *models.Assignment) error {
+	if err := assignment.Validate(); err != nil {
+		return err
+	}
+	if len(assignment.QuestionsIDs) == 0 && len(assignment.GroupsIDs) == 0 {
+		return fmt.Errorf("assignment needs questions or groups")
+	}
+	return nil
+}
+
+// writeAssignment responds with the assignment read back from the database together with its number of questions
+func (h *AssignmentHandler) writeAssignment(ctx context.Context, w http.ResponseWriter, status int, assignmentID int) {
+	assignment, err := h.storage.GetAssignmentByID(ctx, assignmentID)
+	if errors.Is(err, storage.ErrAssignmentNotFound) {
+		http.Error(w, "Assignment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get assignment", zap.Error(err))
+		serverError(ctx, w, err, "Internal server error")
+		return
+	}
+	questions, err := h.storage.GetAssignmentQuestionsIDs(ctx, assignment)
+	if err != nil {
+		h.logger.Error("Failed to get assignment questions", zap.Error(err))
+		serverError(ctx, w, err, "Internal server error")
+		return
+	}
+	assignment.QuestionsCount = len(questions)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err = assignment.ToJSON(w); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
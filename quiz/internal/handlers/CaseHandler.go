@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"go.uber.org/zap"
 	"net/http"
 	"quiz/internal/models"
@@ -22,45 +23,76 @@ func NewCaseHandler(store storage.Store, logger *zap.Logger) *CaseHandler {
 }
 
 func (h *CaseHandler) CreateCase(w http.ResponseWriter, r *http.Request) {
-	var CasePayload models.Case
-	err := CasePayload.FromJSON(r.Body)
+	var casePayload models.Case
+	err := casePayload.FromJSON(r.Body)
 	if err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-	createdCase, err := h.storage.CreateCase(CasePayload)
+	if err = casePayload.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	createdCase, err := h.storage.CreateCase(r.Context(), casePayload)
 	if err != nil {
 		h.logger.Error("Failed to create case", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-	createdParametersValues := make([]models.ParameterValue, 0)
-	parameters := make([]models.Parameter, 0)
-	for _, pValue := range CasePayload.ParameterValues {
-		createdParameter, err := h.storage.CreateCaseParameter(createdCase.ID, pValue)
-		if err != nil {
-			h.logger.Error("Failed to create case parameter", zap.Error(err))
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
-		}
-		createdParametersValues = append(createdParametersValues, createdParameter)
-		parameter, err := h.storage.GetParameterByID(pValue.ParameterID)
-		parameters = append(parameters, parameter)
-	}
-	createdCase.ParameterValues = createdParametersValues
-	createdCase.Parameters = parameters
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	createdCase, err = h.storage.GetCaseByID(r.Context(), createdCase.ID)
+	if err != nil {
+		h.logger.Error("Failed to get created case", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	err = json.NewEncoder(w).Encode(createdCase)
+	err = createdCase.ToJSON(w)
 	if err != nil {
 		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
 func (h *CaseHandler) UpdateCase(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusNotImplemented)
+	caseID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid case ID", http.StatusBadRequest)
+		return
+	}
+	var casePayload models.Case
+	if err = casePayload.FromJSON(r.Body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err = casePayload.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	casePayload.ID = caseID
+	// parameter values are replaced together with the case, a request without them keeps the values of the case
+	_, err = h.storage.UpdateCase(r.Context(), casePayload)
+	if errors.Is(err, storage.ErrCaseNotFound) {
+		http.Error(w, "Case not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update case", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	updatedCase, err := h.storage.GetCaseByID(r.Context(), caseID)
+	if err != nil {
+		h.logger.Error("Failed to get updated case", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = updatedCase.ToJSON(w)
+	if err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
 }
 
 func (h *CaseHandler) DeleteCase(w http.ResponseWriter, r *http.Request) {
@@ -69,28 +101,36 @@ func (h *CaseHandler) DeleteCase(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid case ID", http.StatusBadRequest)
 		return
 	}
-	if err := h.storage.DeleteCaseWithParameters(caseID); err != nil {
+	err = h.storage.DeleteCaseWithParameters(r.Context(), caseID)
+	if errors.Is(err, storage.ErrCaseNotFound) {
+		http.Error(w, "Case not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrCaseInUse) {
+		http.Error(w, "Case is used by questions", http.StatusConflict)
+		return
+	}
+	if err != nil {
 		h.logger.Error("Failed to delete case", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *CaseHandler) GetAllCases(w http.ResponseWriter, _ *http.Request) {
-	cases, err := h.storage.GetAllCases()
+func (h *CaseHandler) GetAllCases(w http.ResponseWriter, r *http.Request) {
+	cases, err := h.storage.GetAllCases(r.Context())
 	if err != nil {
 		h.logger.Error("Failed to get cases", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(cases)
 	if err != nil {
 		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
-	w.WriteHeader(http.StatusOK)
 }
 
 func (h *CaseHandler) GetCaseByID(w http.ResponseWriter, r *http.Request) {
@@ -99,15 +139,75 @@ func (h *CaseHandler) GetCaseByID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid case ID", http.StatusBadRequest)
 		return
 	}
-	dbCase, err := h.storage.GetCaseByID(caseID)
+	dbCase, err := h.storage.GetCaseByID(r.Context(), caseID)
+	if errors.Is(err, storage.ErrCaseNotFound) {
+		http.Error(w, "Case not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to get case", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	err = dbCase.ToJSON(w)
 	if err != nil {
 		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// GetCaseLandmarks returns the reference landmarks of the case
+func (h *CaseHandler) GetCaseLandmarks(w http.ResponseWriter, r *http.Request) {
+	caseID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid case ID", http.StatusBadRequest)
+		return
+	}
+	if _, err = h.storage.GetCaseByID(r.Context(), caseID); errors.Is(err, storage.ErrCaseNotFound) {
+		http.Error(w, "Case not found", http.StatusNotFound)
+		return
+	}
+	landmarks, err := h.storage.GetCaseLandmarks(r.Context(), caseID)
+	if err != nil {
+		h.logger.Error("Failed to get case landmarks", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(models.CaseLandmarks{Landmarks: landmarks}); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// UpdateCaseLandmarks replaces the reference landmarks of the case, the change is recorded
+// as a new version of its questions when they are served next
+func (h *CaseHandler) UpdateCaseLandmarks(w http.ResponseWriter, r *http.Request) {
+	caseID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid case ID", http.StatusBadRequest)
+		return
+	}
+	var payload models.CaseLandmarks
+	if err = payload.FromJSON(r.Body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err = payload.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = h.storage.SaveCaseLandmarks(r.Context(), caseID, payload.Landmarks)
+	if errors.Is(err, storage.ErrCaseNotFound) {
+		http.Error(w, "Case not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to save case landmarks", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(payload); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
 	}
 }
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"quiz/internal/events"
+	"quiz/internal/storage"
+)
+
+// EventsHandler consumes events of other services from the event bus
+type EventsHandler struct {
+	storage storage.Store
+	logger  *zap.Logger
+}
+
+func NewEventsHandler(store storage.Store, logger *zap.Logger) *EventsHandler {
+	return &EventsHandler{
+		storage: store,
+		logger:  logger,
+	}
+}
+
+func (h *EventsHandler) Handle(ctx context.Context, event events.Event) error {
+	switch event.Type {
+	case events.UserDeleted:
+		var payload events.UserPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			// a malformed event would block the consumer forever, it is skipped
+			h.logger.Error("invalid event payload", zap.Int64("event_id", event.ID), zap.Error(err))
+			return nil
+		}
+		return h.storage.DeleteUserData(ctx, payload.UserID)
+	}
+	return nil
+}
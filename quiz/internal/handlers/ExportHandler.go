@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"quiz/internal/archive"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+	"strconv"
+	"time"
+)
+
+type ExportHandler struct {
+	storage      storage.Store
+	logger       *zap.Logger
+	imagesClient *clients.ImagesClient
+}
+
+func NewExportHandler(store storage.Store, logger *zap.Logger, imagesClient *clients.ImagesClient) *ExportHandler {
+	return &ExportHandler{
+		storage:      store,
+		logger:       logger,
+		imagesClient: imagesClient,
+	}
+}
+
+// ExportQuestionBank responds with a zip archive containing the whole question bank and its images,
+// the archive can be imported into another deployment with the import endpoint
+func (h *ExportHandler) ExportQuestionBank(w http.ResponseWriter, r *http.Request) {
+	h.exportArchive(r.Context(), w, "question-bank", archive.WriteExportBundle)
+}
+
+// ExportQTI responds with the questions and their images as an IMS QTI 2.1 content package
+func (h *ExportHandler) ExportQTI(w http.ResponseWriter, r *http.Request) {
+	h.exportArchive(r.Context(), w, "question-bank-qti", archive.WriteQTIPackage)
+}
+
+func (h *ExportHandler) exportArchive(ctx context.Context, w http.ResponseWriter, name string, write func(w io.Writer, bundle *models.ImportBundle) error) {
+	// downloading all images takes longer than the server write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(transferTimeout)); err != nil {
+		h.logger.Warn("Failed to extend write deadline", zap.Error(err))
+	}
+	bundle, err := h.storage.ExportQuestionBank(ctx)
+	if err != nil {
+		h.logger.Error("Failed to export question bank", zap.Error(err))
+		serverError(ctx, w, err, "Internal server error")
+		return
+	}
+	if err = h.addImages(&bundle); err != nil {
+		h.logger.Error("Failed to export images", zap.Error(err))
+		http.Error(w, "Failed to export images", http.StatusBadGateway)
+		return
+	}
+	exportedAt := time.Now().UTC()
+	bundle.ExportedAt = &exportedAt
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="%s-%s.zip"`, name, exportedAt.Format("20060102-150405")))
+	if err = write(w, &bundle); err != nil {
+		h.logger.Error("Failed to write archive", zap.Error(err))
+	}
+}
+
+// addImages downloads images of the questions and parameters into the bundle files
+func (h *ExportHandler) addImages(bundle *models.ImportBundle) error {
+	bundle.Files = make(map[string][]byte)
+	for i := range bundle.Questions {
+		question := &bundle.Questions[i]
+		for number := 1; number <= 3; number++ {
+			image, contentType, err := h.imagesClient.GetQuestionImage(question.ID, number)
+			if err != nil {
+				return fmt.Errorf("question %d image %d: %w", question.ID, number, err)
+			}
+			if image == nil {
+				continue
+			}
+			name := "images/questions/" + strconv.Itoa(question.ID) + "/image" + strconv.Itoa(number) + imageExtension(contentType)
+			bundle.Files[name] = image
+			question.Images = append(question.Images, name)
+		}
+	}
+	for i := range bundle.Parameters {
+		parameter := &bundle.Parameters[i]
+		image, contentType, err := h.imagesClient.GetParameterImage(parameter.ID)
+		if err != nil {
+			return fmt.Errorf("parameter %d image: %w", parameter.ID, err)
+		}
+		if image == nil {
+			continue
+		}
+		name := "images/parameters/" + strconv.Itoa(parameter.ID) + imageExtension(contentType)
+		bundle.Files[name] = image
+		parameter.Image = name
+	}
+	return nil
+}
+
+func imageExtension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	return ".jpg"
+}
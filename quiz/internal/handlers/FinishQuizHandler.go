@@ -1,58 +1,64 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"go.uber.org/zap"
 	"net/http"
-	"quiz/internal/clients"
+	"quiz/internal/events"
 	"quiz/internal/models"
 	"quiz/internal/storage"
-	"strconv"
 	"time"
 )
 
 type FinishQuizHandler struct {
-	storage     storage.Store
-	logger      *zap.Logger
-	statsClient *clients.StatsClient
+	storage storage.Store
+	logger  *zap.Logger
 }
 
-func NewFinishQuizHandler(store storage.Store, logger *zap.Logger, client *clients.StatsClient) *FinishQuizHandler {
+func NewFinishQuizHandler(store storage.Store, logger *zap.Logger) *FinishQuizHandler {
 	return &FinishQuizHandler{
-		storage:     store,
-		logger:      logger,
-		statsClient: client,
+		storage: store,
+		logger:  logger,
 	}
 }
+
+// Handle finishes an open session of the user, the session is then finished in the stats service
 func (h *FinishQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
-	quizSessionIdString := r.PathValue("quizSessionId")
-	if quizSessionIdString == "" {
-		h.logger.Info("no quiz session id provided")
-		http.Error(rw, "invalid quiz session id", http.StatusBadRequest)
+	session, ok := getOpenUserSession(rw, r, h.storage, h.logger)
+	if !ok {
 		return
 	}
-	quizSessionID, err := strconv.Atoi(quizSessionIdString)
-	if err != nil {
-		h.logger.Info("invalid quiz session id")
-		http.Error(rw, "invalid quiz session id", http.StatusBadRequest)
+	err := h.storage.UpdateQuizSession(r.Context(), session.ID, func(session *models.QuizSession) ([]models.OutboxEvent, error) {
+		// the session may have been finished or abandoned meanwhile, it is finished in stats only once
+		if session.IsClosed() {
+			return nil, &requestError{status: http.StatusConflict, message: "quiz session is closed"}
+		}
+		session.Status = models.QuizStatusFinished
+		finishTime := time.Now()
+		session.FinishedAt = &finishTime
+		if session.Sandbox {
+			return nil, nil
+		}
+		event, err := models.NewOutboxEvent(events.QuizFinished, session.ID, events.SessionPayload{SessionID: session.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create session finished event: %w", err)
+		}
+		return []models.OutboxEvent{event}, nil
+	})
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		http.Error(rw, reqErr.message, reqErr.status)
 		return
 	}
-	session, err := h.storage.GetQuizSessionByID(quizSessionID)
-	if err != nil {
-		h.logger.Error("failed to get quiz session from db", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+	if errors.Is(err, storage.ErrQuizSessionNotFound) {
+		http.Error(rw, "quiz session not found", http.StatusNotFound)
 		return
 	}
-	session.Status = models.QuizStatusFinished
-	finishTime := time.Now()
-	session.FinishedAt = &finishTime
-	err = h.storage.UpdateQuizSession(session)
 	if err != nil {
 		h.logger.Error("failed to update quiz session", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
-	}
-	err = h.statsClient.FinishSession(quizSessionID)
-	if err != nil {
-		h.logger.Error("failed to finish stats quiz session", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
 	}
 	rw.WriteHeader(http.StatusOK)
 }
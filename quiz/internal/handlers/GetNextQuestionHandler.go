@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"go.uber.org/zap"
 	"net/http"
+	"quiz/internal/models"
 	"quiz/internal/storage"
 	"strconv"
 	"time"
@@ -33,7 +35,7 @@ func (h *GetNextQuestionHandler) Handle(rw http.ResponseWriter, r *http.Request)
 		return
 	}
 	userID := r.Context().Value("user_id").(int)
-	session, err := h.storage.GetQuizSessionByID(sessionID)
+	session, err := h.storage.GetQuizSessionByID(r.Context(), sessionID)
 	if err != nil {
 		h.logger.Error("failed to get session", zap.Error(err))
 		http.Error(rw, "failed to get session", http.StatusNotFound)
@@ -43,11 +45,11 @@ func (h *GetNextQuestionHandler) Handle(rw http.ResponseWriter, r *http.Request)
 		http.Error(rw, "failed to get session", http.StatusNotFound)
 		return
 	}
-	if session.Status == "finished" {
+	if session.IsClosed() {
 		http.Error(rw, "quiz is finished", http.StatusNotFound)
 		return
 	}
-	question, err := h.storage.GetQuestionByID(session.CurrentQuestionID)
+	question, err := h.storage.GetQuestionByID(r.Context(), session.CurrentQuestionID)
 	if err != nil {
 		http.Error(rw, "failed to get question", http.StatusNotFound)
 		return
@@ -56,14 +58,72 @@ func (h *GetNextQuestionHandler) Handle(rw http.ResponseWriter, r *http.Request)
 	for i := range question.Case.ParameterValues {
 		question.Case.ParameterValues[i].Value3 = nil
 	}
-	session.QuestionRequestedTime = time.Now()
-	err = h.storage.UpdateQuizSession(session)
+	// reference landmarks are the answer of landmark questions, only their names are sent
+	if question.Type == models.QuestionTypeLandmarks {
+		for _, landmark := range question.Case.Landmarks {
+			question.Landmarks = append(question.Landmarks, landmark.Name)
+		}
+	}
+	question.Case.Landmarks = nil
+	// the answer is graded against the version of the question served here
+	version, err := h.storage.SnapshotQuestion(r.Context(), session.CurrentQuestionID)
+	if err != nil {
+		h.logger.Error("failed to snapshot question", zap.Error(err))
+		serverError(r.Context(), rw, err, "failed to get question")
+		return
+	}
+	if servedChanges(session, version.Version) {
+		// the session is changed on its latest state, an answer submitted meanwhile may have moved it on
+		err = h.storage.UpdateQuizSession(r.Context(), session.ID, func(locked *models.QuizSession) ([]models.OutboxEvent, error) {
+			if locked.IsClosed() {
+				return nil, &requestError{status: http.StatusNotFound, message: "quiz is finished"}
+			}
+			if locked.CurrentQuestionID != session.CurrentQuestionID {
+				return nil, &requestError{status: http.StatusConflict, message: "question was already answered, request the next question"}
+			}
+			setServed(locked, version.Version)
+			return nil, nil
+		})
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			http.Error(rw, reqErr.message, reqErr.status)
+			return
+		}
+		if err != nil {
+			h.logger.Error("failed to update session", zap.Error(err))
+			serverError(r.Context(), rw, err, "failed to get question")
+			return
+		}
+	}
+	locales, err := requestLocales(h.storage, r)
+	if err == nil {
+		err = localizeQuestion(r.Context(), h.storage, &question, locales)
+	}
 	if err != nil {
-		h.logger.Error("failed to update session, will result in wrong answer time", zap.Error(err))
+		h.logger.Error("failed to localize question", zap.Error(err))
+		serverError(r.Context(), rw, err, "failed to get question")
+		return
 	}
+	rw.Header().Set("Vary", "Accept-Language")
 	err = question.ToJSON(rw)
 	if err != nil {
-		http.Error(rw, "failed to get question", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "failed to get question")
 		return
 	}
 }
+
+// servedChanges reports whether serving the version of the current question changes the session
+func servedChanges(session models.QuizSession, version int) bool {
+	updated := session
+	setServed(&updated, version)
+	return updated.CurrentQuestionVersion != session.CurrentQuestionVersion || !updated.QuestionRequestedTime.Equal(session.QuestionRequestedTime)
+}
+
+// setServed records the version of the current question served to the user and the time it was requested,
+// in limited time mode requesting the question again does not restart its deadline
+func setServed(session *models.QuizSession, version int) {
+	session.CurrentQuestionVersion = version
+	if session.Mode != models.QuizModeLimitedTime || session.QuestionRequestedTime.IsZero() {
+		session.QuestionRequestedTime = time.Now()
+	}
+}
@@ -21,10 +21,10 @@ func NewGetUserActiveSessionsHandler(store storage.Store, logger *zap.Logger) *G
 
 func (h *GetUserActiveSessionsHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(int)
-	sessions, err := h.storage.GetUserActiveQuizSessions(userID)
+	sessions, err := h.storage.GetUserActiveQuizSessions(r.Context(), userID)
 	if err != nil {
 		h.logger.Error("failed to get user sessions from db", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 	rw.Header().Set("Content-Type", "application/json")
@@ -33,7 +33,7 @@ func (h *GetUserActiveSessionsHandler) Handle(rw http.ResponseWriter, r *http.Re
 	}
 	if err := json.NewEncoder(rw).Encode(data); err != nil {
 		h.logger.Error("failed to encode response", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 }
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"go.uber.org/zap"
 	"net/http"
+	"quiz/internal/models"
 	"quiz/internal/storage"
+	"strconv"
 )
 
-// GroupHandler handles operations on case groups
+// GroupHandler handles operations on questions groups
 type GroupHandler struct {
 	storage storage.Store
 	logger  *zap.Logger
@@ -20,17 +24,183 @@ func NewGroupHandler(store storage.Store, logger *zap.Logger) *GroupHandler {
 }
 
 func (h *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusNotImplemented)
+	var newGroup models.QuestionsGroup
+	if err := newGroup.FromJSON(r.Body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := newGroup.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	createdGroup, err := h.storage.CreateGroup(r.Context(), newGroup)
+	if errors.Is(err, storage.ErrGroupInUse) {
+		http.Error(w, "Group is in use by open quiz sessions", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, storage.ErrQuestionNotFound) {
+		http.Error(w, "Some of the questions do not exist", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to create group", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = createdGroup.ToJSON(w)
+	if err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
 }
 
 func (h *GroupHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusNotImplemented)
+	groupID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+	var updatedGroup models.QuestionsGroup
+	if err := updatedGroup.FromJSON(r.Body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := updatedGroup.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedGroup.ID = groupID
+	err = h.storage.UpdateGroup(r.Context(), updatedGroup)
+	if errors.Is(err, storage.ErrGroupNotFound) {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update group", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
 }
 
 func (h *GroupHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusNotImplemented)
+	groupID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.storage.DeleteGroup(r.Context(), groupID)
+	if errors.Is(err, storage.ErrGroupNotFound) {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrGroupInUse) {
+		http.Error(w, "Group is in use by open quiz sessions", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete group", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *GroupHandler) GetAllGroups(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusNotImplemented)
+	groups, err := h.storage.GetAllGroups(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to get groups", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(groups)
+	if err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *GroupHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	groupID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.storage.GetGroupByID(r.Context(), groupID)
+	if errors.Is(err, storage.ErrGroupNotFound) {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get group", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = group.ToJSON(w)
+	if err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// UpdateOrder sets display order of groups, which is also the order groups are served in the quiz
+func (h *GroupHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
+	var groups []models.QuestionsGroup
+	if err := json.NewDecoder(r.Body).Decode(&groups); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.UpdateGroupsOrder(r.Context(), groups); err != nil {
+		h.logger.Error("Failed to update groups order", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// MoveQuestions moves questions from their current groups to the group from the path
+func (h *GroupHandler) MoveQuestions(w http.ResponseWriter, r *http.Request) {
+	groupID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+	var payload models.GroupQuestionsPayload
+	if err := payload.FromJSON(r.Body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	err = h.storage.MoveQuestionsToGroup(r.Context(), groupID, payload.QuestionsIDs)
+	if errors.Is(err, storage.ErrGroupNotFound) {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, storage.ErrQuestionNotFound) {
+		http.Error(w, "Some of the questions do not exist", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, storage.ErrGroupInUse) {
+		http.Error(w, "Group is in use by open quiz sessions", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to move questions to group", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
 }
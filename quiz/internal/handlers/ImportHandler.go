@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"path"
+	"quiz/internal/archive"
+	"quiz/internal/clients"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxImportSize is the maximum size of an import archive
+const MaxImportSize = 200 << 20
+
+// transferTimeout replaces the server timeouts for import and export of the question bank
+const transferTimeout = 5 * time.Minute
+
+var imageExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
+
+type ImportHandler struct {
+	storage      storage.Store
+	logger       *zap.Logger
+	imagesClient *clients.ImagesClient
+}
+
+func NewImportHandler(store storage.Store, logger *zap.Logger, imagesClient *clients.ImagesClient) *ImportHandler {
+	return &ImportHandler{
+		storage:      store,
+		logger:       logger,
+		imagesClient: imagesClient,
+	}
+}
+
+// ImportQuestionBank imports cases, questions and radiographs from a zip archive sent as the request body.
+// With dry_run=true nothing is saved and the report describes what would be imported
+func (h *ImportHandler) ImportQuestionBank(w http.ResponseWriter, r *http.Request) {
+	h.importArchive(w, r, archive.ReadImportBundle)
+}
+
+// ImportQTI imports choice items of an IMS QTI 2.1 content package sent as the request body
+func (h *ImportHandler) ImportQTI(w http.ResponseWriter, r *http.Request) {
+	h.importArchive(w, r, archive.ReadQTIPackage)
+}
+
+func (h *ImportHandler) importArchive(w http.ResponseWriter, r *http.Request, read func(data []byte) (models.ImportBundle, error)) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	controller := http.NewResponseController(w)
+	if err := controller.SetReadDeadline(time.Now().Add(transferTimeout)); err != nil {
+		h.logger.Warn("Failed to extend read deadline", zap.Error(err))
+	}
+	if err := controller.SetWriteDeadline(time.Now().Add(transferTimeout)); err != nil {
+		h.logger.Warn("Failed to extend write deadline", zap.Error(err))
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxImportSize))
+	if err != nil {
+		http.Error(w, "Archive is too large or could not be read", http.StatusRequestEntityTooLarge)
+		return
+	}
+	bundle, err := read(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := models.ImportReport{
+		DryRun:            dryRun,
+		CasesCreated:      make([]string, 0),
+		CasesReused:       make([]string, 0),
+		ParametersCreated: make([]string, 0),
+		OptionsCreated:    make([]string, 0),
+		GroupsCreated:     make([]string, 0),
+		Skipped:           bundle.Skipped,
+		Errors:            make([]string, 0),
+	}
+	if err = h.resolveBundle(r.Context(), &bundle, &report); err != nil {
+		h.logger.Error("Failed to validate import", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	if len(report.Errors) > 0 {
+		h.writeReport(w, http.StatusUnprocessableEntity, &report)
+		return
+	}
+
+	upload := &imagesUpload{client: h.imagesClient}
+	err = h.storage.ImportQuestionBank(r.Context(), &bundle, dryRun, upload.upload)
+	if err != nil {
+		h.logger.Error("Failed to import question bank", zap.Error(err))
+		upload.rollback(h.logger)
+		serverError(r.Context(), w, err, "Failed to import question bank")
+		return
+	}
+
+	report.QuestionsCreated = len(bundle.Questions)
+	report.Images = len(importedParametersImages(&bundle))
+	for _, question := range bundle.Questions {
+		report.OptionLinks += len(question.OptionsIDs)
+		report.Images += len(question.Images)
+		if !dryRun {
+			report.QuestionsIDs = append(report.QuestionsIDs, question.ID)
+		}
+	}
+	if dryRun {
+		h.writeReport(w, http.StatusOK, &report)
+		return
+	}
+	h.writeReport(w, http.StatusCreated, &report)
+}
+
+// resolveBundle replaces names in the bundle with database IDs and collects validation errors in the report.
+// Parameters, options and groups which are created by the import keep 0 as their ID
+func (h *ImportHandler) resolveBundle(ctx context.Context, bundle *models.ImportBundle, report *models.ImportReport) error {
+	addError := func(format string, args ...any) {
+		report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+	}
+	if bundle.Version > models.BundleVersion {
+		addError("unsupported bundle version %d", bundle.Version)
+		return nil
+	}
+
+	parameters, err := h.storage.GetAllParameters(ctx)
+	if err != nil {
+		return err
+	}
+	parametersIDs := make(map[string]int, len(parameters))
+	for _, parameter := range parameters {
+		parametersIDs[parameter.Name] = parameter.ID
+	}
+	for i := range bundle.Parameters {
+		parameter := &bundle.Parameters[i]
+		if parameter.Name == "" {
+			addError("parameter %d: name is required", i+1)
+			continue
+		}
+		if id, ok := parametersIDs[parameter.Name]; ok {
+			if id == 0 {
+				addError("parameter %s: duplicated name", parameter.Name)
+			}
+			parameter.ID = id
+			// images of existing parameters are not replaced
+			parameter.Image = ""
+			continue
+		}
+		parametersIDs[parameter.Name] = 0
+		report.ParametersCreated = append(report.ParametersCreated, parameter.Name)
+		if parameter.Image != "" {
+			if _, ok := bundle.Files[path.Clean(parameter.Image)]; !ok {
+				addError("parameter %s: image %s is missing in the archive", parameter.Name, parameter.Image)
+			}
+		}
+	}
+
+	options, err := h.storage.GetAllOptions(ctx)
+	if err != nil {
+		return err
+	}
+	optionsIDs := make(map[string]int, len(options))
+	for _, option := range options {
+		optionsIDs[option.Option] = option.ID
+	}
+	for i := range bundle.Options {
+		option := &bundle.Options[i]
+		if strings.TrimSpace(option.Option) == "" {
+			addError("option %d: option is required", i+1)
+			continue
+		}
+		if id, ok := optionsIDs[option.Option]; ok {
+			option.ID = id
+			continue
+		}
+		optionsIDs[option.Option] = 0
+		report.OptionsCreated = append(report.OptionsCreated, option.Option)
+	}
+
+	groups, err := h.storage.GetAllGroups(ctx)
+	if err != nil {
+		return err
+	}
+	groupsIDs := make(map[int]int, len(groups))
+	groupsByName := make(map[string]int, len(groups))
+	for _, group := range groups {
+		groupsIDs[group.ID] = group.ID
+		groupsByName[group.Name] = group.ID
+	}
+	// questions of a bundle with groups reference the bundle groups, which are matched with existing groups by name
+	if len(bundle.Groups) > 0 {
+		groupsIDs = make(map[int]int, len(bundle.Groups))
+		for i := range bundle.Groups {
+			group := &bundle.Groups[i]
+			if group.Name == "" {
+				addError("group %d: name is required", group.ID)
+				continue
+			}
+			if _, ok := groupsIDs[group.ID]; ok || group.ID == 0 {
+				addError("group %s: invalid or duplicated id %d", group.Name, group.ID)
+				continue
+			}
+			group.GroupID = groupsByName[group.Name]
+			if group.GroupID == 0 {
+				report.GroupsCreated = append(report.GroupsCreated, group.Name)
+			}
+			groupsIDs[group.ID] = group.GroupID
+		}
+	}
+
+	for i, setting := range bundle.Settings {
+		if setting.Name == "" {
+			addError("setting %d: name is required", i+1)
+		}
+	}
+	report.SettingsUpdated = len(bundle.Settings)
+
+	cases := make(map[string]bool, len(bundle.Cases))
+	for i := range bundle.Cases {
+		importCase := &bundle.Cases[i]
+		if importCase.Code == "" {
+			addError("case %d: code is required", i+1)
+			continue
+		}
+		if cases[importCase.Code] {
+			addError("case %s: duplicated code", importCase.Code)
+			continue
+		}
+		cases[importCase.Code] = true
+
+		importCase.ID, err = h.storage.GetCaseIDByCode(ctx, importCase.Code)
+		if err == nil {
+			report.CasesReused = append(report.CasesReused, importCase.Code)
+			continue
+		}
+		if !errors.Is(err, storage.ErrCaseNotFound) {
+			return err
+		}
+		report.CasesCreated = append(report.CasesCreated, importCase.Code)
+		if importCase.Age1 < 0 || importCase.Age2 < 0 || importCase.Age3 < 0 {
+			addError("case %s: ages must not be negative", importCase.Code)
+		}
+		landmarks := models.CaseLandmarks{Landmarks: importCase.Landmarks}
+		if err := landmarks.Validate(); err != nil {
+			addError("case %s: %v", importCase.Code, err)
+		}
+		for j := range importCase.Parameters {
+			value := &importCase.Parameters[j]
+			id, ok := parametersIDs[value.Parameter]
+			if !ok {
+				addError("case %s: unknown parameter %q", importCase.Code, value.Parameter)
+				continue
+			}
+			value.ParameterID = id
+		}
+	}
+
+	existingCases := make(map[string]int)
+	for i := range bundle.Questions {
+		question := &bundle.Questions[i]
+		number := i + 1
+		if strings.TrimSpace(question.Question) == "" {
+			addError("question %d: question is required", number)
+		}
+		if !cases[question.CaseCode] {
+			// questions may also reference cases which are already in the database
+			caseID, ok := existingCases[question.CaseCode]
+			if !ok {
+				caseID, err = h.storage.GetCaseIDByCode(ctx, question.CaseCode)
+				if errors.Is(err, storage.ErrCaseNotFound) {
+					addError("question %d: unknown case %q", number, question.CaseCode)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				existingCases[question.CaseCode] = caseID
+				report.CasesReused = append(report.CasesReused, question.CaseCode)
+			}
+			question.CaseID = caseID
+		}
+		if question.Group != 0 {
+			groupID, ok := groupsIDs[question.Group]
+			if !ok {
+				addError("question %d: unknown group %d", number, question.Group)
+			}
+			question.GroupID = groupID
+		}
+		if question.Status == "" {
+			question.Status = models.QuestionStatusDraft
+		} else if !models.ValidQuestionStatus(question.Status) {
+			addError("question %d: unknown status %q", number, question.Status)
+		}
+		switch question.Type {
+		case "", models.QuestionTypeChoice:
+			if len(question.Options) == 0 {
+				addError("question %d: options are required", number)
+			}
+		case models.QuestionTypeNumeric:
+			id, ok := parametersIDs[question.TargetParameter]
+			if !ok {
+				addError("question %d: unknown target parameter %q", number, question.TargetParameter)
+			}
+			question.TargetParameterID = id
+			if question.Tolerance == nil || *question.Tolerance <= 0 {
+				addError("question %d: numeric questions require a positive tolerance", number)
+			}
+		case models.QuestionTypeLandmarks:
+			if question.Tolerance == nil || *question.Tolerance <= 0 {
+				addError("question %d: landmark questions require a positive tolerance", number)
+			}
+		default:
+			addError("question %d: unknown question type %q", number, question.Type)
+		}
+		question.OptionsIDs = make([]int, 0, len(question.Options))
+		for _, option := range question.Options {
+			id, ok := optionsIDs[option]
+			if !ok {
+				addError("question %d: unknown option %q", number, option)
+				continue
+			}
+			question.OptionsIDs = append(question.OptionsIDs, id)
+		}
+		if question.Correct != "" && !slices.Contains(question.Options, question.Correct) {
+			addError("question %d: correct option %q is not one of the question options", number, question.Correct)
+		} else {
+			question.CorrectOptionID = optionsIDs[question.Correct]
+		}
+		if len(question.Images) > 3 {
+			addError("question %d: at most 3 images are allowed", number)
+		}
+		for _, image := range question.Images {
+			if _, ok := bundle.Files[path.Clean(image)]; !ok {
+				addError("question %d: image %s is missing in the archive", number, image)
+			}
+			if !slices.Contains(imageExtensions, strings.ToLower(path.Ext(image))) {
+				addError("question %d: unsupported image type %s", number, image)
+			}
+		}
+	}
+	return nil
+}
+
+// imagesUpload sends images of the imported questions and created parameters to the images service and records
+// what was sent, so images of an import which failed or was rolled back after the upload can be removed
+type imagesUpload struct {
+	client     *clients.ImagesClient
+	parameters []int
+	questions  []int
+}
+
+// upload is called before the import is committed, so a failed upload rolls the import back
+func (u *imagesUpload) upload(bundle *models.ImportBundle) error {
+	for parameterID, image := range importedParametersImages(bundle) {
+		u.parameters = append(u.parameters, parameterID)
+		if err := u.client.UploadParameterImage(parameterID, bundle.Files[image]); err != nil {
+			return fmt.Errorf("upload image of parameter %d: %w", parameterID, err)
+		}
+	}
+	for _, question := range bundle.Questions {
+		if len(question.Images) == 0 {
+			continue
+		}
+		names := make([]string, len(question.Images))
+		for i, image := range question.Images {
+			names[i] = path.Clean(image)
+		}
+		u.questions = append(u.questions, question.ID)
+		if err := u.client.UploadQuestionImages(question.ID, names, bundle.Files); err != nil {
+			return fmt.Errorf("upload images of question %d: %w", question.ID, err)
+		}
+	}
+	return nil
+}
+
+// rollback removes the images sent for questions and parameters which were not imported. IDs are recorded before
+// their upload is sent, so images of a request which failed after the images service saved them are removed too
+func (u *imagesUpload) rollback(logger *zap.Logger) {
+	for _, parameterID := range u.parameters {
+		if err := u.client.DeleteParameterImage(parameterID); err != nil {
+			logger.Warn("Failed to remove image of parameter which was not imported", zap.Int("parameter_id", parameterID), zap.Error(err))
+		}
+	}
+	for _, questionID := range u.questions {
+		if err := u.client.DeleteQuestionImages(questionID); err != nil {
+			logger.Warn("Failed to remove images of question which was not imported", zap.Int("question_id", questionID), zap.Error(err))
+		}
+	}
+}
+
+// importedParametersImages returns images of the parameters created by the import keyed by the parameter ID
+func importedParametersImages(bundle *models.ImportBundle) map[int]string {
+	images := make(map[int]string)
+	for _, parameter := range bundle.Parameters {
+		if parameter.Image != "" {
+			images[parameter.ID] = path.Clean(parameter.Image)
+		}
+	}
+	return images
+}
+
+func (h *ImportHandler) writeReport(w http.ResponseWriter, status int, report *models.ImportReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := report.ToJSON(w); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"quiz/internal/i18n"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+	"strings"
+)
+
+// requestLocales returns the locales to translate the response to, most preferred first.
+// The saved preference of a logged in user wins over the Accept-Language header, no locales mean the original texts
+func requestLocales(store storage.Store, r *http.Request) ([]string, error) {
+	var preference string
+	if userID, ok := r.Context().Value("user_id").(int); ok {
+		var err error
+		preference, err = store.GetUserLocale(r.Context(), userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return i18n.Negotiate(preference, r.Header.Get("Accept-Language")), nil
+}
+
+// localizeQuestion translates the question, its options and the parameters of its case,
+// fields without a translation in any of the locales keep the original text
+func localizeQuestion(ctx context.Context, store storage.Store, question *models.Question, locales []string) error {
+	if len(locales) == 0 {
+		return nil
+	}
+	fields, err := store.GetLocalizedFields(ctx, models.TranslationEntityQuestion, []int{question.ID}, locales)
+	if err != nil {
+		return err
+	}
+	if value, ok := i18n.Pick(fields[question.ID]["question"], locales); ok {
+		question.Question = value
+	}
+	question.Options, err = localizeOptions(ctx, store, question.Options, locales)
+	if err != nil {
+		return err
+	}
+	return localizeParameters(ctx, store, question.Case.Parameters, locales)
+}
+
+// localizeOptions returns the options translated to the locales
+func localizeOptions(ctx context.Context, store storage.Store, options []string, locales []string) ([]string, error) {
+	if len(locales) == 0 || len(options) == 0 {
+		return options, nil
+	}
+	translations, err := store.GetOptionsTranslations(ctx, options, locales)
+	if err != nil {
+		return nil, err
+	}
+	localized := make([]string, len(options))
+	for i, option := range options {
+		localized[i] = option
+		if value, ok := i18n.Pick(translations[option], locales); ok {
+			localized[i] = value
+		}
+	}
+	return localized, nil
+}
+
+// originalOption maps an answer given in one of the locales back to the original text of the option,
+// answers which are not a translated option are returned unchanged
+func originalOption(ctx context.Context, store storage.Store, options []string, answer string, locales []string) (string, error) {
+	localized, err := localizeOptions(ctx, store, options, locales)
+	if err != nil {
+		return "", err
+	}
+	for i, option := range localized {
+		if strings.EqualFold(strings.TrimSpace(answer), strings.TrimSpace(option)) {
+			return options[i], nil
+		}
+	}
+	return answer, nil
+}
+
+// localizeParameters translates names and descriptions of the parameters in place
+func localizeParameters(ctx context.Context, store storage.Store, parameters []models.Parameter, locales []string) error {
+	if len(locales) == 0 || len(parameters) == 0 {
+		return nil
+	}
+	parametersIDs := make([]int, len(parameters))
+	for i, p := range parameters {
+		parametersIDs[i] = p.ID
+	}
+	fields, err := store.GetLocalizedFields(ctx, models.TranslationEntityParameter, parametersIDs, locales)
+	if err != nil {
+		return err
+	}
+	for i := range parameters {
+		translated := fields[parameters[i].ID]
+		if value, ok := i18n.Pick(translated["name"], locales); ok {
+			parameters[i].Name = value
+		}
+		if value, ok := i18n.Pick(translated["description"], locales); ok {
+			parameters[i].Description = value
+		}
+	}
+	return nil
+}
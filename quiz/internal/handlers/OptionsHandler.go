@@ -21,11 +21,11 @@ func NewOptionsHandler(store storage.Store, logger *zap.Logger) *OptionsHandler
 	}
 }
 
-func (h *OptionsHandler) GetAllOptions(w http.ResponseWriter, _ *http.Request) {
-	options, err := h.storage.GetAllOptions()
+func (h *OptionsHandler) GetAllOptions(w http.ResponseWriter, r *http.Request) {
+	options, err := h.storage.GetAllOptions(r.Context())
 	if err != nil {
 		h.logger.Error("Failed to get options", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -42,10 +42,10 @@ func (h *OptionsHandler) CreateOption(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createdOption, err := h.storage.CreateOption(newOption)
+	createdOption, err := h.storage.CreateOption(r.Context(), newOption)
 	if err != nil {
 		h.logger.Error("Failed to create option", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
@@ -54,7 +54,7 @@ func (h *OptionsHandler) CreateOption(w http.ResponseWriter, r *http.Request) {
 	err = json.NewEncoder(w).Encode(createdOption)
 	if err != nil {
 		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 }
@@ -72,10 +72,10 @@ func (h *OptionsHandler) UpdateOption(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.storage.UpdateOption(optionID, updatedOption)
+	err = h.storage.UpdateOption(r.Context(), optionID, updatedOption)
 	if err != nil {
 		h.logger.Error("Failed to update option", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
@@ -90,10 +90,10 @@ func (h *OptionsHandler) DeleteOption(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.storage.DeleteOption(optionID)
+	err = h.storage.DeleteOption(r.Context(), optionID)
 	if err != nil {
 		h.logger.Error("Failed to delete option", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"quiz/internal/outbox"
+	"quiz/internal/storage"
+	"strconv"
+)
+
+type OutboxHandler struct {
+	storage storage.Store
+	logger  *zap.Logger
+}
+
+func NewOutboxHandler(store storage.Store, logger *zap.Logger) *OutboxHandler {
+	return &OutboxHandler{
+		storage: store,
+		logger:  logger,
+	}
+}
+
+// GetStuckEvents lists events the stats service repeatedly failed to accept
+func (h *OutboxHandler) GetStuckEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.storage.GetStuckOutboxEvents(r.Context(), outbox.StuckAttempts)
+	if err != nil {
+		h.logger.Error("Failed to get stuck outbox events", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(events); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// RetryEvent schedules the next delivery attempt of an undelivered event immediately
+func (h *OutboxHandler) RetryEvent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	err = h.storage.RetryOutboxEvent(r.Context(), id)
+	if errors.Is(err, storage.ErrOutboxEventNotFound) {
+		http.Error(w, "Undelivered event not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to retry outbox event", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -29,10 +29,10 @@ func (h *ParameterHandler) CreateParameter(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	createdParameter, err := h.storage.CreateParameter(newParameter)
+	createdParameter, err := h.storage.CreateParameter(r.Context(), newParameter)
 	if err != nil {
 		h.logger.Error("Failed to create parameter", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
@@ -41,7 +41,7 @@ func (h *ParameterHandler) CreateParameter(w http.ResponseWriter, r *http.Reques
 	err = json.NewEncoder(w).Encode(createdParameter)
 	if err != nil {
 		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 }
@@ -60,9 +60,9 @@ func (h *ParameterHandler) UpdateParameter(w http.ResponseWriter, r *http.Reques
 	}
 
 	updatedParameter.ID = parameterID
-	if err := h.storage.UpdateParameter(updatedParameter); err != nil {
+	if err := h.storage.UpdateParameter(r.Context(), updatedParameter); err != nil {
 		h.logger.Error("Failed to update parameter", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
@@ -76,28 +76,39 @@ func (h *ParameterHandler) DeleteParameter(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := h.storage.DeleteParameter(parameterID); err != nil {
+	if err := h.storage.DeleteParameter(r.Context(), parameterID); err != nil {
 		h.logger.Error("Failed to delete parameter", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *ParameterHandler) GetAllParameters(w http.ResponseWriter, _ *http.Request) {
-	parameters, err := h.storage.GetAllParameters()
+// GetAllParameters returns parameters translated to the languages of the Accept-Language header
+func (h *ParameterHandler) GetAllParameters(w http.ResponseWriter, r *http.Request) {
+	parameters, err := h.storage.GetAllParameters(r.Context())
 	if err != nil {
 		h.logger.Error("Failed to get parameters", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	locales, err := requestLocales(h.storage, r)
+	if err == nil {
+		err = localizeParameters(r.Context(), h.storage, parameters, locales)
+	}
+	if err != nil {
+		h.logger.Error("Failed to localize parameters", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Vary", "Accept-Language")
 	err = json.NewEncoder(w).Encode(parameters)
 	if err != nil {
 		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 }
@@ -108,9 +119,9 @@ func (h *ParameterHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.storage.UpdateParametersOrder(params); err != nil {
+	if err := h.storage.UpdateParametersOrder(r.Context(), params); err != nil {
 		h.logger.Error("Failed to update parameters order", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go.uber.org/zap"
+	"net/http"
+	"quiz/internal/i18n"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+)
+
+type PreferencesHandler struct {
+	storage storage.Store
+	logger  *zap.Logger
+}
+
+func NewPreferencesHandler(store storage.Store, logger *zap.Logger) *PreferencesHandler {
+	return &PreferencesHandler{
+		storage: store,
+		logger:  logger,
+	}
+}
+
+func (h *PreferencesHandler) GetPreferences(rw http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	locale, err := h.storage.GetUserLocale(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to get user locale", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(rw).Encode(models.UserPreferences{Locale: locale}); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// UpdatePreferences saves the locale questions are served in, an empty locale goes back to Accept-Language
+func (h *PreferencesHandler) UpdatePreferences(rw http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(int)
+	var preferences models.UserPreferences
+	if err := preferences.FromJSON(r.Body); err != nil {
+		http.Error(rw, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	preferences.Locale = i18n.Normalize(preferences.Locale)
+	if preferences.Locale != "" && !i18n.Valid(preferences.Locale) {
+		http.Error(rw, "invalid locale", http.StatusBadRequest)
+		return
+	}
+	if err := h.storage.SaveUserLocale(r.Context(), userID, preferences.Locale); err != nil {
+		h.logger.Error("failed to save user locale", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(preferences); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"go.uber.org/zap"
 	"net/http"
 	"quiz/internal/models"
@@ -32,17 +34,21 @@ func (h *QuestionHandler) CreateQuestion(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid case ID", http.StatusBadRequest)
 		return
 	}
-	createdQuestion, err := h.storage.CreateQuestion(questionPayload)
+	if err := models.ValidateQuestionType(questionPayload.Type, questionPayload.TargetParameterID, questionPayload.Tolerance); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	createdQuestion, err := h.storage.CreateQuestion(r.Context(), questionPayload)
 	if err != nil {
 		h.logger.Error("Failed to create question", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	err = createdQuestion.ToJSON(w)
 	if err != nil {
 		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 	}
 	w.WriteHeader(http.StatusCreated)
 }
@@ -57,33 +63,55 @@ func (h *QuestionHandler) UpdateQuestion(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	// the content before the change is kept as a version even if the question has never been served
+	before, err := h.storage.SnapshotQuestion(r.Context(), questionID)
+	if err != nil {
+		h.logger.Error("Failed to snapshot question", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	// clients unaware of question types keep the type of the question
+	if questionPayload.Type == "" {
+		questionPayload.Type = before.Type
+		questionPayload.TargetParameterID = before.TargetParameterID
+		questionPayload.Tolerance = before.Tolerance
+	}
+	if err = models.ValidateQuestionType(questionPayload.Type, questionPayload.TargetParameterID, questionPayload.Tolerance); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	questionToUpdate := models.QuestionPayload{
-		Question:      questionPayload.Question,
-		Answers:       questionPayload.Options,
-		CaseID:        questionPayload.Case.ID,
-		PredictionAge: questionPayload.PredictionAge,
-		Group:         questionPayload.Group,
+		Question:          questionPayload.Question,
+		Answers:           questionPayload.Options,
+		CaseID:            questionPayload.Case.ID,
+		PredictionAge:     questionPayload.PredictionAge,
+		Group:             questionPayload.Group,
+		Type:              questionPayload.Type,
+		TargetParameterID: questionPayload.TargetParameterID,
+		Tolerance:         questionPayload.Tolerance,
 	}
-	_, err = h.storage.UpdateQuestionByID(questionID, questionToUpdate)
+	_, err = h.storage.UpdateQuestionByID(r.Context(), questionID, questionToUpdate)
 	if err != nil {
 		h.logger.Error("Failed to update question", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	if questionPayload.Correct != nil {
-		err = h.storage.UpdateQuestionCorrectOption(questionID, *questionPayload.Correct)
+		err = h.storage.UpdateQuestionCorrectOption(r.Context(), questionID, *questionPayload.Correct)
 		if err != nil {
 			h.logger.Error("Failed to update question correct option", zap.Error(err))
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			serverError(r.Context(), w, err, "Internal server error")
 			return
 		}
 	}
 	casePayload := questionPayload.Case
-	if err := h.storage.UpdateCaseParameters(casePayload.ID, casePayload.Parameters, casePayload.ParameterValues); err != nil {
-		http.Error(w, "Failed to update case parameters", http.StatusInternalServerError)
+	if err := h.storage.UpdateCaseParameters(r.Context(), casePayload.ID, casePayload.Parameters, casePayload.ParameterValues); err != nil {
+		serverError(r.Context(), w, err, "Failed to update case parameters")
 		return
 	}
-
+	if _, err = h.storage.SnapshotQuestion(r.Context(), questionID); err != nil {
+		h.logger.Error("Failed to snapshot question", zap.Error(err))
+	}
 }
 func (h *QuestionHandler) DeleteQuestion(w http.ResponseWriter, r *http.Request) {
 	questionID, err := strconv.Atoi(r.PathValue("id"))
@@ -91,10 +119,10 @@ func (h *QuestionHandler) DeleteQuestion(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid question ID", http.StatusBadRequest)
 		return
 	}
-	err = h.storage.DeleteQuestionByID(questionID)
+	err = h.storage.DeleteQuestionByID(r.Context(), questionID)
 	if err != nil {
 		h.logger.Error("Failed to delete question", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -105,38 +133,247 @@ func (h *QuestionHandler) GetQuestion(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid question ID", http.StatusBadRequest)
 		return
 	}
-	question, err := h.storage.GetQuestionByID(questionID)
+	question, err := h.storage.GetQuestionByID(r.Context(), questionID)
 	if err != nil {
 		h.logger.Error("Failed to get question", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	correct, err := h.storage.GetQuestionCorrectOption(questionID)
+	correct, err := h.storage.GetQuestionCorrectOption(r.Context(), questionID)
 	if err != nil {
 		h.logger.Error("Failed to get question correct option", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	question.Correct = &correct
+	// users get the question in their language, the internal API without Accept-Language gets the original texts
+	if err = h.localizeQuestionWithCorrect(r, &question); err != nil {
+		h.logger.Error("Failed to localize question", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
 	err = question.ToJSON(w)
 	if err != nil {
 		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 }
-func (h *QuestionHandler) GetAllQuestions(w http.ResponseWriter, _ *http.Request) {
-	questions, err := h.storage.GetAllQuestions()
+
+// localizeQuestionWithCorrect translates the question and its correct option to the locales of the request
+func (h *QuestionHandler) localizeQuestionWithCorrect(r *http.Request, question *models.Question) error {
+	locales, err := requestLocales(h.storage, r)
+	if err != nil || len(locales) == 0 {
+		return err
+	}
+	if err = localizeQuestion(r.Context(), h.storage, question, locales); err != nil {
+		return err
+	}
+	correct, err := localizeOptions(r.Context(), h.storage, []string{*question.Correct}, locales)
+	if err != nil {
+		return err
+	}
+	question.Correct = &correct[0]
+	return nil
+}
+
+func (h *QuestionHandler) GetAllQuestions(w http.ResponseWriter, r *http.Request) {
+	questions, err := h.storage.GetAllQuestions(r.Context())
 	if err != nil {
 		h.logger.Error("Failed to get questions", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(questions)
 	if err != nil {
 		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "Internal server error")
+	}
+}
+
+// GetQuestionVersions returns the history of the question, oldest version first
+func (h *QuestionHandler) GetQuestionVersions(w http.ResponseWriter, r *http.Request) {
+	questionID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid question ID", http.StatusBadRequest)
+		return
+	}
+	versions, err := h.storage.GetQuestionVersions(r.Context(), questionID)
+	if err != nil {
+		h.logger.Error("Failed to get question versions", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(versions); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// GetQuestionVersionsDiff compares versions given by the from and to query parameters,
+// by default the latest version is compared with the previous one
+func (h *QuestionHandler) GetQuestionVersionsDiff(w http.ResponseWriter, r *http.Request) {
+	questionID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid question ID", http.StatusBadRequest)
+		return
+	}
+	versions, err := h.storage.GetQuestionVersions(r.Context(), questionID)
+	if err != nil {
+		h.logger.Error("Failed to get question versions", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	if len(versions) == 0 {
+		http.Error(w, "Question has no versions", http.StatusNotFound)
+		return
+	}
+	to := versions[len(versions)-1].Version
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "Invalid to version", http.StatusBadRequest)
+			return
+		}
+	}
+	from := to - 1
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "Invalid from version", http.StatusBadRequest)
+			return
+		}
+	}
+	// versions are numbered from 1 without gaps
+	if from < 1 || to < 1 || from > len(versions) || to > len(versions) {
+		http.Error(w, "Question version not found", http.StatusNotFound)
+		return
+	}
+	diff := models.QuestionVersionsDiff{
+		QuestionID:  questionID,
+		FromVersion: from,
+		ToVersion:   to,
+		Changes:     versions[from-1].Diff(versions[to-1]),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(diff); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// UpdateQuestionStatus moves the question through the draft, in_review, published and archived lifecycle,
+// only published questions are asked in quizzes
+func (h *QuestionHandler) UpdateQuestionStatus(w http.ResponseWriter, r *http.Request) {
+	questionID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid question ID", http.StatusBadRequest)
+		return
+	}
+	var payload models.QuestionStatusPayload
+	if err = payload.FromJSON(r.Body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err = payload.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	question, err := h.storage.GetQuestionByID(r.Context(), questionID)
+	if errors.Is(err, storage.ErrQuestionNotFound) {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get question", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	if !models.CanTransitionQuestion(question.Status, payload.Status) {
+		http.Error(w, "Cannot move question from "+question.Status+" to "+payload.Status, http.StatusConflict)
+		return
+	}
+	if payload.Status == models.QuestionStatusPublished && question.Type == models.QuestionTypeNumeric {
+		if _, ok := question.TargetValue(); !ok {
+			http.Error(w, "Target parameter has no value3 in the case", http.StatusConflict)
+			return
+		}
+	} else if payload.Status == models.QuestionStatusPublished && question.Type == models.QuestionTypeLandmarks {
+		if len(question.Case.Landmarks) == 0 {
+			http.Error(w, "Case has no reference landmarks", http.StatusConflict)
+			return
+		}
+	} else if payload.Status == models.QuestionStatusPublished {
+		_, err = h.storage.GetQuestionCorrectOption(r.Context(), questionID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Question has no correct option", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to get question correct option", zap.Error(err))
+			serverError(r.Context(), w, err, "Internal server error")
+			return
+		}
+	}
+	err = h.storage.UpdateQuestionStatus(r.Context(), questionID, question.Status, payload.Status)
+	if errors.Is(err, storage.ErrQuestionStatusChanged) {
+		http.Error(w, "Question status was changed, reload the question", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update question status", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	question.Status = payload.Status
+	w.Header().Set("Content-Type", "application/json")
+	if err = question.ToJSON(w); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// PreviewQuestion creates a sandbox session asking only the question, in any status, for the given user.
+// The user answers it through the regular session endpoints, answers in sandbox sessions are not recorded in stats
+func (h *QuestionHandler) PreviewQuestion(w http.ResponseWriter, r *http.Request) {
+	questionID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid question ID", http.StatusBadRequest)
+		return
+	}
+	var payload models.PreviewPayload
+	if err = payload.FromJSON(r.Body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err = payload.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = h.storage.GetQuestionByID(r.Context(), questionID)
+	if errors.Is(err, storage.ErrQuestionNotFound) {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get question", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	session, err := h.storage.CreateQuizSession(r.Context(), models.QuizSession{
+		Mode:              models.QuizModeEducational,
+		UserID:            payload.UserID,
+		Status:            models.QuizStatusNotStarted,
+		CurrentQuestionID: questionID,
+		GroupOrder:        []int{questionID},
+		Sandbox:           true,
+	})
+	if err != nil {
+		h.logger.Error("Failed to create sandbox session", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err = session.ToJSON(w); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
 	}
 }
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go.uber.org/zap"
+	"net/http"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+	"strconv"
+)
+
+type ResumeQuizHandler struct {
+	storage storage.Store
+	logger  *zap.Logger
+}
+
+func NewResumeQuizHandler(store storage.Store, logger *zap.Logger) *ResumeQuizHandler {
+	return &ResumeQuizHandler{
+		storage: store,
+		logger:  logger,
+	}
+}
+
+// Handle returns an open session of the user in the same shape as starting a new one,
+// the user continues with GET nextQuestion
+func (h *ResumeQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
+	session, ok := getOpenUserSession(rw, r, h.storage, h.logger)
+	if !ok {
+		return
+	}
+	timeLimit, err := h.storage.GetTimeLimit(r.Context())
+	if err != nil {
+		h.logger.Error("failed to get time limit", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{
+		"session":    session,
+		"time_limit": timeLimit,
+	}
+	if err := json.NewEncoder(rw).Encode(response); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+}
+
+// getOpenUserSession returns the session from the path if it belongs to the user and is not closed,
+// otherwise the error response is written
+func getOpenUserSession(rw http.ResponseWriter, r *http.Request, store storage.Store, logger *zap.Logger) (models.QuizSession, bool) {
+	quizSessionID, err := strconv.Atoi(r.PathValue("quizSessionId"))
+	if err != nil {
+		http.Error(rw, "invalid quiz session id", http.StatusBadRequest)
+		return models.QuizSession{}, false
+	}
+	session, err := store.GetQuizSessionByID(r.Context(), quizSessionID)
+	if err != nil {
+		logger.Error("failed to get quiz session from db", zap.Error(err))
+		http.Error(rw, "failed to get session", http.StatusNotFound)
+		return session, false
+	}
+	if session.UserID != r.Context().Value("user_id").(int) {
+		http.Error(rw, "failed to get session", http.StatusNotFound)
+		return session, false
+	}
+	if session.IsClosed() {
+		http.Error(rw, "quiz session is closed", http.StatusConflict)
+		return session, false
+	}
+	return session, true
+}
@@ -1,31 +1,60 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"net/http"
+	"quiz/internal/adaptive"
 	"quiz/internal/clients"
+	"quiz/internal/events"
 	"quiz/internal/models"
+	"quiz/internal/ordering"
+	"quiz/internal/review"
+	"quiz/internal/scoring"
 	"quiz/internal/storage"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// errQuizCompleted is returned when a session with a fixed set of questions has no more questions
+var errQuizCompleted = fmt.Errorf("all questions of the quiz were answered")
+
+// deadlineGrace is added to the time limit of a question to allow for network latency
+const deadlineGrace = 2 * time.Second
+
 type SubmitAnswerHandler struct {
 	storage     storage.Store
 	logger      *zap.Logger
-	statsClient *clients.StatsClient
+	statsClient clients.StatsService
 }
 
-func NewSubmitAnswerHandler(store storage.Store, logger *zap.Logger, statsClient *clients.StatsClient) *SubmitAnswerHandler {
+func NewSubmitAnswerHandler(store storage.Store, logger *zap.Logger, statsClient clients.StatsService) *SubmitAnswerHandler {
 	return &SubmitAnswerHandler{
 		storage:     store,
 		logger:      logger,
 		statsClient: statsClient,
 	}
 }
+
+// requestError is an error with the status and message of the response to the request
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// Handle records the answer to the current question of the session and moves the session to the next question.
+// Answers name the question they answer and carry an idempotency key, answers to a question which is no longer
+// current are rejected and a retried answer gets the response to the first one
 func (h *SubmitAnswerHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	quizSessionIdString := r.PathValue("quizSessionId")
 	if quizSessionIdString == "" {
@@ -40,98 +69,311 @@ func (h *SubmitAnswerHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, err := h.storage.GetQuizSessionByID(quizSessionID)
+	var answer models.QuestionAnswer
+	err = json.NewDecoder(r.Body).Decode(&answer)
 	if err != nil {
-		h.logger.Error("failed to get quiz session from db", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		h.logger.Error("failed to decode answer", zap.Error(err))
+		http.Error(rw, "invalid answer", http.StatusBadRequest)
+		return
+	}
+	if answer.QuestionID == 0 || !answer.ValidIdempotencyKey() {
+		http.Error(rw, "question_id and idempotency_key are required", http.StatusBadRequest)
+		return
+	}
+	if !answer.ValidConfidence() {
+		http.Error(rw, fmt.Sprintf("confidence must be between %d and %d", models.MinConfidence, models.MaxConfidence), http.StatusBadRequest)
+		return
+	}
+	// options are shown translated, answers are graded and recorded with the original text
+	locales, err := requestLocales(h.storage, r)
+	if err != nil {
+		h.logger.Error("failed to get user locale", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+
+	profile, err := h.adaptiveProfile(r.Context(), quizSessionID)
+	if err != nil {
+		h.logger.Error("failed to get adaptive profile", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
-	timeSpend := time.Now().Sub(session.QuestionRequestedTime)
 
 	userID := r.Context().Value("user_id").(int)
-	if userID != session.UserID {
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+	response, err := h.storage.SubmitAnswer(r.Context(), quizSessionID, answer.IdempotencyKey, func(store storage.Store, session *models.QuizSession, recorded []byte) ([]byte, []models.OutboxEvent, error) {
+		if userID != session.UserID {
+			return nil, nil, &requestError{status: http.StatusInternalServerError, message: "internal server error"}
+		}
+		if recorded != nil {
+			h.logger.Info("replaying answer submission", zap.Int("session_id", session.ID), zap.String("idempotency_key", answer.IdempotencyKey))
+			return recorded, nil, nil
+		}
+		return h.submit(r.Context(), store, session, answer, locales, profile)
+	})
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		http.Error(rw, reqErr.message, reqErr.status)
+		return
+	}
+	if errors.Is(err, storage.ErrQuizSessionNotFound) {
+		http.Error(rw, "quiz session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to submit answer", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	if _, err = rw.Write(response); err != nil {
+		h.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+// adaptiveProfile returns the profile of the user of an adaptive session, which selects the next question of the
+// session, nil is returned for other sessions. The profile is read from the stats service before the session is locked
+func (h *SubmitAnswerHandler) adaptiveProfile(ctx context.Context, sessionID int) (*models.AdaptiveProfile, error) {
+	session, err := h.storage.GetQuizSessionByID(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// the missing session is reported by SubmitAnswer
+		return nil, nil
+	}
+	if err != nil || session.Mode != models.QuizModeAdaptive || session.IsClosed() {
+		return nil, err
+	}
+	profile, err := h.statsClient.GetAdaptiveProfile(session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// submit grades the answer and moves the locked session to the next question, the store runs in the transaction
+// holding the lock. It returns the encoded response to the answer and the events announcing it
+func (h *SubmitAnswerHandler) submit(ctx context.Context, store storage.Store, session *models.QuizSession, answer models.QuestionAnswer, locales []string, profile *models.AdaptiveProfile) ([]byte, []models.OutboxEvent, error) {
+	if session.IsClosed() {
+		return nil, nil, &requestError{status: http.StatusNotFound, message: "quiz is finished"}
+	}
+	if session.AssignmentID != nil {
+		assignment, err := store.GetAssignmentByID(ctx, *session.AssignmentID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get assignment of the session: %w", err)
+		}
+		if time.Now().After(assignment.Deadline) {
+			return nil, nil, &requestError{status: http.StatusForbidden, message: "assignment deadline has passed"}
+		}
 	}
+	// a second click or a submission from another tab answers a question which is no longer current
+	if answer.QuestionID != session.CurrentQuestionID {
+		return nil, nil, &requestError{status: http.StatusConflict, message: "question was already answered, request the next question"}
+	}
+	// the time spent and the deadline are measured from the request of the question, the first question of
+	// a session counts from its start
+	if session.QuestionRequestedTime.IsZero() {
+		return nil, nil, &requestError{status: http.StatusConflict, message: "question was not requested, request the next question"}
+	}
+	timeSpend := time.Now().Sub(session.QuestionRequestedTime)
 	data := map[string]interface{}{}
 
-	correct, err := h.storage.GetQuestionCorrectOption(session.CurrentQuestionID)
+	version, err := servedQuestionVersion(ctx, store, *session)
 	if err != nil {
-		h.logger.Error("failed to get question correct option", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
-		return
+		return nil, nil, fmt.Errorf("failed to get served question version: %w", err)
 	}
-	if session.Mode == models.QuizModeEducational {
+	correct := version.Correct
+	if session.Mode == models.QuizModeEducational && version.Type == models.QuestionTypeNumeric {
+		data["correct"], _ = version.TargetValue()
+	} else if session.Mode == models.QuizModeEducational && version.Type == models.QuestionTypeLandmarks {
+		data["correct"] = version.Case.Landmarks
+	} else if session.Mode == models.QuizModeEducational {
 		h.logger.Info("educational mode")
-		data["correct"] = correct
+		localized, err := localizeOptions(ctx, store, []string{correct}, locales)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to localize correct answer: %w", err)
+		}
+		data["correct"] = localized[0]
 		h.logger.Info("educational mode, returning correct answer")
 	}
 	h.logger.Info("submitting answer")
 
 	session.Status = models.QuizStatusInProgress
 
-	var answer models.QuestionAnswer
-	err = json.NewDecoder(r.Body).Decode(&answer)
+	answer.Answer, err = originalOption(ctx, store, version.Options, answer.Answer, locales)
 	if err != nil {
-		h.logger.Error("failed to decode answer", zap.Error(err))
-		http.Error(rw, "invalid answer", http.StatusBadRequest)
-		return
+		return nil, nil, fmt.Errorf("failed to map answer to original option: %w", err)
 	}
-	question, err := h.storage.GetQuestionByID(session.CurrentQuestionID)
+	timedOut, err := isTimedOut(ctx, store, session, timeSpend)
 	if err != nil {
-		h.logger.Error("failed to get question by id", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
-		return
+		return nil, nil, fmt.Errorf("failed to get time limit: %w", err)
+	}
+	if timedOut {
+		h.logger.Info("answer submitted after the deadline", zap.Int("session_id", session.ID), zap.Duration("time_spent", timeSpend))
+		data["timed_out"] = true
+	}
+	isCorrect := !timedOut && strings.EqualFold(strings.TrimSpace(answer.Answer), strings.TrimSpace(correct))
+	var numeric *scoring.NumericResult
+	var landmarks *scoring.LandmarksResult
+	switch version.Type {
+	case models.QuestionTypeNumeric:
+		result, err := gradeNumeric(version, answer.Answer)
+		if errors.Is(err, scoring.ErrInvalidPrediction) {
+			return nil, nil, &requestError{status: http.StatusBadRequest, message: "invalid answer"}
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to grade numeric answer: %w", err)
+		}
+		numeric = &result
+		isCorrect = !timedOut && result.Correct
+		if session.Mode == models.QuizModeEducational {
+			data["prediction_error"] = result.Error
+			data["score"] = result.Score
+		}
+	case models.QuestionTypeLandmarks:
+		if version.Tolerance == nil || len(version.Case.Landmarks) == 0 {
+			return nil, nil, fmt.Errorf("landmark question %d without tolerance or reference landmarks", version.QuestionID)
+		}
+		result, err := scoring.Landmarks(answer.Points, version.Case.Landmarks, *version.Tolerance)
+		if err != nil {
+			return nil, nil, &requestError{status: http.StatusBadRequest, message: "invalid answer: " + err.Error()}
+		}
+		landmarks = &result
+		isCorrect = !timedOut && result.Correct
+		if session.Mode == models.QuizModeEducational {
+			data["points"] = result.Points
+			data["mean_distance"] = result.MeanDistance
+			data["score"] = result.Score
+		}
 	}
 	fmt.Println("question", session.CurrentQuestionID, "answer", answer.Answer, "correct", correct)
-	err = h.statsClient.SaveResponse(session.ID, models.QuestionAnswer{
-		QuestionID: session.CurrentQuestionID,
-		Answer:     answer.Answer,
-		IsCorrect:  strings.EqualFold(strings.TrimSpace(answer.Answer), strings.TrimSpace(correct)),
-		ScreenSize: answer.ScreenSize,
-		TimeSpent:  int(timeSpend.Seconds()),
-		CaseCode:   question.Case.Code,
-	})
+	response := models.QuestionAnswer{
+		QuestionID:      session.CurrentQuestionID,
+		QuestionVersion: version.Version,
+		Answer:          answer.Answer,
+		IsCorrect:       isCorrect,
+		ScreenSize:      answer.ScreenSize,
+		TimeSpent:       int(timeSpend.Seconds()),
+		CaseCode:        version.Case.Code,
+		TimedOut:        timedOut,
+		Position:        slices.Index(session.GroupOrder, session.CurrentQuestionID),
+		Confidence:      answer.Confidence,
+		IdempotencyKey:  answer.IdempotencyKey,
+	}
+	if numeric != nil {
+		response.PredictionError = &numeric.Error
+		response.Score = &numeric.Score
+	}
+	if landmarks != nil {
+		response.Points = landmarks.Points
+		response.MeanDistance = &landmarks.MeanDistance
+		response.Score = &landmarks.Score
+	}
+	// answers in sandbox sessions of authors previewing a question are not recorded
+	var outboxEvents []models.OutboxEvent
+	if !session.Sandbox {
+		event, err := models.NewOutboxEvent(events.AnswerSubmitted, session.ID, events.AnswerSubmittedPayload{SessionID: session.ID, Answer: response})
+		if err != nil {
+			return nil, nil, err
+		}
+		outboxEvents = append(outboxEvents, event)
+	}
+	if session.Mode == models.QuizModeReview {
+		err = scheduleReview(ctx, store, session.UserID, session.CurrentQuestionID, isCorrect)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to schedule review: %w", err)
+		}
+	}
+	err = setNextQuestionID(ctx, store, session, profile)
+	if errors.Is(err, review.ErrNothingDue) || errors.Is(err, errQuizCompleted) {
+		// review sessions end when no questions are due, assignment and sandbox sessions after their last question
+		finishTime := time.Now()
+		session.Status = models.QuizStatusFinished
+		session.FinishedAt = &finishTime
+		data["finished"] = true
+		err = nil
+		if !session.Sandbox {
+			event, err := models.NewOutboxEvent(events.QuizFinished, session.ID, events.SessionPayload{SessionID: session.ID})
+			if err != nil {
+				return nil, nil, err
+			}
+			outboxEvents = append(outboxEvents, event)
+		}
+	}
 	if err != nil {
-		h.logger.Error("failed to save response", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
-		return
+		return nil, nil, fmt.Errorf("failed to set next question id: %w", err)
 	}
-	err = h.SetNextQuestionID(&session)
+	// the deadline of the next question starts when it is requested
+	session.QuestionRequestedTime = time.Time{}
+	session.CurrentQuestionVersion = 0
+	encoded, err := json.Marshal(data)
+	return encoded, outboxEvents, err
+}
+
+// isTimedOut reports whether the answer of a limited time session came after the time limit of the question
+func isTimedOut(ctx context.Context, store storage.Store, session *models.QuizSession, timeSpent time.Duration) (bool, error) {
+	if session.Mode != models.QuizModeLimitedTime {
+		return false, nil
+	}
+	timeLimit, err := store.GetTimeLimit(ctx)
 	if err != nil {
-		h.logger.Error("failed to set next question id", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
-		return
+		return false, err
 	}
-	err = h.storage.UpdateQuizSession(session)
+	if timeLimit <= 0 {
+		return false, nil
+	}
+	return timeSpent > time.Duration(timeLimit)*time.Second+deadlineGrace, nil
+}
+
+// gradeNumeric scores the prediction against the target value of the served version of a numeric question
+func gradeNumeric(version models.QuestionVersion, answer string) (scoring.NumericResult, error) {
+	prediction, err := scoring.ParsePrediction(answer)
 	if err != nil {
-		h.logger.Error("failed to update quiz session", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
-		return
+		return scoring.NumericResult{}, err
 	}
-	rw.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(rw).Encode(data); err != nil {
-		h.logger.Error("failed to encode response", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
-		return
+	target, ok := version.TargetValue()
+	if !ok || version.Tolerance == nil {
+		return scoring.NumericResult{}, fmt.Errorf("version %d of question %d has no target value", version.Version, version.QuestionID)
 	}
+	return scoring.Numeric(prediction, target, *version.Tolerance), nil
 }
 
-func (h *SubmitAnswerHandler) SetNextQuestionID(qs *models.QuizSession) error {
+// servedQuestionVersion returns the version of the current question served to the user,
+// the current version when the answer comes without requesting the question first
+func servedQuestionVersion(ctx context.Context, store storage.Store, session models.QuizSession) (models.QuestionVersion, error) {
+	if session.CurrentQuestionVersion == 0 {
+		return store.SnapshotQuestion(ctx, session.CurrentQuestionID)
+	}
+	return store.GetQuestionVersion(ctx, session.CurrentQuestionID, session.CurrentQuestionVersion)
+}
+
+// setNextQuestionID moves the session to its next question, profile is the profile of the user of an adaptive session
+func setNextQuestionID(ctx context.Context, store storage.Store, qs *models.QuizSession, profile *models.AdaptiveProfile) error {
+	if qs.Mode == models.QuizModeAdaptive {
+		if profile == nil {
+			return fmt.Errorf("adaptive profile of session %d was not read", qs.ID)
+		}
+		return setNextAdaptiveQuestionID(ctx, store, *profile, qs)
+	}
+	if qs.Mode == models.QuizModeReview {
+		return setNextReviewQuestionID(ctx, store, qs)
+	}
+	if qs.AssignmentID != nil || qs.Sandbox {
+		return setNextAssignmentQuestionID(qs)
+	}
 	for i, q := range qs.GroupOrder {
 		if q == qs.CurrentQuestionID {
 			if i+1 < len(qs.GroupOrder) {
 				qs.CurrentQuestionID = qs.GroupOrder[i+1]
 				return nil
 			} else {
-				nextGroup, err := h.storage.GetNextQuestionGroupID(qs.CurrentGroup)
+				nextGroup, err := store.GetNextQuestionGroupID(ctx, qs.CurrentGroup)
 				if err != nil {
 					return err
 				}
 				qs.CurrentGroup = nextGroup
-				qs.GroupOrder, err = h.storage.GetGroupQuestionsIDsRandomOrder(qs.CurrentGroup)
+				questionsIDs, err := store.GetGroupQuestionsIDs(ctx, qs.CurrentGroup)
 				if err != nil {
 					return err
 				}
+				qs.GroupOrder = ordering.GroupOrder(questionsIDs, qs.Seed, qs.CurrentGroup)
 				qs.CurrentQuestionID = qs.GroupOrder[0]
 				return nil
 			}
@@ -139,3 +381,57 @@ func (h *SubmitAnswerHandler) SetNextQuestionID(qs *models.QuizSession) error {
 	}
 	return fmt.Errorf("question not found in group order")
 }
+
+// setNextAdaptiveQuestionID selects the next question matching the ability of the user estimated from all answers
+func setNextAdaptiveQuestionID(ctx context.Context, store storage.Store, profile models.AdaptiveProfile, qs *models.QuizSession) error {
+	candidates, err := store.GetEnabledQuestionsIDs(ctx)
+	if err != nil {
+		return err
+	}
+	qs.CurrentGroup = 0
+	qs.CurrentQuestionID, qs.GroupOrder, err = adaptive.NextQuestion(profile, candidates, qs.GroupOrder)
+	return err
+}
+
+// scheduleReview updates the spaced repetition schedule of the question after an answer in review mode
+func scheduleReview(ctx context.Context, store storage.Store, userID int, questionID int, correct bool) error {
+	now := time.Now()
+	item, err := store.GetReviewItem(ctx, userID, questionID)
+	if errors.Is(err, storage.ErrReviewItemNotFound) {
+		item = review.NewItem(userID, questionID, now)
+	} else if err != nil {
+		return err
+	}
+	return store.SaveReviewItem(ctx, review.Schedule(item, review.Quality(correct), now))
+}
+
+// setNextAssignmentQuestionID moves to the next question of the assignment or sandbox session,
+// errQuizCompleted is returned after the last one
+func setNextAssignmentQuestionID(qs *models.QuizSession) error {
+	i := slices.Index(qs.GroupOrder, qs.CurrentQuestionID)
+	if i < 0 {
+		return fmt.Errorf("question not found in group order")
+	}
+	if i+1 == len(qs.GroupOrder) {
+		return errQuizCompleted
+	}
+	qs.CurrentQuestionID = qs.GroupOrder[i+1]
+	return nil
+}
+
+// setNextReviewQuestionID selects the most overdue question of the review queue which was not asked yet in the session
+func setNextReviewQuestionID(ctx context.Context, store storage.Store, qs *models.QuizSession) error {
+	due, err := store.GetDueReviewQuestionsIDs(ctx, qs.UserID, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, questionID := range due {
+		if !slices.Contains(qs.GroupOrder, questionID) {
+			qs.CurrentGroup = 0
+			qs.CurrentQuestionID = questionID
+			qs.GroupOrder = append(qs.GroupOrder, questionID)
+			return nil
+		}
+	}
+	return review.ErrNothingDue
+}
@@ -5,6 +5,7 @@ import (
 	"go.uber.org/zap"
 	"net/http"
 	"quiz/internal/storage"
+	"strconv"
 )
 
 type SettingsHandler struct {
@@ -37,10 +38,17 @@ func (h *SettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Request)
 			http.Error(w, "invalid setting name", http.StatusBadRequest)
 			return
 		}
-		err = h.storage.SaveSettings(s.Name, s.Value)
+		// order_seed pins the question order of new sessions, an empty value restores random seeds
+		if s.Name == "order_seed" && s.Value != "" {
+			if _, err = strconv.ParseInt(s.Value, 10, 64); err != nil {
+				http.Error(w, "order_seed must be an integer", http.StatusBadRequest)
+				return
+			}
+		}
+		err = h.storage.SaveSettings(r.Context(), s.Name, s.Value)
 		if err != nil {
 			h.logger.Error("failed to save settings", zap.Error(err))
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			serverError(r.Context(), w, err, "internal server error")
 			return
 		}
 	}
@@ -48,17 +56,17 @@ func (h *SettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *SettingsHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
-	settings, err := h.storage.GetSettings()
+	settings, err := h.storage.GetSettings(r.Context())
 	if err != nil {
 		h.logger.Error("failed to get settings", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(settings)
 	if err != nil {
 		h.logger.Error("failed to encode response", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -1,22 +1,26 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"net/http"
 	"quiz/internal/clients"
 	"quiz/internal/models"
+	"quiz/internal/ordering"
+	"quiz/internal/review"
 	"quiz/internal/storage"
 )
 
 type StartQuizHandler struct {
 	storage     storage.Store
 	logger      *zap.Logger
-	statsClient *clients.StatsClient
+	statsClient clients.StatsService
 }
 
-func NewStartQuizHandler(store storage.Store, logger *zap.Logger, client *clients.StatsClient) *StartQuizHandler {
+func NewStartQuizHandler(store storage.Store, logger *zap.Logger, client clients.StatsService) *StartQuizHandler {
 	return &StartQuizHandler{
 		storage:     store,
 		logger:      logger,
@@ -36,47 +40,85 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
 	}
-	groupID, err := h.storage.GetNextQuestionGroupID(0)
-	order, err := h.storage.GetGroupQuestionsIDsRandomOrder(groupID)
+	newQuizSession := models.QuizSession{
+		Mode:       payload.Mode,
+		UserID:     userID,
+		Status:     models.QuizStatusNotStarted,
+		ScreenSize: fmt.Sprintf("%dx%d", payload.ScreenWidth, payload.ScreenHeight),
+	}
+	open, err := h.storage.GetUserOpenQuizSession(r.Context(), userID, payload.Mode, nil)
 	if err != nil {
-		h.logger.Error("failed to start quiz", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		h.logger.Error("failed to get open quiz session", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
-
-	newQuizSession := models.QuizSession{
-		Mode:              payload.Mode,
-		UserID:            userID,
-		Status:            models.QuizStatusNotStarted,
-		ScreenSize:        fmt.Sprintf("%dx%d", payload.ScreenWidth, payload.ScreenHeight),
-		CurrentQuestionID: order[0],
-		CurrentGroup:      groupID,
-		GroupOrder:        order,
-	}
-	session, err := h.storage.GetUserLastQuizSession(userID)
-	if err == nil && session != nil {
+	if open != nil {
+		http.Error(rw, "quiz session in this mode is already open, resume or abandon it", http.StatusConflict)
+		return
+	}
+	session, err := h.storage.GetUserLastQuizSession(r.Context(), userID, payload.Mode)
+	if err != nil {
+		h.logger.Error("failed to get last quiz session", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	// the position in the question bank is kept between sessions of the mode,
+	// review sessions always start from the current review queue
+	if session != nil && payload.Mode != models.QuizModeReview {
 		newQuizSession.CurrentQuestionID = session.CurrentQuestionID
 		newQuizSession.CurrentGroup = session.CurrentGroup
 		newQuizSession.GroupOrder = session.GroupOrder
-		session.FinishedAt = session.UpdatedAt
-		session.Status = models.QuizStatusFinished
-		err = h.storage.UpdateQuizSession(*session)
+		// the order of the remaining groups continues from the seed of the previous session
+		newQuizSession.Seed = session.Seed
+	}
+	if newQuizSession.CurrentQuestionID == 0 {
+		newQuizSession.Seed, err = newSessionSeed(r.Context(), h.storage)
+		if err != nil {
+			h.logger.Error("failed to get pinned seed", zap.Error(err))
+			serverError(r.Context(), rw, err, "internal server error")
+			return
+		}
+		switch payload.Mode {
+		case models.QuizModeAdaptive:
+			var profile models.AdaptiveProfile
+			profile, err = h.statsClient.GetAdaptiveProfile(userID)
+			if err == nil {
+				err = setNextAdaptiveQuestionID(r.Context(), h.storage, profile, &newQuizSession)
+			}
+		case models.QuizModeReview:
+			err = h.fillReviewQueue(r.Context(), userID)
+			if err == nil {
+				err = setNextReviewQuestionID(r.Context(), h.storage, &newQuizSession)
+			}
+		default:
+			err = h.setFirstGroup(r.Context(), &newQuizSession)
+		}
+		if errors.Is(err, review.ErrNothingDue) {
+			http.Error(rw, "no questions to review", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("failed to start quiz", zap.Error(err))
+			serverError(r.Context(), rw, err, "internal server error")
+			return
+		}
 	}
 
-	sessionCreated, err := h.storage.CreateQuizSession(newQuizSession)
-	if err != nil {
-		h.logger.Error("failed to create quiz session in db", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+	sessionCreated, err := h.storage.CreateQuizSession(r.Context(), newQuizSession)
+	// a concurrent start opened a session in the mode after the check above
+	if errors.Is(err, storage.ErrQuizSessionOpen) {
+		http.Error(rw, "quiz session in this mode is already open, resume or abandon it", http.StatusConflict)
 		return
 	}
-	err = h.statsClient.SaveSession(sessionCreated)
 	if err != nil {
-		h.logger.Error("failed to save session in stats service", zap.Error(err))
+		h.logger.Error("failed to create quiz session in db", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
 	}
-	timeLimit, err := h.storage.GetTimeLimit()
+	timeLimit, err := h.storage.GetTimeLimit(r.Context())
 	if err != nil {
 		h.logger.Error("failed to get time limit", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 	rw.Header().Set("Content-Type", "application/json")
@@ -86,7 +128,45 @@ func (h *StartQuizHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	}
 	if err := json.NewEncoder(rw).Encode(response); err != nil {
 		h.logger.Error("failed to encode response", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 }
+
+func (h *StartQuizHandler) setFirstGroup(ctx context.Context, qs *models.QuizSession) error {
+	groupID, err := h.storage.GetNextQuestionGroupID(ctx, 0)
+	if err != nil {
+		return err
+	}
+	questionsIDs, err := h.storage.GetGroupQuestionsIDs(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	qs.CurrentGroup = groupID
+	qs.GroupOrder = ordering.GroupOrder(questionsIDs, qs.Seed, groupID)
+	qs.CurrentQuestionID = qs.GroupOrder[0]
+	return nil
+}
+
+// newSessionSeed returns the seed pinned by admins for a study, or a random one
+func newSessionSeed(ctx context.Context, store storage.Store) (int64, error) {
+	seed, pinned, err := store.GetPinnedSeed(ctx)
+	if err != nil || pinned {
+		return seed, err
+	}
+	return ordering.NewSeed(), nil
+}
+
+// fillReviewQueue adds questions the user has answered incorrectly in other modes to the review queue,
+// questions are due from the time they were last missed
+func (h *StartQuizHandler) fillReviewQueue(ctx context.Context, userID int) error {
+	questions, err := h.statsClient.GetIncorrectQuestions(userID)
+	if err != nil {
+		return err
+	}
+	items := make([]models.ReviewItem, 0, len(questions))
+	for _, question := range questions {
+		items = append(items, review.NewItem(userID, question.QuestionID, question.MissedAt))
+	}
+	return h.storage.AddReviewItems(ctx, items)
+}
@@ -20,13 +20,13 @@ func NewSummaryHandler(storage storage.Store, logger *zap.Logger) *SummaryHandle
 	}
 }
 
-func (h *SummaryHandler) Handle(w http.ResponseWriter, _ *http.Request) {
+func (h *SummaryHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	var summary models.QuizSummary
 	var err error
-	summary.Questions, err = h.storage.CountQuestions()
+	summary.Questions, err = h.storage.CountQuestions(r.Context())
 	if err != nil {
 		h.logger.Error("failed to count questions", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "")
 		return
 	}
 	summary.ActiveSurveys = 0
@@ -35,7 +35,7 @@ func (h *SummaryHandler) Handle(w http.ResponseWriter, _ *http.Request) {
 	err = json.NewEncoder(w).Encode(summary)
 	if err != nil {
 		h.logger.Error("failed to encode response", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
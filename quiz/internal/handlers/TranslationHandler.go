@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"quiz/internal/i18n"
+	"quiz/internal/models"
+	"quiz/internal/storage"
+	"strconv"
+)
+
+type TranslationHandler struct {
+	storage storage.Store
+	logger  *zap.Logger
+}
+
+func NewTranslationHandler(storage storage.Store, logger *zap.Logger) *TranslationHandler {
+	return &TranslationHandler{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// GetTranslations returns all translations of a question, option or parameter
+func (h *TranslationHandler) GetTranslations(w http.ResponseWriter, r *http.Request) {
+	entity := r.PathValue("entity")
+	if _, ok := models.TranslatableFields[entity]; !ok {
+		http.Error(w, "Invalid entity", http.StatusBadRequest)
+		return
+	}
+	entityID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid entity ID", http.StatusBadRequest)
+		return
+	}
+	translations, err := h.storage.GetTranslations(r.Context(), entity, entityID)
+	if err != nil {
+		h.logger.Error("Failed to get translations", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(translations); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// SaveTranslation creates or replaces the translation of a field in a locale
+func (h *TranslationHandler) SaveTranslation(w http.ResponseWriter, r *http.Request) {
+	var translation models.Translation
+	if err := translation.FromJSON(r.Body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	translation.Locale = i18n.Normalize(translation.Locale)
+	if err := translation.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err := h.storage.SaveTranslation(r.Context(), translation)
+	if errors.Is(err, storage.ErrTranslatedEntityNotFound) {
+		http.Error(w, "Translated entity not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to save translation", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(translation); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *TranslationHandler) DeleteTranslation(w http.ResponseWriter, r *http.Request) {
+	entityID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid entity ID", http.StatusBadRequest)
+		return
+	}
+	err = h.storage.DeleteTranslation(r.Context(), r.PathValue("entity"), entityID, r.PathValue("field"), i18n.Normalize(r.PathValue("locale")))
+	if errors.Is(err, storage.ErrTranslationNotFound) {
+		http.Error(w, "Translation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete translation", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMissingTranslations reports the fields which have no translation in the locale query parameter
+func (h *TranslationHandler) GetMissingTranslations(w http.ResponseWriter, r *http.Request) {
+	locale := i18n.Normalize(r.URL.Query().Get("locale"))
+	if !i18n.Valid(locale) || locale == i18n.DefaultLocale {
+		http.Error(w, "Invalid locale", http.StatusBadRequest)
+		return
+	}
+	missing, err := h.storage.GetMissingTranslations(r.Context(), locale)
+	if err != nil {
+		h.logger.Error("Failed to get missing translations", zap.Error(err))
+		serverError(r.Context(), w, err, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(missing); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
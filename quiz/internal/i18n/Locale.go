@@ -0,0 +1,109 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is the locale of the texts stored with questions, options and parameters,
+// translations are kept for all other locales
+const DefaultLocale = "en"
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// Normalize lowercases the locale and accepts underscores as separators, "pt_BR" becomes "pt-br"
+func Normalize(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// Valid reports whether the normalized locale is a language tag like "pl" or "pt-br"
+func Valid(locale string) bool {
+	return localePattern.MatchString(locale)
+}
+
+// Fallbacks returns the locale followed by its less specific forms, "pt-br" gives ["pt-br", "pt"].
+// The default locale ends the chain as it is served by the original texts
+func Fallbacks(locale string) []string {
+	var chain []string
+	for locale != "" && locale != DefaultLocale {
+		chain = append(chain, locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return chain
+}
+
+// ParseAcceptLanguage returns the valid locales of the Accept-Language header ordered by their quality,
+// locales with q=0 and the wildcard are skipped
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		locale, params, _ := strings.Cut(part, ";")
+		locale = Normalize(locale)
+		if !Valid(locale) {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{locale: locale, quality: quality})
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+	locales := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		locales = append(locales, tag.locale)
+	}
+	return locales
+}
+
+// Negotiate returns the locales to look translations up in, most preferred first.
+// The preference of the user wins over the Accept-Language header, the header is walked
+// until a locale served by the original texts. An empty result means the original texts
+func Negotiate(preference string, acceptLanguage string) []string {
+	if preference != "" {
+		return Fallbacks(preference)
+	}
+	var chain []string
+	for _, locale := range ParseAcceptLanguage(acceptLanguage) {
+		fallbacks := Fallbacks(locale)
+		for _, fallback := range fallbacks {
+			if !slices.Contains(chain, fallback) {
+				chain = append(chain, fallback)
+			}
+		}
+		if len(fallbacks) == 0 || strings.HasPrefix(locale, DefaultLocale+"-") {
+			break
+		}
+	}
+	return chain
+}
+
+// Pick returns the translation of the first locale of the chain which has one
+func Pick(translations map[string]string, locales []string) (string, bool) {
+	for _, locale := range locales {
+		if value, ok := translations[locale]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
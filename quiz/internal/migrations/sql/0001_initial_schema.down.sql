@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS quiz_sessions;
+DROP TABLE IF EXISTS question_options;
+DROP TABLE IF EXISTS options;
+DROP TABLE IF EXISTS questions;
+DROP TABLE IF EXISTS case_parameters;
+DROP TABLE IF EXISTS cases;
+DROP TABLE IF EXISTS parameters;
+DROP TABLE IF EXISTS settings;
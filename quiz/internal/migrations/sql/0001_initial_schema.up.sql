@@ -0,0 +1,71 @@
+-- tables are created only when missing so databases created before migrations adopt this version
+CREATE TABLE IF NOT EXISTS settings (
+    name  text PRIMARY KEY,
+    value text NOT NULL
+);
+
+INSERT INTO settings (name, value) VALUES ('time_limit', '30') ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS parameters (
+    id              serial PRIMARY KEY,
+    name            text NOT NULL,
+    description     text NOT NULL DEFAULT '',
+    reference_value text NOT NULL DEFAULT '',
+    display_order   integer NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS cases (
+    id             serial PRIMARY KEY,
+    code           text NOT NULL,
+    patient_gender text NOT NULL,
+    age1           integer NOT NULL,
+    age2           integer NOT NULL,
+    age3           integer NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS case_parameters (
+    case_id      integer NOT NULL REFERENCES cases (id) ON DELETE CASCADE,
+    parameter_id integer NOT NULL REFERENCES parameters (id) ON DELETE CASCADE,
+    value_1      double precision NOT NULL,
+    value_2      double precision NOT NULL,
+    value_3      double precision,
+    PRIMARY KEY (case_id, parameter_id)
+);
+
+CREATE TABLE IF NOT EXISTS questions (
+    id             serial PRIMARY KEY,
+    question       text NOT NULL,
+    prediction_age integer NOT NULL,
+    case_id        integer NOT NULL REFERENCES cases (id),
+    group_number   integer NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS options (
+    id     serial PRIMARY KEY,
+    option text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS question_options (
+    id          serial PRIMARY KEY,
+    question_id integer NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+    option_id   integer NOT NULL REFERENCES options (id) ON DELETE CASCADE,
+    is_correct  boolean NOT NULL DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS quiz_sessions (
+    id                      serial PRIMARY KEY,
+    user_id                 integer NOT NULL,
+    status                  text NOT NULL,
+    mode                    text NOT NULL,
+    screen_size             text NOT NULL DEFAULT '',
+    current_question        integer NOT NULL DEFAULT 0,
+    current_group           integer NOT NULL DEFAULT 0,
+    group_order             integer[] NOT NULL DEFAULT '{}',
+    question_requested_time timestamptz NOT NULL DEFAULT NOW(),
+    created_at              timestamptz NOT NULL DEFAULT NOW(),
+    updated_at              timestamptz NOT NULL DEFAULT NOW(),
+    finished_at             timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS quiz_sessions_user_id_idx ON quiz_sessions (user_id);
+CREATE INDEX IF NOT EXISTS question_options_question_id_idx ON question_options (question_id);
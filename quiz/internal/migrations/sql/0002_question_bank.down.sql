@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS translations;
+DROP TABLE IF EXISTS case_landmarks;
+DROP TABLE IF EXISTS question_versions;
+DROP INDEX IF EXISTS questions_status_idx;
+
+ALTER TABLE questions
+    DROP COLUMN IF EXISTS tolerance,
+    DROP COLUMN IF EXISTS target_parameter_id,
+    DROP COLUMN IF EXISTS type,
+    DROP COLUMN IF EXISTS status;
+
+DROP TABLE IF EXISTS question_groups;
@@ -0,0 +1,58 @@
+CREATE TABLE IF NOT EXISTS question_groups (
+    id            serial PRIMARY KEY,
+    name          text NOT NULL,
+    description   text NOT NULL DEFAULT '',
+    display_order integer NOT NULL DEFAULT 0,
+    enabled       boolean NOT NULL DEFAULT true
+);
+
+-- groups of an existing quiz were only the group numbers of its questions
+INSERT INTO question_groups (id, name, display_order)
+SELECT DISTINCT group_number, 'Group ' || group_number, group_number
+FROM questions
+WHERE group_number <> 0
+ON CONFLICT DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('question_groups', 'id'), coalesce((SELECT max(id) FROM question_groups), 0) + 1, false);
+
+ALTER TABLE questions
+    ADD COLUMN IF NOT EXISTS status              text NOT NULL DEFAULT 'published',
+    ADD COLUMN IF NOT EXISTS type                text NOT NULL DEFAULT 'choice',
+    ADD COLUMN IF NOT EXISTS target_parameter_id integer,
+    ADD COLUMN IF NOT EXISTS tolerance           double precision;
+
+CREATE INDEX IF NOT EXISTS questions_status_idx ON questions (status);
+
+-- versions keep what was shown to users, so they outlive deleted questions
+CREATE TABLE IF NOT EXISTS question_versions (
+    question_id         integer NOT NULL,
+    version             integer NOT NULL,
+    question            text NOT NULL,
+    prediction_age      integer NOT NULL,
+    options             text[] NOT NULL DEFAULT '{}',
+    correct             text NOT NULL DEFAULT '',
+    case_snapshot       jsonb NOT NULL,
+    type                text NOT NULL DEFAULT 'choice',
+    target_parameter_id integer,
+    tolerance           double precision,
+    created_at          timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (question_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS case_landmarks (
+    case_id  integer NOT NULL REFERENCES cases (id) ON DELETE CASCADE,
+    name     text NOT NULL,
+    x        double precision NOT NULL,
+    y        double precision NOT NULL,
+    position integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (case_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS translations (
+    entity    text NOT NULL,
+    entity_id integer NOT NULL,
+    field     text NOT NULL,
+    locale    text NOT NULL,
+    value     text NOT NULL,
+    PRIMARY KEY (entity, entity_id, field, locale)
+);
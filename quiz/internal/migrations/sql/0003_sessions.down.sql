@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS user_preferences;
+DROP TABLE IF EXISTS review_items;
+
+ALTER TABLE quiz_sessions
+    DROP COLUMN IF EXISTS sandbox,
+    DROP COLUMN IF EXISTS current_question_version,
+    DROP COLUMN IF EXISTS seed,
+    DROP COLUMN IF EXISTS assignment_id;
+
+DROP TABLE IF EXISTS assignment_students;
+DROP TABLE IF EXISTS assignments;
@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS assignments (
+    id            serial PRIMARY KEY,
+    teacher_id    integer NOT NULL,
+    title         text NOT NULL,
+    description   text NOT NULL DEFAULT '',
+    mode          text NOT NULL,
+    questions_ids integer[] NOT NULL DEFAULT '{}',
+    groups_ids    integer[] NOT NULL DEFAULT '{}',
+    deadline      timestamptz,
+    max_attempts  integer NOT NULL DEFAULT 0,
+    created_at    timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS assignments_teacher_id_idx ON assignments (teacher_id);
+
+CREATE TABLE IF NOT EXISTS assignment_students (
+    assignment_id integer NOT NULL REFERENCES assignments (id) ON DELETE CASCADE,
+    user_id       integer NOT NULL,
+    PRIMARY KEY (assignment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS assignment_students_user_id_idx ON assignment_students (user_id);
+
+ALTER TABLE quiz_sessions
+    ADD COLUMN IF NOT EXISTS assignment_id            integer REFERENCES assignments (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS seed                     bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS current_question_version integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS sandbox                  boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS review_items (
+    user_id          integer NOT NULL,
+    question_id      integer NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+    repetitions      integer NOT NULL DEFAULT 0,
+    interval_days    integer NOT NULL DEFAULT 0,
+    ease_factor      double precision NOT NULL,
+    due_at           timestamptz NOT NULL,
+    last_reviewed_at timestamptz,
+    PRIMARY KEY (user_id, question_id)
+);
+
+CREATE INDEX IF NOT EXISTS review_items_due_idx ON review_items (user_id, due_at);
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id integer PRIMARY KEY,
+    locale  text NOT NULL
+);
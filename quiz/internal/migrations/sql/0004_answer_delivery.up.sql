@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS answer_submissions (
+    session_id      integer NOT NULL REFERENCES quiz_sessions (id) ON DELETE CASCADE,
+    idempotency_key text NOT NULL,
+    response        jsonb NOT NULL,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (session_id, idempotency_key)
+);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              serial PRIMARY KEY,
+    type            text NOT NULL,
+    session_id      integer NOT NULL,
+    payload         jsonb NOT NULL,
+    attempts        integer NOT NULL DEFAULT 0,
+    last_error      text,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    next_attempt_at timestamptz NOT NULL DEFAULT NOW(),
+    delivered_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx ON outbox_events (next_attempt_at) WHERE delivered_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_events_session_idx ON outbox_events (session_id, id) WHERE delivered_at IS NULL;
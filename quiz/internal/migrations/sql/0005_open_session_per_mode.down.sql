@@ -0,0 +1 @@
+DROP INDEX IF EXISTS quiz_sessions_open_mode_idx;
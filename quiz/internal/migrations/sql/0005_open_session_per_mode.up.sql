@@ -0,0 +1,12 @@
+-- concurrent starts could open several sessions of a user in a mode, only the newest one is kept open
+UPDATE quiz_sessions s
+SET status = 'abandoned', updated_at = NOW()
+WHERE s.status NOT IN ('finished', 'abandoned') AND s.assignment_id IS NULL AND NOT s.sandbox
+  AND EXISTS (
+    SELECT 1 FROM quiz_sessions newer
+    WHERE newer.user_id = s.user_id AND newer.mode = s.mode AND newer.id > s.id
+      AND newer.status NOT IN ('finished', 'abandoned') AND newer.assignment_id IS NULL AND NOT newer.sandbox
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS quiz_sessions_open_mode_idx ON quiz_sessions (user_id, mode)
+    WHERE status NOT IN ('finished', 'abandoned') AND assignment_id IS NULL AND NOT sandbox;
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"time"
+)
+
+// Assignment is a quiz a teacher shares with chosen students. Its questions are the listed questions followed by
+// the questions of the listed groups, asked in this order. MaxAttempts 0 allows unlimited attempts
+type Assignment struct {
+	ID             int        `json:"id"`
+	TeacherID      int        `json:"teacher_id"`
+	Title          string     `json:"title" validate:"required"`
+	Description    string     `json:"description"`
+	Mode           QuizMode   `json:"mode" validate:"required,oneof=educational classic limited_time"`
+	QuestionsIDs   []int      `json:"questions_ids"`
+	GroupsIDs      []int      `json:"groups_ids"`
+	Deadline       time.Time  `json:"deadline" validate:"required"`
+	MaxAttempts    int        `json:"max_attempts" validate:"gte=0"`
+	StudentsIDs    []int      `json:"students_ids"`
+	QuestionsCount int        `json:"questions_count"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+}
+
+// StudentAssignment is an assignment as seen by one of its students
+type StudentAssignment struct {
+	ID           int       `json:"id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	Mode         QuizMode  `json:"mode"`
+	Deadline     time.Time `json:"deadline"`
+	MaxAttempts  int       `json:"max_attempts"`
+	AttemptsUsed int       `json:"attempts_used"`
+	Completed    bool      `json:"completed"`
+}
+
+// AssignmentSession is a quiz session started by a student for an assignment
+type AssignmentSession struct {
+	SessionID  int        `json:"session_id"`
+	UserID     int        `json:"user_id"`
+	Status     QuizStatus `json:"status"`
+	CreatedAt  *time.Time `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (a *Assignment) Validate() error {
+	return validator.New().Struct(a)
+}
+func (a *Assignment) ToJSON(writer io.Writer) error {
+	return json.NewEncoder(writer).Encode(a)
+}
+func (a *Assignment) FromJSON(reader io.Reader) error {
+	return json.NewDecoder(reader).Decode(a)
+}
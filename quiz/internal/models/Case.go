@@ -2,20 +2,26 @@ package models
 
 import (
 	"encoding/json"
+	"github.com/go-playground/validator/v10"
 	"io"
 )
 
 type Case struct {
 	ID              int              `json:"id"`
-	Code            string           `json:"code"`
+	Code            string           `json:"code" validate:"required"`
 	Gender          string           `json:"gender"`
-	Age1            int              `json:"age1"`
-	Age2            int              `json:"age2"`
-	Age3            int              `json:"age3"`
+	Age1            int              `json:"age1" validate:"gte=0"`
+	Age2            int              `json:"age2" validate:"gte=0"`
+	Age3            int              `json:"age3" validate:"gte=0"`
 	Parameters      []Parameter      `json:"parameters"`
 	ParameterValues []ParameterValue `json:"parameters_values"`
+	// Landmarks are the reference landmarks on the final image, hidden when a question is served
+	Landmarks []Landmark `json:"landmarks,omitempty"`
 }
 
+func (c *Case) Validate() error {
+	return validator.New().Struct(c)
+}
 func (c *Case) ToJSON(writer io.Writer) error {
 	return json.NewEncoder(writer).Encode(c)
 }
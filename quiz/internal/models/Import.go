@@ -0,0 +1,129 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// BundleVersion is the version of the manifest format written by the question bank export,
+// version 2 added the status of questions
+const BundleVersion = 2
+
+// ImportBundle is the content of an import archive manifest. Parameters and options are referenced by name
+// and resolved against the database before the import, the ones listed in the bundle are created when missing.
+// Questions reference groups by ID, which are IDs of the bundle groups when the bundle has groups
+type ImportBundle struct {
+	Version    int               `json:"version,omitempty"`
+	ExportedAt *time.Time        `json:"exported_at,omitempty"`
+	Parameters []ImportParameter `json:"parameters,omitempty"`
+	Options    []ImportOption    `json:"options,omitempty"`
+	Groups     []ImportGroup     `json:"groups,omitempty"`
+	Settings   []ImportSetting   `json:"settings,omitempty"`
+	Cases      []ImportCase      `json:"cases"`
+	Questions  []ImportQuestion  `json:"questions"`
+	// Files holds all archive entries other than the manifest, keyed by their path
+	Files map[string][]byte `json:"-"`
+	// Skipped lists archive entries which could not be imported, like unsupported QTI items
+	Skipped []string `json:"-"`
+}
+
+type ImportParameter struct {
+	ID              int    `json:"-"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	ReferenceValues string `json:"reference_values"`
+	Order           int    `json:"order"`
+	Image           string `json:"image,omitempty"`
+}
+
+type ImportOption struct {
+	ID     int    `json:"-"`
+	Option string `json:"option"`
+}
+
+type ImportGroup struct {
+	ID          int    `json:"id"`
+	GroupID     int    `json:"-"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Order       int    `json:"order"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type ImportSetting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type ImportCase struct {
+	ID         int                    `json:"-"`
+	Code       string                 `json:"code"`
+	Gender     string                 `json:"gender"`
+	Age1       int                    `json:"age1"`
+	Age2       int                    `json:"age2"`
+	Age3       int                    `json:"age3"`
+	Parameters []ImportParameterValue `json:"parameters"`
+	Landmarks  []Landmark             `json:"landmarks,omitempty"`
+}
+
+type ImportParameterValue struct {
+	ParameterID int      `json:"-"`
+	Parameter   string   `json:"parameter"`
+	Value1      float64  `json:"value1"`
+	Value2      float64  `json:"value2"`
+	Value3      *float64 `json:"value3,omitempty"`
+}
+
+type ImportQuestion struct {
+	ID              int      `json:"-"`
+	CaseID          int      `json:"-"`
+	OptionsIDs      []int    `json:"-"`
+	CorrectOptionID int      `json:"-"`
+	GroupID         int      `json:"-"`
+	CaseCode        string   `json:"case_code"`
+	Question        string   `json:"question"`
+	PredictionAge   int      `json:"prediction_age"`
+	Group           int      `json:"group"`
+	Options         []string `json:"options"`
+	Correct         string   `json:"correct"`
+	Images          []string `json:"images"`
+	// numeric questions reference their target parameter by name, see Question.Type
+	Type              string   `json:"type,omitempty"`
+	TargetParameterID int      `json:"-"`
+	TargetParameter   string   `json:"target_parameter,omitempty"`
+	Tolerance         *float64 `json:"tolerance,omitempty"`
+	// Status is the lifecycle status of the question, questions without a status are imported as drafts
+	Status string `json:"status,omitempty"`
+}
+
+func (b *ImportBundle) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(b)
+}
+
+func (b *ImportBundle) ToJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(b)
+}
+
+// ImportReport describes changes made by an import, or changes that would be made in dry run mode
+type ImportReport struct {
+	DryRun            bool     `json:"dry_run"`
+	CasesCreated      []string `json:"cases_created"`
+	CasesReused       []string `json:"cases_reused"`
+	ParametersCreated []string `json:"parameters_created"`
+	OptionsCreated    []string `json:"options_created"`
+	GroupsCreated     []string `json:"groups_created"`
+	SettingsUpdated   int      `json:"settings_updated"`
+	QuestionsCreated  int      `json:"questions_created"`
+	QuestionsIDs      []int    `json:"questions_ids,omitempty"`
+	OptionLinks       int      `json:"option_links"`
+	Images            int      `json:"images"`
+	Skipped           []string `json:"skipped,omitempty"`
+	Errors            []string `json:"errors"`
+}
+
+func (r *ImportReport) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
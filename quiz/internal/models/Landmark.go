@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"io"
+)
+
+// Landmark is a cephalometric landmark on the final image of a case, coordinates are in pixels of the image
+type Landmark struct {
+	Name string  `json:"name" validate:"required"`
+	X    float64 `json:"x" validate:"gte=0"`
+	Y    float64 `json:"y" validate:"gte=0"`
+}
+
+// PlacedLandmark is a landmark placed by the user, Error is its distance from the reference landmark
+type PlacedLandmark struct {
+	Name  string  `json:"name"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Error float64 `json:"error"`
+}
+
+// CaseLandmarks are the reference landmarks of a case, answers to landmark questions are scored against them
+type CaseLandmarks struct {
+	Landmarks []Landmark `json:"landmarks" validate:"dive"`
+}
+
+func (c *CaseLandmarks) Validate() error {
+	if err := validator.New().Struct(c); err != nil {
+		return err
+	}
+	names := make(map[string]bool, len(c.Landmarks))
+	for _, landmark := range c.Landmarks {
+		if names[landmark.Name] {
+			return fmt.Errorf("duplicate landmark %s", landmark.Name)
+		}
+		names[landmark.Name] = true
+	}
+	return nil
+}
+func (c *CaseLandmarks) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(c)
+}
@@ -2,7 +2,10 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"github.com/go-playground/validator/v10"
 	"io"
+	"slices"
 )
 
 type Question struct {
@@ -13,6 +16,112 @@ type Question struct {
 	Case          Case     `json:"case"`
 	Correct       *string  `json:"correct"`
 	Group         int      `json:"group"`
+	Status        string   `json:"status"`
+	// Type is choice for questions answered with one of the options, numeric questions
+	// ask for the hidden value3 of the target parameter of the case and landmark questions
+	// for the landmarks of the case placed on its final image
+	Type              string   `json:"type"`
+	TargetParameterID *int     `json:"target_parameter_id,omitempty"`
+	Tolerance         *float64 `json:"tolerance,omitempty"`
+	// Landmarks are the names of the landmarks to place, sent instead of the reference landmarks of the case
+	Landmarks []string `json:"landmarks,omitempty"`
+}
+
+// Question types
+const (
+	QuestionTypeChoice    = "choice"
+	QuestionTypeNumeric   = "numeric"
+	QuestionTypeLandmarks = "landmarks"
+)
+
+// TargetValue returns the value3 of the target parameter in the case, the value predicted in numeric questions
+func (q *Question) TargetValue() (float64, bool) {
+	return targetValue(q.Case, q.TargetParameterID)
+}
+
+func targetValue(c Case, parameterID *int) (float64, bool) {
+	if parameterID == nil {
+		return 0, false
+	}
+	for _, value := range c.ParameterValues {
+		if value.ParameterID == *parameterID && value.Value3 != nil {
+			return *value.Value3, true
+		}
+	}
+	return 0, false
+}
+
+// ValidateQuestionType checks the numeric prediction settings of a question, an empty type is a choice question
+func ValidateQuestionType(questionType string, targetParameterID *int, tolerance *float64) error {
+	switch questionType {
+	case "", QuestionTypeChoice:
+		return nil
+	case QuestionTypeNumeric:
+		if targetParameterID == nil {
+			return fmt.Errorf("numeric questions require a target parameter")
+		}
+		if tolerance == nil || *tolerance <= 0 {
+			return fmt.Errorf("numeric questions require a positive tolerance")
+		}
+		return nil
+	case QuestionTypeLandmarks:
+		if tolerance == nil || *tolerance <= 0 {
+			return fmt.Errorf("landmark questions require a positive tolerance in pixels")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown question type %s", questionType)
+	}
+}
+
+// Question statuses, only published questions are asked in quizzes
+const (
+	QuestionStatusDraft     = "draft"
+	QuestionStatusInReview  = "in_review"
+	QuestionStatusPublished = "published"
+	QuestionStatusArchived  = "archived"
+)
+
+// questionStatusTransitions lists statuses a question may move to from each status
+var questionStatusTransitions = map[string][]string{
+	QuestionStatusDraft:     {QuestionStatusInReview},
+	QuestionStatusInReview:  {QuestionStatusDraft, QuestionStatusPublished},
+	QuestionStatusPublished: {QuestionStatusArchived},
+	QuestionStatusArchived:  {QuestionStatusDraft},
+}
+
+// ValidQuestionStatus reports whether status is one of the question statuses
+func ValidQuestionStatus(status string) bool {
+	_, ok := questionStatusTransitions[status]
+	return ok
+}
+
+// CanTransitionQuestion reports whether a question in status from may be moved to status to
+func CanTransitionQuestion(from string, to string) bool {
+	return slices.Contains(questionStatusTransitions[from], to)
+}
+
+type QuestionStatusPayload struct {
+	Status string `json:"status" validate:"required,oneof=draft in_review published archived"`
+}
+
+func (p *QuestionStatusPayload) Validate() error {
+	return validator.New().Struct(p)
+}
+func (p *QuestionStatusPayload) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(p)
+}
+
+// PreviewPayload asks for a sandbox session for the user previewing a question
+type PreviewPayload struct {
+	UserID int `json:"user_id" validate:"required"`
+}
+
+func (p *PreviewPayload) Validate() error {
+	return validator.New().Struct(p)
+}
+func (p *PreviewPayload) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(p)
 }
 
 func (q *Question) ToJSON(w io.Writer) error {
@@ -23,12 +132,15 @@ func (q *Question) FromJSON(r io.Reader) error {
 }
 
 type QuestionPayload struct {
-	ID            int      `json:"id,omitempty"`
-	Question      string   `json:"question"`
-	Answers       []string `json:"answers"`
-	PredictionAge int      `json:"prediction_age"`
-	CaseID        int      `json:"case_id"`
-	Group         int      `json:"group"`
+	ID                int      `json:"id,omitempty"`
+	Question          string   `json:"question"`
+	Answers           []string `json:"answers"`
+	PredictionAge     int      `json:"prediction_age"`
+	CaseID            int      `json:"case_id"`
+	Group             int      `json:"group"`
+	Type              string   `json:"type"`
+	TargetParameterID *int     `json:"target_parameter_id,omitempty"`
+	Tolerance         *float64 `json:"tolerance,omitempty"`
 }
 
 func (q *QuestionPayload) ToJSON(w io.Writer) error {
@@ -39,10 +151,43 @@ func (q *QuestionPayload) FromJSON(r io.Reader) error {
 }
 
 type QuestionAnswer struct {
-	QuestionID int    `json:"question_id"`
-	Answer     string `json:"answer"`
-	IsCorrect  bool   `json:"is_correct"`
-	ScreenSize string `json:"screen_size"`
-	TimeSpent  int    `json:"time_spent"`
-	CaseCode   string `json:"case_code"`
+	QuestionID int `json:"question_id"`
+	// QuestionVersion is the version of the question the answer was given to
+	QuestionVersion int    `json:"question_version"`
+	Answer          string `json:"answer"`
+	IsCorrect       bool   `json:"is_correct"`
+	ScreenSize      string `json:"screen_size"`
+	TimeSpent       int    `json:"time_spent"`
+	CaseCode        string `json:"case_code"`
+	TimedOut        bool   `json:"timed_out"`
+	// Position is the index of the question in the group order of the session
+	Position int `json:"position"`
+	// PredictionError and Score grade answers to numeric questions, see scoring.Numeric
+	PredictionError *float64 `json:"prediction_error,omitempty"`
+	Score           *float64 `json:"score,omitempty"`
+	// Points are the landmarks placed in answers to landmark questions, see scoring.Landmarks
+	Points       []PlacedLandmark `json:"points,omitempty"`
+	MeanDistance *float64         `json:"mean_distance,omitempty"`
+	// Confidence is how sure the user is of the answer, from MinConfidence to MaxConfidence, nil when not rated
+	Confidence *int `json:"confidence,omitempty"`
+	// IdempotencyKey identifies the submission, retries of the same submission carry the same key
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// maxIdempotencyKeyLength bounds the keys clients generate, a UUID fits easily
+const maxIdempotencyKeyLength = 128
+
+// Confidence ratings users may submit with their answers
+const (
+	MinConfidence = 1
+	MaxConfidence = 5
+)
+
+func (a *QuestionAnswer) ValidIdempotencyKey() bool {
+	return a.IdempotencyKey != "" && len(a.IdempotencyKey) <= maxIdempotencyKeyLength
+}
+
+// ValidConfidence reports whether the confidence rating is missing or within the scale
+func (a *QuestionAnswer) ValidConfidence() bool {
+	return a.Confidence == nil || (*a.Confidence >= MinConfidence && *a.Confidence <= MaxConfidence)
 }
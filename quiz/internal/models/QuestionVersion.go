@@ -0,0 +1,144 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// QuestionVersion is an immutable snapshot of a question as it was served to users. A new version is recorded
+// whenever the question, its options, correct option or case differ from the latest version
+type QuestionVersion struct {
+	QuestionID        int       `json:"question_id"`
+	Version           int       `json:"version"`
+	Question          string    `json:"question"`
+	PredictionAge     int       `json:"prediction_age"`
+	Options           []string  `json:"options"`
+	Correct           string    `json:"correct"`
+	Case              Case      `json:"case"`
+	CreatedAt         time.Time `json:"created_at"`
+	Type              string    `json:"type"`
+	TargetParameterID *int      `json:"target_parameter_id,omitempty"`
+	Tolerance         *float64  `json:"tolerance,omitempty"`
+}
+
+// TargetValue returns the value3 of the target parameter in the case snapshot
+func (v *QuestionVersion) TargetValue() (float64, bool) {
+	return targetValue(v.Case, v.TargetParameterID)
+}
+
+// QuestionChange is a field which differs between two versions of a question
+type QuestionChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type QuestionVersionsDiff struct {
+	QuestionID  int              `json:"question_id"`
+	FromVersion int              `json:"from_version"`
+	ToVersion   int              `json:"to_version"`
+	Changes     []QuestionChange `json:"changes"`
+}
+
+// SameContent reports whether both versions have the same content, version numbers and dates are not compared
+func (v *QuestionVersion) SameContent(other QuestionVersion) bool {
+	return len(v.Diff(other)) == 0
+}
+
+// Diff lists fields changed from v to other, parameter values of the case are matched by parameter ID
+func (v *QuestionVersion) Diff(other QuestionVersion) []QuestionChange {
+	changes := []QuestionChange{}
+	add := func(field string, from interface{}, to interface{}) {
+		changes = append(changes, QuestionChange{Field: field, From: from, To: to})
+	}
+	if v.Question != other.Question {
+		add("question", v.Question, other.Question)
+	}
+	if v.PredictionAge != other.PredictionAge {
+		add("prediction_age", v.PredictionAge, other.PredictionAge)
+	}
+	if !slices.Equal(v.Options, other.Options) {
+		add("options", v.Options, other.Options)
+	}
+	if v.Correct != other.Correct {
+		add("correct", v.Correct, other.Correct)
+	}
+	if v.Type != other.Type {
+		add("type", v.Type, other.Type)
+	}
+	if !equalPointers(v.TargetParameterID, other.TargetParameterID) {
+		add("target_parameter_id", v.TargetParameterID, other.TargetParameterID)
+	}
+	if !equalPointers(v.Tolerance, other.Tolerance) {
+		add("tolerance", v.Tolerance, other.Tolerance)
+	}
+	if v.Case.ID != other.Case.ID {
+		add("case.id", v.Case.ID, other.Case.ID)
+	}
+	if v.Case.Code != other.Case.Code {
+		add("case.code", v.Case.Code, other.Case.Code)
+	}
+	if v.Case.Gender != other.Case.Gender {
+		add("case.gender", v.Case.Gender, other.Case.Gender)
+	}
+	if v.Case.Age1 != other.Case.Age1 {
+		add("case.age1", v.Case.Age1, other.Case.Age1)
+	}
+	if v.Case.Age2 != other.Case.Age2 {
+		add("case.age2", v.Case.Age2, other.Case.Age2)
+	}
+	if v.Case.Age3 != other.Case.Age3 {
+		add("case.age3", v.Case.Age3, other.Case.Age3)
+	}
+	if !slices.Equal(v.Case.Landmarks, other.Case.Landmarks) {
+		add("case.landmarks", v.Case.Landmarks, other.Case.Landmarks)
+	}
+
+	values := make(map[int]ParameterValue)
+	for _, value := range v.Case.ParameterValues {
+		values[value.ParameterID] = value
+	}
+	otherValues := make(map[int]ParameterValue)
+	for _, value := range other.Case.ParameterValues {
+		otherValues[value.ParameterID] = value
+	}
+	parametersIDs := make([]int, 0, len(values)+len(otherValues))
+	for id := range values {
+		parametersIDs = append(parametersIDs, id)
+	}
+	for id := range otherValues {
+		if _, ok := values[id]; !ok {
+			parametersIDs = append(parametersIDs, id)
+		}
+	}
+	slices.Sort(parametersIDs)
+	for _, id := range parametersIDs {
+		from, inV := values[id]
+		to, inOther := otherValues[id]
+		field := fmt.Sprintf("case.parameters_values[%d]", id)
+		switch {
+		case !inOther:
+			add(field, from, nil)
+		case !inV:
+			add(field, nil, to)
+		case !sameParameterValue(from, to):
+			add(field, from, to)
+		}
+	}
+	return changes
+}
+
+func sameParameterValue(a ParameterValue, b ParameterValue) bool {
+	if a.Value1 != b.Value1 || a.Value2 != b.Value2 {
+		return false
+	}
+	return equalPointers(a.Value3, b.Value3)
+}
+
+func equalPointers[T comparable](a *T, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
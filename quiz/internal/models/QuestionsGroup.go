@@ -1,7 +1,36 @@
 package models
 
+import (
+	"encoding/json"
+	"github.com/go-playground/validator/v10"
+	"io"
+)
+
 // QuestionsGroup represents a group of questions
 type QuestionsGroup struct {
-	ID           int   `json:"id"`
+	ID           int    `json:"id"`
+	Name         string `json:"name" validate:"required"`
+	Description  string `json:"description"`
+	Order        int    `json:"order"`
+	Enabled      bool   `json:"enabled"`
+	QuestionsIDs []int  `json:"questions"`
+}
+
+func (g *QuestionsGroup) Validate() error {
+	return validator.New().Struct(g)
+}
+func (g *QuestionsGroup) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(g)
+}
+func (g *QuestionsGroup) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(g)
+}
+
+// GroupQuestionsPayload lists questions to be moved into a group
+type GroupQuestionsPayload struct {
 	QuestionsIDs []int `json:"questions"`
 }
+
+func (p *GroupQuestionsPayload) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(p)
+}
@@ -13,17 +13,21 @@ const (
 	QuizModeEducational QuizMode = "educational"
 	QuizModeClassic     QuizMode = "classic"
 	QuizModeLimitedTime QuizMode = "limited_time"
+	QuizModeAdaptive    QuizMode = "adaptive"
+	QuizModeReview      QuizMode = "review"
 )
 const (
 	QuizStatusNotStarted QuizStatus = "not_started"
 	QuizStatusInProgress QuizStatus = "in_progress"
 	QuizStatusFinished   QuizStatus = "finished"
+	// QuizStatusAbandoned is a session closed by the user before the end, it is not finished in the stats service
+	QuizStatusAbandoned QuizStatus = "abandoned"
 )
 
 type StartQuizPayload struct {
-	Mode         QuizMode `json:"mode" ,validate:"required,oneof=educational classic limited_time"`
-	ScreenWidth  int      `json:"screen_width" ,validate:"required"`
-	ScreenHeight int      `json:"screen_height" ,validate:"required"`
+	Mode         QuizMode `json:"mode" validate:"required,oneof=educational classic limited_time adaptive review"`
+	ScreenWidth  int      `json:"screen_width" validate:"required"`
+	ScreenHeight int      `json:"screen_height" validate:"required"`
 }
 
 func (p *StartQuizPayload) Validate() error {
@@ -32,3 +36,18 @@ func (p *StartQuizPayload) Validate() error {
 func (p *StartQuizPayload) FromJSON(ioReader io.Reader) error {
 	return json.NewDecoder(ioReader).Decode(p)
 }
+
+// AdaptiveProfile is the ability of the user and difficulties of answered questions estimated by the stats service
+type AdaptiveProfile struct {
+	UserID      int                  `json:"user_id"`
+	Ability     float64              `json:"ability"`
+	UserAnswers int                  `json:"user_answers"`
+	Questions   []QuestionDifficulty `json:"questions"`
+}
+
+type QuestionDifficulty struct {
+	QuestionID  int     `json:"question_id"`
+	Difficulty  float64 `json:"difficulty"`
+	Answers     int     `json:"answers"`
+	UserAnswers int     `json:"user_answers"`
+}
@@ -6,19 +6,35 @@ import (
 	"time"
 )
 
+// QuizSession keeps the position of the user in the quiz. In adaptive and review modes questions are not grouped,
+// CurrentGroup is 0 and GroupOrder holds the questions already asked in the session. Sessions of an assignment
+// are not grouped either, GroupOrder holds all questions of the assignment. A user may have one open session
+// per mode and one per assignment
 type QuizSession struct {
-	ID                    int        `json:"session_id"`
-	UserID                int        `json:"user_id"`
-	Mode                  QuizMode   `json:"quiz_mode"`
-	Status                QuizStatus `json:"-"`
-	ScreenSize            string     `json:"-"`
-	CurrentQuestionID     int        `json:"-"`
-	CurrentGroup          int        `json:"-"`
-	GroupOrder            []int      `json:"-"`
-	CreatedAt             *time.Time `json:"-"`
-	UpdatedAt             *time.Time `json:"-"`
-	FinishedAt            *time.Time `json:"-"`
-	QuestionRequestedTime time.Time  `json:"-"`
+	ID                int        `json:"session_id"`
+	UserID            int        `json:"user_id"`
+	Mode              QuizMode   `json:"quiz_mode"`
+	Status            QuizStatus `json:"status"`
+	ScreenSize        string     `json:"-"`
+	CurrentQuestionID int        `json:"-"`
+	// CurrentQuestionVersion is the version of the current question served to the user, 0 until it is requested
+	CurrentQuestionVersion int        `json:"-"`
+	CurrentGroup           int        `json:"-"`
+	GroupOrder             []int      `json:"-"`
+	CreatedAt              *time.Time `json:"created_at,omitempty"`
+	UpdatedAt              *time.Time `json:"updated_at,omitempty"`
+	FinishedAt             *time.Time `json:"-"`
+	QuestionRequestedTime  time.Time  `json:"-"`
+	AssignmentID           *int       `json:"assignment_id,omitempty"`
+	// Sandbox sessions preview a single question for its author, they are not recorded in stats
+	Sandbox bool `json:"sandbox,omitempty"`
+	// Seed determines the order of questions within groups, see ordering.GroupOrder
+	Seed int64 `json:"seed"`
+}
+
+// IsClosed reports whether the session was finished or abandoned, closed sessions cannot be resumed
+func (qs *QuizSession) IsClosed() bool {
+	return qs.Status == QuizStatusFinished || qs.Status == QuizStatusAbandoned
 }
 
 func (qs *QuizSession) ToJSON(writer io.Writer) error {
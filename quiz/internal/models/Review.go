@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ReviewItem is the spaced repetition schedule of a question the user has answered incorrectly
+type ReviewItem struct {
+	UserID         int        `json:"user_id"`
+	QuestionID     int        `json:"question_id"`
+	Repetitions    int        `json:"repetitions"`
+	IntervalDays   int        `json:"interval_days"`
+	EaseFactor     float64    `json:"ease_factor"`
+	DueAt          time.Time  `json:"due_at"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at,omitempty"`
+}
+
+// IncorrectQuestion is a question the user has answered incorrectly outside review sessions, MissedAt is the time
+// of the last incorrect answer
+type IncorrectQuestion struct {
+	QuestionID int       `json:"question_id"`
+	MissedAt   time.Time `json:"missed_at"`
+}
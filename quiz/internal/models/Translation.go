@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"quiz/internal/i18n"
+	"slices"
+)
+
+// Translatable entities
+const (
+	TranslationEntityQuestion  = "question"
+	TranslationEntityOption    = "option"
+	TranslationEntityParameter = "parameter"
+)
+
+// TranslatableFields lists the fields of each entity which can be translated
+var TranslatableFields = map[string][]string{
+	TranslationEntityQuestion:  {"question"},
+	TranslationEntityOption:    {"option"},
+	TranslationEntityParameter: {"name", "description"},
+}
+
+// Translation is the text of a field of a question, option or parameter in a locale
+type Translation struct {
+	Entity   string `json:"entity" validate:"required,oneof=question option parameter"`
+	EntityID int    `json:"entity_id" validate:"required"`
+	Field    string `json:"field" validate:"required"`
+	Locale   string `json:"locale" validate:"required"`
+	Value    string `json:"value" validate:"required"`
+}
+
+func (t *Translation) Validate() error {
+	if err := validator.New().Struct(t); err != nil {
+		return err
+	}
+	if !slices.Contains(TranslatableFields[t.Entity], t.Field) {
+		return fmt.Errorf("field %s of %s cannot be translated", t.Field, t.Entity)
+	}
+	if !i18n.Valid(t.Locale) {
+		return fmt.Errorf("invalid locale %s", t.Locale)
+	}
+	if t.Locale == i18n.DefaultLocale {
+		return fmt.Errorf("%s is the locale of the original texts", t.Locale)
+	}
+	return nil
+}
+func (t *Translation) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(t)
+}
+
+// MissingTranslation is a field without a translation in the requested locale, Source is the original text
+type MissingTranslation struct {
+	Entity   string `json:"entity"`
+	EntityID int    `json:"entity_id"`
+	Field    string `json:"field"`
+	Source   string `json:"source"`
+}
+
+// UserPreferences are settings of the user, an empty locale negotiates the language from Accept-Language
+type UserPreferences struct {
+	Locale string `json:"locale"`
+}
+
+func (p *UserPreferences) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(p)
+}
@@ -0,0 +1,26 @@
+package ordering
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+// NewSeed returns a random seed for a new quiz session
+func NewSeed() int64 {
+	return rand.Int64()
+}
+
+// GroupOrder returns the questions of the group in the order they are asked in a session with the given seed.
+// The order depends only on the seed, the group and the set of questions, so it can be reconstructed later
+// for the analysis of order effects. The Fisher-Yates shuffle is implemented here on top of PCG, which has a
+// fixed specification, so the order does not change with the Go version.
+func GroupOrder(questionsIDs []int, seed int64, groupID int) []int {
+	order := slices.Clone(questionsIDs)
+	slices.Sort(order)
+	source := rand.NewPCG(uint64(seed), uint64(groupID))
+	for i := len(order) - 1; i > 0; i-- {
+		j := int(source.Uint64() % uint64(i+1))
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
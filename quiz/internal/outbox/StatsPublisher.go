@@ -0,0 +1,48 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"quiz/internal/clients"
+	"quiz/internal/events"
+	"quiz/internal/models"
+)
+
+// StatsPublisher delivers quiz events directly to the REST API of the stats service,
+// it is used when no event bus is configured
+type StatsPublisher struct {
+	statsClient clients.StatsService
+}
+
+func NewStatsPublisher(statsClient clients.StatsService) *StatsPublisher {
+	return &StatsPublisher{statsClient: statsClient}
+}
+
+func (p *StatsPublisher) Publish(_ context.Context, eventType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	switch eventType {
+	case events.QuizStarted:
+		var session models.QuizSession
+		if err = json.Unmarshal(data, &session); err != nil {
+			return err
+		}
+		return p.statsClient.SaveSession(session)
+	case events.AnswerSubmitted:
+		var submitted events.AnswerSubmittedPayload
+		if err = json.Unmarshal(data, &submitted); err != nil {
+			return err
+		}
+		return p.statsClient.SaveResponse(submitted.SessionID, submitted.Answer)
+	case events.QuizFinished:
+		var finished events.SessionPayload
+		if err = json.Unmarshal(data, &finished); err != nil {
+			return err
+		}
+		return p.statsClient.FinishSession(finished.SessionID)
+	}
+	// other events have no counterpart in the stats API
+	return nil
+}
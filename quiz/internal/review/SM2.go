@@ -0,0 +1,67 @@
+package review
+
+import (
+	"fmt"
+	"math"
+	"quiz/internal/models"
+	"time"
+)
+
+const (
+	// InitialEaseFactor is the ease factor of a question entering the review queue
+	InitialEaseFactor = 2.5
+	// minEaseFactor keeps intervals of hard questions growing
+	minEaseFactor = 1.3
+	// passingQuality is the lowest quality of an answer which counts as recalled
+	passingQuality = 3
+
+	qualityCorrect   = 4
+	qualityIncorrect = 1
+)
+
+var ErrNothingDue = fmt.Errorf("no questions due for review")
+
+// NewItem returns the schedule of a question entering the review queue, it is due from the given time,
+// the time the question was missed
+func NewItem(userID int, questionID int, dueAt time.Time) models.ReviewItem {
+	return models.ReviewItem{
+		UserID:     userID,
+		QuestionID: questionID,
+		EaseFactor: InitialEaseFactor,
+		DueAt:      dueAt,
+	}
+}
+
+// Quality grades an answer on the 0-5 scale of SM-2. The quiz only knows whether the answer was correct,
+// so a correct answer is graded as recalled with some effort and an incorrect one as not recalled
+func Quality(correct bool) int {
+	if correct {
+		return qualityCorrect
+	}
+	return qualityIncorrect
+}
+
+// Schedule applies the SM-2 algorithm to the item after an answer of the given quality. A failed recall starts
+// the repetitions again with an interval of one day, successful ones extend the interval by the ease factor
+func Schedule(item models.ReviewItem, quality int, now time.Time) models.ReviewItem {
+	quality = max(0, min(5, quality))
+	if quality < passingQuality {
+		item.Repetitions = 0
+		item.IntervalDays = 1
+	} else {
+		switch item.Repetitions {
+		case 0:
+			item.IntervalDays = 1
+		case 1:
+			item.IntervalDays = 6
+		default:
+			item.IntervalDays = int(math.Round(float64(item.IntervalDays) * item.EaseFactor))
+		}
+		item.Repetitions++
+	}
+	lapse := float64(5 - quality)
+	item.EaseFactor = max(minEaseFactor, item.EaseFactor+0.1-lapse*(0.08+lapse*0.02))
+	item.DueAt = now.AddDate(0, 0, item.IntervalDays)
+	item.LastReviewedAt = &now
+	return item
+}
@@ -0,0 +1,51 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"quiz/internal/models"
+)
+
+var ErrInvalidLandmarks = fmt.Errorf("placed landmarks do not match the landmarks of the case")
+
+// LandmarksResult is the grade of landmarks placed on the final image of a case
+type LandmarksResult struct {
+	// Points are the placed landmarks in the order of the reference landmarks with their errors
+	Points       []models.PlacedLandmark
+	MeanDistance float64
+	// Score is the mean of the landmark scores, which fall linearly from 1 for an exact placement to 0 at the tolerance
+	Score float64
+	// Correct answers have the mean distance within the tolerance
+	Correct bool
+}
+
+// Landmarks grades placed landmarks against the reference landmarks matched by name,
+// each reference landmark has to be placed exactly once
+func Landmarks(placed []models.PlacedLandmark, reference []models.Landmark, tolerance float64) (LandmarksResult, error) {
+	var result LandmarksResult
+	if len(reference) == 0 || len(placed) != len(reference) {
+		return result, ErrInvalidLandmarks
+	}
+	placedByName := make(map[string]models.PlacedLandmark, len(placed))
+	for _, point := range placed {
+		if _, ok := placedByName[point.Name]; ok {
+			return result, fmt.Errorf("%w: %s placed twice", ErrInvalidLandmarks, point.Name)
+		}
+		placedByName[point.Name] = point
+	}
+	var totalDistance, totalScore float64
+	for _, landmark := range reference {
+		point, ok := placedByName[landmark.Name]
+		if !ok {
+			return result, fmt.Errorf("%w: %s not placed", ErrInvalidLandmarks, landmark.Name)
+		}
+		point.Error = math.Hypot(point.X-landmark.X, point.Y-landmark.Y)
+		totalDistance += point.Error
+		totalScore += Numeric(point.Error, 0, tolerance).Score
+		result.Points = append(result.Points, point)
+	}
+	result.MeanDistance = totalDistance / float64(len(reference))
+	result.Score = totalScore / float64(len(reference))
+	result.Correct = result.MeanDistance <= tolerance
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPrediction = fmt.Errorf("prediction is not a number")
+
+// NumericResult is the grade of a numeric prediction
+type NumericResult struct {
+	// Error is the signed difference between the prediction and the target value
+	Error float64
+	// Score falls linearly from 1 for an exact prediction to 0 at the tolerance
+	Score float64
+	// Correct predictions are within the tolerance of the target value
+	Correct bool
+}
+
+// ParsePrediction reads a numeric answer, both dot and comma are accepted as the decimal separator
+func ParsePrediction(answer string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(answer), ",", "."), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, ErrInvalidPrediction
+	}
+	return value, nil
+}
+
+// Numeric grades the prediction against the target value with an absolute tolerance
+func Numeric(prediction float64, target float64, tolerance float64) NumericResult {
+	result := NumericResult{Error: prediction - target}
+	distance := math.Abs(result.Error)
+	result.Correct = distance <= tolerance
+	if tolerance > 0 {
+		result.Score = math.Max(0, 1-distance/tolerance)
+	} else if distance == 0 {
+		result.Score = 1
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"quiz/internal/models"
+	"slices"
+	"sync"
+)
+
+// CachedStore is a Store keeping questions read by GetQuestionByID in memory. Questions are read on every
+// request of the next question while they change only when authors edit the question bank, so cached questions
+// are dropped by the methods changing a question, its options, case, parameters or group. The cache is local
+// to the process, all changes of the question bank must go through the same CachedStore
+type CachedStore struct {
+	Store
+	mu        sync.Mutex
+	questions map[int]models.Question
+	// generation is increased by every invalidation, a question read before an invalidation is not cached
+	generation uint64
+}
+
+var _ Store = (*CachedStore)(nil)
+
+func NewCachedStore(store Store) *CachedStore {
+	return &CachedStore{
+		Store:     store,
+		questions: make(map[int]models.Question),
+	}
+}
+
+// GetQuestionByID returns a copy of the cached question, the question is read from the store when it is not cached
+func (s *CachedStore) GetQuestionByID(ctx context.Context, id int) (models.Question, error) {
+	s.mu.Lock()
+	question, ok := s.questions[id]
+	generation := s.generation
+	s.mu.Unlock()
+	if ok {
+		return cloneQuestion(question), nil
+	}
+
+	question, err := s.Store.GetQuestionByID(ctx, id)
+	if err != nil {
+		return question, err
+	}
+	s.mu.Lock()
+	if generation == s.generation {
+		s.questions[id] = cloneQuestion(question)
+	}
+	s.mu.Unlock()
+	return question, nil
+}
+
+// cloneQuestion copies the slices of the question, callers change the values of parameters before sending them
+func cloneQuestion(question models.Question) models.Question {
+	question.Options = slices.Clone(question.Options)
+	question.Landmarks = slices.Clone(question.Landmarks)
+	question.Case.Parameters = slices.Clone(question.Case.Parameters)
+	question.Case.ParameterValues = slices.Clone(question.Case.ParameterValues)
+	question.Case.Landmarks = slices.Clone(question.Case.Landmarks)
+	return question
+}
+
+// invalidate drops the cached questions, all of them when no IDs are given
+func (s *CachedStore) invalidate(questionsIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	if len(questionsIDs) == 0 {
+		clear(s.questions)
+		return
+	}
+	for _, id := range questionsIDs {
+		delete(s.questions, id)
+	}
+}
+
+// Questions
+
+func (s *CachedStore) UpdateQuestionByID(ctx context.Context, questionID int, payload models.QuestionPayload) (models.QuestionPayload, error) {
+	defer s.invalidate(questionID)
+	return s.Store.UpdateQuestionByID(ctx, questionID, payload)
+}
+
+func (s *CachedStore) UpdateQuestionStatus(ctx context.Context, questionID int, from string, to string) error {
+	defer s.invalidate(questionID)
+	return s.Store.UpdateQuestionStatus(ctx, questionID, from, to)
+}
+
+func (s *CachedStore) UpdateQuestionCorrectOption(ctx context.Context, questionID int, option string) error {
+	defer s.invalidate(questionID)
+	return s.Store.UpdateQuestionCorrectOption(ctx, questionID, option)
+}
+
+func (s *CachedStore) DeleteQuestionByID(ctx context.Context, id int) error {
+	defer s.invalidate(id)
+	return s.Store.DeleteQuestionByID(ctx, id)
+}
+
+// Options, cases, parameters and groups are shared by questions, changing them drops all cached questions
+
+func (s *CachedStore) UpdateOption(ctx context.Context, id int, option models.Option) error {
+	defer s.invalidate()
+	return s.Store.UpdateOption(ctx, id, option)
+}
+
+func (s *CachedStore) DeleteOption(ctx context.Context, id int) error {
+	defer s.invalidate()
+	return s.Store.DeleteOption(ctx, id)
+}
+
+func (s *CachedStore) UpdateCase(ctx context.Context, updatedCase models.Case) (models.Case, error) {
+	defer s.invalidate()
+	return s.Store.UpdateCase(ctx, updatedCase)
+}
+
+func (s *CachedStore) CreateCaseParameter(ctx context.Context, caseID int, parameter models.ParameterValue) (models.ParameterValue, error) {
+	defer s.invalidate()
+	return s.Store.CreateCaseParameter(ctx, caseID, parameter)
+}
+
+func (s *CachedStore) UpdateCaseParameters(ctx context.Context, caseID int, parameters []models.Parameter, values []models.ParameterValue) error {
+	defer s.invalidate()
+	return s.Store.UpdateCaseParameters(ctx, caseID, parameters, values)
+}
+
+func (s *CachedStore) SaveCaseLandmarks(ctx context.Context, caseID int, landmarks []models.Landmark) error {
+	defer s.invalidate()
+	return s.Store.SaveCaseLandmarks(ctx, caseID, landmarks)
+}
+
+func (s *CachedStore) UpdateParameter(ctx context.Context, parameter models.Parameter) error {
+	defer s.invalidate()
+	return s.Store.UpdateParameter(ctx, parameter)
+}
+
+func (s *CachedStore) DeleteParameter(ctx context.Context, id int) error {
+	defer s.invalidate()
+	return s.Store.DeleteParameter(ctx, id)
+}
+
+func (s *CachedStore) UpdateParametersOrder(ctx context.Context, params []models.Parameter) error {
+	defer s.invalidate()
+	return s.Store.UpdateParametersOrder(ctx, params)
+}
+
+func (s *CachedStore) CreateGroup(ctx context.Context, group models.QuestionsGroup) (models.QuestionsGroup, error) {
+	defer s.invalidate()
+	return s.Store.CreateGroup(ctx, group)
+}
+
+func (s *CachedStore) DeleteGroup(ctx context.Context, id int) error {
+	defer s.invalidate()
+	return s.Store.DeleteGroup(ctx, id)
+}
+
+func (s *CachedStore) MoveQuestionsToGroup(ctx context.Context, groupID int, questionIDs []int) error {
+	defer s.invalidate()
+	return s.Store.MoveQuestionsToGroup(ctx, groupID, questionIDs)
+}
+
+func (s *CachedStore) ImportQuestionBank(ctx context.Context, bundle *models.ImportBundle, dryRun bool, beforeCommit func(bundle *models.ImportBundle) error) error {
+	defer s.invalidate()
+	return s.Store.ImportQuestionBank(ctx, bundle, dryRun, beforeCommit)
+}
@@ -0,0 +1,1818 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"quiz/internal/events"
+	"quiz/internal/models"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the question bank, sessions and the outbox in memory with the semantics of PostgresStorage,
+// including the references between rows and the missing rows reported as sql.ErrNoRows. It is used by tests
+type MemoryStore struct {
+	mu       sync.Mutex
+	nextID   int
+	settings map[string]string
+
+	parameters map[int]models.Parameter
+	options    map[int]string
+	cases      map[int]models.Case
+	// caseValues are the parameter values of each case, caseLandmarks its reference landmarks in the saved order
+	caseValues    map[int][]models.ParameterValue
+	caseLandmarks map[int][]models.Landmark
+	questions     map[int]memoryQuestion
+	// questionOptions are the options of questions in the order they were linked
+	questionOptions []memoryQuestionOption
+	versions        map[int][]models.QuestionVersion
+	groups          map[int]models.QuestionsGroup
+
+	sessions map[int]models.QuizSession
+	// sessionLocks serialize answer submissions to a session like the row lock taken by PostgresStorage.SubmitAnswer
+	sessionLocks map[int]*sync.Mutex
+	submissions  map[memorySubmission][]byte
+	outbox       []models.OutboxEvent
+
+	assignments  map[int]models.Assignment
+	reviewItems  map[memoryReviewKey]models.ReviewItem
+	translations map[memoryTranslationKey]string
+	locales      map[int]string
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+type memoryQuestion struct {
+	ID                int
+	Question          string
+	PredictionAge     int
+	CaseID            int
+	Group             int
+	Status            string
+	Type              string
+	TargetParameterID *int
+	Tolerance         *float64
+}
+
+type memoryQuestionOption struct {
+	questionID int
+	optionID   int
+	isCorrect  bool
+}
+
+type memorySubmission struct {
+	sessionID      int
+	idempotencyKey string
+}
+
+type memoryReviewKey struct {
+	userID     int
+	questionID int
+}
+
+type memoryTranslationKey struct {
+	entity   string
+	entityID int
+	field    string
+	locale   string
+}
+
+// NewMemoryStore returns an empty store with the settings seeded by the initial migration
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		settings:      map[string]string{"time_limit": "30"},
+		parameters:    make(map[int]models.Parameter),
+		options:       make(map[int]string),
+		cases:         make(map[int]models.Case),
+		caseValues:    make(map[int][]models.ParameterValue),
+		caseLandmarks: make(map[int][]models.Landmark),
+		questions:     make(map[int]memoryQuestion),
+		versions:      make(map[int][]models.QuestionVersion),
+		groups:        make(map[int]models.QuestionsGroup),
+		sessions:      make(map[int]models.QuizSession),
+		sessionLocks:  make(map[int]*sync.Mutex),
+		submissions:   make(map[memorySubmission][]byte),
+		assignments:   make(map[int]models.Assignment),
+		reviewItems:   make(map[memoryReviewKey]models.ReviewItem),
+		translations:  make(map[memoryTranslationKey]string),
+		locales:       make(map[int]string),
+	}
+}
+
+func (m *MemoryStore) newID() int {
+	m.nextID++
+	return m.nextID
+}
+
+// sortedKeys returns the keys of the map in ascending order
+func sortedKeys[V any](values map[int]V) []int {
+	return slices.Sorted(maps.Keys(values))
+}
+
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// Quiz Sessions
+
+func (m *MemoryStore) CreateQuizSession(ctx context.Context, session models.QuizSession) (models.QuizSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createQuizSession(session)
+}
+
+func (m *MemoryStore) CreateAssignmentSession(ctx context.Context, session models.QuizSession, maxAttempts int) (models.QuizSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assignment, ok := m.assignments[*session.AssignmentID]
+	if !ok || !slices.Contains(assignment.StudentsIDs, session.UserID) {
+		return session, ErrAssignmentNotFound
+	}
+	attempts := 0
+	for _, stored := range m.sessions {
+		if stored.UserID != session.UserID || stored.AssignmentID == nil || *stored.AssignmentID != *session.AssignmentID {
+			continue
+		}
+		if !stored.IsClosed() {
+			return session, ErrAssignmentSessionOpen
+		}
+		attempts++
+	}
+	if maxAttempts > 0 && attempts >= maxAttempts {
+		return session, ErrNoAttemptsLeft
+	}
+	return m.createQuizSession(session)
+}
+
+func (m *MemoryStore) createQuizSession(session models.QuizSession) (models.QuizSession, error) {
+	if session.AssignmentID != nil {
+		if _, ok := m.assignments[*session.AssignmentID]; !ok {
+			return session, fmt.Errorf("assignment %d does not exist", *session.AssignmentID)
+		}
+	}
+	// mirrors the unique index on the open sessions of a user in a mode
+	if session.AssignmentID == nil && !session.Sandbox {
+		for _, stored := range m.sessions {
+			if stored.UserID == session.UserID && stored.Mode == session.Mode && stored.AssignmentID == nil && !stored.Sandbox && !stored.IsClosed() {
+				return session, ErrQuizSessionOpen
+			}
+		}
+	}
+	now := time.Now()
+	session.ID = m.newID()
+	session.CreatedAt, session.UpdatedAt = &now, &now
+	stored := session
+	stored.GroupOrder = slices.Clone(session.GroupOrder)
+	stored.FinishedAt = nil
+	stored.QuestionRequestedTime = now
+	m.sessions[session.ID] = stored
+	m.sessionLocks[session.ID] = &sync.Mutex{}
+	// sandbox sessions are not recorded in stats
+	if !session.Sandbox {
+		event, err := models.NewOutboxEvent(events.QuizStarted, session.ID, session)
+		if err != nil {
+			return session, err
+		}
+		m.insertOutboxEvents([]models.OutboxEvent{event})
+	}
+	return session, nil
+}
+
+// quizSession returns a copy of the session with the columns read by PostgresStorage.GetQuizSessionByID
+func (m *MemoryStore) quizSession(id int) (models.QuizSession, error) {
+	session, ok := m.sessions[id]
+	if !ok {
+		return models.QuizSession{GroupOrder: []int{}}, sql.ErrNoRows
+	}
+	session.ScreenSize = ""
+	session.GroupOrder = slices.Clone(session.GroupOrder)
+	if session.GroupOrder == nil {
+		session.GroupOrder = []int{}
+	}
+	return session, nil
+}
+
+func (m *MemoryStore) GetQuizSessionByID(ctx context.Context, id int) (models.QuizSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.quizSession(id)
+}
+
+func (m *MemoryStore) sessionLock(id int) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.sessionLocks[id]
+	if !ok {
+		// the session does not exist, the lock only keeps callers from racing on its absence
+		lock = &sync.Mutex{}
+	}
+	return lock
+}
+
+// UpdateQuizSession runs update with the lock of the session held, see PostgresStorage.UpdateQuizSession
+func (m *MemoryStore) UpdateQuizSession(ctx context.Context, sessionID int, update func(session *models.QuizSession) ([]models.OutboxEvent, error)) error {
+	lock := m.sessionLock(sessionID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	m.mu.Lock()
+	session, err := m.quizSession(sessionID)
+	m.mu.Unlock()
+	if err == sql.ErrNoRows {
+		return ErrQuizSessionNotFound
+	}
+	events, err := update(&session)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updateQuizSession(session)
+	m.insertOutboxEvents(events)
+	return nil
+}
+
+// updateQuizSession saves the columns changed by PostgresStorage.UpdateQuizSession, a missing session is not an error
+func (m *MemoryStore) updateQuizSession(session models.QuizSession) {
+	stored, ok := m.sessions[session.ID]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	stored.Status = session.Status
+	stored.Mode = session.Mode
+	stored.CurrentQuestionID = session.CurrentQuestionID
+	stored.CurrentGroup = session.CurrentGroup
+	stored.GroupOrder = slices.Clone(session.GroupOrder)
+	stored.UpdatedAt = &now
+	stored.FinishedAt = session.FinishedAt
+	stored.QuestionRequestedTime = session.QuestionRequestedTime
+	stored.CurrentQuestionVersion = session.CurrentQuestionVersion
+	m.sessions[session.ID] = stored
+}
+
+// SubmitAnswer runs submit with the lock of the session held, see PostgresStorage.SubmitAnswer. submit gets the store
+// itself, changes of the session are saved only when it succeeds while changes made through the store are kept
+func (m *MemoryStore) SubmitAnswer(ctx context.Context, sessionID int, idempotencyKey string, submit func(store Store, session *models.QuizSession, recorded []byte) ([]byte, []models.OutboxEvent, error)) ([]byte, error) {
+	lock := m.sessionLock(sessionID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	m.mu.Lock()
+	session, err := m.quizSession(sessionID)
+	recorded := slices.Clone(m.submissions[memorySubmission{sessionID, idempotencyKey}])
+	m.mu.Unlock()
+	if err == sql.ErrNoRows {
+		return nil, ErrQuizSessionNotFound
+	}
+
+	response, events, err := submit(m, &session, recorded)
+	if err != nil {
+		return nil, err
+	}
+	if recorded != nil {
+		return response, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updateQuizSession(session)
+	m.insertOutboxEvents(events)
+	m.submissions[memorySubmission{sessionID, idempotencyKey}] = slices.Clone(response)
+	return response, nil
+}
+
+// userSessions returns copies of the sessions of the user outside of the sandbox, the newest first
+func (m *MemoryStore) userSessions(userID int) []models.QuizSession {
+	var sessions []models.QuizSession
+	ids := sortedKeys(m.sessions)
+	for i := len(ids) - 1; i >= 0; i-- {
+		session := m.sessions[ids[i]]
+		if session.UserID == userID && !session.Sandbox {
+			session.GroupOrder = slices.Clone(session.GroupOrder)
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
+
+func (m *MemoryStore) GetUserActiveQuizSessions(ctx context.Context, userID int) ([]models.QuizSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessions []models.QuizSession
+	for _, session := range m.userSessions(userID) {
+		if session.IsClosed() {
+			continue
+		}
+		sessions = append(sessions, models.QuizSession{
+			ID:                session.ID,
+			UserID:            session.UserID,
+			Status:            session.Status,
+			Mode:              session.Mode,
+			CurrentQuestionID: session.CurrentQuestionID,
+			CreatedAt:         session.CreatedAt,
+			UpdatedAt:         session.UpdatedAt,
+			FinishedAt:        session.FinishedAt,
+			AssignmentID:      session.AssignmentID,
+		})
+	}
+	return sessions, nil
+}
+
+// userSession returns the columns of the session read by PostgresStorage.GetUserLastQuizSession
+func userSession(session models.QuizSession) *models.QuizSession {
+	groupOrder := session.GroupOrder
+	if groupOrder == nil {
+		groupOrder = []int{}
+	}
+	return &models.QuizSession{
+		ID:                session.ID,
+		UserID:            session.UserID,
+		Status:            session.Status,
+		Mode:              session.Mode,
+		CurrentQuestionID: session.CurrentQuestionID,
+		CurrentGroup:      session.CurrentGroup,
+		GroupOrder:        groupOrder,
+		CreatedAt:         session.CreatedAt,
+		UpdatedAt:         session.UpdatedAt,
+		FinishedAt:        session.FinishedAt,
+		Seed:              session.Seed,
+	}
+}
+
+func (m *MemoryStore) GetUserLastQuizSession(ctx context.Context, userID int, mode models.QuizMode) (*models.QuizSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, session := range m.userSessions(userID) {
+		if session.Mode == mode && session.AssignmentID == nil {
+			return userSession(session), nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MemoryStore) GetUserOpenQuizSession(ctx context.Context, userID int, mode models.QuizMode, assignmentID *int) (*models.QuizSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, session := range m.userSessions(userID) {
+		if session.IsClosed() {
+			continue
+		}
+		if assignmentID == nil && session.AssignmentID == nil && session.Mode == mode ||
+			assignmentID != nil && session.AssignmentID != nil && *session.AssignmentID == *assignmentID {
+			return userSession(session), nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MemoryStore) GetTimeLimit(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.settings["time_limit"]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return strconv.Atoi(value)
+}
+
+func (m *MemoryStore) GetPinnedSeed(ctx context.Context) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value := m.settings["order_seed"]
+	if value == "" {
+		return 0, false, nil
+	}
+	seed, err := strconv.ParseInt(value, 10, 64)
+	return seed, err == nil, err
+}
+
+func (m *MemoryStore) SaveSettings(ctx context.Context, name string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.settings[name] = value
+	return nil
+}
+
+func (m *MemoryStore) GetSettings(ctx context.Context) ([]models.Settings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var settings []models.Settings
+	for _, name := range slices.Sorted(maps.Keys(m.settings)) {
+		settings = append(settings, models.Settings{Name: name, Value: m.settings[name]})
+	}
+	return settings, nil
+}
+
+// Questions
+
+// questionOptionsOf returns the options of the question ordered by option ID and the correct one
+func (m *MemoryStore) questionOptionsOf(questionID int) (options []string, correct string, hasCorrect bool) {
+	var optionsIDs []int
+	for _, link := range m.questionOptions {
+		if link.questionID != questionID {
+			continue
+		}
+		optionsIDs = append(optionsIDs, link.optionID)
+		if link.isCorrect && !hasCorrect {
+			correct, hasCorrect = m.options[link.optionID], true
+		}
+	}
+	slices.Sort(optionsIDs)
+	for _, id := range optionsIDs {
+		options = append(options, m.options[id])
+	}
+	return options, correct, hasCorrect
+}
+
+// caseOf returns the case with its parameters ordered by their display order and its landmarks
+func (m *MemoryStore) caseOf(id int) (models.Case, bool) {
+	c, ok := m.cases[id]
+	if !ok {
+		return c, false
+	}
+	values := slices.Clone(m.caseValues[id])
+	slices.SortFunc(values, func(a, b models.ParameterValue) int {
+		pa, pb := m.parameters[a.ParameterID], m.parameters[b.ParameterID]
+		return cmp.Or(cmp.Compare(pa.Order, pb.Order), cmp.Compare(pa.ID, pb.ID))
+	})
+	c.Parameters = make([]models.Parameter, 0, len(values))
+	c.ParameterValues = make([]models.ParameterValue, 0, len(values))
+	for _, value := range values {
+		parameter := m.parameters[value.ParameterID]
+		c.Parameters = append(c.Parameters, models.Parameter{ID: parameter.ID, Name: parameter.Name, Description: parameter.Description, ReferenceValues: parameter.ReferenceValues})
+		c.ParameterValues = append(c.ParameterValues, value)
+	}
+	c.Landmarks = slices.Clone(m.caseLandmarks[id])
+	return c, true
+}
+
+// question returns the question with its case, the case of GetAllQuestions has no parameters and landmarks
+func (m *MemoryStore) question(q memoryQuestion) models.Question {
+	c := m.cases[q.CaseID]
+	return models.Question{
+		ID:                q.ID,
+		Question:          q.Question,
+		PredictionAge:     q.PredictionAge,
+		Case:              models.Case{ID: c.ID, Code: c.Code, Gender: c.Gender, Age1: c.Age1, Age2: c.Age2, Age3: c.Age3},
+		Group:             q.Group,
+		Status:            q.Status,
+		Type:              q.Type,
+		TargetParameterID: q.TargetParameterID,
+		Tolerance:         q.Tolerance,
+	}
+}
+
+func (m *MemoryStore) GetQuestionByID(ctx context.Context, id int) (models.Question, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q, ok := m.questions[id]
+	if !ok {
+		return models.Question{}, ErrQuestionNotFound
+	}
+	question := m.question(q)
+	question.Options, _, _ = m.questionOptionsOf(id)
+	question.Case, _ = m.caseOf(q.CaseID)
+	return question, nil
+}
+
+func (m *MemoryStore) GetQuestionOptions(ctx context.Context, id int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	options, _, _ := m.questionOptionsOf(id)
+	return options, nil
+}
+
+func (m *MemoryStore) GetQuestionCorrectOption(ctx context.Context, id int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, correct, ok := m.questionOptionsOf(id)
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return correct, nil
+}
+
+func (m *MemoryStore) GetAllQuestions(ctx context.Context) ([]models.Question, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var questions []models.Question
+	for _, id := range sortedKeys(m.questions) {
+		question := m.question(m.questions[id])
+		var correct string
+		question.Options, correct, _ = m.questionOptionsOf(id)
+		question.Correct = &correct
+		questions = append(questions, question)
+	}
+	return questions, nil
+}
+
+// SnapshotQuestion returns the latest version of the question, a new version is recorded first
+// if the question was changed since, see PostgresStorage.SnapshotQuestion
+func (m *MemoryStore) SnapshotQuestion(ctx context.Context, questionID int) (models.QuestionVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q, ok := m.questions[questionID]
+	if !ok {
+		return models.QuestionVersion{}, ErrQuestionNotFound
+	}
+	current := models.QuestionVersion{
+		QuestionID:        questionID,
+		Question:          q.Question,
+		PredictionAge:     q.PredictionAge,
+		Type:              q.Type,
+		TargetParameterID: q.TargetParameterID,
+		Tolerance:         q.Tolerance,
+	}
+	current.Options, current.Correct, _ = m.questionOptionsOf(questionID)
+	current.Case, _ = m.caseOf(q.CaseID)
+
+	versions := m.versions[questionID]
+	if len(versions) > 0 && versions[len(versions)-1].SameContent(current) {
+		return cloneVersion(versions[len(versions)-1]), nil
+	}
+	current.Version = len(versions) + 1
+	current.CreatedAt = time.Now()
+	m.versions[questionID] = append(versions, cloneVersion(current))
+	return current, nil
+}
+
+// cloneVersion copies the version, so that the snapshots kept by the store are not changed by callers
+func cloneVersion(version models.QuestionVersion) models.QuestionVersion {
+	version.Options = slices.Clone(version.Options)
+	version.Case.Parameters = slices.Clone(version.Case.Parameters)
+	version.Case.ParameterValues = slices.Clone(version.Case.ParameterValues)
+	version.Case.Landmarks = slices.Clone(version.Case.Landmarks)
+	return version
+}
+
+func (m *MemoryStore) GetQuestionVersion(ctx context.Context, questionID int, version int) (models.QuestionVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	versions := m.versions[questionID]
+	if version < 1 || version > len(versions) {
+		return models.QuestionVersion{}, ErrQuestionVersionNotFound
+	}
+	return cloneVersion(versions[version-1]), nil
+}
+
+func (m *MemoryStore) GetQuestionVersions(ctx context.Context, questionID int) ([]models.QuestionVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	versions := []models.QuestionVersion{}
+	for _, version := range m.versions[questionID] {
+		versions = append(versions, cloneVersion(version))
+	}
+	return versions, nil
+}
+
+func (m *MemoryStore) CreateQuestion(ctx context.Context, payload models.QuestionPayload) (models.QuestionPayload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.cases[payload.CaseID]; !ok {
+		return payload, fmt.Errorf("case %d does not exist", payload.CaseID)
+	}
+	if payload.Type == "" {
+		payload.Type = models.QuestionTypeChoice
+	}
+	payload.ID = m.newID()
+	// the group of a new question is set by moving it to a group
+	m.questions[payload.ID] = memoryQuestion{
+		ID:                payload.ID,
+		Question:          payload.Question,
+		PredictionAge:     payload.PredictionAge,
+		CaseID:            payload.CaseID,
+		Status:            models.QuestionStatusDraft,
+		Type:              payload.Type,
+		TargetParameterID: payload.TargetParameterID,
+		Tolerance:         payload.Tolerance,
+	}
+	return payload, nil
+}
+
+func (m *MemoryStore) UpdateQuestionByID(ctx context.Context, questionID int, payload models.QuestionPayload) (models.QuestionPayload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if payload.Type == "" {
+		payload.Type = models.QuestionTypeChoice
+	}
+	payload.ID = questionID
+	q, ok := m.questions[questionID]
+	if !ok {
+		return payload, nil
+	}
+	if _, ok = m.cases[payload.CaseID]; !ok {
+		return payload, fmt.Errorf("case %d does not exist", payload.CaseID)
+	}
+	q.Question, q.PredictionAge, q.CaseID, q.Group = payload.Question, payload.PredictionAge, payload.CaseID, payload.Group
+	q.Type, q.TargetParameterID, q.Tolerance = payload.Type, payload.TargetParameterID, payload.Tolerance
+	m.questions[questionID] = q
+	return payload, nil
+}
+
+func (m *MemoryStore) UpdateQuestionStatus(ctx context.Context, questionID int, from string, to string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q, ok := m.questions[questionID]
+	if !ok || q.Status != from {
+		return ErrQuestionStatusChanged
+	}
+	q.Status = to
+	m.questions[questionID] = q
+	return nil
+}
+
+func (m *MemoryStore) UpdateQuestionCorrectOption(ctx context.Context, questionID int, option string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	optionID := 0
+	for _, id := range sortedKeys(m.options) {
+		if m.options[id] == option {
+			optionID = id
+			break
+		}
+	}
+	if optionID == 0 {
+		return sql.ErrNoRows
+	}
+	for i := range m.questionOptions {
+		if m.questionOptions[i].questionID == questionID {
+			m.questionOptions[i].isCorrect = m.questionOptions[i].optionID == optionID
+		}
+	}
+	return nil
+}
+
+// DeleteQuestionByID removes the question with its options links, review items and translations
+func (m *MemoryStore) DeleteQuestionByID(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.questions, id)
+	m.questionOptions = slices.DeleteFunc(m.questionOptions, func(link memoryQuestionOption) bool {
+		return link.questionID == id
+	})
+	maps.DeleteFunc(m.reviewItems, func(key memoryReviewKey, _ models.ReviewItem) bool {
+		return key.questionID == id
+	})
+	m.deleteTranslations(models.TranslationEntityQuestion, id)
+	return nil
+}
+
+func (m *MemoryStore) CountQuestions(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.questions), nil
+}
+
+// Options
+
+func (m *MemoryStore) GetAllOptions(ctx context.Context) ([]models.Option, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var options []models.Option
+	for _, id := range sortedKeys(m.options) {
+		count := 0
+		for _, link := range m.questionOptions {
+			if link.optionID == id {
+				count++
+			}
+		}
+		options = append(options, models.Option{ID: id, Option: m.options[id], Questions: &count})
+	}
+	return options, nil
+}
+
+func (m *MemoryStore) CreateOption(ctx context.Context, option models.Option) (models.Option, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	option.ID = m.newID()
+	m.options[option.ID] = option.Option
+	return option, nil
+}
+
+func (m *MemoryStore) UpdateOption(ctx context.Context, id int, option models.Option) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.options[id]; ok {
+		m.options[id] = option.Option
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteOption(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.options, id)
+	m.questionOptions = slices.DeleteFunc(m.questionOptions, func(link memoryQuestionOption) bool {
+		return link.optionID == id
+	})
+	m.deleteTranslations(models.TranslationEntityOption, id)
+	return nil
+}
+
+// Cases
+
+// setCaseValues replaces the parameter values of the case, the parameters must exist and be listed once
+func (m *MemoryStore) setCaseValues(caseID int, values []models.ParameterValue) error {
+	seen := make(map[int]bool, len(values))
+	for _, value := range values {
+		if _, ok := m.parameters[value.ParameterID]; !ok {
+			return fmt.Errorf("parameter %d does not exist", value.ParameterID)
+		}
+		if seen[value.ParameterID] {
+			return fmt.Errorf("duplicate value of parameter %d in case %d", value.ParameterID, caseID)
+		}
+		seen[value.ParameterID] = true
+	}
+	m.caseValues[caseID] = slices.Clone(values)
+	return nil
+}
+
+func (m *MemoryStore) CreateCase(ctx context.Context, newCase models.Case) (models.Case, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.newID()
+	if err := m.setCaseValues(id, newCase.ParameterValues); err != nil {
+		return newCase, err
+	}
+	newCase.ID = id
+	m.cases[id] = models.Case{ID: id, Code: newCase.Code, Gender: newCase.Gender, Age1: newCase.Age1, Age2: newCase.Age2, Age3: newCase.Age3}
+	return newCase, nil
+}
+
+func (m *MemoryStore) UpdateCase(ctx context.Context, updatedCase models.Case) (models.Case, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.cases[updatedCase.ID]; !ok {
+		return updatedCase, ErrCaseNotFound
+	}
+	if updatedCase.ParameterValues != nil {
+		if err := m.setCaseValues(updatedCase.ID, updatedCase.ParameterValues); err != nil {
+			return updatedCase, err
+		}
+	}
+	m.cases[updatedCase.ID] = models.Case{ID: updatedCase.ID, Code: updatedCase.Code, Gender: updatedCase.Gender, Age1: updatedCase.Age1, Age2: updatedCase.Age2, Age3: updatedCase.Age3}
+	return updatedCase, nil
+}
+
+func (m *MemoryStore) DeleteCaseWithParameters(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, q := range m.questions {
+		if q.CaseID == id {
+			return ErrCaseInUse
+		}
+	}
+	if _, ok := m.cases[id]; !ok {
+		return ErrCaseNotFound
+	}
+	delete(m.cases, id)
+	delete(m.caseValues, id)
+	delete(m.caseLandmarks, id)
+	return nil
+}
+
+func (m *MemoryStore) GetAllCases(ctx context.Context) ([]models.Case, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cases := make([]models.Case, 0, len(m.cases))
+	for _, id := range sortedKeys(m.cases) {
+		c, _ := m.caseOf(id)
+		c.Landmarks = nil
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+func (m *MemoryStore) GetCaseByID(ctx context.Context, id int) (models.Case, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.caseOf(id)
+	if !ok {
+		return models.Case{}, ErrCaseNotFound
+	}
+	return c, nil
+}
+
+func (m *MemoryStore) GetCaseIDByCode(ctx context.Context, code string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range sortedKeys(m.cases) {
+		if m.cases[id].Code == code {
+			return id, nil
+		}
+	}
+	return 0, ErrCaseNotFound
+}
+
+func (m *MemoryStore) CreateCaseParameter(ctx context.Context, caseID int, parameter models.ParameterValue) (models.ParameterValue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.cases[caseID]; !ok {
+		return parameter, fmt.Errorf("case %d does not exist", caseID)
+	}
+	if err := m.setCaseValues(caseID, append(slices.Clone(m.caseValues[caseID]), parameter)); err != nil {
+		return parameter, err
+	}
+	return parameter, nil
+}
+
+func (m *MemoryStore) UpdateCaseParameters(ctx context.Context, caseID int, parameters []models.Parameter, values []models.ParameterValue) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.cases[caseID]; !ok {
+		return fmt.Errorf("case %d does not exist", caseID)
+	}
+	caseValues := make([]models.ParameterValue, len(parameters))
+	for i := range parameters {
+		caseValues[i] = models.ParameterValue{ParameterID: parameters[i].ID, Value1: values[i].Value1, Value2: values[i].Value2, Value3: values[i].Value3}
+	}
+	return m.setCaseValues(caseID, caseValues)
+}
+
+func (m *MemoryStore) GetCaseLandmarks(ctx context.Context, caseID int) ([]models.Landmark, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.caseLandmarks[caseID]), nil
+}
+
+func (m *MemoryStore) SaveCaseLandmarks(ctx context.Context, caseID int, landmarks []models.Landmark) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.cases[caseID]; !ok {
+		return ErrCaseNotFound
+	}
+	if len(landmarks) == 0 {
+		delete(m.caseLandmarks, caseID)
+		return nil
+	}
+	m.caseLandmarks[caseID] = slices.Clone(landmarks)
+	return nil
+}
+
+// Parameters
+
+func (m *MemoryStore) CreateParameter(ctx context.Context, parameter models.Parameter) (models.Parameter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	parameter.ID = m.newID()
+	// the display order of a new parameter is set by reordering the parameters
+	m.parameters[parameter.ID] = models.Parameter{ID: parameter.ID, Name: parameter.Name, Description: parameter.Description, ReferenceValues: parameter.ReferenceValues}
+	return parameter, nil
+}
+
+func (m *MemoryStore) UpdateParameter(ctx context.Context, parameter models.Parameter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.parameters[parameter.ID]
+	if !ok {
+		return nil
+	}
+	stored.Name, stored.Description, stored.ReferenceValues = parameter.Name, parameter.Description, parameter.ReferenceValues
+	m.parameters[parameter.ID] = stored
+	return nil
+}
+
+// DeleteParameter removes the parameter with its values in cases and its translations
+func (m *MemoryStore) DeleteParameter(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.parameters, id)
+	for caseID, values := range m.caseValues {
+		m.caseValues[caseID] = slices.DeleteFunc(values, func(value models.ParameterValue) bool {
+			return value.ParameterID == id
+		})
+	}
+	m.deleteTranslations(models.TranslationEntityParameter, id)
+	return nil
+}
+
+func (m *MemoryStore) GetAllParameters(ctx context.Context) ([]models.Parameter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var parameters []models.Parameter
+	for _, id := range sortedKeys(m.parameters) {
+		parameters = append(parameters, m.parameters[id])
+	}
+	slices.SortStableFunc(parameters, func(a, b models.Parameter) int {
+		return cmp.Compare(a.Order, b.Order)
+	})
+	return parameters, nil
+}
+
+func (m *MemoryStore) GetParameterByID(ctx context.Context, id int) (models.Parameter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	parameter, ok := m.parameters[id]
+	if !ok {
+		return models.Parameter{}, sql.ErrNoRows
+	}
+	parameter.Order = 0
+	return parameter, nil
+}
+
+func (m *MemoryStore) UpdateParametersOrder(ctx context.Context, params []models.Parameter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, param := range params {
+		if stored, ok := m.parameters[param.ID]; ok {
+			stored.Order = param.Order
+			m.parameters[param.ID] = stored
+		}
+	}
+	return nil
+}
+
+// Groups
+
+// publishedQuestions returns published questions matching the filter ordered by ID
+func (m *MemoryStore) publishedQuestions(filter func(q memoryQuestion) bool) []int {
+	var questions []int
+	for _, id := range sortedKeys(m.questions) {
+		if q := m.questions[id]; q.Status == models.QuestionStatusPublished && filter(q) {
+			questions = append(questions, id)
+		}
+	}
+	return questions
+}
+
+func (m *MemoryStore) GetGroupQuestionsIDs(ctx context.Context, groupID int) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.publishedQuestions(func(q memoryQuestion) bool {
+		return q.Group == groupID
+	}), nil
+}
+
+func (m *MemoryStore) GetEnabledQuestionsIDs(ctx context.Context) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.publishedQuestions(func(q memoryQuestion) bool {
+		group, ok := m.groups[q.Group]
+		return ok && group.Enabled
+	}), nil
+}
+
+// sortedGroups returns the groups in display order
+func (m *MemoryStore) sortedGroups() []models.QuestionsGroup {
+	groups := make([]models.QuestionsGroup, 0, len(m.groups))
+	for _, id := range sortedKeys(m.groups) {
+		group := m.groups[id]
+		group.QuestionsIDs = []int{}
+		for _, questionID := range sortedKeys(m.questions) {
+			if m.questions[questionID].Group == id {
+				group.QuestionsIDs = append(group.QuestionsIDs, questionID)
+			}
+		}
+		groups = append(groups, group)
+	}
+	slices.SortStableFunc(groups, func(a, b models.QuestionsGroup) int {
+		return cmp.Compare(a.Order, b.Order)
+	})
+	return groups
+}
+
+func (m *MemoryStore) GetNextQuestionGroupID(ctx context.Context, currentGroup int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current := -1
+	for _, group := range m.sortedGroups() {
+		if group.ID == currentGroup {
+			current = group.Order
+		}
+	}
+	for _, group := range m.sortedGroups() {
+		after := group.Order > current || group.Order == current && group.ID > currentGroup
+		if !group.Enabled || !after {
+			continue
+		}
+		published := m.publishedQuestions(func(q memoryQuestion) bool {
+			return q.Group == group.ID
+		})
+		if len(published) > 0 {
+			return group.ID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (m *MemoryStore) GetAllGroups(ctx context.Context) ([]models.QuestionsGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sortedGroups(), nil
+}
+
+func (m *MemoryStore) GetGroupByID(ctx context.Context, id int) (models.QuestionsGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, group := range m.sortedGroups() {
+		if group.ID == id {
+			return group, nil
+		}
+	}
+	return models.QuestionsGroup{}, ErrGroupNotFound
+}
+
+func (m *MemoryStore) CreateGroup(ctx context.Context, group models.QuestionsGroup) (models.QuestionsGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkQuestionsMovable(0, group.QuestionsIDs); err != nil {
+		return group, err
+	}
+	group.ID = m.newID()
+	m.groups[group.ID] = models.QuestionsGroup{ID: group.ID, Name: group.Name, Description: group.Description, Order: group.Order, Enabled: group.Enabled}
+	m.moveQuestionsToGroup(group.ID, group.QuestionsIDs)
+	return group, nil
+}
+
+func (m *MemoryStore) UpdateGroup(ctx context.Context, group models.QuestionsGroup) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.groups[group.ID]; !ok {
+		return ErrGroupNotFound
+	}
+	m.groups[group.ID] = models.QuestionsGroup{ID: group.ID, Name: group.Name, Description: group.Description, Order: group.Order, Enabled: group.Enabled}
+	return nil
+}
+
+// DeleteGroup removes the group, questions assigned to it are left without a group.
+// ErrGroupInUse is returned while open sessions are in the group
+func (m *MemoryStore) DeleteGroup(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.groups[id]; !ok {
+		return ErrGroupNotFound
+	}
+	if m.groupInUse(id) {
+		return ErrGroupInUse
+	}
+	delete(m.groups, id)
+	for questionID, q := range m.questions {
+		if q.Group == id {
+			q.Group = 0
+			m.questions[questionID] = q
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) UpdateGroupsOrder(ctx context.Context, groups []models.QuestionsGroup) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, group := range groups {
+		if stored, ok := m.groups[group.ID]; ok {
+			stored.Order = group.Order
+			m.groups[group.ID] = stored
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) MoveQuestionsToGroup(ctx context.Context, groupID int, questionIDs []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.groups[groupID]; !ok {
+		return ErrGroupNotFound
+	}
+	if err := m.checkQuestionsMovable(groupID, questionIDs); err != nil {
+		return err
+	}
+	m.moveQuestionsToGroup(groupID, questionIDs)
+	return nil
+}
+
+// checkQuestionsMovable mirrors the checks of moving questions to the group in Postgres
+func (m *MemoryStore) checkQuestionsMovable(groupID int, questionIDs []int) error {
+	for _, id := range questionIDs {
+		if _, ok := m.questions[id]; !ok {
+			return ErrQuestionNotFound
+		}
+	}
+	for _, id := range questionIDs {
+		if group := m.questions[id].Group; group != 0 && group != groupID && m.groupInUse(group) {
+			return ErrGroupInUse
+		}
+	}
+	return nil
+}
+
+// groupInUse reports whether the group is the current group of an open, non-sandbox session
+func (m *MemoryStore) groupInUse(groupID int) bool {
+	for _, session := range m.sessions {
+		if session.CurrentGroup == groupID && !session.IsClosed() && !session.Sandbox {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) moveQuestionsToGroup(groupID int, questionIDs []int) {
+	for _, id := range questionIDs {
+		q := m.questions[id]
+		q.Group = groupID
+		m.questions[id] = q
+	}
+}
+
+// Assignments
+
+// assignment returns a copy of the assignment with its students sorted
+func (m *MemoryStore) assignment(id int) (models.Assignment, bool) {
+	assignment, ok := m.assignments[id]
+	if !ok {
+		return models.Assignment{}, false
+	}
+	assignment.QuestionsIDs = append([]int{}, assignment.QuestionsIDs...)
+	assignment.GroupsIDs = append([]int{}, assignment.GroupsIDs...)
+	assignment.StudentsIDs = slices.Compact(slices.Sorted(slices.Values(assignment.StudentsIDs)))
+	if assignment.StudentsIDs == nil {
+		assignment.StudentsIDs = []int{}
+	}
+	return assignment, true
+}
+
+func (m *MemoryStore) CreateAssignment(ctx context.Context, assignment models.Assignment) (models.Assignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	assignment.ID = m.newID()
+	assignment.CreatedAt = &now
+	stored := assignment
+	stored.QuestionsIDs = slices.Clone(assignment.QuestionsIDs)
+	stored.GroupsIDs = slices.Clone(assignment.GroupsIDs)
+	stored.StudentsIDs = slices.Clone(assignment.StudentsIDs)
+	m.assignments[assignment.ID] = stored
+	return assignment, nil
+}
+
+func (m *MemoryStore) GetAssignmentByID(ctx context.Context, id int) (models.Assignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assignment, ok := m.assignment(id)
+	if !ok {
+		return assignment, ErrAssignmentNotFound
+	}
+	return assignment, nil
+}
+
+func (m *MemoryStore) GetAssignments(ctx context.Context, teacherID int) ([]models.Assignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assignments := make([]models.Assignment, 0)
+	for _, id := range sortedKeys(m.assignments) {
+		if assignment, _ := m.assignment(id); teacherID == 0 || assignment.TeacherID == teacherID {
+			assignments = append(assignments, assignment)
+		}
+	}
+	slices.SortStableFunc(assignments, func(a, b models.Assignment) int {
+		return b.Deadline.Compare(a.Deadline)
+	})
+	return assignments, nil
+}
+
+func (m *MemoryStore) UpdateAssignment(ctx context.Context, assignment models.Assignment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.assignments[assignment.ID]
+	if !ok {
+		return ErrAssignmentNotFound
+	}
+	stored.Title, stored.Description, stored.Mode = assignment.Title, assignment.Description, assignment.Mode
+	stored.QuestionsIDs = slices.Clone(assignment.QuestionsIDs)
+	stored.GroupsIDs = slices.Clone(assignment.GroupsIDs)
+	stored.Deadline, stored.MaxAttempts = assignment.Deadline, assignment.MaxAttempts
+	stored.StudentsIDs = slices.Clone(assignment.StudentsIDs)
+	m.assignments[assignment.ID] = stored
+	return nil
+}
+
+// DeleteAssignment removes the assignment, its sessions are kept as regular finished sessions
+func (m *MemoryStore) DeleteAssignment(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.assignments[id]; !ok {
+		return ErrAssignmentNotFound
+	}
+	now := time.Now()
+	for sessionID, session := range m.sessions {
+		if session.AssignmentID == nil || *session.AssignmentID != id {
+			continue
+		}
+		session.AssignmentID = nil
+		session.Status = models.QuizStatusFinished
+		if session.FinishedAt == nil {
+			session.FinishedAt = &now
+		}
+		m.sessions[sessionID] = session
+	}
+	delete(m.assignments, id)
+	return nil
+}
+
+func (m *MemoryStore) GetAssignmentQuestionsIDs(ctx context.Context, assignment models.Assignment) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	questions := make([]int, 0)
+	for _, id := range assignment.QuestionsIDs {
+		if q, ok := m.questions[id]; ok && q.Status == models.QuestionStatusPublished {
+			questions = append(questions, id)
+		}
+	}
+	for _, group := range m.sortedGroups() {
+		if !slices.Contains(assignment.GroupsIDs, group.ID) {
+			continue
+		}
+		for _, id := range group.QuestionsIDs {
+			if m.questions[id].Status == models.QuestionStatusPublished && !slices.Contains(questions, id) {
+				questions = append(questions, id)
+			}
+		}
+	}
+	return questions, nil
+}
+
+func (m *MemoryStore) GetStudentAssignments(ctx context.Context, userID int) ([]models.StudentAssignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assignments := make([]models.StudentAssignment, 0)
+	for _, id := range sortedKeys(m.assignments) {
+		assignment := m.assignments[id]
+		if !slices.Contains(assignment.StudentsIDs, userID) {
+			continue
+		}
+		student := models.StudentAssignment{
+			ID:          assignment.ID,
+			Title:       assignment.Title,
+			Description: assignment.Description,
+			Mode:        assignment.Mode,
+			Deadline:    assignment.Deadline,
+			MaxAttempts: assignment.MaxAttempts,
+		}
+		for _, session := range m.sessions {
+			if session.UserID == userID && session.AssignmentID != nil && *session.AssignmentID == id {
+				student.AttemptsUsed++
+				student.Completed = student.Completed || session.Status == models.QuizStatusFinished
+			}
+		}
+		assignments = append(assignments, student)
+	}
+	slices.SortStableFunc(assignments, func(a, b models.StudentAssignment) int {
+		return a.Deadline.Compare(b.Deadline)
+	})
+	return assignments, nil
+}
+
+func (m *MemoryStore) GetAssignmentSessions(ctx context.Context, assignmentID int) ([]models.AssignmentSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := make([]models.AssignmentSession, 0)
+	for _, id := range sortedKeys(m.sessions) {
+		session := m.sessions[id]
+		if session.AssignmentID != nil && *session.AssignmentID == assignmentID {
+			sessions = append(sessions, models.AssignmentSession{
+				SessionID:  session.ID,
+				UserID:     session.UserID,
+				Status:     session.Status,
+				CreatedAt:  session.CreatedAt,
+				FinishedAt: session.FinishedAt,
+			})
+		}
+	}
+	slices.SortStableFunc(sessions, func(a, b models.AssignmentSession) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	return sessions, nil
+}
+
+// Spaced repetition review
+
+func (m *MemoryStore) AddReviewItems(ctx context.Context, items []models.ReviewItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, item := range items {
+		key := memoryReviewKey{item.UserID, item.QuestionID}
+		stored, ok := m.reviewItems[key]
+		if !ok {
+			item.LastReviewedAt = nil
+			m.reviewItems[key] = item
+		} else if stored.LastReviewedAt != nil && stored.LastReviewedAt.Before(item.DueAt) {
+			stored.Repetitions = item.Repetitions
+			stored.IntervalDays = item.IntervalDays
+			stored.DueAt = item.DueAt
+			m.reviewItems[key] = stored
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetDueReviewQuestionsIDs(ctx context.Context, userID int, now time.Time) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []models.ReviewItem
+	for key, item := range m.reviewItems {
+		if key.userID == userID && !item.DueAt.After(now) && m.questions[key.questionID].Status == models.QuestionStatusPublished {
+			due = append(due, item)
+		}
+	}
+	slices.SortFunc(due, func(a, b models.ReviewItem) int {
+		return cmp.Or(a.DueAt.Compare(b.DueAt), cmp.Compare(a.QuestionID, b.QuestionID))
+	})
+	var questions []int
+	for _, item := range due {
+		questions = append(questions, item.QuestionID)
+	}
+	return questions, nil
+}
+
+func (m *MemoryStore) GetReviewItem(ctx context.Context, userID int, questionID int) (models.ReviewItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.reviewItems[memoryReviewKey{userID, questionID}]
+	if !ok {
+		return item, ErrReviewItemNotFound
+	}
+	return item, nil
+}
+
+func (m *MemoryStore) SaveReviewItem(ctx context.Context, item models.ReviewItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reviewItems[memoryReviewKey{item.UserID, item.QuestionID}] = item
+	return nil
+}
+
+// Translations
+
+func (m *MemoryStore) GetTranslations(ctx context.Context, entity string, entityID int) ([]models.Translation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	translations := make([]models.Translation, 0)
+	for key, value := range m.translations {
+		if key.entity == entity && key.entityID == entityID {
+			translations = append(translations, models.Translation{Entity: entity, EntityID: entityID, Field: key.field, Locale: key.locale, Value: value})
+		}
+	}
+	slices.SortFunc(translations, func(a, b models.Translation) int {
+		return cmp.Or(cmp.Compare(a.Locale, b.Locale), cmp.Compare(a.Field, b.Field))
+	})
+	return translations, nil
+}
+
+// translatedEntityExists reports whether the question, option or parameter exists
+func (m *MemoryStore) translatedEntityExists(entity string, id int) bool {
+	var ok bool
+	switch entity {
+	case models.TranslationEntityQuestion:
+		_, ok = m.questions[id]
+	case models.TranslationEntityOption:
+		_, ok = m.options[id]
+	default:
+		_, ok = m.parameters[id]
+	}
+	return ok
+}
+
+func (m *MemoryStore) SaveTranslation(ctx context.Context, t models.Translation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.translatedEntityExists(t.Entity, t.EntityID) {
+		return ErrTranslatedEntityNotFound
+	}
+	m.translations[memoryTranslationKey{t.Entity, t.EntityID, t.Field, t.Locale}] = t.Value
+	return nil
+}
+
+func (m *MemoryStore) DeleteTranslation(ctx context.Context, entity string, entityID int, field string, locale string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memoryTranslationKey{entity, entityID, field, locale}
+	if _, ok := m.translations[key]; !ok {
+		return ErrTranslationNotFound
+	}
+	delete(m.translations, key)
+	return nil
+}
+
+// deleteTranslations removes all translations of the entity
+func (m *MemoryStore) deleteTranslations(entity string, id int) {
+	maps.DeleteFunc(m.translations, func(key memoryTranslationKey, _ string) bool {
+		return key.entity == entity && key.entityID == id
+	})
+}
+
+func (m *MemoryStore) GetMissingTranslations(ctx context.Context, locale string) ([]models.MissingTranslation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var fields []models.MissingTranslation
+	for _, id := range sortedKeys(m.questions) {
+		if q := m.questions[id]; q.Status != models.QuestionStatusArchived {
+			fields = append(fields, models.MissingTranslation{Entity: models.TranslationEntityQuestion, EntityID: id, Field: "question", Source: q.Question})
+		}
+	}
+	for _, id := range sortedKeys(m.options) {
+		fields = append(fields, models.MissingTranslation{Entity: models.TranslationEntityOption, EntityID: id, Field: "option", Source: m.options[id]})
+	}
+	for _, id := range sortedKeys(m.parameters) {
+		parameter := m.parameters[id]
+		fields = append(fields, models.MissingTranslation{Entity: models.TranslationEntityParameter, EntityID: id, Field: "name", Source: parameter.Name})
+		if parameter.Description != "" {
+			fields = append(fields, models.MissingTranslation{Entity: models.TranslationEntityParameter, EntityID: id, Field: "description", Source: parameter.Description})
+		}
+	}
+	missing := make([]models.MissingTranslation, 0)
+	for _, field := range fields {
+		if _, ok := m.translations[memoryTranslationKey{field.Entity, field.EntityID, field.Field, locale}]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	slices.SortStableFunc(missing, func(a, b models.MissingTranslation) int {
+		return cmp.Or(cmp.Compare(a.Entity, b.Entity), cmp.Compare(a.EntityID, b.EntityID), cmp.Compare(a.Field, b.Field))
+	})
+	return missing, nil
+}
+
+func (m *MemoryStore) GetLocalizedFields(ctx context.Context, entity string, entitiesIDs []int, locales []string) (map[int]map[string]map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fields := make(map[int]map[string]map[string]string)
+	for key, value := range m.translations {
+		if key.entity != entity || !slices.Contains(entitiesIDs, key.entityID) || !slices.Contains(locales, key.locale) {
+			continue
+		}
+		if fields[key.entityID] == nil {
+			fields[key.entityID] = make(map[string]map[string]string)
+		}
+		if fields[key.entityID][key.field] == nil {
+			fields[key.entityID][key.field] = make(map[string]string)
+		}
+		fields[key.entityID][key.field][key.locale] = value
+	}
+	return fields, nil
+}
+
+func (m *MemoryStore) GetOptionsTranslations(ctx context.Context, options []string, locales []string) (map[string]map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	translations := make(map[string]map[string]string)
+	for key, value := range m.translations {
+		option, ok := m.options[key.entityID]
+		if key.entity != models.TranslationEntityOption || key.field != "option" || !ok ||
+			!slices.Contains(options, option) || !slices.Contains(locales, key.locale) {
+			continue
+		}
+		if translations[option] == nil {
+			translations[option] = make(map[string]string)
+		}
+		translations[option][key.locale] = value
+	}
+	return translations, nil
+}
+
+func (m *MemoryStore) GetUserLocale(ctx context.Context, userID int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.locales[userID], nil
+}
+
+func (m *MemoryStore) SaveUserLocale(ctx context.Context, userID int, locale string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.locales[userID] = locale
+	return nil
+}
+
+// DeleteUserData removes the preferences and review schedule of a deleted user, sessions are kept for the statistics
+func (m *MemoryStore) DeleteUserData(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.locales, userID)
+	maps.DeleteFunc(m.reviewItems, func(key memoryReviewKey, _ models.ReviewItem) bool {
+		return key.userID == userID
+	})
+	return nil
+}
+
+// Outbox
+
+func (m *MemoryStore) insertOutboxEvents(events []models.OutboxEvent) {
+	now := time.Now()
+	for _, event := range events {
+		m.outbox = append(m.outbox, models.OutboxEvent{
+			ID:            m.newID(),
+			Type:          event.Type,
+			SessionID:     event.SessionID,
+			Payload:       slices.Clone(event.Payload),
+			CreatedAt:     now,
+			NextAttemptAt: now,
+		})
+	}
+}
+
+// GetDueOutboxEvents returns undelivered events whose next attempt is due, oldest first, with only the
+// oldest undelivered event of each session, see PostgresStorage.GetDueOutboxEvents
+func (m *MemoryStore) GetDueOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	events := make([]models.OutboxEvent, 0)
+	blocked := make(map[int]bool)
+	for _, event := range m.outbox {
+		if event.DeliveredAt != nil {
+			continue
+		}
+		if !blocked[event.SessionID] && !event.NextAttemptAt.After(now) && len(events) < limit {
+			events = append(events, event)
+		}
+		blocked[event.SessionID] = true
+	}
+	return events, nil
+}
+
+// outboxEvent returns the event with the ID, or nil when there is none
+func (m *MemoryStore) outboxEvent(id int) *models.OutboxEvent {
+	for i := range m.outbox {
+		if m.outbox[i].ID == id {
+			return &m.outbox[i]
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) MarkOutboxEventDelivered(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if event := m.outboxEvent(id); event != nil {
+		now := time.Now()
+		event.DeliveredAt = &now
+		event.Attempts++
+		event.LastError = nil
+	}
+	return nil
+}
+
+func (m *MemoryStore) MarkOutboxEventFailed(ctx context.Context, id int, lastError string, nextAttemptAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if event := m.outboxEvent(id); event != nil {
+		event.Attempts++
+		event.LastError = &lastError
+		event.NextAttemptAt = nextAttemptAt
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetStuckOutboxEvents(ctx context.Context, minAttempts int) ([]models.OutboxEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	events := make([]models.OutboxEvent, 0)
+	for _, event := range m.outbox {
+		if event.DeliveredAt == nil && event.Attempts >= minAttempts {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *MemoryStore) RetryOutboxEvent(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	event := m.outboxEvent(id)
+	if event == nil || event.DeliveredAt != nil {
+		return ErrOutboxEventNotFound
+	}
+	event.NextAttemptAt = time.Now()
+	return nil
+}
+
+// Import and export
+
+// ImportQuestionBank creates the rows of the bundle like PostgresStorage.ImportQuestionBank. Rows created by
+// the import have IDs above the ones existing before it, rolling it back removes them and restores the settings
+func (m *MemoryStore) ImportQuestionBank(ctx context.Context, bundle *models.ImportBundle, dryRun bool, beforeCommit func(bundle *models.ImportBundle) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lastID, settings, links := m.nextID, maps.Clone(m.settings), len(m.questionOptions)
+
+	err := m.importQuestionBank(bundle)
+	if err == nil && !dryRun && beforeCommit != nil {
+		err = beforeCommit(bundle)
+	}
+	if err == nil && !dryRun {
+		return nil
+	}
+	deleteCreated(m.parameters, lastID)
+	deleteCreated(m.options, lastID)
+	deleteCreated(m.groups, lastID)
+	deleteCreated(m.cases, lastID)
+	deleteCreated(m.caseValues, lastID)
+	deleteCreated(m.caseLandmarks, lastID)
+	deleteCreated(m.questions, lastID)
+	m.questionOptions = m.questionOptions[:links]
+	m.settings = settings
+	return err
+}
+
+// deleteCreated removes the rows with IDs above lastID
+func deleteCreated[V any](rows map[int]V, lastID int) {
+	maps.DeleteFunc(rows, func(id int, _ V) bool {
+		return id > lastID
+	})
+}
+
+func (m *MemoryStore) importQuestionBank(bundle *models.ImportBundle) error {
+	parametersIDs := make(map[string]int, len(bundle.Parameters))
+	for i := range bundle.Parameters {
+		parameter := &bundle.Parameters[i]
+		if parameter.ID == 0 {
+			parameter.ID = m.newID()
+			m.parameters[parameter.ID] = models.Parameter{ID: parameter.ID, Name: parameter.Name, Description: parameter.Description, ReferenceValues: parameter.ReferenceValues, Order: parameter.Order}
+		}
+		parametersIDs[parameter.Name] = parameter.ID
+	}
+
+	optionsIDs := make(map[string]int, len(bundle.Options))
+	for i := range bundle.Options {
+		option := &bundle.Options[i]
+		if option.ID == 0 {
+			option.ID = m.newID()
+			m.options[option.ID] = option.Option
+		}
+		optionsIDs[option.Option] = option.ID
+	}
+
+	groupsIDs := make(map[int]int, len(bundle.Groups))
+	for i := range bundle.Groups {
+		group := &bundle.Groups[i]
+		if group.GroupID == 0 {
+			group.GroupID = m.newID()
+			m.groups[group.GroupID] = models.QuestionsGroup{ID: group.GroupID, Name: group.Name, Description: group.Description, Order: group.Order, Enabled: group.Enabled}
+		}
+		groupsIDs[group.ID] = group.GroupID
+	}
+
+	for _, setting := range bundle.Settings {
+		m.settings[setting.Name] = setting.Value
+	}
+
+	casesIDs := make(map[string]int, len(bundle.Cases))
+	for i := range bundle.Cases {
+		importCase := &bundle.Cases[i]
+		if importCase.ID == 0 {
+			importCase.ID = m.newID()
+			m.cases[importCase.ID] = models.Case{ID: importCase.ID, Code: importCase.Code, Gender: importCase.Gender, Age1: importCase.Age1, Age2: importCase.Age2, Age3: importCase.Age3}
+			values := make([]models.ParameterValue, 0, len(importCase.Parameters))
+			for j := range importCase.Parameters {
+				value := &importCase.Parameters[j]
+				if value.ParameterID == 0 {
+					value.ParameterID = parametersIDs[value.Parameter]
+				}
+				values = append(values, models.ParameterValue{ParameterID: value.ParameterID, Value1: value.Value1, Value2: value.Value2, Value3: value.Value3})
+			}
+			if err := m.setCaseValues(importCase.ID, values); err != nil {
+				return fmt.Errorf("insert parameters of case %s: %w", importCase.Code, err)
+			}
+			if len(importCase.Landmarks) > 0 {
+				m.caseLandmarks[importCase.ID] = slices.Clone(importCase.Landmarks)
+			}
+		}
+		casesIDs[importCase.Code] = importCase.ID
+	}
+
+	for i := range bundle.Questions {
+		question := &bundle.Questions[i]
+		if caseID, ok := casesIDs[question.CaseCode]; ok {
+			question.CaseID = caseID
+		}
+		if question.GroupID == 0 && question.Group != 0 {
+			question.GroupID = groupsIDs[question.Group]
+		}
+		for j := range question.OptionsIDs {
+			if question.OptionsIDs[j] == 0 {
+				question.OptionsIDs[j] = optionsIDs[question.Options[j]]
+			}
+		}
+		if question.CorrectOptionID == 0 {
+			question.CorrectOptionID = optionsIDs[question.Correct]
+		}
+		if question.Type == "" {
+			question.Type = models.QuestionTypeChoice
+		}
+		var targetParameterID *int
+		if question.TargetParameter != "" {
+			if question.TargetParameterID == 0 {
+				question.TargetParameterID = parametersIDs[question.TargetParameter]
+			}
+			targetParameterID = &question.TargetParameterID
+		}
+		if _, ok := m.cases[question.CaseID]; !ok {
+			return fmt.Errorf("insert question %d: case %d does not exist", i+1, question.CaseID)
+		}
+		question.ID = m.newID()
+		m.questions[question.ID] = memoryQuestion{
+			ID:                question.ID,
+			Question:          question.Question,
+			PredictionAge:     question.PredictionAge,
+			CaseID:            question.CaseID,
+			Group:             question.GroupID,
+			Status:            question.Status,
+			Type:              question.Type,
+			TargetParameterID: targetParameterID,
+			Tolerance:         question.Tolerance,
+		}
+		for _, optionID := range question.OptionsIDs {
+			if _, ok := m.options[optionID]; !ok {
+				return fmt.Errorf("insert options of question %d: option %d does not exist", i+1, optionID)
+			}
+			m.questionOptions = append(m.questionOptions, memoryQuestionOption{question.ID, optionID, optionID == question.CorrectOptionID})
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ExportQuestionBank(ctx context.Context) (models.ImportBundle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bundle := models.ImportBundle{Version: models.BundleVersion}
+
+	parameters := slices.Collect(maps.Values(m.parameters))
+	slices.SortFunc(parameters, func(a, b models.Parameter) int {
+		return cmp.Or(cmp.Compare(a.Order, b.Order), cmp.Compare(a.ID, b.ID))
+	})
+	for _, p := range parameters {
+		bundle.Parameters = append(bundle.Parameters, models.ImportParameter{ID: p.ID, Name: p.Name, Description: p.Description, ReferenceValues: p.ReferenceValues, Order: p.Order})
+	}
+	for _, id := range sortedKeys(m.options) {
+		bundle.Options = append(bundle.Options, models.ImportOption{ID: id, Option: m.options[id]})
+	}
+	for _, g := range m.sortedGroups() {
+		bundle.Groups = append(bundle.Groups, models.ImportGroup{ID: g.ID, GroupID: g.ID, Name: g.Name, Description: g.Description, Order: g.Order, Enabled: g.Enabled})
+	}
+	for _, name := range slices.Sorted(maps.Keys(m.settings)) {
+		bundle.Settings = append(bundle.Settings, models.ImportSetting{Name: name, Value: m.settings[name]})
+	}
+
+	for _, id := range sortedKeys(m.cases) {
+		c, _ := m.caseOf(id)
+		exported := models.ImportCase{ID: c.ID, Code: c.Code, Gender: c.Gender, Age1: c.Age1, Age2: c.Age2, Age3: c.Age3, Landmarks: c.Landmarks}
+		exported.Parameters = make([]models.ImportParameterValue, 0, len(c.ParameterValues))
+		for i, value := range c.ParameterValues {
+			exported.Parameters = append(exported.Parameters, models.ImportParameterValue{
+				ParameterID: value.ParameterID,
+				Parameter:   c.Parameters[i].Name,
+				Value1:      value.Value1,
+				Value2:      value.Value2,
+				Value3:      value.Value3,
+			})
+		}
+		bundle.Cases = append(bundle.Cases, exported)
+	}
+
+	for _, id := range sortedKeys(m.questions) {
+		q := m.questions[id]
+		exported := models.ImportQuestion{
+			ID:            q.ID,
+			CaseID:        q.CaseID,
+			CaseCode:      m.cases[q.CaseID].Code,
+			Question:      q.Question,
+			PredictionAge: q.PredictionAge,
+			Group:         q.Group,
+			GroupID:       q.Group,
+			Type:          q.Type,
+			Tolerance:     q.Tolerance,
+			Status:        q.Status,
+			Options:       make([]string, 0),
+			Images:        make([]string, 0),
+		}
+		if q.TargetParameterID != nil {
+			exported.TargetParameterID = *q.TargetParameterID
+			exported.TargetParameter = m.parameters[*q.TargetParameterID].Name
+		}
+		var links []memoryQuestionOption
+		for _, link := range m.questionOptions {
+			if link.questionID == id {
+				links = append(links, link)
+			}
+		}
+		slices.SortFunc(links, func(a, b memoryQuestionOption) int {
+			return cmp.Compare(a.optionID, b.optionID)
+		})
+		for _, link := range links {
+			exported.Options = append(exported.Options, m.options[link.optionID])
+			exported.OptionsIDs = append(exported.OptionsIDs, link.optionID)
+			if link.isCorrect {
+				exported.Correct = m.options[link.optionID]
+				exported.CorrectOptionID = link.optionID
+			}
+		}
+		bundle.Questions = append(bundle.Questions, exported)
+	}
+	return bundle, nil
+}
@@ -1,81 +1,204 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/lib/pq"
 	"go.uber.org/zap"
+	"quiz/internal/events"
 	"quiz/internal/models"
+	"slices"
 	"strconv"
+	"time"
 )
 
 type Store interface {
-	Ping() error
+	Ping(ctx context.Context) error
 	Close() error
 
 	// sessions
-	CreateQuizSession(session models.QuizSession) (models.QuizSession, error)
-	GetQuizSessionByID(id int) (models.QuizSession, error)
-	UpdateQuizSession(session models.QuizSession) error
-	GetUserActiveQuizSessions(userID int) ([]models.QuizSession, error)
-	GetUserLastQuizSession(userID int) (*models.QuizSession, error)
-	GetTimeLimit() (int, error)
-	SaveSettings(name string, value string) error
+	CreateQuizSession(ctx context.Context, session models.QuizSession) (models.QuizSession, error)
+	GetQuizSessionByID(ctx context.Context, id int) (models.QuizSession, error)
+	UpdateQuizSession(ctx context.Context, sessionID int, update func(session *models.QuizSession) ([]models.OutboxEvent, error)) error
+	GetUserActiveQuizSessions(ctx context.Context, userID int) ([]models.QuizSession, error)
+	GetUserLastQuizSession(ctx context.Context, userID int, mode models.QuizMode) (*models.QuizSession, error)
+	GetUserOpenQuizSession(ctx context.Context, userID int, mode models.QuizMode, assignmentID *int) (*models.QuizSession, error)
+	SubmitAnswer(ctx context.Context, sessionID int, idempotencyKey string, submit func(store Store, session *models.QuizSession, recorded []byte) ([]byte, []models.OutboxEvent, error)) ([]byte, error)
+	GetTimeLimit(ctx context.Context) (int, error)
+	GetPinnedSeed(ctx context.Context) (seed int64, pinned bool, err error)
+	SaveSettings(ctx context.Context, name string, value string) error
 
 	// questions
-	GetQuestionByID(id int) (models.Question, error)
-	GetAllQuestions() ([]models.Question, error)
-	CreateQuestion(newCase models.QuestionPayload) (models.QuestionPayload, error)
-	UpdateQuestionByID(questionID int, updatedCase models.QuestionPayload) (models.QuestionPayload, error)
-	UpdateQuestionCorrectOption(questionID int, option string) error
-	DeleteQuestionByID(id int) error
-	CountQuestions() (int, error)
-	GetQuestionOptions(id int) ([]string, error)
-	GetQuestionCorrectOption(id int) (string, error)
+	GetQuestionByID(ctx context.Context, id int) (models.Question, error)
+	GetAllQuestions(ctx context.Context) ([]models.Question, error)
+	CreateQuestion(ctx context.Context, newCase models.QuestionPayload) (models.QuestionPayload, error)
+	UpdateQuestionByID(ctx context.Context, questionID int, updatedCase models.QuestionPayload) (models.QuestionPayload, error)
+	UpdateQuestionStatus(ctx context.Context, questionID int, from string, to string) error
+	UpdateQuestionCorrectOption(ctx context.Context, questionID int, option string) error
+	DeleteQuestionByID(ctx context.Context, id int) error
+	CountQuestions(ctx context.Context) (int, error)
+	GetQuestionOptions(ctx context.Context, id int) ([]string, error)
+	SnapshotQuestion(ctx context.Context, questionID int) (models.QuestionVersion, error)
+	GetQuestionVersion(ctx context.Context, questionID int, version int) (models.QuestionVersion, error)
+	GetQuestionVersions(ctx context.Context, questionID int) ([]models.QuestionVersion, error)
+	GetQuestionCorrectOption(ctx context.Context, id int) (string, error)
 
 	// options
-	GetAllOptions() ([]models.Option, error)
-	CreateOption(option models.Option) (models.Option, error)
-	UpdateOption(id int, option models.Option) error
+	GetAllOptions(ctx context.Context) ([]models.Option, error)
+	CreateOption(ctx context.Context, option models.Option) (models.Option, error)
+	UpdateOption(ctx context.Context, id int, option models.Option) error
 
 	// cases
-	CreateCase(newCase models.Case) (models.Case, error)
-	UpdateCase(updatedCase models.Case) (models.Case, error)
-	DeleteCaseWithParameters(id int) error
-	GetAllCases() ([]models.Case, error)
-	GetCaseByID(id int) (models.Case, error)
-	CreateCaseParameter(caseID int, parameter models.ParameterValue) (models.ParameterValue, error)
-	UpdateCaseParameters(caseID int, parameters []models.Parameter, values []models.ParameterValue) error
+	CreateCase(ctx context.Context, newCase models.Case) (models.Case, error)
+	UpdateCase(ctx context.Context, updatedCase models.Case) (models.Case, error)
+	DeleteCaseWithParameters(ctx context.Context, id int) error
+	GetAllCases(ctx context.Context) ([]models.Case, error)
+	GetCaseByID(ctx context.Context, id int) (models.Case, error)
+	GetCaseIDByCode(ctx context.Context, code string) (int, error)
+	CreateCaseParameter(ctx context.Context, caseID int, parameter models.ParameterValue) (models.ParameterValue, error)
+	UpdateCaseParameters(ctx context.Context, caseID int, parameters []models.Parameter, values []models.ParameterValue) error
+	GetCaseLandmarks(ctx context.Context, caseID int) ([]models.Landmark, error)
+	SaveCaseLandmarks(ctx context.Context, caseID int, landmarks []models.Landmark) error
 
 	// parameters
-	CreateParameter(parameter models.Parameter) (models.Parameter, error)
-	UpdateParameter(parameter models.Parameter) error
-	DeleteParameter(id int) error
-	GetAllParameters() ([]models.Parameter, error)
-	GetParameterByID(id int) (models.Parameter, error)
-	UpdateParametersOrder(params []models.Parameter) error
+	CreateParameter(ctx context.Context, parameter models.Parameter) (models.Parameter, error)
+	UpdateParameter(ctx context.Context, parameter models.Parameter) error
+	DeleteParameter(ctx context.Context, id int) error
+	GetAllParameters(ctx context.Context) ([]models.Parameter, error)
+	GetParameterByID(ctx context.Context, id int) (models.Parameter, error)
+	UpdateParametersOrder(ctx context.Context, params []models.Parameter) error
 
 	//groups
-	GetGroupQuestionsIDsRandomOrder(groupID int) ([]int, error)
-	GetNextQuestionGroupID(currentGroup int) (int, error)
-	DeleteOption(id int) error
-	GetSettings() ([]models.Settings, error)
+	GetGroupQuestionsIDs(ctx context.Context, groupID int) ([]int, error)
+	GetEnabledQuestionsIDs(ctx context.Context) ([]int, error)
+	GetNextQuestionGroupID(ctx context.Context, currentGroup int) (int, error)
+	GetAllGroups(ctx context.Context) ([]models.QuestionsGroup, error)
+	GetGroupByID(ctx context.Context, id int) (models.QuestionsGroup, error)
+	CreateGroup(ctx context.Context, group models.QuestionsGroup) (models.QuestionsGroup, error)
+	UpdateGroup(ctx context.Context, group models.QuestionsGroup) error
+	DeleteGroup(ctx context.Context, id int) error
+	UpdateGroupsOrder(ctx context.Context, groups []models.QuestionsGroup) error
+	MoveQuestionsToGroup(ctx context.Context, groupID int, questionIDs []int) error
+	DeleteOption(ctx context.Context, id int) error
+	GetSettings(ctx context.Context) ([]models.Settings, error)
+
+	// assignments
+	CreateAssignment(ctx context.Context, assignment models.Assignment) (models.Assignment, error)
+	GetAssignmentByID(ctx context.Context, id int) (models.Assignment, error)
+	GetAssignments(ctx context.Context, teacherID int) ([]models.Assignment, error)
+	UpdateAssignment(ctx context.Context, assignment models.Assignment) error
+	DeleteAssignment(ctx context.Context, id int) error
+	GetAssignmentQuestionsIDs(ctx context.Context, assignment models.Assignment) ([]int, error)
+	GetStudentAssignments(ctx context.Context, userID int) ([]models.StudentAssignment, error)
+	GetAssignmentSessions(ctx context.Context, assignmentID int) ([]models.AssignmentSession, error)
+	CreateAssignmentSession(ctx context.Context, session models.QuizSession, maxAttempts int) (models.QuizSession, error)
+
+	// spaced repetition review
+	AddReviewItems(ctx context.Context, items []models.ReviewItem) error
+	GetDueReviewQuestionsIDs(ctx context.Context, userID int, now time.Time) ([]int, error)
+	GetReviewItem(ctx context.Context, userID int, questionID int) (models.ReviewItem, error)
+	SaveReviewItem(ctx context.Context, item models.ReviewItem) error
+
+	// translations
+	GetTranslations(ctx context.Context, entity string, entityID int) ([]models.Translation, error)
+	SaveTranslation(ctx context.Context, translation models.Translation) error
+	DeleteTranslation(ctx context.Context, entity string, entityID int, field string, locale string) error
+	GetMissingTranslations(ctx context.Context, locale string) ([]models.MissingTranslation, error)
+	GetLocalizedFields(ctx context.Context, entity string, entitiesIDs []int, locales []string) (map[int]map[string]map[string]string, error)
+	GetOptionsTranslations(ctx context.Context, options []string, locales []string) (map[string]map[string]string, error)
+	GetUserLocale(ctx context.Context, userID int) (string, error)
+	SaveUserLocale(ctx context.Context, userID int, locale string) error
+	DeleteUserData(ctx context.Context, userID int) error
+
+	// outbox of events published to other services
+	GetDueOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	MarkOutboxEventDelivered(ctx context.Context, id int) error
+	MarkOutboxEventFailed(ctx context.Context, id int, lastError string, nextAttemptAt time.Time) error
+	GetStuckOutboxEvents(ctx context.Context, minAttempts int) ([]models.OutboxEvent, error)
+	RetryOutboxEvent(ctx context.Context, id int) error
+
+	// import and export
+	ImportQuestionBank(ctx context.Context, bundle *models.ImportBundle, dryRun bool, beforeCommit func(bundle *models.ImportBundle) error) error
+	ExportQuestionBank(ctx context.Context) (models.ImportBundle, error)
 }
 
+var ErrQuizSessionNotFound = fmt.Errorf("quiz session not found")
+var ErrGroupNotFound = fmt.Errorf("group not found")
+var ErrQuestionVersionNotFound = fmt.Errorf("question version not found")
+var ErrQuestionNotFound = fmt.Errorf("question not found")
+var ErrQuestionStatusChanged = fmt.Errorf("question status was changed concurrently")
+var ErrCaseNotFound = fmt.Errorf("case not found")
+var ErrCaseInUse = fmt.Errorf("case is used by questions")
+var ErrGroupInUse = fmt.Errorf("group is the current group of open quiz sessions")
+var ErrReviewItemNotFound = fmt.Errorf("review item not found")
+var ErrAssignmentNotFound = fmt.Errorf("assignment not found")
+var ErrAssignmentSessionOpen = fmt.Errorf("assignment session is already open")
+var ErrNoAttemptsLeft = fmt.Errorf("no attempts left")
+var ErrQuizSessionOpen = fmt.Errorf("quiz session in this mode is already open")
+var ErrTranslationNotFound = fmt.Errorf("translation not found")
+var ErrTranslatedEntityNotFound = fmt.Errorf("translated entity not found")
+var ErrOutboxEventNotFound = fmt.Errorf("outbox event not found")
+var ErrNestedTransaction = fmt.Errorf("storage method starting a transaction called in a transaction")
+
 type PostgresStorage struct {
-	db     *sql.DB
+	// db is nil for a store bound to a transaction, see inTx
+	db *sql.DB
+	// conn runs the statements of the store, it is db or the transaction of the store
+	conn   queryer
 	logger *zap.Logger
+	// queryTimeout bounds each storage call, the deadline of the caller's context applies when it is earlier
+	queryTimeout time.Duration
 }
 
-func NewPostgresStorage(db *sql.DB, logger *zap.Logger) *PostgresStorage {
+func NewPostgresStorage(db *sql.DB, logger *zap.Logger, queryTimeout time.Duration) *PostgresStorage {
 	return &PostgresStorage{
-		db:     db,
-		logger: logger,
+		db:           db,
+		conn:         db,
+		logger:       logger,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// inTx returns a store running its statements in the transaction. Methods starting their own transaction fail
+// with ErrNestedTransaction, other methods see and make changes of the transaction
+func (s *PostgresStorage) inTx(tx *sql.Tx) *PostgresStorage {
+	return &PostgresStorage{conn: tx, logger: s.logger, queryTimeout: s.queryTimeout}
+}
+
+func (s *PostgresStorage) beginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if s.db == nil {
+		return nil, ErrNestedTransaction
 	}
+	return s.db.BeginTx(ctx, opts)
+}
+
+func (s *PostgresStorage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.queryTimeout)
 }
 
-func (s *PostgresStorage) Ping() error {
-	return s.db.Ping()
+// IsTimeout reports whether a storage call failed because it ran out of time,
+// either on the query timeout or on the deadline of the context
+func IsTimeout(err error) bool {
+	var pqErr *pq.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &pqErr) && pqErr.Code == queryCanceled
+}
+
+// queryCanceled is the code of the error returned by Postgres for statements cancelled on a deadline
+const queryCanceled = "57014"
+
+// uniqueViolation is the code of the error returned by Postgres for rows breaking a unique index
+const uniqueViolation = "23505"
+
+// openSessionIndex allows a single open session per user and mode outside assignments and the sandbox
+const openSessionIndex = "quiz_sessions_open_mode_idx"
+
+func (s *PostgresStorage) Ping(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.db.PingContext(ctx)
 }
 
 func (s *PostgresStorage) Close() error {
@@ -83,13 +206,71 @@ func (s *PostgresStorage) Close() error {
 }
 
 // Quiz Sessions
-func (s *PostgresStorage) CreateQuizSession(session models.QuizSession) (models.QuizSession, error) {
+// CreateQuizSession saves a new session together with the event announcing it
+func (s *PostgresStorage) CreateQuizSession(ctx context.Context, session models.QuizSession) (models.QuizSession, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return session, err
+	}
+	defer tx.Rollback()
+
+	if session, err = insertQuizSession(ctx, tx, session); err != nil {
+		return session, err
+	}
+	return session, tx.Commit()
+}
+
+// CreateAssignmentSession saves a new session of the student for the assignment of the session. The row of the student
+// in the assignment is locked while the sessions are counted, so concurrent starts cannot exceed maxAttempts or open
+// a second session. ErrAssignmentNotFound is returned when the user is not a student of the assignment
+func (s *PostgresStorage) CreateAssignmentSession(ctx context.Context, session models.QuizSession, maxAttempts int) (models.QuizSession, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return session, err
+	}
+	defer tx.Rollback()
+
+	var locked int
+	err = tx.QueryRowContext(ctx, `SELECT user_id FROM assignment_students WHERE assignment_id = $1 AND user_id = $2 FOR UPDATE`,
+		session.AssignmentID, session.UserID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return session, ErrAssignmentNotFound
+	}
+	if err != nil {
+		return session, err
+	}
+	var attempts, open int
+	err = tx.QueryRowContext(ctx, `
+        SELECT count(*), count(*) FILTER (WHERE status NOT IN ('finished', 'abandoned'))
+        FROM quiz_sessions
+        WHERE assignment_id = $1 AND user_id = $2`, session.AssignmentID, session.UserID).Scan(&attempts, &open)
+	if err != nil {
+		return session, err
+	}
+	if open > 0 {
+		return session, ErrAssignmentSessionOpen
+	}
+	if maxAttempts > 0 && attempts >= maxAttempts {
+		return session, ErrNoAttemptsLeft
+	}
+	if session, err = insertQuizSession(ctx, tx, session); err != nil {
+		return session, err
+	}
+	return session, tx.Commit()
+}
+
+// insertQuizSession inserts the session and the event announcing it in the transaction
+func insertQuizSession(ctx context.Context, tx *sql.Tx, session models.QuizSession) (models.QuizSession, error) {
 	query := `
-        INSERT INTO quiz_sessions (user_id, status, mode, screen_size, current_question, current_group, group_order, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+        INSERT INTO quiz_sessions (user_id, status, mode, screen_size, current_question, current_group, group_order, assignment_id, seed, current_question_version, sandbox, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
         RETURNING id, created_at, updated_at`
 
-	err := s.db.QueryRow(
+	err := tx.QueryRowContext(ctx,
 		query,
 		session.UserID,
 		session.Status,
@@ -98,20 +279,46 @@ func (s *PostgresStorage) CreateQuizSession(session models.QuizSession) (models.
 		session.CurrentQuestionID,
 		session.CurrentGroup,
 		pq.Array(session.GroupOrder),
+		session.AssignmentID,
+		session.Seed,
+		session.CurrentQuestionVersion,
+		session.Sandbox,
 	).Scan(&session.ID, &session.CreatedAt, &session.UpdatedAt)
-
-	return session, err
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == openSessionIndex {
+		return session, ErrQuizSessionOpen
+	}
+	if err != nil {
+		return session, err
+	}
+	// sandbox sessions are not recorded in stats
+	if !session.Sandbox {
+		event, err := models.NewOutboxEvent(events.QuizStarted, session.ID, session)
+		if err != nil {
+			return session, err
+		}
+		if err = insertOutboxEvents(ctx, tx, []models.OutboxEvent{event}); err != nil {
+			return session, err
+		}
+	}
+	return session, nil
 }
 
-func (s *PostgresStorage) GetQuizSessionByID(id int) (models.QuizSession, error) {
-	var session models.QuizSession
-	query := `
-        SELECT id, user_id, status, mode, current_question, current_group, group_order, created_at, updated_at, finished_at, question_requested_time
+const quizSessionQuery = `
+        SELECT id, user_id, status, mode, current_question, current_group, group_order, created_at, updated_at, finished_at, question_requested_time, assignment_id, seed, current_question_version, sandbox
         FROM quiz_sessions
         WHERE id = $1`
 
+func (s *PostgresStorage) GetQuizSessionByID(ctx context.Context, id int) (models.QuizSession, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return scanQuizSession(s.conn.QueryRowContext(ctx, quizSessionQuery, id))
+}
+
+func scanQuizSession(row *sql.Row) (models.QuizSession, error) {
+	var session models.QuizSession
 	var intermediateArray []sql.NullInt64
-	err := s.db.QueryRow(query, id).Scan(
+	err := row.Scan(
 		&session.ID,
 		&session.UserID,
 		&session.Status,
@@ -123,6 +330,10 @@ func (s *PostgresStorage) GetQuizSessionByID(id int) (models.QuizSession, error)
 		&session.UpdatedAt,
 		&session.FinishedAt,
 		&session.QuestionRequestedTime,
+		&session.AssignmentID,
+		&session.Seed,
+		&session.CurrentQuestionVersion,
+		&session.Sandbox,
 	)
 	session.GroupOrder = make([]int, 0, len(intermediateArray))
 	for _, nullInt := range intermediateArray {
@@ -134,7 +345,51 @@ func (s *PostgresStorage) GetQuizSessionByID(id int) (models.QuizSession, error)
 	return session, err
 }
 
-func (s *PostgresStorage) UpdateQuizSession(session models.QuizSession) error {
+// UpdateQuizSession runs update with the row of the session locked like SubmitAnswer, so that the changes are made
+// to the latest state of the session. The session changed by update is saved with the events it returns in the same
+// transaction, an error of update rolls back all changes
+func (s *PostgresStorage) UpdateQuizSession(ctx context.Context, sessionID int, update func(session *models.QuizSession) ([]models.OutboxEvent, error)) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	session, err := scanQuizSession(tx.QueryRowContext(ctx, quizSessionQuery+" FOR UPDATE", sessionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrQuizSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	events, err := update(&session)
+	if err != nil {
+		return err
+	}
+	if err = updateQuizSession(ctx, tx, session); err != nil {
+		return err
+	}
+	if err = insertOutboxEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execer runs statements either directly on the database or in a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// queryer runs statements and queries either directly on the database or in a transaction
+type queryer interface {
+	execer
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func updateQuizSession(ctx context.Context, db execer, session models.QuizSession) error {
 	query := `
         UPDATE quiz_sessions
         SET status = $1, 
@@ -144,10 +399,11 @@ func (s *PostgresStorage) UpdateQuizSession(session models.QuizSession) error {
             group_order = $5, 
             updated_at = NOW(), 
             finished_at = $6,
-        question_requested_time = $8
+        question_requested_time = $8,
+            current_question_version = $9
         WHERE id = $7`
 
-	_, err := s.db.Exec(
+	_, err := db.ExecContext(ctx,
 		query,
 		session.Status,
 		session.Mode,
@@ -157,20 +413,70 @@ func (s *PostgresStorage) UpdateQuizSession(session models.QuizSession) error {
 		session.FinishedAt,
 		session.ID,
 		session.QuestionRequestedTime,
+		session.CurrentQuestionVersion,
 	)
 
 	return err
 }
 
+// SubmitAnswer runs submit with the row of the session locked, so that concurrent submissions to the same session
+// are processed one after another. submit gets a store bound to the transaction holding the lock, the response
+// recorded earlier for the idempotency key, nil for a new submission, and returns the response to send with the
+// events of the submission. Only new submissions save the session changed by submit, the response and the events,
+// in the same transaction. An error of submit rolls back all changes, including the ones made through the store.
+// submit should not wait on other services, the lock and the connection are held until it returns
+func (s *PostgresStorage) SubmitAnswer(ctx context.Context, sessionID int, idempotencyKey string, submit func(store Store, session *models.QuizSession, recorded []byte) ([]byte, []models.OutboxEvent, error)) ([]byte, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := scanQuizSession(tx.QueryRowContext(ctx, quizSessionQuery+" FOR UPDATE", sessionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrQuizSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var recorded []byte
+	err = tx.QueryRowContext(ctx, `SELECT response FROM answer_submissions WHERE session_id = $1 AND idempotency_key = $2`, sessionID, idempotencyKey).Scan(&recorded)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	response, events, err := submit(s.inTx(tx), &session, recorded)
+	if err != nil {
+		return nil, err
+	}
+	if recorded != nil {
+		return response, tx.Commit()
+	}
+	if err = updateQuizSession(ctx, tx, session); err != nil {
+		return nil, err
+	}
+	if err = insertOutboxEvents(ctx, tx, events); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO answer_submissions (session_id, idempotency_key, response, created_at) VALUES ($1, $2, $3, NOW())`, sessionID, idempotencyKey, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, tx.Commit()
+}
+
 // todo: handle group & group order
-func (s *PostgresStorage) GetUserActiveQuizSessions(userID int) ([]models.QuizSession, error) {
+func (s *PostgresStorage) GetUserActiveQuizSessions(ctx context.Context, userID int) ([]models.QuizSession, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
-        SELECT id, user_id, status, mode, current_question, created_at, updated_at, finished_at
+        SELECT id, user_id, status, mode, current_question, created_at, updated_at, finished_at, assignment_id
         FROM quiz_sessions
-        WHERE user_id = $1 and status != 'finished'
+        WHERE user_id = $1 and status NOT IN ('finished', 'abandoned') AND NOT sandbox
         ORDER BY created_at DESC`
 
-	rows, err := s.db.Query(query, userID)
+	rows, err := s.conn.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -188,6 +494,7 @@ func (s *PostgresStorage) GetUserActiveQuizSessions(userID int) ([]models.QuizSe
 			&session.CreatedAt,
 			&session.UpdatedAt,
 			&session.FinishedAt,
+			&session.AssignmentID,
 		)
 		if err != nil {
 			return nil, err
@@ -198,18 +505,41 @@ func (s *PostgresStorage) GetUserActiveQuizSessions(userID int) ([]models.QuizSe
 	return sessions, rows.Err()
 }
 
-func (s *PostgresStorage) GetUserLastQuizSession(userID int) (*models.QuizSession, error) {
+// GetUserLastQuizSession returns the latest session of the user in the mode, sessions of assignments are skipped
+func (s *PostgresStorage) GetUserLastQuizSession(ctx context.Context, userID int, mode models.QuizMode) (*models.QuizSession, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
-        SELECT id, user_id, status, mode, current_question, current_group, group_order, created_at, updated_at, finished_at
+        SELECT id, user_id, status, mode, current_question, current_group, group_order, created_at, updated_at, finished_at, seed
         FROM quiz_sessions
-        WHERE user_id = $1
+        WHERE user_id = $1 AND mode = $2 AND assignment_id IS NULL AND NOT sandbox
         ORDER BY created_at DESC
         LIMIT 1`
 
+	return s.scanUserSession(s.conn.QueryRowContext(ctx, query, userID, mode))
+}
+
+// GetUserOpenQuizSession returns the session of the user which is neither finished nor abandoned,
+// either of the assignment or, when assignmentID is nil, the one in the mode outside of assignments
+func (s *PostgresStorage) GetUserOpenQuizSession(ctx context.Context, userID int, mode models.QuizMode, assignmentID *int) (*models.QuizSession, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+        SELECT id, user_id, status, mode, current_question, current_group, group_order, created_at, updated_at, finished_at, seed
+        FROM quiz_sessions
+        WHERE user_id = $1 AND status NOT IN ('finished', 'abandoned') AND NOT sandbox
+          AND (($3::int IS NULL AND assignment_id IS NULL AND mode = $2) OR assignment_id = $3)
+        ORDER BY created_at DESC
+        LIMIT 1`
+
+	return s.scanUserSession(s.conn.QueryRowContext(ctx, query, userID, mode, assignmentID))
+}
+
+func (s *PostgresStorage) scanUserSession(row *sql.Row) (*models.QuizSession, error) {
 	var session models.QuizSession
 	var intermediateArray []int64
 
-	err := s.db.QueryRow(query, userID).Scan(
+	err := row.Scan(
 		&session.ID,
 		&session.UserID,
 		&session.Status,
@@ -220,6 +550,7 @@ func (s *PostgresStorage) GetUserLastQuizSession(userID int) (*models.QuizSessio
 		&session.CreatedAt,
 		&session.UpdatedAt,
 		&session.FinishedAt,
+		&session.Seed,
 	)
 
 	if err != nil {
@@ -238,16 +569,56 @@ func (s *PostgresStorage) GetUserLastQuizSession(userID int) (*models.QuizSessio
 }
 
 // Questions
-func (s *PostgresStorage) GetQuestionByID(id int) (models.Question, error) {
-	query := `
+func (s *PostgresStorage) GetQuestionByID(ctx context.Context, id int) (models.Question, error) {
+	question, _, err := s.getQuestion(ctx, id)
+	return question, err
+}
+
+// questionQuery reads a question together with its options, the correct option, the parameters of its case
+// and the case landmarks, the related rows are aggregated so that the question is read in a single round trip
+const questionQuery = `
         SELECT q.id, q.question, q.prediction_age,
-               c.id, c.code, c.patient_gender, c.age1, c.age2, c.age3, q.group_number
+               c.id, c.code, c.patient_gender, c.age1, c.age2, c.age3, q.group_number, q.status,
+               q.type, q.target_parameter_id, q.tolerance,
+               (SELECT array_agg(o.option ORDER BY o.id)
+                FROM question_options qo JOIN options o ON o.id = qo.option_id
+                WHERE qo.question_id = q.id),
+               (SELECT o.option
+                FROM question_options qo JOIN options o ON o.id = qo.option_id
+                WHERE qo.question_id = q.id AND qo.is_correct
+                LIMIT 1),
+               (SELECT json_agg(json_build_object(
+                           'parameter_id', p.id, 'name', p.name, 'description', p.description, 'reference_values', p.reference_value,
+                           'value1', cp.value_1, 'value2', cp.value_2, 'value3', cp.value_3) ORDER BY p.display_order, p.id)
+                FROM case_parameters cp JOIN parameters p ON p.id = cp.parameter_id
+                WHERE cp.case_id = c.id),
+               (SELECT json_agg(json_build_object('name', l.name, 'x', l.x, 'y', l.y) ORDER BY l.position)
+                FROM case_landmarks l
+                WHERE l.case_id = c.id)
         FROM questions q
         JOIN cases c ON q.case_id = c.id
         WHERE q.id = $1`
 
+// caseParameterRow is a parameter of a case aggregated by questionQuery
+type caseParameterRow struct {
+	ParameterID     int      `json:"parameter_id"`
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	ReferenceValues string   `json:"reference_values"`
+	Value1          float64  `json:"value1"`
+	Value2          float64  `json:"value2"`
+	Value3          *float64 `json:"value3"`
+}
+
+// getQuestion returns the question and its correct option, which is empty when the question has none
+func (s *PostgresStorage) getQuestion(ctx context.Context, id int) (models.Question, string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	var question models.Question
-	err := s.db.QueryRow(query, id).Scan(
+	var options pq.StringArray
+	var correct sql.NullString
+	var parameters, landmarks []byte
+	err := s.conn.QueryRowContext(ctx, questionQuery, id).Scan(
 		&question.ID,
 		&question.Question,
 		&question.PredictionAge,
@@ -258,31 +629,62 @@ func (s *PostgresStorage) GetQuestionByID(id int) (models.Question, error) {
 		&question.Case.Age2,
 		&question.Case.Age3,
 		&question.Group,
+		&question.Status,
+		&question.Type,
+		&question.TargetParameterID,
+		&question.Tolerance,
+		&options,
+		&correct,
+		&parameters,
+		&landmarks,
 	)
-	if err != nil {
-		return question, err
+	if err == sql.ErrNoRows {
+		return question, "", ErrQuestionNotFound
 	}
-
-	question.Options, err = s.GetQuestionOptions(id)
 	if err != nil {
-		return question, err
+		return question, "", err
 	}
+	question.Options = options
 
-	question.Case.Parameters, question.Case.ParameterValues, err = s.getCaseParameters(question.Case.ID)
-	if err != nil {
-		return question, err
+	var rows []caseParameterRow
+	if parameters != nil {
+		if err = json.Unmarshal(parameters, &rows); err != nil {
+			return question, "", err
+		}
 	}
-
-	return question, nil
+	question.Case.Parameters = make([]models.Parameter, 0, len(rows))
+	question.Case.ParameterValues = make([]models.ParameterValue, 0, len(rows))
+	for _, row := range rows {
+		question.Case.Parameters = append(question.Case.Parameters, models.Parameter{
+			ID:              row.ParameterID,
+			Name:            row.Name,
+			Description:     row.Description,
+			ReferenceValues: row.ReferenceValues,
+		})
+		question.Case.ParameterValues = append(question.Case.ParameterValues, models.ParameterValue{
+			ParameterID: row.ParameterID,
+			Value1:      row.Value1,
+			Value2:      row.Value2,
+			Value3:      row.Value3,
+		})
+	}
+	if landmarks != nil {
+		if err = json.Unmarshal(landmarks, &question.Case.Landmarks); err != nil {
+			return question, "", err
+		}
+	}
+	return question, correct.String, nil
 }
 
-func (s *PostgresStorage) GetQuestionOptions(id int) ([]string, error) {
+func (s *PostgresStorage) GetQuestionOptions(ctx context.Context, id int) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
 		SELECT o.option from options o
 			JOIN question_options qo on o.id = qo.option_id
 			WHERE qo.question_id = $1 ORDER BY o.id`
 
-	rows, err := s.db.Query(query, id)
+	rows, err := s.conn.QueryContext(ctx, query, id)
 	if err != nil {
 		return nil, err
 	}
@@ -298,30 +700,167 @@ func (s *PostgresStorage) GetQuestionOptions(id int) ([]string, error) {
 
 	return options, rows.Err()
 }
-func (s *PostgresStorage) GetQuestionCorrectOption(id int) (string, error) {
+
+// SnapshotQuestion returns the latest version of the question, a new version is recorded first
+// if the question was changed since. Versions are numbered from 1 for each question
+func (s *PostgresStorage) SnapshotQuestion(ctx context.Context, questionID int) (models.QuestionVersion, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	question, correct, err := s.getQuestion(ctx, questionID)
+	if err != nil {
+		return models.QuestionVersion{}, err
+	}
+	current := models.QuestionVersion{
+		QuestionID:        questionID,
+		Question:          question.Question,
+		PredictionAge:     question.PredictionAge,
+		Options:           question.Options,
+		Case:              question.Case,
+		Type:              question.Type,
+		TargetParameterID: question.TargetParameterID,
+		Tolerance:         question.Tolerance,
+		Correct:           correct,
+	}
+
+	latest, err := s.getLatestQuestionVersion(ctx, questionID)
+	if err == nil && latest.SameContent(current) {
+		return latest, nil
+	}
+	if err != nil && err != ErrQuestionVersionNotFound {
+		return current, err
+	}
+	caseSnapshot, err := json.Marshal(current.Case)
+	if err != nil {
+		return current, err
+	}
+	// concurrent snapshots of the same change may race for the version number, the loser reads the winner's version
+	_, err = s.conn.ExecContext(ctx, `
+		INSERT INTO question_versions (question_id, version, question, prediction_age, options, correct, case_snapshot, created_at,
+		                               type, target_parameter_id, tolerance)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), $8, $9, $10)
+		ON CONFLICT (question_id, version) DO NOTHING`,
+		questionID, latest.Version+1, current.Question, current.PredictionAge, pq.Array(current.Options), current.Correct, caseSnapshot,
+		current.Type, current.TargetParameterID, current.Tolerance)
+	if err != nil {
+		return current, err
+	}
+	return s.getLatestQuestionVersion(ctx, questionID)
+}
+
+func (s *PostgresStorage) getLatestQuestionVersion(ctx context.Context, questionID int) (models.QuestionVersion, error) {
+	query := `
+		SELECT question_id, version, question, prediction_age, options, correct, case_snapshot, created_at,
+		       type, target_parameter_id, tolerance
+		FROM question_versions
+		WHERE question_id = $1
+		ORDER BY version DESC
+		LIMIT 1`
+	return scanQuestionVersion(s.conn.QueryRowContext(ctx, query, questionID))
+}
+
+func (s *PostgresStorage) GetQuestionVersion(ctx context.Context, questionID int, version int) (models.QuestionVersion, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT question_id, version, question, prediction_age, options, correct, case_snapshot, created_at,
+		       type, target_parameter_id, tolerance
+		FROM question_versions
+		WHERE question_id = $1 AND version = $2`
+	return scanQuestionVersion(s.conn.QueryRowContext(ctx, query, questionID, version))
+}
+
+// GetQuestionVersions returns the history of the question, oldest version first
+func (s *PostgresStorage) GetQuestionVersions(ctx context.Context, questionID int) ([]models.QuestionVersion, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT question_id, version, question, prediction_age, options, correct, case_snapshot, created_at,
+		       type, target_parameter_id, tolerance
+		FROM question_versions
+		WHERE question_id = $1
+		ORDER BY version`
+	rows, err := s.conn.QueryContext(ctx, query, questionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	versions := []models.QuestionVersion{}
+	for rows.Next() {
+		version, err := scanQuestionVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+func scanQuestionVersion(row interface{ Scan(dest ...any) error }) (models.QuestionVersion, error) {
+	var version models.QuestionVersion
+	var caseSnapshot []byte
+	err := row.Scan(
+		&version.QuestionID,
+		&version.Version,
+		&version.Question,
+		&version.PredictionAge,
+		pq.Array(&version.Options),
+		&version.Correct,
+		&caseSnapshot,
+		&version.CreatedAt,
+		&version.Type,
+		&version.TargetParameterID,
+		&version.Tolerance,
+	)
+	if err == sql.ErrNoRows {
+		return version, ErrQuestionVersionNotFound
+	}
+	if err != nil {
+		return version, err
+	}
+	err = json.Unmarshal(caseSnapshot, &version.Case)
+	return version, err
+}
+
+func (s *PostgresStorage) GetQuestionCorrectOption(ctx context.Context, id int) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
 		SELECT o.option from options o
 			JOIN question_options qo on o.id = qo.option_id
 			WHERE qo.question_id = $1 and qo.is_correct = true`
 
 	var option string
-	err := s.db.QueryRow(query, id).Scan(&option)
+	err := s.conn.QueryRowContext(ctx, query, id).Scan(&option)
 	return option, err
 }
-func (s *PostgresStorage) GetAllQuestions() ([]models.Question, error) {
+
+// GetAllQuestions returns all questions with their options and correct option, cases are read without parameters
+func (s *PostgresStorage) GetAllQuestions(ctx context.Context) ([]models.Question, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
-				SELECT q.id, q.question, q.prediction_age,
-               c.id, c.code, c.patient_gender, c.age1, c.age2, c.age3, group_number
+        SELECT q.id, q.question, q.prediction_age,
+               c.id, c.code, c.patient_gender, c.age1, c.age2, c.age3, q.group_number, q.status,
+               q.type, q.target_parameter_id, q.tolerance,
+               array_agg(o.option ORDER BY o.id) FILTER (WHERE o.id IS NOT NULL),
+               coalesce((array_agg(o.option ORDER BY o.id) FILTER (WHERE qo.is_correct))[1], '')
         FROM questions q
-        JOIN cases c ON q.case_id = c.id order by q.id`
+        JOIN cases c ON q.case_id = c.id
+        LEFT JOIN question_options qo ON qo.question_id = q.id
+        LEFT JOIN options o ON o.id = qo.option_id
+        GROUP BY q.id, c.id
+        ORDER BY q.id`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.conn.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	var questions []models.Question
 	for rows.Next() {
 		var question models.Question
+		var options pq.StringArray
+		var correct string
 		err = rows.Scan(
 			&question.ID,
 			&question.Question,
@@ -332,37 +871,33 @@ func (s *PostgresStorage) GetAllQuestions() ([]models.Question, error) {
 			&question.Case.Age1,
 			&question.Case.Age2,
 			&question.Case.Age3,
-			&question.Group)
+			&question.Group,
+			&question.Status,
+			&question.Type,
+			&question.TargetParameterID,
+			&question.Tolerance,
+			&options,
+			&correct)
 		if err != nil {
 			return nil, err
 		}
-		//question.Case.Parameters, question.Case.ParameterValues, err = s.getCaseParameters(question.Case.ID)
-		//if err != nil {
-		//	return nil, err
-		//}
-		question.Options, err = s.GetQuestionOptions(question.ID)
-		if err != nil {
-			s.logger.Error("Failed to get question options", zap.Error(err))
-		}
-		correct, err := s.GetQuestionCorrectOption(question.ID)
-		if err != nil {
-			s.logger.Error("Failed to get question correct option", zap.Error(err))
-		}
+		question.Options = options
 		question.Correct = &correct
 		questions = append(questions, question)
 	}
-	defer rows.Close()
-	return questions, nil
+	return questions, rows.Err()
 }
 
-func (s *PostgresStorage) GetAllOptions() ([]models.Option, error) {
+func (s *PostgresStorage) GetAllOptions(ctx context.Context) ([]models.Option, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
 		SELECT o.id, o.option, count(qo.id) from options o
 		      left join public.question_options qo on o.id = qo.option_id
 				group by o.id, o.option ORDER BY o.id 
 		`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.conn.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -378,13 +913,16 @@ func (s *PostgresStorage) GetAllOptions() ([]models.Option, error) {
 	return options, nil
 }
 
-func (s *PostgresStorage) GetGroupQuestionsIDsRandomOrder(groupNumber int) ([]int, error) {
+// GetGroupQuestionsIDs returns questions of the group ordered by ID, the session order is generated by ordering.GroupOrder
+func (s *PostgresStorage) GetGroupQuestionsIDs(ctx context.Context, groupNumber int) ([]int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
 		SELECT id from questions
-		WHERE group_number = $1
-		order by random()`
+		WHERE group_number = $1 AND status = 'published'
+		order by id`
 
-	rows, err := s.db.Query(query, groupNumber)
+	rows, err := s.conn.QueryContext(ctx, query, groupNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -400,166 +938,570 @@ func (s *PostgresStorage) GetGroupQuestionsIDsRandomOrder(groupNumber int) ([]in
 	defer rows.Close()
 	return questions, nil
 }
-func (s *PostgresStorage) GetNextQuestionGroupID(currentGroup int) (int, error) {
+
+// GetEnabledQuestionsIDs returns questions of all enabled groups, which are the questions used by the adaptive mode
+func (s *PostgresStorage) GetEnabledQuestionsIDs(ctx context.Context) ([]int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
-		SELECT group_number from questions
-		WHERE group_number > $1
-		ORDER BY group_number
-		LIMIT 1`
+		SELECT q.id FROM questions q
+		JOIN ` + quizGroups + ` g ON g.id = q.group_number
+		WHERE g.enabled AND q.status = 'published'
+		ORDER BY q.id`
 
-	var nextGroup int
-	err := s.db.QueryRow(query, currentGroup).Scan(&nextGroup)
-	return nextGroup, err
+	rows, err := s.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var questions []int
+	for rows.Next() {
+		var questionID int
+		if err = rows.Scan(&questionID); err != nil {
+			return nil, err
+		}
+		questions = append(questions, questionID)
+	}
+	return questions, rows.Err()
 }
 
-func (s *PostgresStorage) CreateQuestion(payload models.QuestionPayload) (models.QuestionPayload, error) {
+// quizGroups are the groups the quiz is made of. A database which predates question_groups has no rows there,
+// its quiz is made of the groups given by group_number of the questions, in the order of the numbers
+const quizGroups = `(
+		SELECT id, display_order, enabled FROM question_groups
+		UNION ALL
+		SELECT DISTINCT group_number, group_number, true FROM questions
+		WHERE group_number <> 0 AND NOT EXISTS (SELECT 1 FROM question_groups)
+	)`
+
+// GetNextQuestionGroupID returns the enabled, non-empty group following currentGroup in display order.
+// Passing 0 as currentGroup returns the first group of the quiz.
+func (s *PostgresStorage) GetNextQuestionGroupID(ctx context.Context, currentGroup int) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
-        INSERT INTO questions (question, prediction_age, case_id)
-        VALUES ($1, $2, $3)
-        RETURNING id`
-
-	err := s.db.QueryRow(
-		query,
-		payload.Question,
-		payload.PredictionAge,
-		payload.CaseID,
-	).Scan(&payload.ID)
+		WITH groups AS ` + quizGroups + `,
+		current_group AS (
+			SELECT coalesce((SELECT display_order FROM groups WHERE id = $1), -1) AS display_order
+		)
+		SELECT g.id FROM groups g, current_group cg
+		WHERE g.enabled
+		  AND EXISTS (SELECT 1 FROM questions q WHERE q.group_number = g.id AND q.status = 'published')
+		  AND (g.display_order, g.id) > (cg.display_order, $1)
+		ORDER BY g.display_order, g.id
+		LIMIT 1`
 
-	return payload, err
+	var nextGroup int
+	err := s.conn.QueryRowContext(ctx, query, currentGroup).Scan(&nextGroup)
+	return nextGroup, err
 }
 
-func (s *PostgresStorage) UpdateQuestionByID(questionID int, payload models.QuestionPayload) (models.QuestionPayload, error) {
+func (s *PostgresStorage) GetAllGroups(ctx context.Context) ([]models.QuestionsGroup, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
-        UPDATE questions
-        SET question = $1, prediction_age = $2, case_id = $3, group_number = $5
-        WHERE id = $4`
-
-	_, err := s.db.Exec(
-		query,
-		payload.Question,
-		payload.PredictionAge,
-		payload.CaseID,
-		questionID,
-		payload.Group,
-	)
+		SELECT g.id, g.name, g.description, g.display_order, g.enabled,
+		       coalesce(array_agg(q.id ORDER BY q.id) FILTER (WHERE q.id IS NOT NULL), '{}')
+		FROM question_groups g
+		LEFT JOIN questions q ON q.group_number = g.id
+		GROUP BY g.id
+		ORDER BY g.display_order, g.id`
+
+	rows, err := s.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	payload.ID = questionID
-	return payload, err
+	groups := make([]models.QuestionsGroup, 0)
+	for rows.Next() {
+		var group models.QuestionsGroup
+		var questionsIDs []int64
+		err = rows.Scan(&group.ID, &group.Name, &group.Description, &group.Order, &group.Enabled, pq.Array(&questionsIDs))
+		if err != nil {
+			return nil, err
+		}
+		group.QuestionsIDs = make([]int, len(questionsIDs))
+		for i, id := range questionsIDs {
+			group.QuestionsIDs[i] = int(id)
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
 }
 
-func (s *PostgresStorage) UpdateQuestionCorrectOption(questionID int, option string) error {
-	var newCorrectID int
-	err := s.db.QueryRow("SELECT id FROM options WHERE option = $1", option).Scan(&newCorrectID)
+func (s *PostgresStorage) GetGroupByID(ctx context.Context, id int) (models.QuestionsGroup, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT g.id, g.name, g.description, g.display_order, g.enabled,
+		       coalesce(array_agg(q.id ORDER BY q.id) FILTER (WHERE q.id IS NOT NULL), '{}')
+		FROM question_groups g
+		LEFT JOIN questions q ON q.group_number = g.id
+		WHERE g.id = $1
+		GROUP BY g.id`
+
+	var group models.QuestionsGroup
+	var questionsIDs []int64
+	err := s.conn.QueryRowContext(ctx, query, id).Scan(&group.ID, &group.Name, &group.Description, &group.Order, &group.Enabled, pq.Array(&questionsIDs))
+	if err == sql.ErrNoRows {
+		return group, ErrGroupNotFound
+	}
 	if err != nil {
-		return err
+		return group, err
 	}
-	tx, err := s.db.Begin()
+	group.QuestionsIDs = make([]int, len(questionsIDs))
+	for i, qID := range questionsIDs {
+		group.QuestionsIDs[i] = int(qID)
+	}
+	return group, nil
+}
+
+// CreateGroup inserts the group and moves its questions to it in the same transaction, it fails
+// with ErrQuestionNotFound when some of the questions don't exist
+func (s *PostgresStorage) CreateGroup(ctx context.Context, group models.QuestionsGroup) (models.QuestionsGroup, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
+		return group, err
 	}
 	defer tx.Rollback()
+	query := `
+		INSERT INTO question_groups (name, description, display_order, enabled)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`
 
-	// Reset all options to false for this question
-	if _, err := tx.Exec(`
-        UPDATE question_options 
-        SET is_correct = false 
-        WHERE question_id = $1`, questionID); err != nil {
-		return fmt.Errorf("reset options: %w", err)
+	err = tx.QueryRowContext(ctx, query, group.Name, group.Description, group.Order, group.Enabled).Scan(&group.ID)
+	if err != nil {
+		return group, err
 	}
-
-	// Set new correct option
-	if _, err := tx.Exec(`
-        UPDATE question_options 
-        SET is_correct = true 
-        WHERE question_id = $1 AND option_id = $2`,
-		questionID, newCorrectID); err != nil {
-		return fmt.Errorf("update correct option: %w", err)
+	if err = moveQuestionsToGroup(ctx, tx, group.ID, group.QuestionsIDs); err != nil {
+		return group, err
 	}
-
-	return tx.Commit()
+	return group, tx.Commit()
 }
 
-func (s *PostgresStorage) DeleteQuestionByID(id int) error {
-	query := "DELETE FROM questions WHERE id = $1"
-	_, err := s.db.Exec(query, id)
-	return err
-}
+func (s *PostgresStorage) UpdateGroup(ctx context.Context, group models.QuestionsGroup) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		UPDATE question_groups
+		SET name = $1, description = $2, display_order = $3, enabled = $4
+		WHERE id = $5`
 
-func (s *PostgresStorage) CountQuestions() (int, error) {
-	var count int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM questions").Scan(&count)
-	return count, err
+	res, err := s.conn.ExecContext(ctx, query, group.Name, group.Description, group.Order, group.Enabled, group.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrGroupNotFound
+	}
+	return nil
 }
 
-// Cases
-func (s *PostgresStorage) CreateCase(newCase models.Case) (models.Case, error) {
-	query := `
-        INSERT INTO cases (code, patient_gender, age1, age2)
-        VALUES ($1, $2, $3, $4)
+// DeleteGroup removes the group, questions assigned to it are left without a group.
+// ErrGroupInUse is returned while open sessions are in the group, they would lose their place in the quiz
+func (s *PostgresStorage) DeleteGroup(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var inUse bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM quiz_sessions WHERE current_group = $1 AND `+openQuizSession+`)`, id).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrGroupInUse
+	}
+	if _, err = tx.ExecContext(ctx, "UPDATE questions SET group_number = 0 WHERE group_number = $1", id); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM question_groups WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrGroupNotFound
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStorage) UpdateGroupsOrder(ctx context.Context, groups []models.QuestionsGroup) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE question_groups SET display_order = $1 WHERE id = $2`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, group := range groups {
+		_, err = stmt.ExecContext(ctx, group.Order, group.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// MoveQuestionsToGroup assigns the given questions to the group, removing them from their previous groups.
+// Nothing is moved when some of the questions don't exist, the error is then ErrQuestionNotFound
+func (s *PostgresStorage) MoveQuestionsToGroup(ctx context.Context, groupID int, questionIDs []int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM question_groups WHERE id = $1)", groupID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrGroupNotFound
+	}
+	if err = moveQuestionsToGroup(ctx, tx, groupID, questionIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// openQuizSession filters quiz_sessions to the sessions still in progress, sandbox sessions are left out
+// as they ask a single question
+const openQuizSession = `status NOT IN ('finished', 'abandoned') AND NOT sandbox`
+
+// moveQuestionsToGroup moves the questions in the transaction, it fails with ErrQuestionNotFound
+// when fewer questions than requested were moved and with ErrGroupInUse when a question would leave
+// the current group of an open session
+func moveQuestionsToGroup(ctx context.Context, tx *sql.Tx, groupID int, questionIDs []int) error {
+	if len(questionIDs) == 0 {
+		return nil
+	}
+	ids := slices.Clone(questionIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	var inUse bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM questions q
+			JOIN quiz_sessions qs ON qs.current_group = q.group_number
+			WHERE q.id = ANY($1) AND q.group_number NOT IN (0, $2) AND `+openQuizSession+`
+		)`, pq.Array(ids), groupID).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrGroupInUse
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE questions SET group_number = $1 WHERE id = ANY($2)", groupID, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != int64(len(ids)) {
+		return ErrQuestionNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStorage) CreateQuestion(ctx context.Context, payload models.QuestionPayload) (models.QuestionPayload, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+        INSERT INTO questions (question, prediction_age, case_id, status, type, target_parameter_id, tolerance)
+        VALUES ($1, $2, $3, 'draft', $4, $5, $6)
+        RETURNING id`
+
+	if payload.Type == "" {
+		payload.Type = models.QuestionTypeChoice
+	}
+	err := s.conn.QueryRowContext(ctx,
+		query,
+		payload.Question,
+		payload.PredictionAge,
+		payload.CaseID,
+		payload.Type,
+		payload.TargetParameterID,
+		payload.Tolerance,
+	).Scan(&payload.ID)
+
+	return payload, err
+}
+
+func (s *PostgresStorage) UpdateQuestionByID(ctx context.Context, questionID int, payload models.QuestionPayload) (models.QuestionPayload, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+        UPDATE questions
+        SET question = $1, prediction_age = $2, case_id = $3, group_number = $5,
+            type = $6, target_parameter_id = $7, tolerance = $8
+        WHERE id = $4`
+
+	if payload.Type == "" {
+		payload.Type = models.QuestionTypeChoice
+	}
+	_, err := s.conn.ExecContext(ctx,
+		query,
+		payload.Question,
+		payload.PredictionAge,
+		payload.CaseID,
+		questionID,
+		payload.Group,
+		payload.Type,
+		payload.TargetParameterID,
+		payload.Tolerance,
+	)
+
+	payload.ID = questionID
+	return payload, err
+}
+
+// UpdateQuestionStatus moves the question from status from to status to,
+// ErrQuestionStatusChanged is returned when the question is no longer in status from
+func (s *PostgresStorage) UpdateQuestionStatus(ctx context.Context, questionID int, from string, to string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	result, err := s.conn.ExecContext(ctx, `UPDATE questions SET status = $1 WHERE id = $2 AND status = $3`, to, questionID, from)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrQuestionStatusChanged
+	}
+	return nil
+}
+
+func (s *PostgresStorage) UpdateQuestionCorrectOption(ctx context.Context, questionID int, option string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var newCorrectID int
+	err := s.conn.QueryRowContext(ctx, "SELECT id FROM options WHERE option = $1", option).Scan(&newCorrectID)
+	if err != nil {
+		return err
+	}
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Reset all options to false for this question
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE question_options 
+        SET is_correct = false 
+        WHERE question_id = $1`, questionID); err != nil {
+		return fmt.Errorf("reset options: %w", err)
+	}
+
+	// Set new correct option
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE question_options 
+        SET is_correct = true 
+        WHERE question_id = $1 AND option_id = $2`,
+		questionID, newCorrectID); err != nil {
+		return fmt.Errorf("update correct option: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStorage) DeleteQuestionByID(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	// translations have no foreign key as they refer to several tables
+	query := `
+		WITH deleted AS (DELETE FROM questions WHERE id = $1)
+		DELETE FROM translations WHERE entity = 'question' AND entity_id = $1`
+	_, err := s.conn.ExecContext(ctx, query, id)
+	return err
+}
+
+func (s *PostgresStorage) CountQuestions(ctx context.Context) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var count int
+	err := s.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM questions").Scan(&count)
+	return count, err
+}
+
+// Cases
+
+// CreateCase inserts the case together with its parameter values in a single transaction
+func (s *PostgresStorage) CreateCase(ctx context.Context, newCase models.Case) (models.Case, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return newCase, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO cases (code, patient_gender, age1, age2, age3)
+        VALUES ($1, $2, $3, $4, $5)
         RETURNING id`
 
-	err := s.db.QueryRow(
+	err = tx.QueryRowContext(ctx,
 		query,
 		newCase.Code,
 		newCase.Gender,
 		newCase.Age1,
 		newCase.Age2,
+		newCase.Age3,
 	).Scan(&newCase.ID)
+	if err != nil {
+		return newCase, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO case_parameters (case_id, parameter_id, value_1, value_2, value_3)
+        VALUES ($1, $2, $3, $4, $5)
+    `)
+	if err != nil {
+		return newCase, err
+	}
+	defer stmt.Close()
+
+	for _, value := range newCase.ParameterValues {
+		_, err = stmt.ExecContext(ctx, newCase.ID, value.ParameterID, value.Value1, value.Value2, value.Value3)
+		if err != nil {
+			return newCase, err
+		}
+	}
 
-	return newCase, err
+	return newCase, tx.Commit()
 }
 
-func (s *PostgresStorage) UpdateCase(updatedCase models.Case) (models.Case, error) {
+// UpdateCase updates the case and, when ParameterValues are given, replaces its parameter values in the same
+// transaction. A case without ParameterValues keeps its values
+func (s *PostgresStorage) UpdateCase(ctx context.Context, updatedCase models.Case) (models.Case, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return updatedCase, err
+	}
+	defer tx.Rollback()
 	query := `
         UPDATE cases
-        SET code = $1, patient_gender = $2, age1 = $3, age2 = $4
-        WHERE id = $5`
+        SET code = $1, patient_gender = $2, age1 = $3, age2 = $4, age3 = $5
+        WHERE id = $6`
 
-	_, err := s.db.Exec(
+	res, err := tx.ExecContext(ctx,
 		query,
 		updatedCase.Code,
 		updatedCase.Gender,
 		updatedCase.Age1,
 		updatedCase.Age2,
+		updatedCase.Age3,
 		updatedCase.ID,
 	)
+	if err != nil {
+		return updatedCase, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return updatedCase, err
+	}
+	if affected == 0 {
+		return updatedCase, ErrCaseNotFound
+	}
+	if updatedCase.ParameterValues != nil {
+		if err = replaceCaseParameters(ctx, tx, updatedCase.ID, updatedCase.ParameterValues); err != nil {
+			return updatedCase, err
+		}
+	}
 
-	return updatedCase, err
+	return updatedCase, tx.Commit()
 }
 
-func (s *PostgresStorage) DeleteCaseWithParameters(id int) error {
-	tx, err := s.db.Begin()
+// DeleteCaseWithParameters removes the case and its parameter values, cases used by questions are not removed
+func (s *PostgresStorage) DeleteCaseWithParameters(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var questionsCount int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM questions WHERE case_id = $1", id).Scan(&questionsCount)
 	if err != nil {
 		return err
 	}
+	if questionsCount > 0 {
+		return ErrCaseInUse
+	}
 
-	_, err = tx.Exec("DELETE FROM case_parameters WHERE case_id = $1", id)
+	_, err = tx.ExecContext(ctx, "DELETE FROM case_parameters WHERE case_id = $1", id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM case_landmarks WHERE case_id = $1", id)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	_, err = tx.Exec("DELETE FROM cases WHERE id = $1", id)
+	res, err := tx.ExecContext(ctx, "DELETE FROM cases WHERE id = $1", id)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCaseNotFound
+	}
 	return tx.Commit()
 }
 
-func (s *PostgresStorage) GetAllCases() ([]models.Case, error) {
+func (s *PostgresStorage) GetAllCases(ctx context.Context) ([]models.Case, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
-        SELECT id, code, patient_gender, age1, age2
+        SELECT id, code, patient_gender, age1, age2, age3
         FROM cases
         ORDER BY id`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.conn.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var cases []models.Case
+	cases := make([]models.Case, 0)
 	for rows.Next() {
 		var c models.Case
 		err = rows.Scan(
@@ -568,13 +1510,14 @@ func (s *PostgresStorage) GetAllCases() ([]models.Case, error) {
 			&c.Gender,
 			&c.Age1,
 			&c.Age2,
+			&c.Age3,
 		)
 		if err != nil {
 			return nil, err
 		}
 
 		// Get parameters for each case
-		c.Parameters, c.ParameterValues, err = s.getCaseParameters(c.ID)
+		c.Parameters, c.ParameterValues, err = s.getCaseParameters(ctx, c.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -585,53 +1528,77 @@ func (s *PostgresStorage) GetAllCases() ([]models.Case, error) {
 	return cases, rows.Err()
 }
 
-func (s *PostgresStorage) GetCaseByID(id int) (models.Case, error) {
+func (s *PostgresStorage) GetCaseByID(ctx context.Context, id int) (models.Case, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
-        SELECT id, code, patient_gender, age1, age2
+        SELECT id, code, patient_gender, age1, age2, age3
         FROM cases
         WHERE id=$1`
 
 	var c models.Case
-	err := s.db.QueryRow(query, id).Scan(
+	err := s.conn.QueryRowContext(ctx, query, id).Scan(
 		&c.ID,
 		&c.Code,
 		&c.Gender,
 		&c.Age1,
-		&c.Age2)
+		&c.Age2,
+		&c.Age3)
+	if err == sql.ErrNoRows {
+		return c, ErrCaseNotFound
+	}
 	if err != nil {
 		return c, err
 	}
-	c.Parameters, c.ParameterValues, err = s.getCaseParameters(c.ID)
+	c.Parameters, c.ParameterValues, err = s.getCaseParameters(ctx, c.ID)
 	if err != nil {
 		return c, err
 	}
-	return c, nil
+	c.Landmarks, err = s.GetCaseLandmarks(ctx, c.ID)
+	return c, err
+}
+
+func (s *PostgresStorage) GetCaseIDByCode(ctx context.Context, code string) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var id int
+	err := s.conn.QueryRowContext(ctx, "SELECT id FROM cases WHERE code = $1 ORDER BY id LIMIT 1", code).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrCaseNotFound
+	}
+	return id, err
 }
-func (s *PostgresStorage) CreateCaseParameter(caseID int, parameter models.ParameterValue) (models.ParameterValue, error) {
+
+func (s *PostgresStorage) CreateCaseParameter(ctx context.Context, caseID int, parameter models.ParameterValue) (models.ParameterValue, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
-		INSERT INTO case_parameters (case_id, parameter_id, value_1, value_2)
-		VALUES ($1, $2, $3, $4)
-		RETURNING parameter_id, value_1, value_2`
+		INSERT INTO case_parameters (case_id, parameter_id, value_1, value_2, value_3)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING parameter_id, value_1, value_2, value_3`
 
-	err := s.db.QueryRow(
+	err := s.conn.QueryRowContext(ctx,
 		query,
 		caseID,
 		parameter.ParameterID,
 		parameter.Value1,
 		parameter.Value2,
-	).Scan(&parameter.ParameterID, &parameter.Value1, &parameter.Value2)
+		parameter.Value3,
+	).Scan(&parameter.ParameterID, &parameter.Value1, &parameter.Value2, &parameter.Value3)
 
 	return parameter, err
 }
 
 // Parameters
-func (s *PostgresStorage) CreateParameter(parameter models.Parameter) (models.Parameter, error) {
+func (s *PostgresStorage) CreateParameter(ctx context.Context, parameter models.Parameter) (models.Parameter, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
         INSERT INTO parameters (name, description, reference_value)
         VALUES ($1, $2, $3)
         RETURNING id`
 
-	err := s.db.QueryRow(
+	err := s.conn.QueryRowContext(ctx,
 		query,
 		parameter.Name,
 		parameter.Description,
@@ -641,13 +1608,15 @@ func (s *PostgresStorage) CreateParameter(parameter models.Parameter) (models.Pa
 	return parameter, err
 }
 
-func (s *PostgresStorage) UpdateParameter(parameter models.Parameter) error {
+func (s *PostgresStorage) UpdateParameter(ctx context.Context, parameter models.Parameter) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
         UPDATE parameters
         SET name = $1, description = $2, reference_value = $3
         WHERE id = $4`
 
-	_, err := s.db.Exec(
+	_, err := s.conn.ExecContext(ctx,
 		query,
 		parameter.Name,
 		parameter.Description,
@@ -658,20 +1627,26 @@ func (s *PostgresStorage) UpdateParameter(parameter models.Parameter) error {
 	return err
 }
 
-func (s *PostgresStorage) DeleteParameter(id int) error {
-	query := "DELETE FROM parameters WHERE id = $1"
-	_, err := s.db.Exec(query, id)
+func (s *PostgresStorage) DeleteParameter(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		WITH deleted AS (DELETE FROM parameters WHERE id = $1)
+		DELETE FROM translations WHERE entity = 'parameter' AND entity_id = $1`
+	_, err := s.conn.ExecContext(ctx, query, id)
 	return err
 }
 
-func (s *PostgresStorage) GetParameterByID(id int) (models.Parameter, error) {
+func (s *PostgresStorage) GetParameterByID(ctx context.Context, id int) (models.Parameter, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
 		SELECT id, name, description, reference_value
 		FROM parameters
 		WHERE id = $1`
 
 	var p models.Parameter
-	err := s.db.QueryRow(query, id).Scan(
+	err := s.conn.QueryRowContext(ctx, query, id).Scan(
 		&p.ID,
 		&p.Name,
 		&p.Description,
@@ -680,13 +1655,15 @@ func (s *PostgresStorage) GetParameterByID(id int) (models.Parameter, error) {
 	return p, err
 }
 
-func (s *PostgresStorage) GetAllParameters() ([]models.Parameter, error) {
+func (s *PostgresStorage) GetAllParameters(ctx context.Context) ([]models.Parameter, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
         SELECT id, name, description, reference_value, display_order
         FROM parameters
         ORDER BY display_order, id`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.conn.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -712,12 +1689,12 @@ func (s *PostgresStorage) GetAllParameters() ([]models.Parameter, error) {
 }
 
 // Helper functions
-func (s *PostgresStorage) getCaseParameters(caseID int) ([]models.Parameter, []models.ParameterValue, error) {
+func (s *PostgresStorage) getCaseParameters(ctx context.Context, caseID int) ([]models.Parameter, []models.ParameterValue, error) {
 	query := `select cp.parameter_id, cp.value_1, cp.value_2, cp.value_3, p.description, p.name, p.reference_value from cases c
 		join case_parameters cp on c.id = cp.case_id
 		join parameters p on cp.parameter_id = p.id
 		where c.id=$1 ORDER BY p.display_order, p.id`
-	rows, err := s.db.Query(query, caseID)
+	rows, err := s.conn.QueryContext(ctx, query, caseID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -738,21 +1715,87 @@ func (s *PostgresStorage) getCaseParameters(caseID int) ([]models.Parameter, []m
 	return parameters, parameterValues, nil
 }
 
-func (s *PostgresStorage) UpdateCaseParameters(caseID int, parameters []models.Parameter, values []models.ParameterValue) error {
-	tx, err := s.db.Begin()
+// GetCaseLandmarks returns the reference landmarks of the case in the order they were saved
+func (s *PostgresStorage) GetCaseLandmarks(ctx context.Context, caseID int) ([]models.Landmark, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn.QueryContext(ctx, `SELECT name, x, y FROM case_landmarks WHERE case_id = $1 ORDER BY position`, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var landmarks []models.Landmark
+	for rows.Next() {
+		var landmark models.Landmark
+		if err = rows.Scan(&landmark.Name, &landmark.X, &landmark.Y); err != nil {
+			return nil, err
+		}
+		landmarks = append(landmarks, landmark)
+	}
+	return landmarks, rows.Err()
+}
+
+// SaveCaseLandmarks replaces the reference landmarks of the case
+func (s *PostgresStorage) SaveCaseLandmarks(ctx context.Context, caseID int, landmarks []models.Landmark) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM cases WHERE id = $1)`, caseID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCaseNotFound
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM case_landmarks WHERE case_id = $1`, caseID); err != nil {
+		return err
+	}
+	for i, landmark := range landmarks {
+		_, err = tx.ExecContext(ctx, `INSERT INTO case_landmarks (case_id, name, x, y, position) VALUES ($1, $2, $3, $4, $5)`,
+			caseID, landmark.Name, landmark.X, landmark.Y, i)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStorage) UpdateCaseParameters(ctx context.Context, caseID int, parameters []models.Parameter, values []models.ParameterValue) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	// values are matched with parameters by position
+	caseValues := make([]models.ParameterValue, len(parameters))
+	for i := range parameters {
+		caseValues[i] = models.ParameterValue{ParameterID: parameters[i].ID, Value1: values[i].Value1, Value2: values[i].Value2, Value3: values[i].Value3}
+	}
+	if err = replaceCaseParameters(ctx, tx, caseID, caseValues); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceCaseParameters replaces all parameter values of the case in the transaction
+func replaceCaseParameters(ctx context.Context, tx *sql.Tx, caseID int, values []models.ParameterValue) error {
 	// Delete existing parameters for this case
-	_, err = tx.Exec("DELETE FROM case_parameters WHERE case_id = $1", caseID)
+	_, err := tx.ExecContext(ctx, "DELETE FROM case_parameters WHERE case_id = $1", caseID)
 	if err != nil {
 		return err
 	}
 
 	// Insert new parameters
-	stmt, err := tx.Prepare(`
+	stmt, err := tx.PrepareContext(ctx, `
         INSERT INTO case_parameters (case_id, parameter_id, value_1, value_2, value_3) 
         VALUES ($1, $2, $3, $4, $5)
     `)
@@ -761,62 +1804,71 @@ func (s *PostgresStorage) UpdateCaseParameters(caseID int, parameters []models.P
 	}
 	defer stmt.Close()
 
-	for i := range parameters {
-		_, err = stmt.Exec(
+	for _, value := range values {
+		_, err = stmt.ExecContext(ctx,
 			caseID,
-			parameters[i].ID,
-			values[i].Value1,
-			values[i].Value2,
-			values[i].Value3,
+			value.ParameterID,
+			value.Value1,
+			value.Value2,
+			value.Value3,
 		)
 		if err != nil {
 			return err
 		}
 	}
-
-	return tx.Commit()
+	return nil
 }
 
-func (s *PostgresStorage) CreateOption(option models.Option) (models.Option, error) {
+func (s *PostgresStorage) CreateOption(ctx context.Context, option models.Option) (models.Option, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
 		INSERT INTO options (option)
 		VALUES ($1)
 		RETURNING id`
 
-	err := s.db.QueryRow(query, option.Option).Scan(&option.ID)
+	err := s.conn.QueryRowContext(ctx, query, option.Option).Scan(&option.ID)
 	return option, err
 }
 
-func (s *PostgresStorage) UpdateOption(id int, option models.Option) error {
+func (s *PostgresStorage) UpdateOption(ctx context.Context, id int, option models.Option) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
 		UPDATE options
 		SET option = $1
 		WHERE id = $2`
 
-	_, err := s.db.Exec(query, option.Option, id)
+	_, err := s.conn.ExecContext(ctx, query, option.Option, id)
 	return err
 }
 
-func (s *PostgresStorage) DeleteOption(id int) error {
-	query := "DELETE FROM options WHERE id = $1"
-	_, err := s.db.Exec(query, id)
+func (s *PostgresStorage) DeleteOption(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		WITH deleted AS (DELETE FROM options WHERE id = $1)
+		DELETE FROM translations WHERE entity = 'option' AND entity_id = $1`
+	_, err := s.conn.ExecContext(ctx, query, id)
 	return err
 }
-func (s *PostgresStorage) UpdateParametersOrder(params []models.Parameter) error {
-	tx, err := s.db.Begin()
+func (s *PostgresStorage) UpdateParametersOrder(ctx context.Context, params []models.Parameter) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`UPDATE parameters SET display_order = $1 WHERE id = $2`)
+	stmt, err := tx.PrepareContext(ctx, `UPDATE parameters SET display_order = $1 WHERE id = $2`)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	for _, param := range params {
-		_, err = stmt.Exec(param.Order, param.ID)
+		_, err = stmt.ExecContext(ctx, param.Order, param.ID)
 		if err != nil {
 			return err
 		}
@@ -825,30 +1877,52 @@ func (s *PostgresStorage) UpdateParametersOrder(params []models.Parameter) error
 	return tx.Commit()
 }
 
-func (s *PostgresStorage) GetTimeLimit() (int, error) {
+func (s *PostgresStorage) GetTimeLimit(ctx context.Context) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	var timeLimitStr string
-	err := s.db.QueryRow("SELECT value FROM settings WHERE name = 'time_limit'").Scan(&timeLimitStr)
+	err := s.conn.QueryRowContext(ctx, "SELECT value FROM settings WHERE name = 'time_limit'").Scan(&timeLimitStr)
 	if err != nil {
 		return 0, err
 	}
 	timeLimit, err := strconv.Atoi(timeLimitStr)
 	return timeLimit, err
 }
-func (s *PostgresStorage) SaveSettings(name string, value string) error {
+
+// GetPinnedSeed returns the order_seed setting, admins set it to give all new sessions of a study the same question order
+func (s *PostgresStorage) GetPinnedSeed(ctx context.Context) (int64, bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var seedStr string
+	err := s.conn.QueryRowContext(ctx, "SELECT value FROM settings WHERE name = 'order_seed'").Scan(&seedStr)
+	if err == sql.ErrNoRows || (err == nil && seedStr == "") {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	seed, err := strconv.ParseInt(seedStr, 10, 64)
+	return seed, err == nil, err
+}
+func (s *PostgresStorage) SaveSettings(ctx context.Context, name string, value string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
 		INSERT INTO settings (name, value)
 		VALUES ($1, $2)
 		ON CONFLICT (name) DO UPDATE SET value = $2`
 
-	_, err := s.db.Exec(query, name, value)
+	_, err := s.conn.ExecContext(ctx, query, name, value)
 	return err
 }
-func (s *PostgresStorage) GetSettings() ([]models.Settings, error) {
+func (s *PostgresStorage) GetSettings(ctx context.Context) ([]models.Settings, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	query := `
 		SELECT name, value
 		FROM settings`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.conn.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -866,3 +1940,1117 @@ func (s *PostgresStorage) GetSettings() ([]models.Settings, error) {
 
 	return settings, nil
 }
+
+// Import and export
+
+// ImportQuestionBank inserts parameters, options, groups and cases without an ID and all questions of the bundle
+// in a single transaction, IDs of the created rows are written back to the bundle. References left as 0 by the
+// validation are resolved by name against the rows created here. In dry run mode the transaction is always
+// rolled back, otherwise beforeCommit is called right before the commit and the import is rolled back if it fails
+func (s *PostgresStorage) ImportQuestionBank(ctx context.Context, bundle *models.ImportBundle, dryRun bool, beforeCommit func(bundle *models.ImportBundle) error) error {
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	parametersIDs := make(map[string]int, len(bundle.Parameters))
+	for i := range bundle.Parameters {
+		parameter := &bundle.Parameters[i]
+		if parameter.ID == 0 {
+			err = tx.QueryRowContext(ctx, `
+                INSERT INTO parameters (name, description, reference_value, display_order)
+                VALUES ($1, $2, $3, $4)
+                RETURNING id`,
+				parameter.Name,
+				parameter.Description,
+				parameter.ReferenceValues,
+				parameter.Order,
+			).Scan(&parameter.ID)
+			if err != nil {
+				return fmt.Errorf("insert parameter %s: %w", parameter.Name, err)
+			}
+		}
+		parametersIDs[parameter.Name] = parameter.ID
+	}
+
+	optionsIDs := make(map[string]int, len(bundle.Options))
+	for i := range bundle.Options {
+		option := &bundle.Options[i]
+		if option.ID == 0 {
+			err = tx.QueryRowContext(ctx, "INSERT INTO options (option) VALUES ($1) RETURNING id", option.Option).Scan(&option.ID)
+			if err != nil {
+				return fmt.Errorf("insert option %s: %w", option.Option, err)
+			}
+		}
+		optionsIDs[option.Option] = option.ID
+	}
+
+	groupsIDs := make(map[int]int, len(bundle.Groups))
+	for i := range bundle.Groups {
+		group := &bundle.Groups[i]
+		if group.GroupID == 0 {
+			err = tx.QueryRowContext(ctx, `
+                INSERT INTO question_groups (name, description, display_order, enabled)
+                VALUES ($1, $2, $3, $4)
+                RETURNING id`,
+				group.Name,
+				group.Description,
+				group.Order,
+				group.Enabled,
+			).Scan(&group.GroupID)
+			if err != nil {
+				return fmt.Errorf("insert group %s: %w", group.Name, err)
+			}
+		}
+		groupsIDs[group.ID] = group.GroupID
+	}
+
+	for _, setting := range bundle.Settings {
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO settings (name, value)
+            VALUES ($1, $2)
+            ON CONFLICT (name) DO UPDATE SET value = $2`, setting.Name, setting.Value)
+		if err != nil {
+			return fmt.Errorf("save setting %s: %w", setting.Name, err)
+		}
+	}
+
+	parameterStmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO case_parameters (case_id, parameter_id, value_1, value_2, value_3)
+        VALUES ($1, $2, $3, $4, $5)`)
+	if err != nil {
+		return err
+	}
+	defer parameterStmt.Close()
+
+	casesIDs := make(map[string]int, len(bundle.Cases))
+	for i := range bundle.Cases {
+		importCase := &bundle.Cases[i]
+		if importCase.ID == 0 {
+			err = tx.QueryRowContext(ctx, `
+                INSERT INTO cases (code, patient_gender, age1, age2, age3)
+                VALUES ($1, $2, $3, $4, $5)
+                RETURNING id`,
+				importCase.Code,
+				importCase.Gender,
+				importCase.Age1,
+				importCase.Age2,
+				importCase.Age3,
+			).Scan(&importCase.ID)
+			if err != nil {
+				return fmt.Errorf("insert case %s: %w", importCase.Code, err)
+			}
+			for j := range importCase.Parameters {
+				value := &importCase.Parameters[j]
+				if value.ParameterID == 0 {
+					value.ParameterID = parametersIDs[value.Parameter]
+				}
+				_, err = parameterStmt.ExecContext(ctx, importCase.ID, value.ParameterID, value.Value1, value.Value2, value.Value3)
+				if err != nil {
+					return fmt.Errorf("insert parameter %s of case %s: %w", value.Parameter, importCase.Code, err)
+				}
+			}
+			for j, landmark := range importCase.Landmarks {
+				_, err = tx.ExecContext(ctx, `INSERT INTO case_landmarks (case_id, name, x, y, position) VALUES ($1, $2, $3, $4, $5)`,
+					importCase.ID, landmark.Name, landmark.X, landmark.Y, j)
+				if err != nil {
+					return fmt.Errorf("insert landmark %s of case %s: %w", landmark.Name, importCase.Code, err)
+				}
+			}
+		}
+		casesIDs[importCase.Code] = importCase.ID
+	}
+
+	optionStmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO question_options (question_id, option_id, is_correct)
+        VALUES ($1, $2, $3)`)
+	if err != nil {
+		return err
+	}
+	defer optionStmt.Close()
+
+	for i := range bundle.Questions {
+		question := &bundle.Questions[i]
+		if caseID, ok := casesIDs[question.CaseCode]; ok {
+			question.CaseID = caseID
+		}
+		if question.GroupID == 0 && question.Group != 0 {
+			question.GroupID = groupsIDs[question.Group]
+		}
+		for j := range question.OptionsIDs {
+			if question.OptionsIDs[j] == 0 {
+				question.OptionsIDs[j] = optionsIDs[question.Options[j]]
+			}
+		}
+		if question.CorrectOptionID == 0 {
+			question.CorrectOptionID = optionsIDs[question.Correct]
+		}
+		if question.Type == "" {
+			question.Type = models.QuestionTypeChoice
+		}
+		var targetParameterID *int
+		if question.TargetParameter != "" {
+			if question.TargetParameterID == 0 {
+				question.TargetParameterID = parametersIDs[question.TargetParameter]
+			}
+			targetParameterID = &question.TargetParameterID
+		}
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO questions (question, prediction_age, case_id, group_number, type, target_parameter_id, tolerance, status)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+            RETURNING id`,
+			question.Question,
+			question.PredictionAge,
+			question.CaseID,
+			question.GroupID,
+			question.Type,
+			targetParameterID,
+			question.Tolerance,
+			question.Status,
+		).Scan(&question.ID)
+		if err != nil {
+			return fmt.Errorf("insert question %d: %w", i+1, err)
+		}
+		for _, optionID := range question.OptionsIDs {
+			_, err = optionStmt.ExecContext(ctx, question.ID, optionID, optionID == question.CorrectOptionID)
+			if err != nil {
+				return fmt.Errorf("insert options of question %d: %w", i+1, err)
+			}
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+	if beforeCommit != nil {
+		if err = beforeCommit(bundle); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ExportQuestionBank reads the whole question bank from a single read only snapshot. Database IDs of questions
+// and parameters are kept in the bundle so that their images can be fetched, they are not part of the manifest
+func (s *PostgresStorage) ExportQuestionBank(ctx context.Context) (models.ImportBundle, error) {
+	bundle := models.ImportBundle{Version: models.BundleVersion}
+	tx, err := s.beginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return bundle, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT id, name, description, reference_value, display_order
+        FROM parameters
+        ORDER BY display_order, id`)
+	if err != nil {
+		return bundle, err
+	}
+	parametersNames := make(map[int]string)
+	for rows.Next() {
+		var p models.ImportParameter
+		if err = rows.Scan(&p.ID, &p.Name, &p.Description, &p.ReferenceValues, &p.Order); err != nil {
+			rows.Close()
+			return bundle, err
+		}
+		parametersNames[p.ID] = p.Name
+		bundle.Parameters = append(bundle.Parameters, p)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return bundle, err
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT id, option FROM options ORDER BY id")
+	if err != nil {
+		return bundle, err
+	}
+	for rows.Next() {
+		var o models.ImportOption
+		if err = rows.Scan(&o.ID, &o.Option); err != nil {
+			rows.Close()
+			return bundle, err
+		}
+		bundle.Options = append(bundle.Options, o)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return bundle, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+        SELECT id, name, description, display_order, enabled
+        FROM question_groups
+        ORDER BY display_order, id`)
+	if err != nil {
+		return bundle, err
+	}
+	for rows.Next() {
+		var g models.ImportGroup
+		if err = rows.Scan(&g.ID, &g.Name, &g.Description, &g.Order, &g.Enabled); err != nil {
+			rows.Close()
+			return bundle, err
+		}
+		g.GroupID = g.ID
+		bundle.Groups = append(bundle.Groups, g)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return bundle, err
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT name, value FROM settings ORDER BY name")
+	if err != nil {
+		return bundle, err
+	}
+	for rows.Next() {
+		var setting models.ImportSetting
+		if err = rows.Scan(&setting.Name, &setting.Value); err != nil {
+			rows.Close()
+			return bundle, err
+		}
+		bundle.Settings = append(bundle.Settings, setting)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return bundle, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+        SELECT id, code, patient_gender, age1, age2, age3
+        FROM cases
+        ORDER BY id`)
+	if err != nil {
+		return bundle, err
+	}
+	casesIndex := make(map[int]int)
+	for rows.Next() {
+		var c models.ImportCase
+		if err = rows.Scan(&c.ID, &c.Code, &c.Gender, &c.Age1, &c.Age2, &c.Age3); err != nil {
+			rows.Close()
+			return bundle, err
+		}
+		c.Parameters = make([]models.ImportParameterValue, 0)
+		casesIndex[c.ID] = len(bundle.Cases)
+		bundle.Cases = append(bundle.Cases, c)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return bundle, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+        SELECT cp.case_id, cp.parameter_id, cp.value_1, cp.value_2, cp.value_3
+        FROM case_parameters cp
+        JOIN parameters p ON p.id = cp.parameter_id
+        ORDER BY cp.case_id, p.display_order, p.id`)
+	if err != nil {
+		return bundle, err
+	}
+	for rows.Next() {
+		var caseID int
+		var value models.ImportParameterValue
+		if err = rows.Scan(&caseID, &value.ParameterID, &value.Value1, &value.Value2, &value.Value3); err != nil {
+			rows.Close()
+			return bundle, err
+		}
+		i, ok := casesIndex[caseID]
+		if !ok {
+			continue
+		}
+		value.Parameter = parametersNames[value.ParameterID]
+		bundle.Cases[i].Parameters = append(bundle.Cases[i].Parameters, value)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return bundle, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `SELECT case_id, name, x, y FROM case_landmarks ORDER BY case_id, position`)
+	if err != nil {
+		return bundle, err
+	}
+	for rows.Next() {
+		var caseID int
+		var landmark models.Landmark
+		if err = rows.Scan(&caseID, &landmark.Name, &landmark.X, &landmark.Y); err != nil {
+			rows.Close()
+			return bundle, err
+		}
+		if i, ok := casesIndex[caseID]; ok {
+			bundle.Cases[i].Landmarks = append(bundle.Cases[i].Landmarks, landmark)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return bundle, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+        SELECT q.id, q.question, q.prediction_age, q.case_id, c.code, q.group_number,
+               q.type, q.target_parameter_id, q.tolerance, q.status
+        FROM questions q
+        JOIN cases c ON q.case_id = c.id
+        ORDER BY q.id`)
+	if err != nil {
+		return bundle, err
+	}
+	questionsIndex := make(map[int]int)
+	for rows.Next() {
+		var q models.ImportQuestion
+		var targetParameterID sql.NullInt64
+		err = rows.Scan(&q.ID, &q.Question, &q.PredictionAge, &q.CaseID, &q.CaseCode, &q.Group, &q.Type, &targetParameterID, &q.Tolerance, &q.Status)
+		if err != nil {
+			rows.Close()
+			return bundle, err
+		}
+		if targetParameterID.Valid {
+			q.TargetParameterID = int(targetParameterID.Int64)
+			q.TargetParameter = parametersNames[q.TargetParameterID]
+		}
+		q.GroupID = q.Group
+		q.Options = make([]string, 0)
+		q.Images = make([]string, 0)
+		questionsIndex[q.ID] = len(bundle.Questions)
+		bundle.Questions = append(bundle.Questions, q)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return bundle, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+        SELECT qo.question_id, o.id, o.option, qo.is_correct
+        FROM question_options qo
+        JOIN options o ON o.id = qo.option_id
+        ORDER BY qo.question_id, o.id`)
+	if err != nil {
+		return bundle, err
+	}
+	for rows.Next() {
+		var questionID, optionID int
+		var option string
+		var isCorrect bool
+		if err = rows.Scan(&questionID, &optionID, &option, &isCorrect); err != nil {
+			rows.Close()
+			return bundle, err
+		}
+		i, ok := questionsIndex[questionID]
+		if !ok {
+			continue
+		}
+		question := &bundle.Questions[i]
+		question.Options = append(question.Options, option)
+		question.OptionsIDs = append(question.OptionsIDs, optionID)
+		if isCorrect {
+			question.Correct = option
+			question.CorrectOptionID = optionID
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return bundle, err
+	}
+
+	return bundle, tx.Commit()
+}
+
+// AddReviewItems adds questions to the review queues of users. Questions already in the queue keep their schedule,
+// unless they were missed again after their last review, then their repetitions start again from the new due time
+func (s *PostgresStorage) AddReviewItems(ctx context.Context, items []models.ReviewItem) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	if len(items) == 0 {
+		return nil
+	}
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO review_items (user_id, question_id, repetitions, interval_days, ease_factor, due_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, question_id) DO UPDATE
+		SET repetitions = EXCLUDED.repetitions, interval_days = EXCLUDED.interval_days, due_at = EXCLUDED.due_at
+		WHERE review_items.last_reviewed_at < EXCLUDED.due_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, item := range items {
+		_, err = stmt.ExecContext(ctx, item.UserID, item.QuestionID, item.Repetitions, item.IntervalDays, item.EaseFactor, item.DueAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDueReviewQuestionsIDs returns questions of the review queue of the user which are due at the given time,
+// the most overdue first. Questions deleted from the bank are skipped
+func (s *PostgresStorage) GetDueReviewQuestionsIDs(ctx context.Context, userID int, now time.Time) ([]int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT r.question_id FROM review_items r
+		JOIN questions q ON q.id = r.question_id
+		WHERE r.user_id = $1 AND r.due_at <= $2 AND q.status = 'published'
+		ORDER BY r.due_at, r.question_id`
+
+	rows, err := s.conn.QueryContext(ctx, query, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var questions []int
+	for rows.Next() {
+		var questionID int
+		if err = rows.Scan(&questionID); err != nil {
+			return nil, err
+		}
+		questions = append(questions, questionID)
+	}
+	return questions, rows.Err()
+}
+
+func (s *PostgresStorage) GetReviewItem(ctx context.Context, userID int, questionID int) (models.ReviewItem, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT user_id, question_id, repetitions, interval_days, ease_factor, due_at, last_reviewed_at
+		FROM review_items
+		WHERE user_id = $1 AND question_id = $2`
+
+	var item models.ReviewItem
+	err := s.conn.QueryRowContext(ctx, query, userID, questionID).Scan(
+		&item.UserID,
+		&item.QuestionID,
+		&item.Repetitions,
+		&item.IntervalDays,
+		&item.EaseFactor,
+		&item.DueAt,
+		&item.LastReviewedAt,
+	)
+	if err == sql.ErrNoRows {
+		return item, ErrReviewItemNotFound
+	}
+	return item, err
+}
+
+func (s *PostgresStorage) SaveReviewItem(ctx context.Context, item models.ReviewItem) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		INSERT INTO review_items (user_id, question_id, repetitions, interval_days, ease_factor, due_at, last_reviewed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, question_id) DO UPDATE
+		SET repetitions = $3, interval_days = $4, ease_factor = $5, due_at = $6, last_reviewed_at = $7`
+
+	_, err := s.conn.ExecContext(ctx, query, item.UserID, item.QuestionID, item.Repetitions, item.IntervalDays, item.EaseFactor, item.DueAt, item.LastReviewedAt)
+	return err
+}
+
+func (s *PostgresStorage) CreateAssignment(ctx context.Context, assignment models.Assignment) (models.Assignment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return assignment, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO assignments (teacher_id, title, description, mode, questions_ids, groups_ids, deadline, max_attempts, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query,
+		assignment.TeacherID,
+		assignment.Title,
+		assignment.Description,
+		assignment.Mode,
+		pq.Array(assignment.QuestionsIDs),
+		pq.Array(assignment.GroupsIDs),
+		assignment.Deadline,
+		assignment.MaxAttempts,
+	).Scan(&assignment.ID, &assignment.CreatedAt)
+	if err != nil {
+		return assignment, err
+	}
+	if err = insertAssignmentStudents(ctx, tx, assignment.ID, assignment.StudentsIDs); err != nil {
+		return assignment, err
+	}
+	return assignment, tx.Commit()
+}
+
+func insertAssignmentStudents(ctx context.Context, tx *sql.Tx, assignmentID int, studentsIDs []int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO assignment_students (assignment_id, user_id)
+		SELECT $1, unnest($2::int[])
+		ON CONFLICT DO NOTHING`, assignmentID, pq.Array(studentsIDs))
+	return err
+}
+
+const assignmentColumns = `
+		a.id, a.teacher_id, a.title, a.description, a.mode, a.questions_ids, a.groups_ids, a.deadline, a.max_attempts, a.created_at,
+		coalesce((SELECT array_agg(st.user_id ORDER BY st.user_id) FROM assignment_students st WHERE st.assignment_id = a.id), '{}')`
+
+func scanAssignment(row interface{ Scan(dest ...any) error }) (models.Assignment, error) {
+	var assignment models.Assignment
+	var questionsIDs, groupsIDs, studentsIDs pq.Int64Array
+	err := row.Scan(
+		&assignment.ID,
+		&assignment.TeacherID,
+		&assignment.Title,
+		&assignment.Description,
+		&assignment.Mode,
+		&questionsIDs,
+		&groupsIDs,
+		&assignment.Deadline,
+		&assignment.MaxAttempts,
+		&assignment.CreatedAt,
+		&studentsIDs,
+	)
+	assignment.QuestionsIDs = toInts(questionsIDs)
+	assignment.GroupsIDs = toInts(groupsIDs)
+	assignment.StudentsIDs = toInts(studentsIDs)
+	return assignment, err
+}
+
+func toInts(values pq.Int64Array) []int {
+	result := make([]int, len(values))
+	for i, value := range values {
+		result[i] = int(value)
+	}
+	return result
+}
+
+func (s *PostgresStorage) GetAssignmentByID(ctx context.Context, id int) (models.Assignment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	row := s.conn.QueryRowContext(ctx, `SELECT `+assignmentColumns+` FROM assignments a WHERE a.id = $1`, id)
+	assignment, err := scanAssignment(row)
+	if err == sql.ErrNoRows {
+		return assignment, ErrAssignmentNotFound
+	}
+	return assignment, err
+}
+
+// GetAssignments returns assignments created by the teacher, or all assignments when teacherID is 0
+func (s *PostgresStorage) GetAssignments(ctx context.Context, teacherID int) ([]models.Assignment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn.QueryContext(ctx, `SELECT `+assignmentColumns+` FROM assignments a
+		WHERE $1 = 0 OR a.teacher_id = $1
+		ORDER BY a.deadline DESC, a.id`, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	assignments := make([]models.Assignment, 0)
+	for rows.Next() {
+		assignment, err := scanAssignment(rows)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, rows.Err()
+}
+
+// UpdateAssignment replaces the assignment and its students, the teacher of an assignment is not changed
+func (s *PostgresStorage) UpdateAssignment(ctx context.Context, assignment models.Assignment) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE assignments
+		SET title = $1, description = $2, mode = $3, questions_ids = $4, groups_ids = $5, deadline = $6, max_attempts = $7
+		WHERE id = $8`,
+		assignment.Title,
+		assignment.Description,
+		assignment.Mode,
+		pq.Array(assignment.QuestionsIDs),
+		pq.Array(assignment.GroupsIDs),
+		assignment.Deadline,
+		assignment.MaxAttempts,
+		assignment.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrAssignmentNotFound
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM assignment_students WHERE assignment_id = $1`, assignment.ID); err != nil {
+		return err
+	}
+	if err = insertAssignmentStudents(ctx, tx, assignment.ID, assignment.StudentsIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteAssignment removes the assignment, its sessions are kept as regular finished sessions
+func (s *PostgresStorage) DeleteAssignment(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE quiz_sessions
+		SET assignment_id = NULL, status = 'finished', finished_at = coalesce(finished_at, NOW())
+		WHERE assignment_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM assignment_students WHERE assignment_id = $1`, id); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM assignments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrAssignmentNotFound
+	}
+	return tx.Commit()
+}
+
+// GetAssignmentQuestionsIDs returns existing questions of the assignment in the order they are asked:
+// the listed questions first, then questions of the listed groups in the group display order
+func (s *PostgresStorage) GetAssignmentQuestionsIDs(ctx context.Context, assignment models.Assignment) ([]int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT q.id FROM unnest($1::int[]) WITH ORDINALITY AS l(id, position)
+		JOIN questions q ON q.id = l.id
+		WHERE q.status = 'published'
+		ORDER BY l.position`
+	rows, err := s.conn.QueryContext(ctx, query, pq.Array(assignment.QuestionsIDs))
+	if err != nil {
+		return nil, err
+	}
+	questions, err := collectIDs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT q.id FROM questions q
+		JOIN question_groups g ON g.id = q.group_number
+		WHERE g.id = ANY($1) AND q.status = 'published'
+		ORDER BY g.display_order, g.id, q.id`
+	rows, err = s.conn.QueryContext(ctx, query, pq.Array(assignment.GroupsIDs))
+	if err != nil {
+		return nil, err
+	}
+	groupsQuestions, err := collectIDs(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range groupsQuestions {
+		if !slices.Contains(questions, id) {
+			questions = append(questions, id)
+		}
+	}
+	return questions, nil
+}
+
+func collectIDs(rows *sql.Rows) ([]int, error) {
+	defer rows.Close()
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetStudentAssignments returns assignments shared with the user together with the attempts the user has made
+func (s *PostgresStorage) GetStudentAssignments(ctx context.Context, userID int) ([]models.StudentAssignment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT a.id, a.title, a.description, a.mode, a.deadline, a.max_attempts,
+		       count(qs.id), coalesce(bool_or(qs.status = 'finished'), false)
+		FROM assignments a
+		JOIN assignment_students st ON st.assignment_id = a.id AND st.user_id = $1
+		LEFT JOIN quiz_sessions qs ON qs.assignment_id = a.id AND qs.user_id = $1
+		GROUP BY a.id
+		ORDER BY a.deadline, a.id`
+	rows, err := s.conn.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	assignments := make([]models.StudentAssignment, 0)
+	for rows.Next() {
+		var assignment models.StudentAssignment
+		err = rows.Scan(
+			&assignment.ID,
+			&assignment.Title,
+			&assignment.Description,
+			&assignment.Mode,
+			&assignment.Deadline,
+			&assignment.MaxAttempts,
+			&assignment.AttemptsUsed,
+			&assignment.Completed,
+		)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, rows.Err()
+}
+
+func (s *PostgresStorage) GetAssignmentSessions(ctx context.Context, assignmentID int) ([]models.AssignmentSession, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT id, user_id, status, created_at, finished_at
+		FROM quiz_sessions
+		WHERE assignment_id = $1
+		ORDER BY user_id, created_at`
+	rows, err := s.conn.QueryContext(ctx, query, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := make([]models.AssignmentSession, 0)
+	for rows.Next() {
+		var session models.AssignmentSession
+		err = rows.Scan(&session.SessionID, &session.UserID, &session.Status, &session.CreatedAt, &session.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *PostgresStorage) GetTranslations(ctx context.Context, entity string, entityID int) ([]models.Translation, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT entity, entity_id, field, locale, value
+		FROM translations
+		WHERE entity = $1 AND entity_id = $2
+		ORDER BY locale, field`
+	rows, err := s.conn.QueryContext(ctx, query, entity, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	translations := make([]models.Translation, 0)
+	for rows.Next() {
+		var t models.Translation
+		if err = rows.Scan(&t.Entity, &t.EntityID, &t.Field, &t.Locale, &t.Value); err != nil {
+			return nil, err
+		}
+		translations = append(translations, t)
+	}
+	return translations, rows.Err()
+}
+
+// SaveTranslation creates or replaces the translation, ErrTranslatedEntityNotFound is returned
+// when the question, option or parameter does not exist
+func (s *PostgresStorage) SaveTranslation(ctx context.Context, t models.Translation) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var exists bool
+	err := s.conn.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM `+translatedTable(t.Entity)+` WHERE id = $1)`, t.EntityID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrTranslatedEntityNotFound
+	}
+	query := `
+		INSERT INTO translations (entity, entity_id, field, locale, value)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (entity, entity_id, field, locale) DO UPDATE SET value = $5`
+	_, err = s.conn.ExecContext(ctx, query, t.Entity, t.EntityID, t.Field, t.Locale, t.Value)
+	return err
+}
+
+func (s *PostgresStorage) DeleteTranslation(ctx context.Context, entity string, entityID int, field string, locale string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	result, err := s.conn.ExecContext(ctx, `DELETE FROM translations WHERE entity = $1 AND entity_id = $2 AND field = $3 AND locale = $4`, entity, entityID, field, locale)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrTranslationNotFound
+	}
+	return nil
+}
+
+// GetMissingTranslations lists the fields of questions which are not archived, options and parameters
+// without a translation in the locale
+func (s *PostgresStorage) GetMissingTranslations(ctx context.Context, locale string) ([]models.MissingTranslation, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT f.entity, f.entity_id, f.field, f.source FROM (
+			SELECT 'question' AS entity, id AS entity_id, 'question' AS field, question AS source
+				FROM questions WHERE status <> 'archived'
+			UNION ALL
+			SELECT 'option', id, 'option', option FROM options
+			UNION ALL
+			SELECT 'parameter', id, 'name', name FROM parameters
+			UNION ALL
+			SELECT 'parameter', id, 'description', description FROM parameters WHERE description <> ''
+		) f
+		WHERE NOT EXISTS (
+			SELECT 1 FROM translations t
+			WHERE t.entity = f.entity AND t.entity_id = f.entity_id AND t.field = f.field AND t.locale = $1)
+		ORDER BY f.entity, f.entity_id, f.field`
+	rows, err := s.conn.QueryContext(ctx, query, locale)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	missing := make([]models.MissingTranslation, 0)
+	for rows.Next() {
+		var m models.MissingTranslation
+		if err = rows.Scan(&m.Entity, &m.EntityID, &m.Field, &m.Source); err != nil {
+			return nil, err
+		}
+		missing = append(missing, m)
+	}
+	return missing, rows.Err()
+}
+
+// GetLocalizedFields returns the translations of the entities in the locales keyed by entity id, field and locale
+func (s *PostgresStorage) GetLocalizedFields(ctx context.Context, entity string, entitiesIDs []int, locales []string) (map[int]map[string]map[string]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT entity_id, field, locale, value
+		FROM translations
+		WHERE entity = $1 AND entity_id = ANY($2) AND locale = ANY($3)`
+	rows, err := s.conn.QueryContext(ctx, query, entity, pq.Array(entitiesIDs), pq.Array(locales))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	fields := make(map[int]map[string]map[string]string)
+	for rows.Next() {
+		var entityID int
+		var field, locale, value string
+		if err = rows.Scan(&entityID, &field, &locale, &value); err != nil {
+			return nil, err
+		}
+		if fields[entityID] == nil {
+			fields[entityID] = make(map[string]map[string]string)
+		}
+		if fields[entityID][field] == nil {
+			fields[entityID][field] = make(map[string]string)
+		}
+		fields[entityID][field][locale] = value
+	}
+	return fields, rows.Err()
+}
+
+// GetOptionsTranslations returns the translations of the options in the locales keyed by the original text and locale,
+// questions refer to their options by text
+func (s *PostgresStorage) GetOptionsTranslations(ctx context.Context, options []string, locales []string) (map[string]map[string]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		SELECT o.option, t.locale, t.value
+		FROM options o
+		JOIN translations t ON t.entity = 'option' AND t.entity_id = o.id AND t.field = 'option'
+		WHERE o.option = ANY($1) AND t.locale = ANY($2)`
+	rows, err := s.conn.QueryContext(ctx, query, pq.Array(options), pq.Array(locales))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	translations := make(map[string]map[string]string)
+	for rows.Next() {
+		var option, locale, value string
+		if err = rows.Scan(&option, &locale, &value); err != nil {
+			return nil, err
+		}
+		if translations[option] == nil {
+			translations[option] = make(map[string]string)
+		}
+		translations[option][locale] = value
+	}
+	return translations, rows.Err()
+}
+
+// GetUserLocale returns the preferred locale of the user, empty when the user has not chosen one
+func (s *PostgresStorage) GetUserLocale(ctx context.Context, userID int) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var locale string
+	err := s.conn.QueryRowContext(ctx, `SELECT locale FROM user_preferences WHERE user_id = $1`, userID).Scan(&locale)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return locale, err
+}
+
+func (s *PostgresStorage) SaveUserLocale(ctx context.Context, userID int, locale string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	query := `
+		INSERT INTO user_preferences (user_id, locale)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET locale = $2`
+	_, err := s.conn.ExecContext(ctx, query, userID, locale)
+	return err
+}
+
+// DeleteUserData removes the preferences and review schedule of a deleted user, sessions are kept for the statistics
+func (s *PostgresStorage) DeleteUserData(ctx context.Context, userID int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.ExecContext(ctx, `DELETE FROM user_preferences WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM review_items WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// translatedTable returns the table of the translated entity, the entity is validated against models.TranslatableFields
+func translatedTable(entity string) string {
+	switch entity {
+	case models.TranslationEntityQuestion:
+		return "questions"
+	case models.TranslationEntityOption:
+		return "options"
+	default:
+		return "parameters"
+	}
+}
+
+// Outbox
+func insertOutboxEvents(ctx context.Context, db execer, events []models.OutboxEvent) error {
+	for _, event := range events {
+		_, err := db.ExecContext(ctx, `INSERT INTO outbox_events (type, session_id, payload, attempts, created_at, next_attempt_at) VALUES ($1, $2, $3, 0, NOW(), NOW())`,
+			event.Type, event.SessionID, []byte(event.Payload))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const outboxEventColumns = `id, type, session_id, payload, attempts, last_error, created_at, next_attempt_at, delivered_at`
+
+func scanOutboxEvents(rows *sql.Rows) ([]models.OutboxEvent, error) {
+	defer rows.Close()
+	events := make([]models.OutboxEvent, 0)
+	for rows.Next() {
+		var event models.OutboxEvent
+		var payload []byte
+		err := rows.Scan(&event.ID, &event.Type, &event.SessionID, &payload, &event.Attempts, &event.LastError, &event.CreatedAt, &event.NextAttemptAt, &event.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// GetDueOutboxEvents returns undelivered events whose next attempt is due, oldest first. Only the oldest undelivered
+// event of each session is returned, so that events of a session reach the stats service in the order they happened
+func (s *PostgresStorage) GetDueOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn.QueryContext(ctx, `
+        SELECT `+outboxEventColumns+`
+        FROM outbox_events o
+        WHERE o.delivered_at IS NULL AND o.next_attempt_at <= NOW()
+          AND NOT EXISTS (SELECT 1 FROM outbox_events e WHERE e.session_id = o.session_id AND e.delivered_at IS NULL AND e.id < o.id)
+        ORDER BY o.id
+        LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+func (s *PostgresStorage) MarkOutboxEventDelivered(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn.ExecContext(ctx, `UPDATE outbox_events SET delivered_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = $1`, id)
+	return err
+}
+
+func (s *PostgresStorage) MarkOutboxEventFailed(ctx context.Context, id int, lastError string, nextAttemptAt time.Time) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.conn.ExecContext(ctx, `UPDATE outbox_events SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1`, id, lastError, nextAttemptAt)
+	return err
+}
+
+// GetStuckOutboxEvents returns undelivered events which failed at least minAttempts times
+func (s *PostgresStorage) GetStuckOutboxEvents(ctx context.Context, minAttempts int) ([]models.OutboxEvent, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.conn.QueryContext(ctx, `
+        SELECT `+outboxEventColumns+`
+        FROM outbox_events
+        WHERE delivered_at IS NULL AND attempts >= $1
+        ORDER BY id`, minAttempts)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+// RetryOutboxEvent makes an undelivered event due immediately
+func (s *PostgresStorage) RetryOutboxEvent(ctx context.Context, id int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	res, err := s.conn.ExecContext(ctx, `UPDATE outbox_events SET next_attempt_at = NOW() WHERE id = $1 AND delivered_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrOutboxEventNotFound
+	}
+	return nil
+}
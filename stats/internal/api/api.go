@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"stats/internal/clients"
+	"stats/internal/events"
 	"stats/internal/handlers"
 	"stats/internal/middleware"
 	"stats/internal/storage"
@@ -17,17 +18,23 @@ import (
 
 type ApiServer struct {
 	addr       string
-	authClient *clients.AuthClient
+	authClient clients.AuthService
 	storage    storage.Storage
 	logger     *zap.Logger
+	// bus is nil when no event bus is configured, quiz events then come through the REST API only
+	bus events.Bus
+	// adaptiveHandler keeps the adaptive ratings, which are rebuilt in the background while the server runs
+	adaptiveHandler *handlers.AdaptiveHandler
 }
 
-func NewApiServer(addr string, storage storage.Storage, logger *zap.Logger, authClient *clients.AuthClient) *ApiServer {
+func NewApiServer(addr string, storage storage.Storage, logger *zap.Logger, authClient clients.AuthService, bus events.Bus) *ApiServer {
 	return &ApiServer{
-		addr:       addr,
-		authClient: authClient,
-		storage:    storage,
-		logger:     logger,
+		addr:            addr,
+		authClient:      authClient,
+		storage:         storage,
+		logger:          logger,
+		bus:             bus,
+		adaptiveHandler: handlers.NewAdaptiveHandler(storage, logger),
 	}
 }
 
@@ -48,6 +55,18 @@ func (a *ApiServer) Run() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// quiz events are consumed in the background until the server exits
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+	if a.bus != nil {
+		a.bus.Subscribe("stats", handlers.EventTypes, handlers.NewEventsHandler(a.storage, a.logger).Handle)
+		go func() {
+			if err := a.bus.Run(eventsCtx); err != nil {
+				a.logger.Error("event bus stopped", zap.Error(err))
+			}
+		}()
+	}
+	go a.adaptiveHandler.Run(eventsCtx)
 	// Start server
 	go func() {
 		a.logger.Info("Starting server on " + a.addr)
@@ -80,24 +99,31 @@ func (a *ApiServer) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /stats/sessions/save", middleware.InternalAuth(handlers.NewQuizStatsHandler(a.storage, a.logger).SaveSession, a.logger, internalApiKey))
 	mux.HandleFunc("POST /stats/sessions/{quizSessionId}/respond", middleware.InternalAuth(handlers.NewQuizStatsHandler(a.storage, a.logger).SaveResponse, a.logger, internalApiKey))
 	mux.HandleFunc("POST /stats/sessions/{quizSessionId}/finish", middleware.InternalAuth(handlers.NewQuizStatsHandler(a.storage, a.logger).FinishSession, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/sessions/scores", middleware.InternalAuth(handlers.NewQuizStatsHandler(a.storage, a.logger).GetSessionsStats, a.logger, internalApiKey))
 	// admin
-	allStatsHandler := handlers.NewGetAllStatsHandler(a.storage, a.logger)
-	userStatsHandler := handlers.NewUserStatsHandler(a.storage, a.logger)
+	allStatsHandler := handlers.NewGetAllStatsHandler(a.storage, a.logger, a.authClient)
+	userStatsHandler := handlers.NewUserStatsHandler(a.storage, a.logger, a.authClient)
 	mux.HandleFunc("GET /stats/users/{id}", middleware.InternalAuth(userStatsHandler.Handle, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/responses", middleware.InternalAuth(allStatsHandler.GetResponses, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/questions/{id}/stats", middleware.InternalAuth(allStatsHandler.GetStatsForQuestion, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/questions/{id}/landmarks", middleware.InternalAuth(allStatsHandler.GetLandmarkAnswers, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/activity", middleware.InternalAuth(allStatsHandler.GetActivity, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/summary", middleware.InternalAuth(allStatsHandler.GetSummary, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/surveys/users/{id}", middleware.InternalAuth(handlers.NewSurveysHandler(a.storage, a.logger).GetSurvey, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/grouped", middleware.InternalAuth(allStatsHandler.GetStatsGroupedBySurvey, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/calibration/grouped", middleware.InternalAuth(allStatsHandler.GetCalibrationGroupedBySurvey, a.logger, internalApiKey))
 	mux.HandleFunc("DELETE /stats/users/{id}/responses", middleware.InternalAuth(userStatsHandler.DeleteUserResponses, a.logger, internalApiKey))
 	mux.HandleFunc("DELETE /stats/responses/{id}", middleware.InternalAuth(allStatsHandler.DeleteResponse, a.logger, internalApiKey))
 	mux.HandleFunc("GET /stats/users/stats", middleware.InternalAuth(userStatsHandler.GetAllUsersStats, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/users/{id}/adaptive", middleware.InternalAuth(a.adaptiveHandler.GetProfile, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/users/{id}/incorrect", middleware.InternalAuth(userStatsHandler.GetIncorrectQuestions, a.logger, internalApiKey))
+	mux.HandleFunc("GET /stats/users/{id}/calibration", middleware.InternalAuth(userStatsHandler.GetCalibration, a.logger, internalApiKey))
 
 	//external
-	mux.HandleFunc("GET /stats/userStats", middleware.VerifyToken(handlers.NewUserStatsHandler(a.storage, a.logger).Handle, a.authClient))
+	mux.HandleFunc("GET /stats/userStats", middleware.VerifyToken(handlers.NewUserStatsHandler(a.storage, a.logger, a.authClient).Handle, a.authClient))
 	mux.HandleFunc("GET /stats/quiz/{quizSessionId}", middleware.VerifyToken(handlers.NewQuizStatsHandler(a.storage, a.logger).GetStats, a.authClient))
-	mux.HandleFunc("GET /stats/sessions", middleware.VerifyToken(handlers.NewUserStatsHandler(a.storage, a.logger).GetUserSessions, a.authClient))
+	mux.HandleFunc("GET /stats/sessions", middleware.VerifyToken(handlers.NewUserStatsHandler(a.storage, a.logger, a.authClient).GetUserSessions, a.authClient))
+	mux.HandleFunc("GET /stats/calibration", middleware.VerifyToken(handlers.NewUserStatsHandler(a.storage, a.logger, a.authClient).GetCalibration, a.authClient))
 	mux.HandleFunc("POST /stats/survey", middleware.VerifyToken(handlers.NewSurveysHandler(a.storage, a.logger).Save, a.authClient))
 	mux.HandleFunc("GET /stats/survey", middleware.VerifyToken(handlers.NewSurveysHandler(a.storage, a.logger).GetSurvey, a.authClient))
 }
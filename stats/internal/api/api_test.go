@@ -0,0 +1,415 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"stats/internal/clients"
+	"stats/internal/models"
+	"stats/internal/storage"
+	"strings"
+	"testing"
+)
+
+const testApiKey = "test-key"
+
+type testServer struct {
+	store *storage.MemoryStorage
+	auth  *clients.FakeAuthClient
+	mux   *http.ServeMux
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	t.Setenv("INTERNAL_API_KEY", testApiKey)
+	store := storage.NewMemoryStorage()
+	auth := clients.NewFakeAuthClient()
+	mux := http.NewServeMux()
+	NewApiServer(":0", store, zap.NewNop(), auth, nil).registerRoutes(mux)
+	return &testServer{store: store, auth: auth, mux: mux}
+}
+
+// do sends the request with the api key of the internal routes, or with the access token when it is set
+func (s *testServer) do(method string, path string, body string, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", token)
+	} else {
+		r.Header.Set("X-Api-Key", testApiKey)
+	}
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, r)
+	return w
+}
+
+// addUser makes the token "token-<userID>" valid for the user
+func (s *testServer) addUser(userID int) string {
+	token := fmt.Sprintf("token-%d", userID)
+	s.auth.AddToken(token, models.UserData{UserID: userID, Role: models.RoleUser})
+	return token
+}
+
+func (s *testServer) saveSession(t *testing.T, sessionID int, userID int, mode models.QuizMode) {
+	t.Helper()
+	w := s.do(http.MethodPost, "/stats/sessions/save", fmt.Sprintf(`{"session_id":%d,"user_id":%d,"quiz_mode":%q}`, sessionID, userID, mode), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("save session: status %d, body %q", w.Code, w.Body.String())
+	}
+}
+
+func (s *testServer) respond(sessionID int, questionID int, correct bool, idempotencyKey string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"question_id":%d,"is_correct":%t,"idempotency_key":%q}`, questionID, correct, idempotencyKey)
+	return s.do(http.MethodPost, fmt.Sprintf("/stats/sessions/%d/respond", sessionID), body, "")
+}
+
+func (s *testServer) mustRespond(t *testing.T, sessionID int, questionID int, correct bool, idempotencyKey string) {
+	t.Helper()
+	if w := s.respond(sessionID, questionID, correct, idempotencyKey); w.Code != http.StatusOK {
+		t.Fatalf("respond: status %d, body %q", w.Code, w.Body.String())
+	}
+}
+
+func (s *testServer) finish(t *testing.T, sessionID int) {
+	t.Helper()
+	if w := s.do(http.MethodPost, fmt.Sprintf("/stats/sessions/%d/finish", sessionID), "", ""); w.Code != http.StatusOK {
+		t.Fatalf("finish: status %d, body %q", w.Code, w.Body.String())
+	}
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+func TestSaveResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare runs after the session 1 of user 1 was saved
+		prepare     func(t *testing.T, s *testServer)
+		sessionID   int
+		body        string
+		apiKey      string
+		wantStatus  int
+		wantAnswers int
+	}{
+		{
+			name:        "first answer",
+			wantStatus:  http.StatusOK,
+			wantAnswers: 1,
+		},
+		{
+			name: "retried answer is saved once",
+			prepare: func(t *testing.T, s *testServer) {
+				s.mustRespond(t, 1, 7, true, "key")
+			},
+			wantStatus:  http.StatusOK,
+			wantAnswers: 1,
+		},
+		{
+			name:        "answer to a session which was not saved",
+			sessionID:   2,
+			wantStatus:  http.StatusOK,
+			wantAnswers: 1,
+		},
+		{
+			name: "answer after finish",
+			prepare: func(t *testing.T, s *testServer) {
+				s.mustRespond(t, 1, 6, true, "other")
+				s.finish(t, 1)
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantAnswers: 1,
+		},
+		{
+			name: "last answer retried after finish",
+			prepare: func(t *testing.T, s *testServer) {
+				s.mustRespond(t, 1, 7, true, "key")
+				s.finish(t, 1)
+			},
+			wantStatus:  http.StatusOK,
+			wantAnswers: 1,
+		},
+		{
+			name:       "malformed body",
+			body:       `{"question_id":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "wrong api key",
+			apiKey:     "wrong",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.saveSession(t, 1, 1, models.QuizModeClassic)
+			if tt.prepare != nil {
+				tt.prepare(t, s)
+			}
+			sessionID := tt.sessionID
+			if sessionID == 0 {
+				sessionID = 1
+			}
+			body := tt.body
+			if body == "" {
+				body = `{"question_id":7,"is_correct":true,"idempotency_key":"key"}`
+			}
+			r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/stats/sessions/%d/respond", sessionID), strings.NewReader(body))
+			apiKey := tt.apiKey
+			if apiKey == "" {
+				apiKey = testApiKey
+			}
+			r.Header.Set("X-Api-Key", apiKey)
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			answers, err := s.store.CountAnswers(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if answers != tt.wantAnswers {
+				t.Errorf("%d answers saved, want %d", answers, tt.wantAnswers)
+			}
+		})
+	}
+}
+
+func TestGetQuizStats(t *testing.T) {
+	s := newTestServer(t)
+	owner, other := s.addUser(1), s.addUser(2)
+	s.saveSession(t, 1, 1, models.QuizModeClassic)
+	s.mustRespond(t, 1, 10, true, "a")
+	s.mustRespond(t, 1, 11, false, "b")
+	s.mustRespond(t, 1, 12, true, "c")
+	s.finish(t, 1)
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{"owner of the session", "/stats/quiz/1", owner, http.StatusOK},
+		{"another user", "/stats/quiz/1", other, http.StatusNotFound},
+		{"unknown session", "/stats/quiz/2", owner, http.StatusNotFound},
+		{"invalid session id", "/stats/quiz/x", owner, http.StatusBadRequest},
+		{"invalid token", "/stats/quiz/1", "invalid", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, tt.path, "", tt.token)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			stats := decode[models.QuizStats](t, w)
+			if stats.Mode != models.QuizModeClassic || stats.TotalQuestions != 3 || stats.CorrectAnswers != 2 || len(stats.Questions) != 3 {
+				t.Errorf("stats %+v, want 2 of 3 classic questions answered correctly", stats)
+			}
+		})
+	}
+}
+
+func TestUserStats(t *testing.T) {
+	s := newTestServer(t)
+	token := s.addUser(1)
+	s.saveSession(t, 1, 1, models.QuizModeClassic)
+	s.mustRespond(t, 1, 10, true, "a")
+	s.mustRespond(t, 1, 11, false, "b")
+	s.saveSession(t, 2, 1, models.QuizModeEducational)
+	s.mustRespond(t, 2, 10, true, "a")
+	// answers of other users and sessions without answers are not counted
+	s.saveSession(t, 3, 2, models.QuizModeClassic)
+	s.mustRespond(t, 3, 10, false, "a")
+	s.saveSession(t, 4, 1, models.QuizModeClassic)
+
+	w := s.do(http.MethodGet, "/stats/userStats", "", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("user stats: status %d, body %q", w.Code, w.Body.String())
+	}
+	stats := decode[models.UserStats](t, w)
+	if stats.TotalQuestions[models.QuizModeClassic] != 2 || stats.CorrectAnswers[models.QuizModeClassic] != 1 {
+		t.Errorf("classic stats %+v, want 1 of 2 answers correct", stats)
+	}
+	if stats.TotalQuestions[models.QuizModeEducational] != 1 || stats.Accuracy[models.QuizModeEducational] != 1 {
+		t.Errorf("educational stats %+v, want 1 correct answer", stats)
+	}
+
+	w = s.do(http.MethodGet, "/stats/sessions", "", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("user sessions: status %d, body %q", w.Code, w.Body.String())
+	}
+	sessions := decode[[]models.QuizStats](t, w)
+	if len(sessions) != 2 || sessions[0].SessionID != 2 || sessions[1].SessionID != 1 {
+		t.Fatalf("sessions %+v, want sessions 2 and 1 with answers, newest first", sessions)
+	}
+	if sessions[1].TotalQuestions != 2 || sessions[1].Accuracy != 0.5 || len(sessions[1].Questions) != 2 {
+		t.Errorf("session 1 stats %+v, want 1 of 2 answers correct", sessions[1])
+	}
+}
+
+func TestQuestionStatsForCohort(t *testing.T) {
+	s := newTestServer(t)
+	s.auth.AddCohort(models.CohortMembers{CohortID: 1, OwnerID: 9, UsersIDs: []int{1}})
+	s.auth.AddCohort(models.CohortMembers{CohortID: 2, OwnerID: 9})
+	s.saveSession(t, 1, 1, models.QuizModeClassic)
+	s.mustRespond(t, 1, 10, true, "a")
+	s.mustRespond(t, 1, 11, false, "b")
+	s.saveSession(t, 2, 2, models.QuizModeClassic)
+	s.mustRespond(t, 2, 10, false, "a")
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantStats  []models.QuestionAllStats
+	}{
+		{"all users", "", http.StatusOK, []models.QuestionAllStats{{QuestionID: 10, Total: 2, Correct: 1}, {QuestionID: 11, Total: 1}}},
+		{"cohort members", "?cohort_id=1", http.StatusOK, []models.QuestionAllStats{{QuestionID: 10, Total: 1, Correct: 1}, {QuestionID: 11, Total: 1}}},
+		{"empty cohort", "?cohort_id=2", http.StatusOK, nil},
+		{"unknown cohort", "?cohort_id=3", http.StatusNotFound, nil},
+		{"invalid cohort id", "?cohort_id=x", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/stats/questions/-/stats"+tt.query, "", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			stats := decode[[]models.QuestionAllStats](t, w)
+			if fmt.Sprint(stats) != fmt.Sprint(tt.wantStats) {
+				t.Errorf("stats %+v, want %+v", stats, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestStatsGroupedBySurvey(t *testing.T) {
+	s := newTestServer(t)
+	for userID, gender := range map[int]string{1: "female", 2: "male", 3: "female"} {
+		token := s.addUser(userID)
+		w := s.do(http.MethodPost, "/stats/survey", fmt.Sprintf(`{"gender":%q,"country":"PL"}`, gender), token)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("save survey: status %d, body %q", w.Code, w.Body.String())
+		}
+		s.saveSession(t, userID, userID, models.QuizModeClassic)
+		s.mustRespond(t, userID, 10, userID != 3, "a")
+	}
+	if w := s.do(http.MethodPost, "/stats/survey", `{"gender":"male"}`, "token-1"); w.Code != http.StatusConflict {
+		t.Errorf("second survey: status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantStats  []models.SurveyGroupedStats
+	}{
+		{
+			name:       "gender",
+			query:      "?groupBy=gender",
+			wantStatus: http.StatusOK,
+			wantStats: []models.SurveyGroupedStats{
+				{Group: "gender", Value: "female", Total: 2, Correct: 1, Accuracy: 0.5},
+				{Group: "gender", Value: "male", Total: 1, Correct: 1, Accuracy: 1},
+			},
+		},
+		{
+			name:       "country",
+			query:      "?groupBy=country",
+			wantStatus: http.StatusOK,
+			wantStats:  []models.SurveyGroupedStats{{Group: "country", Value: "PL", Total: 3, Correct: 2, Accuracy: 2.0 / 3}},
+		},
+		{"missing field", "", http.StatusBadRequest, nil},
+		{"unsupported field", "?groupBy=name", http.StatusInternalServerError, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/stats/grouped"+tt.query, "", "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d, body %q", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			stats := decode[[]models.SurveyGroupedStats](t, w)
+			if fmt.Sprint(stats) != fmt.Sprint(tt.wantStats) {
+				t.Errorf("stats %+v, want %+v", stats, tt.wantStats)
+			}
+		})
+	}
+
+	w := s.do(http.MethodGet, "/stats/summary", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("summary: status %d, body %q", w.Code, w.Body.String())
+	}
+	if summary := decode[models.StatsSummary](t, w); summary != (models.StatsSummary{QuizSessions: 3, TotalResponses: 3, TotalCorrect: 2}) {
+		t.Errorf("summary %+v, want 3 sessions with 2 of 3 answers correct", summary)
+	}
+}
+
+func TestAdaptiveProfile(t *testing.T) {
+	s := newTestServer(t)
+	s.saveSession(t, 1, 1, models.QuizModeAdaptive)
+	s.saveSession(t, 2, 2, models.QuizModeAdaptive)
+	s.mustRespond(t, 1, 7, true, "1")
+	s.mustRespond(t, 2, 7, false, "2")
+
+	profile := func() models.AdaptiveProfile {
+		t.Helper()
+		w := s.do(http.MethodGet, "/stats/users/1/adaptive", "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("profile: status %d, body %q", w.Code, w.Body.String())
+		}
+		return decode[models.AdaptiveProfile](t, w)
+	}
+	first := profile()
+	if first.UserAnswers != 1 || first.Ability <= 0 || len(first.Questions) != 1 || first.Questions[0].Answers != 2 {
+		t.Fatalf("profile %+v, want positive ability after 1 correct answer to the question answered twice", first)
+	}
+
+	// answers saved after the previous request update the ratings kept by the handler
+	s.mustRespond(t, 1, 8, false, "3")
+	second := profile()
+	if second.UserAnswers != 2 || second.Ability >= first.Ability || len(second.Questions) != 2 {
+		t.Fatalf("profile %+v, want lower ability after an incorrect answer to a new question", second)
+	}
+	if second.Questions[1].QuestionID != 8 || second.Questions[1].UserAnswers != 1 {
+		t.Errorf("questions %+v, want question 8 answered once by the user", second.Questions)
+	}
+}
+
+func TestIncorrectQuestions(t *testing.T) {
+	s := newTestServer(t)
+	s.saveSession(t, 1, 1, models.QuizModeClassic)
+	s.saveSession(t, 2, 1, models.QuizModeReview)
+	s.mustRespond(t, 1, 7, false, "1")
+	s.mustRespond(t, 1, 8, true, "2")
+	// misses in review sessions are scheduled by the quiz service
+	s.mustRespond(t, 2, 9, false, "3")
+
+	w := s.do(http.MethodGet, "/stats/users/1/incorrect", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+	}
+	questions := decode[[]models.IncorrectQuestion](t, w)
+	if len(questions) != 1 || questions[0].QuestionID != 7 || questions[0].MissedAt.IsZero() {
+		t.Errorf("incorrect questions %+v, want question 7 with the time it was missed", questions)
+	}
+}
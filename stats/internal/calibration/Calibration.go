@@ -0,0 +1,59 @@
+package calibration
+
+import (
+	"stats/internal/models"
+)
+
+// Confidence is rated on a scale from MinConfidence (guessing) to MaxConfidence (certain),
+// the levels are spread evenly over probabilities from 0 to 1
+
+const (
+	MinConfidence = 1
+	MaxConfidence = 5
+)
+
+// Probability returns the confidence level as the probability of a correct answer
+func Probability(confidence int) float64 {
+	return float64(confidence-MinConfidence) / float64(MaxConfidence-MinConfidence)
+}
+
+// Compute returns accuracy per confidence level, the Brier score and the overconfidence index of the answers,
+// outcomes with a confidence out of the scale are ignored
+func Compute(outcomes []models.ConfidenceOutcome) models.Calibration {
+	result := models.Calibration{Buckets: make([]models.CalibrationBucket, 0, MaxConfidence-MinConfidence+1)}
+	for confidence := MinConfidence; confidence <= MaxConfidence; confidence++ {
+		result.Buckets = append(result.Buckets, models.CalibrationBucket{
+			Confidence:       confidence,
+			ExpectedAccuracy: Probability(confidence),
+		})
+	}
+	var squaredError, confidenceSum float64
+	correct := 0
+	for _, outcome := range outcomes {
+		if outcome.Confidence < MinConfidence || outcome.Confidence > MaxConfidence {
+			continue
+		}
+		bucket := &result.Buckets[outcome.Confidence-MinConfidence]
+		bucket.Total++
+		probability := Probability(outcome.Confidence)
+		actual := 0.0
+		if outcome.Correct {
+			bucket.Correct++
+			correct++
+			actual = 1
+		}
+		squaredError += (probability - actual) * (probability - actual)
+		confidenceSum += probability
+		result.Answers++
+	}
+	for i := range result.Buckets {
+		if result.Buckets[i].Total > 0 {
+			result.Buckets[i].Accuracy = float64(result.Buckets[i].Correct) / float64(result.Buckets[i].Total)
+		}
+	}
+	if result.Answers > 0 {
+		result.BrierScore = squaredError / float64(result.Answers)
+		result.Overconfidence = (confidenceSum - float64(correct)) / float64(result.Answers)
+	}
+	return result
+}
@@ -7,16 +7,27 @@ import (
 	"go.uber.org/zap"
 	"net/http"
 	"stats/internal/models"
+	"strconv"
 )
 
+// AuthService is the API of the auth service used by stats, AuthClient calls the service over HTTP
+type AuthService interface {
+	VerifyAuthToken(token string) (models.UserData, error)
+	GetCohortMembers(cohortID int) (models.CohortMembers, error)
+}
+
 type AuthClient struct {
 	addr   string
+	apiKey string
 	logger *zap.Logger
 }
 
-func NewAuthClient(addr string, logger *zap.Logger) *AuthClient {
+var ErrCohortNotFound = fmt.Errorf("cohort not found")
+
+func NewAuthClient(addr string, apiKey string, logger *zap.Logger) *AuthClient {
 	return &AuthClient{
 		addr:   addr,
+		apiKey: apiKey,
 		logger: logger,
 	}
 }
@@ -63,3 +74,33 @@ func (c *AuthClient) VerifyAuthToken(token string) (models.UserData, error) {
 
 	return userDataResponse, nil
 }
+
+func (c *AuthClient) GetCohortMembers(cohortID int) (models.CohortMembers, error) {
+	var members models.CohortMembers
+	req, err := http.NewRequest("GET", c.addr+"/cohorts/"+strconv.Itoa(cohortID)+"/members", nil)
+	if err != nil {
+		return members, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return members, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return members, ErrCohortNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("unexpected status code", zap.Int("status_code", resp.StatusCode))
+		return members, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&members)
+	if err != nil {
+		return members, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return members, nil
+}
@@ -0,0 +1,55 @@
+package clients
+
+import (
+	"fmt"
+	"stats/internal/models"
+	"sync"
+)
+
+// FakeAuthClient is an AuthService answering from memory, for tests which do not run the auth service
+type FakeAuthClient struct {
+	mu      sync.Mutex
+	users   map[string]models.UserData
+	cohorts map[int]models.CohortMembers
+}
+
+func NewFakeAuthClient() *FakeAuthClient {
+	return &FakeAuthClient{
+		users:   make(map[string]models.UserData),
+		cohorts: make(map[int]models.CohortMembers),
+	}
+}
+
+// AddToken makes the token valid for the user
+func (c *FakeAuthClient) AddToken(token string, user models.UserData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.users[token] = user
+}
+
+// AddCohort makes the members of the cohort available by its id
+func (c *FakeAuthClient) AddCohort(members models.CohortMembers) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cohorts[members.CohortID] = members
+}
+
+func (c *FakeAuthClient) VerifyAuthToken(token string) (models.UserData, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	user, ok := c.users[token]
+	if !ok {
+		return models.UserData{}, fmt.Errorf("unexpected status code: 401")
+	}
+	return user, nil
+}
+
+func (c *FakeAuthClient) GetCohortMembers(cohortID int) (models.CohortMembers, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	members, ok := c.cohorts[cohortID]
+	if !ok {
+		return models.CohortMembers{}, ErrCohortNotFound
+	}
+	return members, nil
+}
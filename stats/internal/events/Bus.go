@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"stats/internal/models"
+	"time"
+)
+
+// Types of the events exchanged between services
+const (
+	QuizStarted     = "quiz_started"
+	AnswerSubmitted = "answer_submitted"
+	QuizFinished    = "quiz_finished"
+	UserRegistered  = "user_registered"
+	UserDeleted     = "user_deleted"
+)
+
+// Event is a published event, Payload is the JSON encoded payload of its type
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Payload of QuizStarted is the started models.QuizSession
+
+// AnswerSubmittedPayload is the payload of AnswerSubmitted
+type AnswerSubmittedPayload struct {
+	SessionID int                     `json:"session_id"`
+	Answer    models.QuestionResponse `json:"answer"`
+}
+
+// SessionPayload is the payload of QuizFinished
+type SessionPayload struct {
+	SessionID int `json:"session_id"`
+}
+
+// UserPayload is the payload of UserRegistered and UserDeleted
+type UserPayload struct {
+	UserID int `json:"user_id"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, eventType string, payload any) error
+}
+
+// Handler processes an event delivered to a consumer. Events are delivered at least once,
+// so handlers have to tolerate an event they already processed
+type Handler func(ctx context.Context, event Event) error
+
+type Bus interface {
+	Publisher
+	// Subscribe registers the handler of the consumer for the event types, it has to be called before Run.
+	// Each consumer receives every event of its types once it is running, including events published while it was not
+	Subscribe(consumer string, eventTypes []string, handler Handler)
+	// Run delivers events to the subscribed handlers until the context is cancelled
+	Run(ctx context.Context) error
+	Close() error
+}
+
+// Connect returns the bus on the events database configured by EVENTS_DB_* environment variables,
+// nil when EVENTS_DB_HOST is not set
+func Connect(logger *zap.Logger) (Bus, error) {
+	host := os.Getenv("EVENTS_DB_HOST")
+	if host == "" {
+		return nil, nil
+	}
+	connString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		host, os.Getenv("EVENTS_DB_PORT"), os.Getenv("EVENTS_DB_USER"), os.Getenv("EVENTS_DB_PASSWORD"), os.Getenv("EVENTS_DB_NAME"))
+	return NewPostgresBus(connString, logger)
+}
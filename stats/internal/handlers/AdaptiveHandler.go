@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"net/http"
+	"sort"
+	"stats/internal/models"
+	"stats/internal/rating"
+	"stats/internal/storage"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ratingsRebuildInterval is how often the ratings are replayed from all answers. In between only new answers are
+// added, a full replay picks up answers committed out of the order of their IDs and sessions saved after their answers
+const ratingsRebuildInterval = 10 * time.Minute
+
+type AdaptiveHandler struct {
+	storage storage.Storage
+	logger  *zap.Logger
+
+	// mu guards the ratings, it is never held during storage calls
+	mu      sync.Mutex
+	ratings *adaptiveRatings
+}
+
+// adaptiveRatings are the ratings replayed from answers up to lastAnswerID
+type adaptiveRatings struct {
+	ratings rating.Ratings
+	// userAnswers counts answers of users to questions
+	userAnswers map[int]map[int]int
+	// lastAnswerID is the ID of the last answer added to the ratings
+	lastAnswerID int
+}
+
+func newAdaptiveRatings() *adaptiveRatings {
+	return &adaptiveRatings{ratings: rating.NewRatings(), userAnswers: make(map[int]map[int]int)}
+}
+
+func NewAdaptiveHandler(storage storage.Storage, logger *zap.Logger) *AdaptiveHandler {
+	return &AdaptiveHandler{storage: storage, logger: logger, ratings: newAdaptiveRatings()}
+}
+
+// Run replays the ratings from all answers every ratingsRebuildInterval until the context is cancelled.
+// The ratings are rebuilt aside and swapped in, requests meanwhile use and update the previous ratings
+func (h *AdaptiveHandler) Run(ctx context.Context) {
+	ticker := time.NewTicker(ratingsRebuildInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		outcomes, err := h.storage.GetAnswerOutcomes(ctx, 0)
+		if err != nil {
+			h.logger.Error("failed to rebuild adaptive ratings", zap.Error(err))
+			continue
+		}
+		rebuilt := newAdaptiveRatings()
+		rebuilt.addOutcomes(outcomes)
+		// answers added to the previous ratings after the replay read them are added again by the next request
+		h.mu.Lock()
+		h.ratings = rebuilt
+		h.mu.Unlock()
+	}
+}
+
+// updateRatings adds answers saved since the last update to the ratings
+func (h *AdaptiveHandler) updateRatings(ctx context.Context) error {
+	h.mu.Lock()
+	lastAnswerID := h.ratings.lastAnswerID
+	h.mu.Unlock()
+	outcomes, err := h.storage.GetAnswerOutcomes(ctx, lastAnswerID)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// a concurrent request or a rebuild may have added some of the answers already
+	added := h.ratings.lastAnswerID
+	for i, outcome := range outcomes {
+		if outcome.ID > added {
+			h.ratings.addOutcomes(outcomes[i:])
+			break
+		}
+	}
+	return nil
+}
+
+// addOutcomes adds answers in the order of their IDs
+func (r *adaptiveRatings) addOutcomes(outcomes []models.AnswerOutcome) {
+	for _, outcome := range outcomes {
+		r.ratings.Add(outcome)
+		if r.userAnswers[outcome.UserID] == nil {
+			r.userAnswers[outcome.UserID] = make(map[int]int)
+		}
+		r.userAnswers[outcome.UserID][outcome.QuestionID]++
+		r.lastAnswerID = max(r.lastAnswerID, outcome.ID)
+	}
+}
+
+// GetProfile returns the ability of the user and difficulties of questions used by the adaptive quiz mode.
+// Estimates are kept in memory, updated with answers saved since the previous request and rebuilt by Run
+func (h *AdaptiveHandler) GetProfile(rw http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(rw, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	if err = h.updateRatings(r.Context()); err != nil {
+		h.logger.Error("failed to get answers", zap.Error(err))
+		serverError(r.Context(), rw, err, "internal server error")
+		return
+	}
+	h.mu.Lock()
+	ratings := h.ratings.ratings
+	userAnswers := h.ratings.userAnswers[userID]
+	profile := models.AdaptiveProfile{
+		UserID:    userID,
+		Questions: make([]models.QuestionDifficulty, 0, len(ratings.Questions)),
+	}
+	if user, ok := ratings.Users[userID]; ok {
+		profile.Ability = user.Value
+		profile.UserAnswers = user.Answers
+	}
+	for questionID, question := range ratings.Questions {
+		profile.Questions = append(profile.Questions, models.QuestionDifficulty{
+			QuestionID:  questionID,
+			Difficulty:  question.Value,
+			Answers:     question.Answers,
+			UserAnswers: userAnswers[questionID],
+		})
+	}
+	h.mu.Unlock()
+	sort.Slice(profile.Questions, func(i, j int) bool {
+		return profile.Questions[i].QuestionID < profile.Questions[j].QuestionID
+	})
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err = profile.ToJSON(rw); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
@@ -2,31 +2,71 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"net/http"
+	"slices"
+	"stats/internal/calibration"
+	"stats/internal/clients"
 	"stats/internal/models"
 	"stats/internal/storage"
 	"strconv"
+	"strings"
 )
 
 type GetAllStatsHandler struct {
-	storage storage.Storage
-	logger  *zap.Logger
+	storage    storage.Storage
+	logger     *zap.Logger
+	authClient clients.AuthService
 }
 
-func NewGetAllStatsHandler(storage storage.Storage, logger *zap.Logger) *GetAllStatsHandler {
+func NewGetAllStatsHandler(storage storage.Storage, logger *zap.Logger, authClient clients.AuthService) *GetAllStatsHandler {
 	return &GetAllStatsHandler{
-		storage: storage,
-		logger:  logger,
+		storage:    storage,
+		logger:     logger,
+		authClient: authClient,
 	}
 }
 
-func (h *GetAllStatsHandler) GetResponses(w http.ResponseWriter, _ *http.Request) {
-	stats, err := h.storage.GetAllResponses()
+// getCohortUsersIDs resolves the optional cohort_id query parameter to the IDs of the cohort members.
+// Without the parameter it returns nil, which means stats of all users. On failure the error response is written
+func getCohortUsersIDs(w http.ResponseWriter, r *http.Request, authClient clients.AuthService, logger *zap.Logger) ([]int, bool) {
+	cohortId := r.URL.Query().Get("cohort_id")
+	if cohortId == "" {
+		return nil, true
+	}
+	cohortID, err := strconv.Atoi(cohortId)
+	if err != nil {
+		http.Error(w, "invalid cohort id", http.StatusBadRequest)
+		return nil, false
+	}
+	members, err := authClient.GetCohortMembers(cohortID)
+	if errors.Is(err, clients.ErrCohortNotFound) {
+		http.Error(w, "cohort not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		logger.Error("failed to get cohort members", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
+		return nil, false
+	}
+	// an empty cohort must not fall back to all users
+	if members.UsersIDs == nil {
+		members.UsersIDs = []int{}
+	}
+	return members.UsersIDs, true
+}
+
+func (h *GetAllStatsHandler) GetResponses(w http.ResponseWriter, r *http.Request) {
+	usersIDs, ok := getCohortUsersIDs(w, r, h.authClient, h.logger)
+	if !ok {
+		return
+	}
+	stats, err := h.storage.GetAllResponses(r.Context(), usersIDs)
 	if err != nil {
 		h.logger.Error("failed to get stats", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -34,19 +74,23 @@ func (h *GetAllStatsHandler) GetResponses(w http.ResponseWriter, _ *http.Request
 	_, err = w.Write(statsJson)
 	if err != nil {
 		h.logger.Error("failed to write response", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 }
 
 func (h *GetAllStatsHandler) GetStatsForQuestion(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("GetStatsForQuestion")
+	usersIDs, ok := getCohortUsersIDs(w, r, h.authClient, h.logger)
+	if !ok {
+		return
+	}
 	questionId := r.PathValue("id")
 	if questionId == "-" {
-		stats, err := h.storage.GetStatsForAllQuestions()
+		stats, err := h.storage.GetStatsForAllQuestions(r.Context(), usersIDs)
 		if err != nil {
 			h.logger.Error("failed to get stats", zap.Error(err))
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			serverError(r.Context(), w, err, "internal server error")
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -60,10 +104,10 @@ func (h *GetAllStatsHandler) GetStatsForQuestion(w http.ResponseWriter, r *http.
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
-	stats, err := h.storage.GetStatsForQuestion(questionID)
+	stats, err := h.storage.GetStatsForQuestion(r.Context(), questionID, usersIDs)
 	if err != nil {
 		h.logger.Error("failed to get stats", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -71,16 +115,43 @@ func (h *GetAllStatsHandler) GetStatsForQuestion(w http.ResponseWriter, r *http.
 	_, err = w.Write(statsJson)
 	if err != nil {
 		h.logger.Error("failed to write response", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 }
 
+// GetLandmarkAnswers returns the landmarks placed in answers to a landmark question
+func (h *GetAllStatsHandler) GetLandmarkAnswers(w http.ResponseWriter, r *http.Request) {
+	usersIDs, ok := getCohortUsersIDs(w, r, h.authClient, h.logger)
+	if !ok {
+		return
+	}
+	questionID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid question id", http.StatusBadRequest)
+		return
+	}
+	answers, err := h.storage.GetLandmarkAnswers(r.Context(), questionID, usersIDs)
+	if err != nil {
+		h.logger.Error("failed to get landmark answers", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(answers); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
 func (h *GetAllStatsHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.storage.GetActivityStats()
+	usersIDs, ok := getCohortUsersIDs(w, r, h.authClient, h.logger)
+	if !ok {
+		return
+	}
+	stats, err := h.storage.GetActivityStats(r.Context(), usersIDs)
 	if err != nil {
 		h.logger.Error("failed to get stats", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -88,7 +159,7 @@ func (h *GetAllStatsHandler) GetActivity(w http.ResponseWriter, r *http.Request)
 	_, err = w.Write(statsJson)
 	if err != nil {
 		h.logger.Error("failed to write response", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 }
@@ -96,22 +167,22 @@ func (h *GetAllStatsHandler) GetActivity(w http.ResponseWriter, r *http.Request)
 func (h *GetAllStatsHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
 	var summary models.StatsSummary
 	var err error
-	summary.QuizSessions, err = h.storage.CountQuizSessions()
+	summary.QuizSessions, err = h.storage.CountQuizSessions(r.Context())
 	if err != nil {
 		h.logger.Error("failed to count quiz sessions", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
-	summary.TotalResponses, err = h.storage.CountAnswers()
+	summary.TotalResponses, err = h.storage.CountAnswers(r.Context())
 	if err != nil {
 		h.logger.Error("failed to count answers", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
-	summary.TotalCorrect, err = h.storage.CountCorrectAnswers()
+	summary.TotalCorrect, err = h.storage.CountCorrectAnswers(r.Context())
 	if err != nil {
 		h.logger.Error("failed to count correct answers", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 
@@ -119,7 +190,7 @@ func (h *GetAllStatsHandler) GetSummary(w http.ResponseWriter, r *http.Request)
 	err = json.NewEncoder(w).Encode(summary)
 	if err != nil {
 		h.logger.Error("failed to encode response", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 }
@@ -130,10 +201,14 @@ func (h *GetAllStatsHandler) GetStatsGroupedBySurvey(w http.ResponseWriter, r *h
 		http.Error(w, "groupBy parameter is required", http.StatusBadRequest)
 		return
 	}
-	stats, err := h.storage.GetStatsGroupedBySurveyField(groupBy)
+	usersIDs, ok := getCohortUsersIDs(w, r, h.authClient, h.logger)
+	if !ok {
+		return
+	}
+	stats, err := h.storage.GetStatsGroupedBySurveyField(r.Context(), groupBy, usersIDs)
 	if err != nil {
 		h.logger.Error("failed to get grouped stats", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 
@@ -144,6 +219,41 @@ func (h *GetAllStatsHandler) GetStatsGroupedBySurvey(w http.ResponseWriter, r *h
 	}
 }
 
+// GetCalibrationGroupedBySurvey returns the calibration of confidence ratings for each answer to a survey question
+func (h *GetAllStatsHandler) GetCalibrationGroupedBySurvey(w http.ResponseWriter, r *http.Request) {
+	groupBy := r.URL.Query().Get("groupBy")
+	if groupBy == "" {
+		http.Error(w, "groupBy parameter is required", http.StatusBadRequest)
+		return
+	}
+	usersIDs, ok := getCohortUsersIDs(w, r, h.authClient, h.logger)
+	if !ok {
+		return
+	}
+	outcomes, err := h.storage.GetConfidenceOutcomesBySurveyField(r.Context(), groupBy, usersIDs)
+	if err != nil {
+		h.logger.Error("failed to get grouped confidence outcomes", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
+		return
+	}
+	stats := make([]models.SurveyGroupedCalibration, 0, len(outcomes))
+	for value, groupOutcomes := range outcomes {
+		stats = append(stats, models.SurveyGroupedCalibration{
+			Group:       groupBy,
+			Value:       value,
+			Calibration: calibration.Compute(groupOutcomes),
+		})
+	}
+	slices.SortFunc(stats, func(a, b models.SurveyGroupedCalibration) int {
+		return strings.Compare(a.Value, b.Value)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
 func (h *GetAllStatsHandler) DeleteResponse(w http.ResponseWriter, r *http.Request) {
 	resId := r.PathValue("id")
 	ID, err := strconv.Atoi(resId)
@@ -151,9 +261,9 @@ func (h *GetAllStatsHandler) DeleteResponse(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "Invalid id", http.StatusBadRequest)
 		return
 	}
-	err = h.storage.DeleteResponse(ID)
+	err = h.storage.DeleteResponse(r.Context(), ID)
 	if err != nil {
-		http.Error(w, "couldn't delete response", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "couldn't delete response")
 		return
 	}
 }
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"stats/internal/events"
+	"stats/internal/models"
+	"stats/internal/storage"
+)
+
+// EventsHandler records quiz sessions and answers published on the event bus
+type EventsHandler struct {
+	storage storage.Storage
+	logger  *zap.Logger
+}
+
+func NewEventsHandler(store storage.Storage, logger *zap.Logger) *EventsHandler {
+	return &EventsHandler{
+		storage: store,
+		logger:  logger,
+	}
+}
+
+// EventTypes are the types of the events consumed by the handler
+var EventTypes = []string{events.QuizStarted, events.AnswerSubmitted, events.QuizFinished}
+
+func (h *EventsHandler) Handle(ctx context.Context, event events.Event) error {
+	switch event.Type {
+	case events.QuizStarted:
+		var session models.QuizSession
+		if !h.decode(event, &session) {
+			return nil
+		}
+		return h.storage.SaveSession(ctx, &session)
+	case events.AnswerSubmitted:
+		var submitted events.AnswerSubmittedPayload
+		if !h.decode(event, &submitted) {
+			return nil
+		}
+		err := saveResponse(ctx, h.storage, submitted.SessionID, &submitted.Answer)
+		if errors.Is(err, errSessionFinished) {
+			// retrying would not help, the answer is dropped as it is by the REST API
+			h.logger.Error("answer to a finished session", zap.Int64("event_id", event.ID), zap.Int("session_id", submitted.SessionID))
+			return nil
+		}
+		return err
+	case events.QuizFinished:
+		var finished events.SessionPayload
+		if !h.decode(event, &finished) {
+			return nil
+		}
+		return h.storage.FinishQuizSession(ctx, finished.SessionID)
+	}
+	return nil
+}
+
+// decode reads the payload of the event, malformed events are logged and skipped
+// since they would block the consumer forever
+func (h *EventsHandler) decode(event events.Event, payload any) bool {
+	if err := json.Unmarshal(event.Payload, payload); err != nil {
+		h.logger.Error("invalid event payload", zap.Int64("event_id", event.ID), zap.String("type", event.Type), zap.Error(err))
+		return false
+	}
+	return true
+}
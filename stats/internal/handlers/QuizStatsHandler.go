@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"net/http"
 	"stats/internal/models"
 	"stats/internal/storage"
 	"strconv"
+	"strings"
 )
 
 type QuizStatsHandler struct {
@@ -32,7 +36,7 @@ func (h *QuizStatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid quiz id", http.StatusBadRequest)
 		return
 	}
-	session, err := h.storage.GetQuizSessionByID(quizSessionID)
+	session, err := h.storage.GetQuizSessionByID(r.Context(), quizSessionID)
 	if err != nil {
 		http.Error(w, "failed to get session", http.StatusNotFound)
 		return
@@ -41,10 +45,10 @@ func (h *QuizStatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to get session", http.StatusNotFound)
 		return
 	}
-	stats, err := h.storage.GetUserQuizStats(quizSessionID)
+	stats, err := h.storage.GetUserQuizStats(r.Context(), quizSessionID)
 	if err != nil {
 		h.logger.Error("failed to get stats", zap.Error(err))
-		http.Error(w, "failed to get stats", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "failed to get stats")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -58,9 +62,9 @@ func (h *QuizStatsHandler) SaveSession(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to decode session", http.StatusBadRequest)
 		return
 	}
-	err = h.storage.SaveSession(&sessionData)
+	err = h.storage.SaveSession(r.Context(), &sessionData)
 	if err != nil {
-		http.Error(w, "failed to save session", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "failed to save session")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -77,9 +81,9 @@ func (h *QuizStatsHandler) FinishSession(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "invalid quiz id", http.StatusBadRequest)
 		return
 	}
-	err = h.storage.FinishQuizSession(quizSessionID)
+	err = h.storage.FinishQuizSession(r.Context(), quizSessionID)
 	if err != nil {
-		http.Error(w, "failed to finish session", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "failed to finish session")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -101,27 +105,73 @@ func (h *QuizStatsHandler) SaveResponse(w http.ResponseWriter, r *http.Request)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	session, err := h.storage.GetQuizSessionByID(sessionID)
+	err = saveResponse(r.Context(), h.storage, sessionID, &response)
+	if errors.Is(err, errSessionFinished) {
+		h.logger.Error("session already finished")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to save response", zap.Error(err))
+		serverError(r.Context(), w, err, "")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+var errSessionFinished = fmt.Errorf("session already finished")
+
+// saveResponse saves the answer to the session, creating the session if it was not saved yet.
+// Retried submissions with the same idempotency key are saved once, see PostgresStorage.SaveResponse,
+// the last answer of a session may be retried after the session was finished
+func saveResponse(ctx context.Context, store storage.Storage, sessionID int, response *models.QuestionResponse) error {
+	session, err := store.GetQuizSessionByID(ctx, sessionID)
 	if err == storage.ErrSessionNotFound {
-		err = h.storage.SaveSession(&models.QuizSession{
-			SessionID: sessionID,
-		})
-		if err != nil {
-			h.logger.Error("failed to save session", zap.Error(err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+		session = &models.QuizSession{SessionID: sessionID}
+		if err = store.SaveSession(ctx, session); err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
 		}
 	} else if err != nil {
-		h.logger.Error("failed to get session", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return fmt.Errorf("failed to get session: %w", err)
 	}
 	if session.FinishTime != nil {
-		h.logger.Error("session already finished")
-		w.WriteHeader(http.StatusBadRequest)
+		if response.IdempotencyKey != nil {
+			saved, err := store.HasResponse(ctx, sessionID, *response.IdempotencyKey)
+			if err != nil {
+				return fmt.Errorf("failed to check saved response: %w", err)
+			}
+			if saved {
+				return nil
+			}
+		}
+		return errSessionFinished
+	}
+	return store.SaveResponse(ctx, sessionID, response)
+}
+
+// GetSessionsStats returns scores of the sessions listed in the ids query parameter as comma separated IDs,
+// sessions without answers are omitted
+func (h *QuizStatsHandler) GetSessionsStats(w http.ResponseWriter, r *http.Request) {
+	sessionsIDs := make([]int, 0)
+	for _, value := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "invalid session id", http.StatusBadRequest)
+			return
+		}
+		sessionsIDs = append(sessionsIDs, id)
+	}
+	stats, err := h.storage.GetSessionsStats(r.Context(), sessionsIDs)
+	if err != nil {
+		h.logger.Error("failed to get sessions stats", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
-	// todo: check if response already exists
-	err = h.storage.SaveResponse(sessionID, &response)
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
 }
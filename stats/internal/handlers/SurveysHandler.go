@@ -32,16 +32,16 @@ func (h *SurveysHandler) Save(w http.ResponseWriter, r *http.Request) {
 	}
 	fmt.Println(surveyResponse)
 	userID := r.Context().Value("user_id").(int)
-	_, err = h.storage.GetSurveyResponseForUser(userID)
+	_, err = h.storage.GetSurveyResponseForUser(r.Context(), userID)
 	if err == nil {
 		http.Error(w, "survey response already exists", http.StatusConflict)
 		return
 	}
 	surveyResponse.UserID = userID
-	err = h.storage.SaveSurveyResponse(&surveyResponse)
+	err = h.storage.SaveSurveyResponse(r.Context(), &surveyResponse)
 	if err != nil {
 		h.logger.Error("failed to save survey response", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -61,19 +61,19 @@ func (h *SurveysHandler) GetSurvey(w http.ResponseWriter, r *http.Request) {
 	var err error
 
 	if userId == "-" {
-		surveyResponses, err = h.storage.GetAllSurveyResponses()
+		surveyResponses, err = h.storage.GetAllSurveyResponses(r.Context())
 	} else {
 		userID, err := strconv.Atoi(userId)
 		if err != nil {
 			http.Error(w, "invalid user id", http.StatusBadRequest)
 			return
 		}
-		surveyResponses, err = h.storage.GetSurveyResponseForUser(userID)
+		surveyResponses, err = h.storage.GetSurveyResponseForUser(r.Context(), userID)
 	}
 
 	if err != nil {
 		h.logger.Error("failed to get survey response", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 
@@ -81,7 +81,7 @@ func (h *SurveysHandler) GetSurvey(w http.ResponseWriter, r *http.Request) {
 	res, err := json.Marshal(surveyResponses)
 	if err != nil {
 		h.logger.Error("failed to marshal response", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 
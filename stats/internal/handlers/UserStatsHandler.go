@@ -5,18 +5,21 @@ import (
 	"fmt"
 	"go.uber.org/zap"
 	"net/http"
+	"stats/internal/calibration"
+	"stats/internal/clients"
 	"stats/internal/models"
 	"stats/internal/storage"
 	"strconv"
 )
 
 type UserStatsHandler struct {
-	storage storage.Storage
-	logger  *zap.Logger
+	storage    storage.Storage
+	logger     *zap.Logger
+	authClient clients.AuthService
 }
 
-func NewUserStatsHandler(storage storage.Storage, logger *zap.Logger) *UserStatsHandler {
-	return &UserStatsHandler{storage: storage, logger: logger}
+func NewUserStatsHandler(storage storage.Storage, logger *zap.Logger, authClient clients.AuthService) *UserStatsHandler {
+	return &UserStatsHandler{storage: storage, logger: logger, authClient: authClient}
 }
 
 func (h *UserStatsHandler) Handle(rw http.ResponseWriter, r *http.Request) {
@@ -35,19 +38,21 @@ func (h *UserStatsHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	stats := models.UserStats{
 		TotalQuestions: make(map[models.QuizMode]int),
 		CorrectAnswers: make(map[models.QuizMode]int),
+		TimedOut:       make(map[models.QuizMode]int),
 		Accuracy:       make(map[models.QuizMode]float64),
 	}
-	for _, mode := range []string{models.QuizModeEducational, models.QuizModeClassic, models.QuizModeLimitedTime} {
-		correct, wrong, err := h.storage.GetUserStatsForMode(userID, mode)
+	for _, mode := range []string{models.QuizModeEducational, models.QuizModeClassic, models.QuizModeLimitedTime, models.QuizModeAdaptive, models.QuizModeReview} {
+		correct, wrong, timedOut, err := h.storage.GetUserStatsForMode(r.Context(), userID, mode)
 		if err == storage.ErrStatsNotFound {
 			continue
 		}
 		if err != nil {
-			h.logger.Error(fmt.Sprintf("failed to get stats for userID: %s, quizMode: %s", userID, mode))
-			http.Error(rw, "failed to get statistics", http.StatusInternalServerError)
+			h.logger.Error(fmt.Sprintf("failed to get stats for userID: %d, quizMode: %s", userID, mode))
+			serverError(r.Context(), rw, err, "failed to get statistics")
 		}
 		stats.TotalQuestions[mode] = correct + wrong
 		stats.CorrectAnswers[mode] = correct
+		stats.TimedOut[mode] = timedOut
 		if stats.TotalQuestions[mode] != 0 {
 			stats.Accuracy[mode] = float64(correct) / float64(correct+wrong)
 		} else {
@@ -59,17 +64,17 @@ func (h *UserStatsHandler) Handle(rw http.ResponseWriter, r *http.Request) {
 	err := stats.ToJSON(rw)
 	if err != nil {
 		h.logger.Error("failed to encode stats")
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 	}
 }
 
 func (h *UserStatsHandler) GetUserSessions(rw http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(int)
 
-	stats, err := h.storage.GetUserQuizSessionsStats(userID)
+	stats, err := h.storage.GetUserQuizSessionsStats(r.Context(), userID)
 	if err != nil {
 		h.logger.Error("failed to get user sessions", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 		return
 	}
 
@@ -77,7 +82,7 @@ func (h *UserStatsHandler) GetUserSessions(rw http.ResponseWriter, r *http.Reque
 	err = json.NewEncoder(rw).Encode(stats)
 	if err != nil {
 		h.logger.Error("failed to encode response", zap.Error(err))
-		http.Error(rw, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), rw, err, "internal server error")
 	}
 }
 
@@ -89,20 +94,70 @@ func (h *UserStatsHandler) DeleteUserResponses(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = h.storage.DeleteUserResponses(userID)
+	err = h.storage.DeleteUserResponses(r.Context(), userID)
 	if err != nil {
 		h.logger.Error("failed to delete user responses", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *UserStatsHandler) GetAllUsersStats(w http.ResponseWriter, _ *http.Request) {
-	stats, err := h.storage.GetAllUsersStats()
+func (h *UserStatsHandler) GetAllUsersStats(w http.ResponseWriter, r *http.Request) {
+	usersIDs, ok := getCohortUsersIDs(w, r, h.authClient, h.logger)
+	if !ok {
+		return
+	}
+	stats, err := h.storage.GetAllUsersStats(r.Context(), usersIDs)
 	if err != nil {
 		h.logger.Error("failed to get user stats", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		serverError(r.Context(), w, err, "internal server error")
 		return
 	}
 	json.NewEncoder(w).Encode(stats)
 }
+
+// GetIncorrectQuestions returns questions the user has answered incorrectly at least once with the time of the last
+// incorrect answer, they are the source of the review queue of the quiz service
+func (h *UserStatsHandler) GetIncorrectQuestions(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	questions, err := h.storage.GetUserIncorrectQuestions(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to get incorrect questions", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(questions); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// GetCalibration compares the confidence ratings of the user with the accuracy of their answers,
+// the user is taken from the path for internal requests and from the token otherwise
+func (h *UserStatsHandler) GetCalibration(w http.ResponseWriter, r *http.Request) {
+	var userID int
+	if id := r.PathValue("id"); id != "" {
+		var err error
+		userID, err = strconv.Atoi(id)
+		if err != nil {
+			http.Error(w, "invalid user id", http.StatusBadRequest)
+			return
+		}
+	} else {
+		userID = r.Context().Value("user_id").(int)
+	}
+	outcomes, err := h.storage.GetUserConfidenceOutcomes(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to get confidence outcomes", zap.Error(err))
+		serverError(r.Context(), w, err, "internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(calibration.Compute(outcomes)); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"stats/internal/clients"
 )
 
-func VerifyToken(next http.HandlerFunc, authClient *clients.AuthClient) http.HandlerFunc {
+func VerifyToken(next http.HandlerFunc, authClient clients.AuthService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		accessToken, err := ExtractAccessTokenFromRequest(r)
 		log.Println("Extracted token: ", accessToken)
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS users_surveys;
+DROP TABLE IF EXISTS answers;
+DROP TABLE IF EXISTS quiz_sessions;
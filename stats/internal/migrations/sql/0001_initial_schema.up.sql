@@ -0,0 +1,36 @@
+-- tables are created only when missing so databases created before migrations adopt this version
+CREATE TABLE IF NOT EXISTS quiz_sessions (
+    session_id  integer PRIMARY KEY,
+    user_id     integer NOT NULL,
+    quiz_mode   text NOT NULL,
+    finish_time timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS quiz_sessions_user_id_idx ON quiz_sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS answers (
+    id          serial PRIMARY KEY,
+    session_id  integer NOT NULL REFERENCES quiz_sessions (session_id) ON DELETE CASCADE,
+    question_id integer NOT NULL,
+    answer      text NOT NULL,
+    correct     boolean NOT NULL,
+    answer_time timestamptz NOT NULL DEFAULT NOW(),
+    screen_size text NOT NULL DEFAULT '',
+    time_spent  integer NOT NULL DEFAULT 0,
+    case_code   text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS answers_session_id_idx ON answers (session_id);
+CREATE INDEX IF NOT EXISTS answers_question_id_idx ON answers (question_id);
+
+CREATE TABLE IF NOT EXISTS users_surveys (
+    user_id       integer PRIMARY KEY,
+    gender        text NOT NULL,
+    age           text NOT NULL,
+    vision_defect text NOT NULL,
+    education     text NOT NULL,
+    experience    text NOT NULL,
+    country       text NOT NULL,
+    name          text NOT NULL,
+    surname       text NOT NULL
+);
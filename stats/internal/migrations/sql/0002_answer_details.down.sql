@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS answers_idempotency_key_idx;
+
+ALTER TABLE answers
+    DROP COLUMN IF EXISTS idempotency_key,
+    DROP COLUMN IF EXISTS confidence,
+    DROP COLUMN IF EXISTS mean_distance,
+    DROP COLUMN IF EXISTS points,
+    DROP COLUMN IF EXISTS score,
+    DROP COLUMN IF EXISTS prediction_error,
+    DROP COLUMN IF EXISTS question_version,
+    DROP COLUMN IF EXISTS position,
+    DROP COLUMN IF EXISTS timed_out;
+
+ALTER TABLE quiz_sessions
+    DROP COLUMN IF EXISTS seed;
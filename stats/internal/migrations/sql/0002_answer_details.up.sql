@@ -0,0 +1,16 @@
+ALTER TABLE quiz_sessions
+    ADD COLUMN IF NOT EXISTS seed bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE answers
+    ADD COLUMN IF NOT EXISTS timed_out        boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS position         integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS question_version integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS prediction_error double precision,
+    ADD COLUMN IF NOT EXISTS score            double precision,
+    ADD COLUMN IF NOT EXISTS points           jsonb,
+    ADD COLUMN IF NOT EXISTS mean_distance    double precision,
+    ADD COLUMN IF NOT EXISTS confidence       smallint CHECK (confidence BETWEEN 1 AND 5),
+    ADD COLUMN IF NOT EXISTS idempotency_key  text;
+
+-- answers without a key never conflict, as nulls are distinct
+CREATE UNIQUE INDEX IF NOT EXISTS answers_idempotency_key_idx ON answers (session_id, idempotency_key);
@@ -0,0 +1,35 @@
+package models
+
+// ConfidenceOutcome is an answer given with a confidence rating
+type ConfidenceOutcome struct {
+	Confidence int
+	Correct    bool
+}
+
+// Calibration compares the confidence users report with how often they are right
+type Calibration struct {
+	Answers int                 `json:"answers"`
+	Buckets []CalibrationBucket `json:"buckets"`
+	// BrierScore is the mean squared difference between the confidence as a probability and the outcome,
+	// 0 is perfect and 0.25 is what answering every question with medium confidence scores
+	BrierScore float64 `json:"brier_score"`
+	// Overconfidence is the mean confidence as a probability minus the accuracy, positive values mean overconfidence
+	Overconfidence float64 `json:"overconfidence"`
+}
+
+// CalibrationBucket are the answers given with one confidence level, well calibrated users have
+// Accuracy close to ExpectedAccuracy
+type CalibrationBucket struct {
+	Confidence       int     `json:"confidence"`
+	Total            int     `json:"total"`
+	Correct          int     `json:"correct"`
+	Accuracy         float64 `json:"accuracy"`
+	ExpectedAccuracy float64 `json:"expected_accuracy"`
+}
+
+// SurveyGroupedCalibration is the calibration of users with the same answer to a survey question
+type SurveyGroupedCalibration struct {
+	Group string `json:"group"`
+	Value string `json:"value"`
+	Calibration
+}
@@ -0,0 +1,8 @@
+package models
+
+// CohortMembers is the member list of a cohort as returned by the auth service
+type CohortMembers struct {
+	CohortID int   `json:"cohort_id"`
+	OwnerID  int   `json:"owner_id"`
+	UsersIDs []int `json:"users_ids"`
+}
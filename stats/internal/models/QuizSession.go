@@ -11,6 +11,8 @@ type QuizSession struct {
 	UserID     int        `json:"user_id"`
 	FinishTime *time.Time `json:"finish_time"`
 	QuizMode   string     `json:"quiz_mode"`
+	// Seed determines the order of questions within groups in the quiz service
+	Seed int64 `json:"seed"`
 }
 
 func (q *QuizSession) FromJSON(r io.Reader) error {
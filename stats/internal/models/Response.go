@@ -7,15 +7,51 @@ import (
 )
 
 type QuestionResponse struct {
-	ID         int        `json:"id"`
-	QuestionID int        `json:"question_id"`
-	CaseCode   string     `json:"case_code"`
-	Answer     string     `json:"answer"`
-	IsCorrect  bool       `json:"is_correct"`
-	Time       *time.Time `json:"time,omitempty"`
-	UserID     *int       `json:"user_id,omitempty"`
-	ScreenSize string     `json:"screen_size"`
-	TimeSpent  int        `json:"time_spent"`
+	ID         int `json:"id"`
+	QuestionID int `json:"question_id"`
+	// QuestionVersion is the version of the question in the quiz service the answer was given to
+	QuestionVersion int        `json:"question_version"`
+	CaseCode        string     `json:"case_code"`
+	Answer          string     `json:"answer"`
+	IsCorrect       bool       `json:"is_correct"`
+	Time            *time.Time `json:"time,omitempty"`
+	UserID          *int       `json:"user_id,omitempty"`
+	ScreenSize      string     `json:"screen_size"`
+	TimeSpent       int        `json:"time_spent"`
+	TimedOut        bool       `json:"timed_out"`
+	// Position is the index of the question in the group order of the session
+	Position int   `json:"position"`
+	Seed     int64 `json:"seed"`
+	// PredictionError is the signed distance of a numeric prediction from the target value,
+	// Score falls from 1 for an exact prediction to 0 at the tolerance of the question
+	PredictionError *float64 `json:"prediction_error,omitempty"`
+	Score           *float64 `json:"score,omitempty"`
+	// Points are the landmarks placed in answers to landmark questions, MeanDistance is their mean error
+	Points       []PlacedLandmark `json:"points,omitempty"`
+	MeanDistance *float64         `json:"mean_distance,omitempty"`
+	// Confidence is the rating from 1 to 5 the user gave with the answer, nil when not rated
+	Confidence *int `json:"confidence,omitempty"`
+	// IdempotencyKey identifies the submission in the quiz service, a response is saved once per key and session
+	IdempotencyKey *string `json:"idempotency_key,omitempty"`
+}
+
+// PlacedLandmark is a landmark placed by the user on the final image, Error is its distance from the reference landmark
+type PlacedLandmark struct {
+	Name  string  `json:"name"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Error float64 `json:"error"`
+}
+
+// LandmarkAnswer is an answer to a landmark question, used to visualize where users place the landmarks
+type LandmarkAnswer struct {
+	ResponseID      int              `json:"response_id"`
+	UserID          int              `json:"user_id"`
+	QuestionVersion int              `json:"question_version"`
+	Time            time.Time        `json:"time"`
+	Points          []PlacedLandmark `json:"points"`
+	MeanDistance    float64          `json:"mean_distance"`
+	Score           float64          `json:"score"`
 }
 
 func (q *QuestionResponse) FromJSON(r io.Reader) error {
@@ -11,18 +11,22 @@ type QuizMode = string
 const (
 	QuizModeEducational QuizMode = "educational"
 	QuizModeClassic     QuizMode = "classic"
-	QuizModeLimitedTime QuizMode = "time_limited"
+	QuizModeLimitedTime QuizMode = "limited_time"
+	QuizModeAdaptive    QuizMode = "adaptive"
+	QuizModeReview      QuizMode = "review"
 )
 
 type UserStats struct {
 	TotalQuestions map[QuizMode]int
 	CorrectAnswers map[QuizMode]int
+	TimedOut       map[QuizMode]int
 	Accuracy       map[QuizMode]float64
 }
 type QuestionStat struct {
 	QuestionID int
 	Answer     string
 	IsCorrect  bool
+	TimedOut   bool
 }
 
 type QuizStats struct {
@@ -30,6 +34,7 @@ type QuizStats struct {
 	Mode           QuizMode       `json:"mode"`
 	TotalQuestions int            `json:"total_questions"`
 	CorrectAnswers int            `json:"correct_answers"`
+	TimedOut       int            `json:"timed_out"`
 	Accuracy       float64        `json:"accuracy"`
 	Questions      []QuestionStat `json:"questions"`
 	StartTime      *time.Time     `json:"start_time"`
@@ -56,6 +61,9 @@ type QuestionAllStats struct {
 	CaseCode   string `json:"case_id"`
 	Total      int    `json:"total"`
 	Correct    int    `json:"correct"`
+	TimedOut   int    `json:"timed_out"`
+	// MeanAbsoluteError of predictions, set only for numeric questions
+	MeanAbsoluteError *float64 `json:"mean_absolute_error,omitempty"`
 }
 
 type ActivityStats struct {
@@ -70,3 +78,37 @@ type SurveyGroupedStats struct {
 	Correct  int     `json:"correct"`
 	Accuracy float64 `json:"accuracy"`
 }
+
+// AnswerOutcome is a single answer used to estimate abilities and difficulties
+type AnswerOutcome struct {
+	// ID is the ID of the answer, answers are replayed in the order of their IDs
+	ID         int
+	UserID     int
+	QuestionID int
+	Correct    bool
+}
+
+// IncorrectQuestion is a question the user has answered incorrectly with the time of the last incorrect answer
+type IncorrectQuestion struct {
+	QuestionID int       `json:"question_id"`
+	MissedAt   time.Time `json:"missed_at"`
+}
+
+// AdaptiveProfile is the ability of the user and difficulties of all answered questions, estimated from answers
+type AdaptiveProfile struct {
+	UserID      int                  `json:"user_id"`
+	Ability     float64              `json:"ability"`
+	UserAnswers int                  `json:"user_answers"`
+	Questions   []QuestionDifficulty `json:"questions"`
+}
+
+type QuestionDifficulty struct {
+	QuestionID  int     `json:"question_id"`
+	Difficulty  float64 `json:"difficulty"`
+	Answers     int     `json:"answers"`
+	UserAnswers int     `json:"user_answers"`
+}
+
+func (p *AdaptiveProfile) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,76 @@
+package rating
+
+import (
+	"math"
+	"stats/internal/models"
+)
+
+// Elo-style estimation of the one parameter IRT model. The probability that a user with ability theta answers
+// a question with difficulty b correctly is 1 / (1 + e^(b - theta)). Both parameters start at 0 and are updated
+// after every answer, the step decreases with the number of answers, so that early estimates move quickly
+// and settle down once enough answers are collected
+
+const (
+	// stepBase and stepDecay define the update step base / (1 + decay * answers)
+	stepBase  = 0.8
+	stepDecay = 0.05
+)
+
+type Estimate struct {
+	Value   float64
+	Answers int
+}
+
+type Ratings struct {
+	Users     map[int]*Estimate
+	Questions map[int]*Estimate
+}
+
+// Probability returns the expected probability of a correct answer
+func Probability(ability float64, difficulty float64) float64 {
+	return 1 / (1 + math.Exp(difficulty-ability))
+}
+
+func step(answers int) float64 {
+	return stepBase / (1 + stepDecay*float64(answers))
+}
+
+// NewRatings returns ratings without any answers
+func NewRatings() Ratings {
+	return Ratings{
+		Users:     make(map[int]*Estimate),
+		Questions: make(map[int]*Estimate),
+	}
+}
+
+// Add updates the ability of the user and the difficulty of the question with the answer
+func (r Ratings) Add(answer models.AnswerOutcome) {
+	user, ok := r.Users[answer.UserID]
+	if !ok {
+		user = &Estimate{}
+		r.Users[answer.UserID] = user
+	}
+	question, ok := r.Questions[answer.QuestionID]
+	if !ok {
+		question = &Estimate{}
+		r.Questions[answer.QuestionID] = question
+	}
+	result := 0.0
+	if answer.Correct {
+		result = 1
+	}
+	surprise := result - Probability(user.Value, question.Value)
+	user.Value += step(user.Answers) * surprise
+	question.Value -= step(question.Answers) * surprise
+	user.Answers++
+	question.Answers++
+}
+
+// Compute replays answers in chronological order and returns abilities of users and difficulties of questions
+func Compute(answers []models.AnswerOutcome) Ratings {
+	ratings := NewRatings()
+	for _, answer := range answers {
+		ratings.Add(answer)
+	}
+	return ratings
+}
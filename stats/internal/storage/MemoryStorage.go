@@ -0,0 +1,607 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"stats/internal/models"
+	"sync"
+	"time"
+)
+
+// MemoryStorage keeps sessions, answers and surveys in memory with the semantics of PostgresStorage,
+// answers belong to saved sessions and a user fills in the survey once. It is used by tests
+type MemoryStorage struct {
+	mu       sync.Mutex
+	sessions map[int]models.QuizSession
+	// answers are kept in the order they were saved
+	answers      []memoryAnswer
+	surveys      map[int]models.SurveyResponse
+	nextAnswerID int
+}
+
+var _ Storage = (*MemoryStorage)(nil)
+
+type memoryAnswer struct {
+	models.QuestionResponse
+	sessionID int
+	time      time.Time
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		sessions: make(map[int]models.QuizSession),
+		surveys:  make(map[int]models.SurveyResponse),
+	}
+}
+
+func (m *MemoryStorage) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (m *MemoryStorage) Close() error {
+	return nil
+}
+
+// userAnswers returns the answers of the users with the id of their user, nil usersIDs means all users
+func (m *MemoryStorage) userAnswers(usersIDs []int) []memoryAnswer {
+	answers := make([]memoryAnswer, 0, len(m.answers))
+	for _, answer := range m.answers {
+		if usersIDs == nil || slices.Contains(usersIDs, m.sessions[answer.sessionID].UserID) {
+			answers = append(answers, answer)
+		}
+	}
+	return answers
+}
+
+func (m *MemoryStorage) userID(answer memoryAnswer) int {
+	return m.sessions[answer.sessionID].UserID
+}
+
+// SaveResponse records the answer, a retried submission with an idempotency key already saved in the session is ignored
+func (m *MemoryStorage) SaveResponse(ctx context.Context, sessionID int, response *models.QuestionResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sessions[sessionID]; !ok {
+		return fmt.Errorf("session %d does not exist", sessionID)
+	}
+	if response.IdempotencyKey != nil && m.hasResponse(sessionID, *response.IdempotencyKey) {
+		return nil
+	}
+	m.nextAnswerID++
+	answer := memoryAnswer{QuestionResponse: *response, sessionID: sessionID, time: time.Now()}
+	answer.ID = m.nextAnswerID
+	answer.Points = slices.Clone(response.Points)
+	m.answers = append(m.answers, answer)
+	return nil
+}
+
+func (m *MemoryStorage) hasResponse(sessionID int, idempotencyKey string) bool {
+	for _, answer := range m.answers {
+		if answer.sessionID == sessionID && answer.IdempotencyKey != nil && *answer.IdempotencyKey == idempotencyKey {
+			return true
+		}
+	}
+	return false
+}
+
+// HasResponse reports whether an answer with the idempotency key was saved in the session
+func (m *MemoryStorage) HasResponse(ctx context.Context, sessionID int, idempotencyKey string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.hasResponse(sessionID, idempotencyKey), nil
+}
+
+// SaveSession records the session, a session saved again replaces the earlier record but keeps its finish time
+func (m *MemoryStorage) SaveSession(ctx context.Context, session *models.QuizSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := models.QuizSession{SessionID: session.SessionID, UserID: session.UserID, QuizMode: session.QuizMode, Seed: session.Seed}
+	if existing, ok := m.sessions[session.SessionID]; ok {
+		saved.FinishTime = existing.FinishTime
+	}
+	m.sessions[session.SessionID] = saved
+	return nil
+}
+
+// GetUserStatsForMode counts answers of the user, timed out answers are counted also as wrong ones
+func (m *MemoryStorage) GetUserStatsForMode(ctx context.Context, userID int, mode models.QuizMode) (correctCount int, wrongCount int, timedOutCount int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, answer := range m.userAnswers([]int{userID}) {
+		if m.sessions[answer.sessionID].QuizMode != mode {
+			continue
+		}
+		if answer.IsCorrect {
+			correctCount++
+		} else {
+			wrongCount++
+		}
+		if answer.TimedOut {
+			timedOutCount++
+		}
+	}
+	return
+}
+
+func (m *MemoryStorage) GetQuizSessionByID(ctx context.Context, quizSessionID int) (*models.QuizSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[quizSessionID]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+func (m *MemoryStorage) GetQuizQuestionsStats(ctx context.Context, quizSessionID int) ([]models.QuestionStat, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.questionsStats(quizSessionID), nil
+}
+
+func (m *MemoryStorage) questionsStats(sessionID int) []models.QuestionStat {
+	var questionsStats []models.QuestionStat
+	for _, answer := range m.answers {
+		if answer.sessionID == sessionID {
+			questionsStats = append(questionsStats, models.QuestionStat{QuestionID: answer.QuestionID, Answer: answer.Answer, IsCorrect: answer.IsCorrect, TimedOut: answer.TimedOut})
+		}
+	}
+	return questionsStats
+}
+
+// sessionStats counts the answers of the session, it returns nil for sessions without answers
+func (m *MemoryStorage) sessionStats(sessionID int) *models.QuizStats {
+	var stats *models.QuizStats
+	for _, answer := range m.answers {
+		if answer.sessionID != sessionID {
+			continue
+		}
+		if stats == nil {
+			startTime := answer.time
+			stats = &models.QuizStats{SessionID: sessionID, Mode: m.sessions[sessionID].QuizMode, StartTime: &startTime}
+		}
+		stats.TotalQuestions++
+		if answer.IsCorrect {
+			stats.CorrectAnswers++
+		}
+		if answer.TimedOut {
+			stats.TimedOut++
+		}
+		if answer.time.Before(*stats.StartTime) {
+			*stats.StartTime = answer.time
+		}
+	}
+	if stats != nil {
+		stats.Accuracy = float64(stats.CorrectAnswers) / float64(stats.TotalQuestions)
+	}
+	return stats
+}
+
+func (m *MemoryStorage) GetUserQuizStats(ctx context.Context, quizSessionID int) (*models.QuizStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.sessionStats(quizSessionID)
+	if stats == nil {
+		return nil, sql.ErrNoRows
+	}
+	stats.SessionID, stats.StartTime = 0, nil
+	stats.Questions = m.questionsStats(quizSessionID)
+	return stats, nil
+}
+
+func (m *MemoryStorage) FinishQuizSession(ctx context.Context, quizSessionID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// a repeated finish keeps the time of the first one
+	if session, ok := m.sessions[quizSessionID]; ok && session.FinishTime == nil {
+		now := time.Now()
+		session.FinishTime = &now
+		m.sessions[quizSessionID] = session
+	}
+	return nil
+}
+
+func (m *MemoryStorage) SaveSurveyResponse(ctx context.Context, response *models.SurveyResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.surveys[response.UserID]; ok {
+		return fmt.Errorf("survey response of user %d already exists", response.UserID)
+	}
+	saved := *response
+	saved.Acknowledgments = ""
+	m.surveys[response.UserID] = saved
+	return nil
+}
+
+func (m *MemoryStorage) GetSurveyResponseForUser(ctx context.Context, userID int) (*models.SurveyResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	survey, ok := m.surveys[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &survey, nil
+}
+
+func (m *MemoryStorage) GetAllSurveyResponses(ctx context.Context) ([]models.SurveyResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var surveys []models.SurveyResponse
+	for _, survey := range m.surveys {
+		surveys = append(surveys, survey)
+	}
+	slices.SortFunc(surveys, func(a, b models.SurveyResponse) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	return surveys, nil
+}
+
+func (m *MemoryStorage) GetAllResponses(ctx context.Context, usersIDs []int) ([]models.QuestionResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var responses []models.QuestionResponse
+	answers := m.userAnswers(usersIDs)
+	for i := len(answers) - 1; i >= 0; i-- {
+		response := answers[i].QuestionResponse
+		userID, answerTime := m.userID(answers[i]), answers[i].time
+		response.UserID, response.Time = &userID, &answerTime
+		response.Seed = m.sessions[answers[i].sessionID].Seed
+		response.IdempotencyKey = nil
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// questionStats aggregates the answers by question, in the order of question ids
+func questionStats(answers []memoryAnswer) []models.QuestionAllStats {
+	byQuestion := make(map[int]*models.QuestionAllStats)
+	errorsSum := make(map[int]float64)
+	errorsCount := make(map[int]int)
+	for _, answer := range answers {
+		stats, ok := byQuestion[answer.QuestionID]
+		if !ok {
+			stats = &models.QuestionAllStats{QuestionID: answer.QuestionID}
+			byQuestion[answer.QuestionID] = stats
+		}
+		stats.Total++
+		if answer.IsCorrect {
+			stats.Correct++
+		}
+		if answer.TimedOut {
+			stats.TimedOut++
+		}
+		if answer.PredictionError != nil {
+			errorsSum[answer.QuestionID] += math.Abs(*answer.PredictionError)
+			errorsCount[answer.QuestionID]++
+		}
+	}
+	stats := make([]models.QuestionAllStats, 0, len(byQuestion))
+	for questionID, s := range byQuestion {
+		if errorsCount[questionID] > 0 {
+			meanAbsoluteError := errorsSum[questionID] / float64(errorsCount[questionID])
+			s.MeanAbsoluteError = &meanAbsoluteError
+		}
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b models.QuestionAllStats) int {
+		return cmp.Compare(a.QuestionID, b.QuestionID)
+	})
+	return stats
+}
+
+func (m *MemoryStorage) GetStatsForQuestion(ctx context.Context, id int, usersIDs []int) (models.QuestionAllStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var answers []memoryAnswer
+	for _, answer := range m.userAnswers(usersIDs) {
+		if answer.QuestionID == id {
+			answers = append(answers, answer)
+		}
+	}
+	if stats := questionStats(answers); len(stats) > 0 {
+		return stats[0], nil
+	}
+	return models.QuestionAllStats{}, nil
+}
+
+func (m *MemoryStorage) GetStatsForAllQuestions(ctx context.Context, usersIDs []int) ([]models.QuestionAllStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := questionStats(m.userAnswers(usersIDs))
+	if len(stats) == 0 {
+		return nil, nil
+	}
+	return stats, nil
+}
+
+// GetLandmarkAnswers returns answers with placed landmarks to the question, newest first
+func (m *MemoryStorage) GetLandmarkAnswers(ctx context.Context, questionID int, usersIDs []int) ([]models.LandmarkAnswer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	answers := make([]models.LandmarkAnswer, 0)
+	userAnswers := m.userAnswers(usersIDs)
+	for i := len(userAnswers) - 1; i >= 0; i-- {
+		answer := userAnswers[i]
+		if answer.QuestionID != questionID || len(answer.Points) == 0 {
+			continue
+		}
+		landmarkAnswer := models.LandmarkAnswer{
+			ResponseID:      answer.ID,
+			UserID:          m.userID(answer),
+			QuestionVersion: answer.QuestionVersion,
+			Time:            answer.time,
+			Points:          slices.Clone(answer.Points),
+		}
+		if answer.MeanDistance != nil {
+			landmarkAnswer.MeanDistance = *answer.MeanDistance
+		}
+		if answer.Score != nil {
+			landmarkAnswer.Score = *answer.Score
+		}
+		answers = append(answers, landmarkAnswer)
+	}
+	return answers, nil
+}
+
+// GetActivityStats counts answers per day for the last 10 days with answers, oldest first
+func (m *MemoryStorage) GetActivityStats(ctx context.Context, usersIDs []int) ([]models.ActivityStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	byDay := make(map[time.Time]*models.ActivityStats)
+	for _, answer := range m.userAnswers(usersIDs) {
+		year, month, day := answer.time.Date()
+		date := time.Date(year, month, day, 0, 0, 0, 0, answer.time.Location())
+		stats, ok := byDay[date]
+		if !ok {
+			stats = &models.ActivityStats{Date: date}
+			byDay[date] = stats
+		}
+		stats.Total++
+		if answer.IsCorrect {
+			stats.Correct++
+		}
+	}
+	var stats []models.ActivityStats
+	for _, s := range byDay {
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b models.ActivityStats) int {
+		return a.Date.Compare(b.Date)
+	})
+	if len(stats) > 10 {
+		stats = stats[len(stats)-10:]
+	}
+	return stats, nil
+}
+
+func (m *MemoryStorage) CountQuizSessions(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions), nil
+}
+
+func (m *MemoryStorage) CountAnswers(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.answers), nil
+}
+
+func (m *MemoryStorage) CountCorrectAnswers(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, answer := range m.answers {
+		if answer.IsCorrect {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemoryStorage) GetUserQuizSessionsStats(ctx context.Context, userID int) ([]*models.QuizStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessionsIDs []int
+	for id, session := range m.sessions {
+		if session.UserID == userID {
+			sessionsIDs = append(sessionsIDs, id)
+		}
+	}
+	slices.Sort(sessionsIDs)
+	slices.Reverse(sessionsIDs)
+	var stats []*models.QuizStats
+	for _, id := range sessionsIDs {
+		if s := m.sessionStats(id); s != nil {
+			s.Questions = m.questionsStats(id)
+			stats = append(stats, s)
+		}
+	}
+	return stats, nil
+}
+
+// surveyField returns the answer to the survey question field, one of surveyFields
+func surveyField(survey models.SurveyResponse, field string) string {
+	switch field {
+	case "gender":
+		return survey.Gender
+	case "age":
+		return survey.Age
+	case "vision_defect":
+		return survey.VisionDefect
+	case "education":
+		return survey.Education
+	case "experience":
+		return survey.Experience
+	}
+	return survey.Country
+}
+
+func (m *MemoryStorage) GetStatsGroupedBySurveyField(ctx context.Context, field string, usersIDs []int) ([]models.SurveyGroupedStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !slices.Contains(surveyFields, field) {
+		return nil, fmt.Errorf("unsupported field: %s", field)
+	}
+	byValue := make(map[string]*models.SurveyGroupedStats)
+	for userID, survey := range m.surveys {
+		if usersIDs != nil && !slices.Contains(usersIDs, userID) {
+			continue
+		}
+		value := surveyField(survey, field)
+		stats, ok := byValue[value]
+		if !ok {
+			stats = &models.SurveyGroupedStats{Group: field, Value: value}
+			byValue[value] = stats
+		}
+		for _, answer := range m.userAnswers([]int{userID}) {
+			stats.Total++
+			if answer.IsCorrect {
+				stats.Correct++
+			}
+		}
+	}
+	var stats []models.SurveyGroupedStats
+	for _, s := range byValue {
+		if s.Total > 0 {
+			s.Accuracy = float64(s.Correct) / float64(s.Total)
+		}
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b models.SurveyGroupedStats) int {
+		return cmp.Compare(a.Value, b.Value)
+	})
+	return stats, nil
+}
+
+func (m *MemoryStorage) DeleteUserResponses(ctx context.Context, userId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.answers = slices.DeleteFunc(m.answers, func(answer memoryAnswer) bool {
+		return m.userID(answer) == userId
+	})
+	return nil
+}
+
+func (m *MemoryStorage) DeleteResponse(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.answers = slices.DeleteFunc(m.answers, func(answer memoryAnswer) bool {
+		return answer.ID == id
+	})
+	return nil
+}
+
+func (m *MemoryStorage) GetAllUsersStats(ctx context.Context, usersIDs []int) ([]models.UserQuizStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	byUser := make(map[int]*models.UserQuizStats)
+	for _, answer := range m.userAnswers(usersIDs) {
+		userID := m.userID(answer)
+		stats, ok := byUser[userID]
+		if !ok {
+			survey := m.surveys[userID]
+			stats = &models.UserQuizStats{UserID: userID, Experience: survey.Experience, Education: survey.Education}
+			byUser[userID] = stats
+		}
+		stats.TotalAnswers++
+		if answer.IsCorrect {
+			stats.CorrectAnswers++
+		}
+	}
+	var stats []models.UserQuizStats
+	for _, s := range byUser {
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b models.UserQuizStats) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	return stats, nil
+}
+
+// GetAnswerOutcomes returns answers saved after the answer afterID in the order they were saved
+func (m *MemoryStorage) GetAnswerOutcomes(ctx context.Context, afterID int) ([]models.AnswerOutcome, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var outcomes []models.AnswerOutcome
+	for _, answer := range m.answers {
+		if answer.ID > afterID {
+			outcomes = append(outcomes, models.AnswerOutcome{ID: answer.ID, UserID: m.userID(answer), QuestionID: answer.QuestionID, Correct: answer.IsCorrect})
+		}
+	}
+	return outcomes, nil
+}
+
+// GetUserIncorrectQuestions returns questions with at least one incorrect answer of the user outside review sessions,
+// the most recently missed first
+func (m *MemoryStorage) GetUserIncorrectQuestions(ctx context.Context, userID int) ([]models.IncorrectQuestion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// answers are in chronological order, so the last incorrect answer to a question is the most recent one
+	lastMissed := make(map[int]int)
+	missedAt := make(map[int]time.Time)
+	for i, answer := range m.userAnswers([]int{userID}) {
+		if !answer.IsCorrect && m.sessions[answer.sessionID].QuizMode != models.QuizModeReview {
+			lastMissed[answer.QuestionID] = i
+			missedAt[answer.QuestionID] = answer.time
+		}
+	}
+	questions := make([]models.IncorrectQuestion, 0, len(lastMissed))
+	for questionID := range lastMissed {
+		questions = append(questions, models.IncorrectQuestion{QuestionID: questionID, MissedAt: missedAt[questionID]})
+	}
+	slices.SortFunc(questions, func(a, b models.IncorrectQuestion) int {
+		return cmp.Or(cmp.Compare(lastMissed[b.QuestionID], lastMissed[a.QuestionID]), cmp.Compare(a.QuestionID, b.QuestionID))
+	})
+	return questions, nil
+}
+
+// GetSessionsStats returns the number of answers and correct answers of the sessions, without per question stats
+func (m *MemoryStorage) GetSessionsStats(ctx context.Context, sessionsIDs []int) ([]*models.QuizStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := slices.Clone(sessionsIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	stats := make([]*models.QuizStats, 0, len(ids))
+	for _, id := range ids {
+		if s := m.sessionStats(id); s != nil {
+			stats = append(stats, s)
+		}
+	}
+	return stats, nil
+}
+
+func (m *MemoryStorage) GetUserConfidenceOutcomes(ctx context.Context, userID int) ([]models.ConfidenceOutcome, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	outcomes := make([]models.ConfidenceOutcome, 0)
+	for _, answer := range m.userAnswers([]int{userID}) {
+		if answer.Confidence != nil {
+			outcomes = append(outcomes, models.ConfidenceOutcome{Confidence: *answer.Confidence, Correct: answer.IsCorrect})
+		}
+	}
+	return outcomes, nil
+}
+
+// GetConfidenceOutcomesBySurveyField returns rated answers keyed by the survey answer of their users
+func (m *MemoryStorage) GetConfidenceOutcomesBySurveyField(ctx context.Context, field string, usersIDs []int) (map[string][]models.ConfidenceOutcome, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !slices.Contains(surveyFields, field) {
+		return nil, fmt.Errorf("unsupported field: %s", field)
+	}
+	outcomes := make(map[string][]models.ConfidenceOutcome)
+	for _, answer := range m.userAnswers(usersIDs) {
+		survey, ok := m.surveys[m.userID(answer)]
+		if !ok || answer.Confidence == nil {
+			continue
+		}
+		value := surveyField(survey, field)
+		outcomes[value] = append(outcomes[value], models.ConfidenceOutcome{Confidence: *answer.Confidence, Correct: answer.IsCorrect})
+	}
+	return outcomes, nil
+}
@@ -1,41 +1,57 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
+	"slices"
 	"stats/internal/models"
+	"time"
 )
 
 type Storage interface {
-	Ping() error
+	Ping(ctx context.Context) error
 	Close() error
-	SaveResponse(sessionID int, response *models.QuestionResponse) error
-	SaveSession(session *models.QuizSession) error
-	GetUserStatsForMode(userID int, mode models.QuizMode) (correctCount int, wrongCount int, err error)
-	GetQuizSessionByID(quizSessionID int) (*models.QuizSession, error)
-	GetQuizQuestionsStats(quizSessionID int) ([]models.QuestionStat, error)
-	GetUserQuizStats(quizSessionID int) (*models.QuizStats, error)
-	FinishQuizSession(quizSessionID int) error
+	SaveResponse(ctx context.Context, sessionID int, response *models.QuestionResponse) error
+	HasResponse(ctx context.Context, sessionID int, idempotencyKey string) (bool, error)
+	SaveSession(ctx context.Context, session *models.QuizSession) error
+	GetUserStatsForMode(ctx context.Context, userID int, mode models.QuizMode) (correctCount int, wrongCount int, timedOutCount int, err error)
+	GetQuizSessionByID(ctx context.Context, quizSessionID int) (*models.QuizSession, error)
+	GetQuizQuestionsStats(ctx context.Context, quizSessionID int) ([]models.QuestionStat, error)
+	GetUserQuizStats(ctx context.Context, quizSessionID int) (*models.QuizStats, error)
+	FinishQuizSession(ctx context.Context, quizSessionID int) error
 
 	// survey
-	SaveSurveyResponse(response *models.SurveyResponse) error
-	GetSurveyResponseForUser(userID int) (*models.SurveyResponse, error)
-	GetAllSurveyResponses() ([]models.SurveyResponse, error)
+	SaveSurveyResponse(ctx context.Context, response *models.SurveyResponse) error
+	GetSurveyResponseForUser(ctx context.Context, userID int) (*models.SurveyResponse, error)
+	GetAllSurveyResponses(ctx context.Context) ([]models.SurveyResponse, error)
 
 	// stats
-	GetAllResponses() ([]models.QuestionResponse, error)
-	GetStatsForQuestion(id int) (models.QuestionAllStats, error)
-	GetStatsForAllQuestions() ([]models.QuestionAllStats, error)
-	GetActivityStats() ([]models.ActivityStats, error)
-	CountQuizSessions() (int, error)
-	CountAnswers() (int, error)
-	CountCorrectAnswers() (int, error)
-	GetUserQuizSessionsStats(userID int) ([]*models.QuizStats, error)
-	GetStatsGroupedBySurveyField(field string) ([]models.SurveyGroupedStats, error)
-	DeleteUserResponses(userId int) error
-	DeleteResponse(id int) error
-	GetAllUsersStats() ([]models.UserQuizStats, error)
+	// usersIDs restricts the stats to the given users (e.g. members of a cohort), nil means all users
+	GetAllResponses(ctx context.Context, usersIDs []int) ([]models.QuestionResponse, error)
+	GetStatsForQuestion(ctx context.Context, id int, usersIDs []int) (models.QuestionAllStats, error)
+	GetStatsForAllQuestions(ctx context.Context, usersIDs []int) ([]models.QuestionAllStats, error)
+	GetLandmarkAnswers(ctx context.Context, questionID int, usersIDs []int) ([]models.LandmarkAnswer, error)
+	GetActivityStats(ctx context.Context, usersIDs []int) ([]models.ActivityStats, error)
+	CountQuizSessions(ctx context.Context) (int, error)
+	CountAnswers(ctx context.Context) (int, error)
+	CountCorrectAnswers(ctx context.Context) (int, error)
+	GetUserQuizSessionsStats(ctx context.Context, userID int) ([]*models.QuizStats, error)
+	GetStatsGroupedBySurveyField(ctx context.Context, field string, usersIDs []int) ([]models.SurveyGroupedStats, error)
+	DeleteUserResponses(ctx context.Context, userId int) error
+	DeleteResponse(ctx context.Context, id int) error
+	GetAllUsersStats(ctx context.Context, usersIDs []int) ([]models.UserQuizStats, error)
+	GetAnswerOutcomes(ctx context.Context, afterID int) ([]models.AnswerOutcome, error)
+	GetUserIncorrectQuestions(ctx context.Context, userID int) ([]models.IncorrectQuestion, error)
+	GetSessionsStats(ctx context.Context, sessionsIDs []int) ([]*models.QuizStats, error)
+
+	// calibration, answers without a confidence rating are left out
+	GetUserConfidenceOutcomes(ctx context.Context, userID int) ([]models.ConfidenceOutcome, error)
+	GetConfidenceOutcomesBySurveyField(ctx context.Context, field string, usersIDs []int) (map[string][]models.ConfidenceOutcome, error)
 }
 
 var ErrSessionNotFound = fmt.Errorf("session not found")
@@ -44,67 +60,127 @@ var ErrStatsNotFound = fmt.Errorf("stats not found")
 type PostgresStorage struct {
 	db     *sql.DB
 	logger *zap.Logger
+	// queryTimeout bounds each storage call, the deadline of the caller's context applies when it is earlier
+	queryTimeout time.Duration
 }
 
-func NewPostgresStorage(db *sql.DB, logger *zap.Logger) *PostgresStorage {
+func NewPostgresStorage(db *sql.DB, logger *zap.Logger, queryTimeout time.Duration) *PostgresStorage {
 	return &PostgresStorage{
-		db:     db,
-		logger: logger,
+		db:           db,
+		logger:       logger,
+		queryTimeout: queryTimeout,
 	}
 }
 
-func (p *PostgresStorage) Ping() error {
-	return p.db.Ping()
+func (p *PostgresStorage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, p.queryTimeout)
+}
+
+// IsTimeout reports whether a storage call failed because it ran out of time,
+// either on the query timeout or on the deadline of the context
+func IsTimeout(err error) bool {
+	var pqErr *pq.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &pqErr) && pqErr.Code == queryCanceled
+}
+
+// queryCanceled is the code of the error returned by Postgres for statements cancelled on a deadline
+const queryCanceled = "57014"
+
+func (p *PostgresStorage) Ping(ctx context.Context) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	return p.db.PingContext(ctx)
 }
 
 func (p *PostgresStorage) Close() error {
 	return p.db.Close()
 }
-func (p *PostgresStorage) SaveResponse(sessionID int, response *models.QuestionResponse) error {
-	_, err := p.db.Exec(`INSERT INTO answers (session_id, question_id, answer, correct, screen_size, time_spent, case_code) values ($1, $2, $3, $4, $5, $6, $7)`, sessionID, response.QuestionID, response.Answer, response.IsCorrect, response.ScreenSize, response.TimeSpent, response.CaseCode)
+
+// SaveResponse records the answer, a retried submission with an idempotency key already saved in the session is ignored
+func (p *PostgresStorage) SaveResponse(ctx context.Context, sessionID int, response *models.QuestionResponse) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	// points are stored as jsonb, answers to other question types leave them NULL
+	var points []byte
+	if len(response.Points) > 0 {
+		var err error
+		if points, err = json.Marshal(response.Points); err != nil {
+			return err
+		}
+	}
+	_, err := p.db.ExecContext(ctx, `INSERT INTO answers (session_id, question_id, answer, correct, screen_size, time_spent, case_code, timed_out, position, question_version, prediction_error, score, points, mean_distance, confidence, idempotency_key) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (session_id, idempotency_key) DO NOTHING`, sessionID, response.QuestionID, response.Answer, response.IsCorrect, response.ScreenSize, response.TimeSpent, response.CaseCode, response.TimedOut, response.Position, response.QuestionVersion, response.PredictionError, response.Score, points, response.MeanDistance, response.Confidence, response.IdempotencyKey)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (p *PostgresStorage) SaveSession(session *models.QuizSession) error {
-	_, err := p.db.Exec(`INSERT INTO quiz_sessions (user_id, quiz_mode, session_id) values ($1, $2, $3)`, session.UserID, session.QuizMode, session.SessionID)
+// HasResponse reports whether an answer with the idempotency key was saved in the session
+func (p *PostgresStorage) HasResponse(ctx context.Context, sessionID int, idempotencyKey string) (bool, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	var exists bool
+	err := p.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM answers WHERE session_id = $1 AND idempotency_key = $2)`, sessionID, idempotencyKey).Scan(&exists)
+	return exists, err
+}
+
+// SaveSession records the session, events of the quiz service may be delivered more than once,
+// so a session saved again replaces the earlier record
+func (p *PostgresStorage) SaveSession(ctx context.Context, session *models.QuizSession) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, `INSERT INTO quiz_sessions (user_id, quiz_mode, session_id, seed) values ($1, $2, $3, $4)
+		ON CONFLICT (session_id) DO UPDATE SET user_id = EXCLUDED.user_id, quiz_mode = EXCLUDED.quiz_mode, seed = EXCLUDED.seed`, session.UserID, session.QuizMode, session.SessionID, session.Seed)
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func (p *PostgresStorage) GetUserStatsForMode(userID int, mode models.QuizMode) (correctCount int, wrongCount int, err error) {
-	rows, err := p.db.Query(`select correct, count(*) from answers a
+
+// GetUserStatsForMode counts answers of the user, timed out answers are counted also as wrong ones
+func (p *PostgresStorage) GetUserStatsForMode(ctx context.Context, userID int, mode models.QuizMode) (correctCount int, wrongCount int, timedOutCount int, err error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	rows, err := p.db.QueryContext(ctx, `select correct, timed_out, count(*) from answers a
     join quiz_sessions s on a.session_id = s.session_id
     where user_id=$1 and quiz_mode=$2
-    group by quiz_mode, correct`, userID, mode)
+    group by quiz_mode, correct, timed_out`, userID, mode)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, 0, ErrStatsNotFound
+			return 0, 0, 0, ErrStatsNotFound
 		}
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
+	defer rows.Close()
 	for rows.Next() {
-		var isCorrect bool
+		var isCorrect, timedOut bool
 		var count int
-		err = rows.Scan(&isCorrect, &count)
+		err = rows.Scan(&isCorrect, &timedOut, &count)
+		if err != nil {
+			return 0, 0, 0, err
+		}
 		if isCorrect {
-			correctCount = count
+			correctCount += count
 		} else {
-			wrongCount = count
+			wrongCount += count
+		}
+		if timedOut {
+			timedOutCount += count
 		}
 	}
 	return
 }
 
-func (p *PostgresStorage) GetUserQuizSessionsStats(userID int) ([]*models.QuizStats, error) {
+func (p *PostgresStorage) GetUserQuizSessionsStats(ctx context.Context, userID int) ([]*models.QuizStats, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	query := `
         SELECT s.session_id, s.quiz_mode, 
                COUNT(*) as total_questions,
                SUM(CASE WHEN correct THEN 1 ELSE 0 END) as correct_answers,
+               SUM(CASE WHEN timed_out THEN 1 ELSE 0 END) as timed_out,
 			   MIN(a.answer_time) as start_time
         FROM quiz_sessions s
         JOIN answers a ON s.session_id = a.session_id
@@ -112,7 +188,7 @@ func (p *PostgresStorage) GetUserQuizSessionsStats(userID int) ([]*models.QuizSt
         GROUP BY s.session_id, s.quiz_mode
         ORDER BY s.session_id DESC`
 
-	rows, err := p.db.Query(query, userID)
+	rows, err := p.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +197,7 @@ func (p *PostgresStorage) GetUserQuizSessionsStats(userID int) ([]*models.QuizSt
 	var stats []*models.QuizStats
 	for rows.Next() {
 		stat := &models.QuizStats{}
-		err = rows.Scan(&stat.SessionID, &stat.Mode, &stat.TotalQuestions, &stat.CorrectAnswers, &stat.StartTime)
+		err = rows.Scan(&stat.SessionID, &stat.Mode, &stat.TotalQuestions, &stat.CorrectAnswers, &stat.TimedOut, &stat.StartTime)
 		if err != nil {
 			return nil, err
 		}
@@ -129,7 +205,7 @@ func (p *PostgresStorage) GetUserQuizSessionsStats(userID int) ([]*models.QuizSt
 			stat.Accuracy = float64(stat.CorrectAnswers) / float64(stat.TotalQuestions)
 		}
 
-		stat.Questions, err = p.GetQuizQuestionsStats(stat.SessionID)
+		stat.Questions, err = p.GetQuizQuestionsStats(ctx, stat.SessionID)
 		if err != nil {
 			return nil, err
 		}
@@ -139,25 +215,32 @@ func (p *PostgresStorage) GetUserQuizSessionsStats(userID int) ([]*models.QuizSt
 	return stats, nil
 }
 
-func (p *PostgresStorage) GetQuizSessionByID(quizSessionID int) (*models.QuizSession, error) {
+func (p *PostgresStorage) GetQuizSessionByID(ctx context.Context, quizSessionID int) (*models.QuizSession, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var session models.QuizSession
-	err := p.db.QueryRow(`SELECT user_id, session_id, finish_time, quiz_mode FROM quiz_sessions WHERE session_id = $1`, quizSessionID).Scan(&session.UserID, &session.SessionID, &session.FinishTime, &session.QuizMode)
+	err := p.db.QueryRowContext(ctx, `SELECT user_id, session_id, finish_time, quiz_mode FROM quiz_sessions WHERE session_id = $1`, quizSessionID).Scan(&session.UserID, &session.SessionID, &session.FinishTime, &session.QuizMode)
 	if err == sql.ErrNoRows {
 		return nil, ErrSessionNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
 	return &session, nil
 }
-func (p *PostgresStorage) GetQuizQuestionsStats(quizSessionID int) ([]models.QuestionStat, error) {
-	query := `select a.question_id, a.answer, a.correct from answers a
+func (p *PostgresStorage) GetQuizQuestionsStats(ctx context.Context, quizSessionID int) ([]models.QuestionStat, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	query := `select a.question_id, a.answer, a.correct, a.timed_out from answers a
 where a.session_id = $1`
-	rows, err := p.db.Query(query, quizSessionID)
+	rows, err := p.db.QueryContext(ctx, query, quizSessionID)
 	if err != nil {
 		return nil, err
 	}
 	var questionsStats []models.QuestionStat
 	for rows.Next() {
 		var qs models.QuestionStat
-		err = rows.Scan(&qs.QuestionID, &qs.Answer, &qs.IsCorrect)
+		err = rows.Scan(&qs.QuestionID, &qs.Answer, &qs.IsCorrect, &qs.TimedOut)
 		if err != nil {
 			return nil, err
 		}
@@ -165,12 +248,14 @@ where a.session_id = $1`
 	}
 	return questionsStats, nil
 }
-func (p *PostgresStorage) GetUserQuizStats(quizSessionID int) (*models.QuizStats, error) {
+func (p *PostgresStorage) GetUserQuizStats(ctx context.Context, quizSessionID int) (*models.QuizStats, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var quizStats models.QuizStats
-	err := p.db.QueryRow(`select quiz_mode, count(*) as total_questions, sum(CASE WHEN correct THEN 1 ELSE 0 END) as correct_answers from answers a
+	err := p.db.QueryRowContext(ctx, `select quiz_mode, count(*) as total_questions, sum(CASE WHEN correct THEN 1 ELSE 0 END) as correct_answers, sum(CASE WHEN timed_out THEN 1 ELSE 0 END) as timed_out from answers a
 join quiz_sessions s on a.session_id = s.session_id
 where a.session_id = $1
-group by quiz_mode`, quizSessionID).Scan(&quizStats.Mode, &quizStats.TotalQuestions, &quizStats.CorrectAnswers)
+group by quiz_mode`, quizSessionID).Scan(&quizStats.Mode, &quizStats.TotalQuestions, &quizStats.CorrectAnswers, &quizStats.TimedOut)
 	if err != nil {
 		return nil, err
 	}
@@ -179,36 +264,43 @@ group by quiz_mode`, quizSessionID).Scan(&quizStats.Mode, &quizStats.TotalQuesti
 	} else {
 		quizStats.Accuracy = 0
 	}
-	quizStats.Questions, err = p.GetQuizQuestionsStats(quizSessionID)
+	quizStats.Questions, err = p.GetQuizQuestionsStats(ctx, quizSessionID)
 	if err != nil {
 		return nil, err
 	}
 	return &quizStats, nil
 }
-func (p *PostgresStorage) FinishQuizSession(quizSessionID int) error {
-	_, err := p.db.Exec(`UPDATE quiz_sessions SET finish_time = now() WHERE session_id = $1`, quizSessionID)
+func (p *PostgresStorage) FinishQuizSession(ctx context.Context, quizSessionID int) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	// a repeated finish keeps the time of the first one
+	_, err := p.db.ExecContext(ctx, `UPDATE quiz_sessions SET finish_time = coalesce(finish_time, now()) WHERE session_id = $1`, quizSessionID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (p *PostgresStorage) SaveSurveyResponse(response *models.SurveyResponse) error {
+func (p *PostgresStorage) SaveSurveyResponse(ctx context.Context, response *models.SurveyResponse) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	query := `INSERT INTO users_surveys 
     			(user_id, gender, age, vision_defect, education, experience, country, name, surname)
 				values ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-	_, err := p.db.Exec(query, response.UserID, response.Gender, response.Age, response.VisionDefect, response.Education, response.Experience, response.Country, response.Name, response.Surname)
+	_, err := p.db.ExecContext(ctx, query, response.UserID, response.Gender, response.Age, response.VisionDefect, response.Education, response.Experience, response.Country, response.Name, response.Surname)
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func (p *PostgresStorage) GetSurveyResponseForUser(userID int) (*models.SurveyResponse, error) {
+func (p *PostgresStorage) GetSurveyResponseForUser(ctx context.Context, userID int) (*models.SurveyResponse, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	query := `SELECT user_id, gender, age, vision_defect, education, experience, country, name, surname 
               FROM users_surveys
               WHERE user_id = $1`
 	var surveyResponse models.SurveyResponse
-	err := p.db.QueryRow(query, userID).Scan(
+	err := p.db.QueryRowContext(ctx, query, userID).Scan(
 		&surveyResponse.UserID,
 		&surveyResponse.Gender,
 		&surveyResponse.Age,
@@ -225,12 +317,14 @@ func (p *PostgresStorage) GetSurveyResponseForUser(userID int) (*models.SurveyRe
 	return &surveyResponse, nil
 }
 
-func (p *PostgresStorage) GetAllSurveyResponses() ([]models.SurveyResponse, error) {
+func (p *PostgresStorage) GetAllSurveyResponses(ctx context.Context) ([]models.SurveyResponse, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	query := `SELECT user_id, gender, age, vision_defect, education, experience, country, name, surname 
               FROM users_surveys
               ORDER BY user_id`
 
-	rows, err := p.db.Query(query)
+	rows, err := p.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -262,31 +356,43 @@ func (p *PostgresStorage) GetAllSurveyResponses() ([]models.SurveyResponse, erro
 
 	return surveys, nil
 }
-func (p *PostgresStorage) GetAllResponses() ([]models.QuestionResponse, error) {
-	query := `SELECT id, user_id, question_id, answer, correct, answer_time, answers.screen_size, answers.time_spent, answers.case_code FROM answers
+func (p *PostgresStorage) GetAllResponses(ctx context.Context, usersIDs []int) ([]models.QuestionResponse, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	query := `SELECT id, user_id, question_id, answers.question_version, answer, correct, answer_time, answers.screen_size, answers.time_spent, answers.case_code, answers.timed_out, answers.position, quiz_sessions.seed, answers.prediction_error, answers.score, answers.points, answers.mean_distance, answers.confidence FROM answers
     			join quiz_sessions on answers.session_id = quiz_sessions.session_id
+    			WHERE ($1::int[] IS NULL OR quiz_sessions.user_id = ANY($1))
                 order by answer_time desc;`
-	rows, err := p.db.Query(query)
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(usersIDs))
 	if err != nil {
 		return nil, err
 	}
 	var stats []models.QuestionResponse
 	for rows.Next() {
 		var stat models.QuestionResponse
-		err = rows.Scan(&stat.ID, &stat.UserID, &stat.QuestionID, &stat.Answer, &stat.IsCorrect, &stat.Time, &stat.ScreenSize, &stat.TimeSpent, &stat.CaseCode)
+		var points []byte
+		err = rows.Scan(&stat.ID, &stat.UserID, &stat.QuestionID, &stat.QuestionVersion, &stat.Answer, &stat.IsCorrect, &stat.Time, &stat.ScreenSize, &stat.TimeSpent, &stat.CaseCode, &stat.TimedOut, &stat.Position, &stat.Seed, &stat.PredictionError, &stat.Score, &points, &stat.MeanDistance, &stat.Confidence)
 		if err != nil {
 			return nil, err
 		}
+		if points != nil {
+			if err = json.Unmarshal(points, &stat.Points); err != nil {
+				return nil, err
+			}
+		}
 		stats = append(stats, stat)
 	}
 	return stats, nil
 }
-func (p *PostgresStorage) GetStatsForQuestion(id int) (models.QuestionAllStats, error) {
-	query := `SELECT question_id, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END) FROM answers
-				WHERE question_id = $1
+func (p *PostgresStorage) GetStatsForQuestion(ctx context.Context, id int, usersIDs []int) (models.QuestionAllStats, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	query := `SELECT question_id, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END), sum(CASE WHEN timed_out THEN 1 ELSE 0 END), avg(abs(prediction_error)) FROM answers
+				join quiz_sessions on answers.session_id = quiz_sessions.session_id
+				WHERE question_id = $1 AND ($2::int[] IS NULL OR quiz_sessions.user_id = ANY($2))
 				group by question_id`
 	var stats models.QuestionAllStats
-	err := p.db.QueryRow(query, id).Scan(&stats.QuestionID, &stats.Total, &stats.Correct)
+	err := p.db.QueryRowContext(ctx, query, id, pq.Array(usersIDs)).Scan(&stats.QuestionID, &stats.Total, &stats.Correct, &stats.TimedOut, &stats.MeanAbsoluteError)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.QuestionAllStats{}, nil
@@ -296,10 +402,14 @@ func (p *PostgresStorage) GetStatsForQuestion(id int) (models.QuestionAllStats,
 	return stats, nil
 }
 
-func (p *PostgresStorage) GetStatsForAllQuestions() ([]models.QuestionAllStats, error) {
-	query := `SELECT question_id, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END) FROM answers
+func (p *PostgresStorage) GetStatsForAllQuestions(ctx context.Context, usersIDs []int) ([]models.QuestionAllStats, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	query := `SELECT question_id, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END), sum(CASE WHEN timed_out THEN 1 ELSE 0 END), avg(abs(prediction_error)) FROM answers
+				join quiz_sessions on answers.session_id = quiz_sessions.session_id
+				WHERE $1::int[] IS NULL OR quiz_sessions.user_id = ANY($1)
 				group by question_id`
-	rows, err := p.db.Query(query)
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(usersIDs))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return []models.QuestionAllStats{}, nil
@@ -309,7 +419,7 @@ func (p *PostgresStorage) GetStatsForAllQuestions() ([]models.QuestionAllStats,
 	var stats []models.QuestionAllStats
 	for rows.Next() {
 		var stat models.QuestionAllStats
-		err = rows.Scan(&stat.QuestionID, &stat.Total, &stat.Correct)
+		err = rows.Scan(&stat.QuestionID, &stat.Total, &stat.Correct, &stat.TimedOut, &stat.MeanAbsoluteError)
 		if err != nil {
 			return nil, err
 		}
@@ -318,13 +428,48 @@ func (p *PostgresStorage) GetStatsForAllQuestions() ([]models.QuestionAllStats,
 	return stats, nil
 }
 
-func (p *PostgresStorage) GetActivityStats() ([]models.ActivityStats, error) {
+// GetLandmarkAnswers returns answers with placed landmarks to the question, newest first
+func (p *PostgresStorage) GetLandmarkAnswers(ctx context.Context, questionID int, usersIDs []int) ([]models.LandmarkAnswer, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	query := `SELECT answers.id, quiz_sessions.user_id, answers.question_version, answers.answer_time, answers.points,
+					 answers.mean_distance, coalesce(answers.score, 0) FROM answers
+				join quiz_sessions on answers.session_id = quiz_sessions.session_id
+				WHERE answers.question_id = $1 AND answers.points IS NOT NULL
+				  AND ($2::int[] IS NULL OR quiz_sessions.user_id = ANY($2))
+				order by answers.answer_time desc`
+	rows, err := p.db.QueryContext(ctx, query, questionID, pq.Array(usersIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	answers := make([]models.LandmarkAnswer, 0)
+	for rows.Next() {
+		var answer models.LandmarkAnswer
+		var points []byte
+		err = rows.Scan(&answer.ResponseID, &answer.UserID, &answer.QuestionVersion, &answer.Time, &points, &answer.MeanDistance, &answer.Score)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(points, &answer.Points); err != nil {
+			return nil, err
+		}
+		answers = append(answers, answer)
+	}
+	return answers, rows.Err()
+}
+
+func (p *PostgresStorage) GetActivityStats(ctx context.Context, usersIDs []int) ([]models.ActivityStats, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	query := `SELECT * FROM (SELECT date_trunc('day', answer_time) as date, count(*), sum(CASE WHEN correct THEN 1 ELSE 0 END) FROM answers
+				join quiz_sessions on answers.session_id = quiz_sessions.session_id
+				WHERE $1::int[] IS NULL OR quiz_sessions.user_id = ANY($1)
 				group by date_trunc('day', answer_time)
 				order by date_trunc('day', answer_time) desc
 				limit 10) as dcs ORDER BY date ASC`
 	var stats []models.ActivityStats
-	rows, err := p.db.Query(query)
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(usersIDs))
 	if err != nil {
 		return []models.ActivityStats{}, err
 	}
@@ -339,34 +484,42 @@ func (p *PostgresStorage) GetActivityStats() ([]models.ActivityStats, error) {
 	return stats, nil
 }
 
-func (p *PostgresStorage) CountQuizSessions() (int, error) {
+func (p *PostgresStorage) CountQuizSessions(ctx context.Context) (int, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var count int
-	err := p.db.QueryRow(`SELECT count(*) FROM quiz_sessions`).Scan(&count)
+	err := p.db.QueryRowContext(ctx, `SELECT count(*) FROM quiz_sessions`).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-func (p *PostgresStorage) CountAnswers() (int, error) {
+func (p *PostgresStorage) CountAnswers(ctx context.Context) (int, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var count int
-	err := p.db.QueryRow(`SELECT count(*) FROM answers`).Scan(&count)
+	err := p.db.QueryRowContext(ctx, `SELECT count(*) FROM answers`).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-func (p *PostgresStorage) CountCorrectAnswers() (int, error) {
+func (p *PostgresStorage) CountCorrectAnswers(ctx context.Context) (int, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var count int
-	err := p.db.QueryRow(`SELECT count(*) FROM answers WHERE correct = true`).Scan(&count)
+	err := p.db.QueryRowContext(ctx, `SELECT count(*) FROM answers WHERE correct = true`).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.SurveyGroupedStats, error) {
+func (p *PostgresStorage) GetStatsGroupedBySurveyField(ctx context.Context, field string, usersIDs []int) ([]models.SurveyGroupedStats, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	var query string
 	switch field {
 	case "gender":
@@ -378,7 +531,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
            LEFT JOIN answers a ON qs.session_id = a.session_id
-           WHERE us.gender IS NOT NULL
+           WHERE us.gender IS NOT NULL AND ($1::int[] IS NULL OR us.user_id = ANY($1))
            GROUP BY us.gender`
 	case "age":
 		query = `
@@ -389,7 +542,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
            LEFT JOIN answers a ON qs.session_id = a.session_id
-           WHERE us.age IS NOT NULL
+           WHERE us.age IS NOT NULL AND ($1::int[] IS NULL OR us.user_id = ANY($1))
            GROUP BY us.age`
 	case "vision_defect":
 		query = `
@@ -400,7 +553,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
            LEFT JOIN answers a ON qs.session_id = a.session_id
-           WHERE us.vision_defect IS NOT NULL
+           WHERE us.vision_defect IS NOT NULL AND ($1::int[] IS NULL OR us.user_id = ANY($1))
            GROUP BY us.vision_defect`
 	case "education":
 		query = `
@@ -411,7 +564,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
            LEFT JOIN answers a ON qs.session_id = a.session_id
-           WHERE us.education IS NOT NULL
+           WHERE us.education IS NOT NULL AND ($1::int[] IS NULL OR us.user_id = ANY($1))
            GROUP BY us.education`
 	case "experience":
 		query = `
@@ -422,7 +575,7 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
            LEFT JOIN answers a ON qs.session_id = a.session_id
-           WHERE us.experience IS NOT NULL
+           WHERE us.experience IS NOT NULL AND ($1::int[] IS NULL OR us.user_id = ANY($1))
            GROUP BY us.experience`
 	case "country":
 		query = `
@@ -433,13 +586,13 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
            FROM users_surveys us
            LEFT JOIN quiz_sessions qs ON us.user_id = qs.user_id
            LEFT JOIN answers a ON qs.session_id = a.session_id
-           WHERE us.country IS NOT NULL
+           WHERE us.country IS NOT NULL AND ($1::int[] IS NULL OR us.user_id = ANY($1))
            GROUP BY us.country`
 	default:
 		return nil, fmt.Errorf("unsupported field: %s", field)
 	}
 
-	rows, err := p.db.Query(query)
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(usersIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -461,21 +614,27 @@ func (p *PostgresStorage) GetStatsGroupedBySurveyField(field string) ([]models.S
 	return stats, nil
 }
 
-func (p *PostgresStorage) DeleteUserResponses(userId int) error {
-	_, err := p.db.Exec(`DELETE FROM answers WHERE session_id IN (SELECT session_id FROM quiz_sessions WHERE user_id = $1)`, userId)
+func (p *PostgresStorage) DeleteUserResponses(ctx context.Context, userId int) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, `DELETE FROM answers WHERE session_id IN (SELECT session_id FROM quiz_sessions WHERE user_id = $1)`, userId)
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func (p *PostgresStorage) DeleteResponse(id int) error {
-	_, err := p.db.Exec(`DELETE FROM answers where id = $1`, id)
+func (p *PostgresStorage) DeleteResponse(ctx context.Context, id int) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, err := p.db.ExecContext(ctx, `DELETE FROM answers where id = $1`, id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func (p *PostgresStorage) GetAllUsersStats() ([]models.UserQuizStats, error) {
+func (p *PostgresStorage) GetAllUsersStats(ctx context.Context, usersIDs []int) ([]models.UserQuizStats, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
 	query := `
         SELECT s.user_id,
                COUNT(*) as total_answers,
@@ -485,9 +644,10 @@ func (p *PostgresStorage) GetAllUsersStats() ([]models.UserQuizStats, error) {
         FROM answers a
         JOIN quiz_sessions s ON a.session_id = s.session_id
         LEFT JOIN users_surveys us ON s.user_id = us.user_id
+        WHERE $1::int[] IS NULL OR s.user_id = ANY($1)
         GROUP BY s.user_id, us.experience, us.education`
 
-	rows, err := p.db.Query(query)
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(usersIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -507,3 +667,160 @@ func (p *PostgresStorage) GetAllUsersStats() ([]models.UserQuizStats, error) {
 	}
 	return stats, nil
 }
+
+// GetAnswerOutcomes returns answers saved after the answer afterID in the order they were saved
+func (p *PostgresStorage) GetAnswerOutcomes(ctx context.Context, afterID int) ([]models.AnswerOutcome, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	query := `
+        SELECT a.id, s.user_id, a.question_id, a.correct
+        FROM answers a
+        JOIN quiz_sessions s ON a.session_id = s.session_id
+        WHERE a.id > $1
+        ORDER BY a.id`
+
+	rows, err := p.db.QueryContext(ctx, query, afterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var outcomes []models.AnswerOutcome
+	for rows.Next() {
+		var outcome models.AnswerOutcome
+		if err := rows.Scan(&outcome.ID, &outcome.UserID, &outcome.QuestionID, &outcome.Correct); err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes, rows.Err()
+}
+
+// GetUserIncorrectQuestions returns questions with at least one incorrect answer of the user outside review sessions,
+// the most recently missed first. Answers in review sessions are scheduled by the quiz service itself
+func (p *PostgresStorage) GetUserIncorrectQuestions(ctx context.Context, userID int) ([]models.IncorrectQuestion, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	query := `
+        SELECT a.question_id, max(a.answer_time)
+        FROM answers a
+        JOIN quiz_sessions s ON a.session_id = s.session_id
+        WHERE s.user_id = $1 AND NOT a.correct AND s.quiz_mode <> $2
+        GROUP BY a.question_id
+        ORDER BY max(a.answer_time) DESC, a.question_id`
+
+	rows, err := p.db.QueryContext(ctx, query, userID, models.QuizModeReview)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	questions := make([]models.IncorrectQuestion, 0)
+	for rows.Next() {
+		var question models.IncorrectQuestion
+		if err := rows.Scan(&question.QuestionID, &question.MissedAt); err != nil {
+			return nil, err
+		}
+		questions = append(questions, question)
+	}
+	return questions, rows.Err()
+}
+
+// GetSessionsStats returns the number of answers and correct answers of the sessions, without per question stats
+func (p *PostgresStorage) GetSessionsStats(ctx context.Context, sessionsIDs []int) ([]*models.QuizStats, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	query := `
+        SELECT s.session_id, s.quiz_mode,
+               COUNT(*) as total_questions,
+               SUM(CASE WHEN correct THEN 1 ELSE 0 END) as correct_answers,
+               SUM(CASE WHEN timed_out THEN 1 ELSE 0 END) as timed_out,
+               MIN(a.answer_time) as start_time
+        FROM quiz_sessions s
+        JOIN answers a ON s.session_id = a.session_id
+        WHERE s.session_id = ANY($1)
+        GROUP BY s.session_id, s.quiz_mode
+        ORDER BY s.session_id`
+
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(sessionsIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]*models.QuizStats, 0, len(sessionsIDs))
+	for rows.Next() {
+		stat := &models.QuizStats{}
+		err = rows.Scan(&stat.SessionID, &stat.Mode, &stat.TotalQuestions, &stat.CorrectAnswers, &stat.TimedOut, &stat.StartTime)
+		if err != nil {
+			return nil, err
+		}
+		if stat.TotalQuestions != 0 {
+			stat.Accuracy = float64(stat.CorrectAnswers) / float64(stat.TotalQuestions)
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// surveyFields are the columns of users_surveys answers can be grouped by
+var surveyFields = []string{"gender", "age", "vision_defect", "education", "experience", "country"}
+
+func (p *PostgresStorage) GetUserConfidenceOutcomes(ctx context.Context, userID int) ([]models.ConfidenceOutcome, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	query := `
+        SELECT a.confidence, a.correct
+        FROM answers a
+        JOIN quiz_sessions s ON a.session_id = s.session_id
+        WHERE s.user_id = $1 AND a.confidence IS NOT NULL`
+
+	rows, err := p.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	outcomes := make([]models.ConfidenceOutcome, 0)
+	for rows.Next() {
+		var outcome models.ConfidenceOutcome
+		if err := rows.Scan(&outcome.Confidence, &outcome.Correct); err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes, rows.Err()
+}
+
+// GetConfidenceOutcomesBySurveyField returns rated answers keyed by the survey answer of their users
+func (p *PostgresStorage) GetConfidenceOutcomesBySurveyField(ctx context.Context, field string, usersIDs []int) (map[string][]models.ConfidenceOutcome, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	if !slices.Contains(surveyFields, field) {
+		return nil, fmt.Errorf("unsupported field: %s", field)
+	}
+	// field is one of surveyFields, so it is safe to format into the query
+	query := fmt.Sprintf(`
+        SELECT us.%[1]s, a.confidence, a.correct
+        FROM users_surveys us
+        JOIN quiz_sessions s ON us.user_id = s.user_id
+        JOIN answers a ON s.session_id = a.session_id
+        WHERE us.%[1]s IS NOT NULL AND a.confidence IS NOT NULL AND ($1::int[] IS NULL OR us.user_id = ANY($1))`, field)
+
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(usersIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	outcomes := make(map[string][]models.ConfidenceOutcome)
+	for rows.Next() {
+		var value string
+		var outcome models.ConfidenceOutcome
+		if err := rows.Scan(&value, &outcome.Confidence, &outcome.Correct); err != nil {
+			return nil, err
+		}
+		outcomes[value] = append(outcomes[value], outcome)
+	}
+	return outcomes, rows.Err()
+}